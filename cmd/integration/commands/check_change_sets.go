@@ -0,0 +1,217 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/types/accounts"
+
+	"github.com/erigontech/erigon/cmd/hack/tool/fromdb"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/debug"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+var cmdCheckChangeSets = &cobra.Command{
+	Use:     "check_change_sets",
+	Short:   "Re-execute blocks and compare keys changed by execution with keys stored in state history (changesets) of each block",
+	Example: "go run ./cmd/integration check_change_sets --datadir=<datadir> --from=1000000 --to=1001000 --failFast=false",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := debug.SetupCobra(cmd, "integration")
+		db, err := openDB(dbCfg(kv.ChainDB, chaindata), false, logger)
+		if err != nil {
+			logger.Error("Opening DB", "error", err)
+			return
+		}
+		defer db.Close()
+
+		if err := checkChangeSets(cmd.Context(), db, verifyFrom, verifyTo, verifyFailFast, logger); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+	},
+}
+
+func init() {
+	withDataDir(cmdCheckChangeSets)
+	withChain(cmdCheckChangeSets)
+	withHeimdall(cmdCheckChangeSets)
+	cmdCheckChangeSets.Flags().Uint64Var(&verifyFrom, "from", 1, "first block to check")
+	cmdCheckChangeSets.Flags().Uint64Var(&verifyTo, "to", 0, "last block to check (inclusive). 0 means: until last executed block")
+	cmdCheckChangeSets.Flags().BoolVar(&verifyFailFast, "failFast", true, "stop after 1st divergent block or print WARN log and continue")
+	rootCmd.AddCommand(cmdCheckChangeSets)
+}
+
+func checkChangeSets(ctx context.Context, db kv.TemporalRwDB, from, to uint64, failFast bool, logger log.Logger) error {
+	if from == 0 {
+		from = 1 // genesis is not executed
+	}
+	chainConfig := fromdb.ChainConfig(db)
+	br, _ := blocksIO(db, logger)
+	engine, _ := initConsensusEngine(ctx, chainConfig, datadir.New(datadirCli).DataDir, db, br, logger)
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, br))
+
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if to == 0 {
+		if to, err = stages.GetStageProgress(tx, stages.Execution); err != nil {
+			return err
+		}
+	}
+
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	var divergentBlocks int
+	for blockNum := from; blockNum <= to; blockNum++ {
+		stateWriter, minTxNum, maxTxNum, err := reExecuteBlock(ctx, tx, br, txNumsReader, engine, chainConfig, blockNum, logger)
+		if err != nil {
+			return err
+		}
+		divergences, err := changeSetDivergences(tx, stateWriter, minTxNum, maxTxNum+1)
+		if err != nil {
+			return err
+		}
+		for _, d := range divergences {
+			logger.Warn("[check_change_sets] divergence", "block", blockNum, "domain", d.domain, "key", fmt.Sprintf("%x", d.key), "reason", d.reason)
+		}
+		if len(divergences) > 0 {
+			if failFast {
+				return fmt.Errorf("block %d: %d changeset divergences", blockNum, len(divergences))
+			}
+			divergentBlocks++
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-logEvery.C:
+			logger.Info("[check_change_sets]", "block", blockNum, "to", to, "divergentBlocks", divergentBlocks)
+		default:
+		}
+	}
+	if divergentBlocks > 0 {
+		return fmt.Errorf("check_change_sets: %d blocks of [%d, %d] have divergences", divergentBlocks, from, to)
+	}
+	logger.Info("[check_change_sets] done", "from", from, "to", to)
+	return nil
+}
+
+type changeSetDivergence struct {
+	domain kv.Domain
+	key    []byte
+	reason string
+}
+
+// changeSetDivergences - compares keys changed by execution with keys of stored changeset of [fromTxNum, toTxNum),
+// and values written by execution with values of history after the block. History after the block is previous value
+// stored by next changeset of the key (or latest value): so wrong previous values of changesets are found by check
+// of block which changed the key before. Keys written with unchanged value may be or may not be in stored changeset.
+// Storage and code of deleted accounts are removed without passing through StateWriter - so such storage and code keys are skipped.
+func changeSetDivergences(tx kv.TemporalTx, w *verifyStateWriter, fromTxNum, toTxNum uint64) ([]changeSetDivergence, error) {
+	executed := map[kv.Domain]map[string][]byte{
+		kv.AccountsDomain: {},
+		kv.StorageDomain:  {},
+		kv.CodeDomain:     {},
+	}
+	for addr, acc := range w.accounts {
+		var v []byte
+		if acc != nil {
+			v = accounts.SerialiseV3(acc)
+		}
+		executed[kv.AccountsDomain][string(addr[:])] = v
+	}
+	for k, v := range w.storage {
+		executed[kv.StorageDomain][string(append(k.addr[:], k.key[:]...))] = v.Bytes()
+	}
+	for addr, code := range w.code {
+		executed[kv.CodeDomain][string(addr[:])] = code
+	}
+
+	var res []changeSetDivergence
+	for _, domain := range []kv.Domain{kv.AccountsDomain, kv.StorageDomain, kv.CodeDomain} {
+		stored := map[string]struct{}{}
+		it, err := tx.HistoryRange(domain, int(fromTxNum), int(toTxNum), order.Asc, -1)
+		if err != nil {
+			return nil, err
+		}
+		for it.HasNext() {
+			k, _, err := it.Next()
+			if err != nil {
+				it.Close()
+				return nil, err
+			}
+			stored[string(k)] = struct{}{}
+			if _, ok := executed[domain][string(k)]; ok {
+				continue
+			}
+			if domain == kv.StorageDomain || domain == kv.CodeDomain {
+				if _, ok := w.deleted[libcommon.BytesToAddress(k[:length.Addr])]; ok {
+					continue
+				}
+			}
+			res = append(res, changeSetDivergence{domain: domain, key: libcommon.CopyBytes(k), reason: "stored, but not changed by execution"})
+		}
+		it.Close()
+
+		for k, v := range executed[domain] {
+			if _, ok := stored[k]; !ok {
+				prev, _, err := tx.GetAsOf(domain, []byte(k), fromTxNum)
+				if err != nil {
+					return nil, err
+				}
+				if !bytes.Equal(prev, v) {
+					res = append(res, changeSetDivergence{domain: domain, key: []byte(k), reason: "changed by execution, but not stored"})
+					continue
+				}
+			}
+			if domain == kv.StorageDomain || domain == kv.CodeDomain {
+				if _, ok := w.deleted[libcommon.BytesToAddress([]byte(k[:length.Addr]))]; ok {
+					continue
+				}
+			}
+			after, _, err := tx.GetAsOf(domain, []byte(k), toTxNum)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(after, v) {
+				res = append(res, changeSetDivergence{domain: domain, key: []byte(k), reason: fmt.Sprintf("value after block: executed=%x, stored=%x", v, after)})
+			}
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,177 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/state"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+	"github.com/erigontech/erigon/turbo/stages/mock"
+)
+
+// selfDestructChain - block 1 deploys contract with 1 storage slot, which self-destructs in block 2 when called
+func selfDestructChain(t *testing.T) (*mock.MockSentry, libcommon.Address) {
+	t.Helper()
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.LatestSignerForChainID(nil)
+		gspec   = &types.Genesis{
+			Config: params.TestChainConfig, // pre-Cancun: SELFDESTRUCT deletes account with its code and storage
+			Alloc:  types.GenesisAlloc{address: {Balance: big.NewInt(1e18)}},
+		}
+		// SSTORE(0, 1); return runtime code: SELFDESTRUCT(CALLER)
+		initCode = hexutil.MustDecode("0x600160005561" + "33ff" + "600052" + "6002601ef3")
+		contract libcommon.Address
+	)
+	m := mock.MockWithGenesis(t, gspec, key, false)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 2, func(i int, b *core.BlockGen) {
+		nonce := b.TxNonce(address)
+		var txn types.Transaction
+		switch i {
+		case 0:
+			txn = types.NewContractCreation(nonce, new(uint256.Int), 100_000, new(uint256.Int), initCode)
+			contract = crypto.CreateAddress(address, nonce)
+		case 1:
+			txn = types.NewTransaction(nonce, contract, new(uint256.Int), 100_000, new(uint256.Int), nil)
+		}
+		txn, err := types.SignTx(txn, *signer, key)
+		require.NoError(t, err)
+		b.AddTx(txn)
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(chain))
+	return m, contract
+}
+
+func TestCheckChangeSetsSelfDestruct(t *testing.T) {
+	ctx := context.Background()
+	m, contract := selfDestructChain(t)
+	tx, err := m.DB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, m.BlockReader))
+
+	for blockNum := uint64(1); blockNum <= 2; blockNum++ {
+		require.NoError(t, verifyBlock(ctx, tx, m.BlockReader, txNumsReader, m.Engine, m.ChainConfig, blockNum, m.Log))
+
+		w, minTxNum, maxTxNum, err := reExecuteBlock(ctx, tx, m.BlockReader, txNumsReader, m.Engine, m.ChainConfig, blockNum, m.Log)
+		require.NoError(t, err)
+		divergences, err := changeSetDivergences(tx, w, minTxNum, maxTxNum+1)
+		require.NoError(t, err)
+		require.Empty(t, divergences, "block %d", blockNum)
+		if blockNum == 2 {
+			require.Contains(t, w.deleted, contract)
+		}
+	}
+}
+
+func TestCheckChangeSetsDivergence(t *testing.T) {
+	ctx := context.Background()
+	m, contract := selfDestructChain(t)
+	tx, err := m.DB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, m.BlockReader))
+
+	// execution which "forgot" to write contract's storage: stored changeset has key execution didn't change
+	w, minTxNum, maxTxNum, err := reExecuteBlock(ctx, tx, m.BlockReader, txNumsReader, m.Engine, m.ChainConfig, 1, m.Log)
+	require.NoError(t, err)
+	for k := range w.storage {
+		delete(w.storage, k)
+	}
+	divergences, err := changeSetDivergences(tx, w, minTxNum, maxTxNum+1)
+	require.NoError(t, err)
+	require.Len(t, divergences, 1)
+	require.Equal(t, contract[:], divergences[0].key[:20])
+	require.Equal(t, "stored, but not changed by execution", divergences[0].reason)
+
+	// re-executed state differs from stored one
+	w, _, _, err = reExecuteBlock(ctx, tx, m.BlockReader, txNumsReader, m.Engine, m.ChainConfig, 1, m.Log)
+	require.NoError(t, err)
+	for k := range w.storage {
+		w.storage[k] = *uint256.NewInt(2)
+	}
+	afterReader := state.NewHistoryReaderV3()
+	afterReader.SetTx(tx)
+	afterReader.SetTxNum(maxTxNum + 1)
+	require.ErrorContains(t, w.compare(afterReader), "executed=0x2, stored=0x1")
+}
+
+func TestCheckChangeSetsWrongPrevValue(t *testing.T) {
+	ctx := context.Background()
+	m, contract := selfDestructChain(t)
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, m.BlockReader))
+
+	// changeset of block 2 keeps right key of contract's storage, but wrong previous value: 0x05 instead of 0x01
+	slot := append(contract.Bytes(), make([]byte, length.Hash)...)
+	require.NoError(t, m.DB.Update(ctx, func(tx kv.RwTx) error {
+		minTxNum, err := txNumsReader.Min(tx, 2)
+		if err != nil {
+			return err
+		}
+		c, err := tx.RwCursorDupSort(kv.TblStorageHistoryVals)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		for k, v, err := c.SeekExact(slot); k != nil; k, v, err = c.NextDup() {
+			if err != nil {
+				return err
+			}
+			if txNum := binary.BigEndian.Uint64(v); txNum >= minTxNum {
+				require.Equal(t, []byte{1}, v[8:])
+				if err := c.DeleteCurrent(); err != nil {
+					return err
+				}
+				return tx.Put(kv.TblStorageHistoryVals, slot, append(libcommon.Copy(v[:8]), 5))
+			}
+		}
+		return errors.New("no changeset of contract's storage in block 2")
+	}))
+
+	tx, err := m.DB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	// keys of both blocks are still right, value is found by block which changed the slot before
+	w, minTxNum, maxTxNum, err := reExecuteBlock(ctx, tx, m.BlockReader, txNumsReader, m.Engine, m.ChainConfig, 1, m.Log)
+	require.NoError(t, err)
+	divergences, err := changeSetDivergences(tx, w, minTxNum, maxTxNum+1)
+	require.NoError(t, err)
+	require.Len(t, divergences, 1)
+	require.Equal(t, kv.StorageDomain, divergences[0].domain)
+	require.Equal(t, slot, divergences[0].key)
+	require.Equal(t, "value after block: executed=01, stored=05", divergences[0].reason)
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+
+	"github.com/erigontech/erigon/turbo/debug"
+)
+
+var (
+	inspectPrevCsvFile string
+	inspectPrefixLen   int
+	inspectTopPrefixes int
+)
+
+var cmdDbInspect = &cobra.Command{
+	Use:     "db_inspect",
+	Short:   "Print size and keys amount of each table, largest key prefixes and growth since previous run",
+	Example: "go run ./cmd/integration db_inspect --datadir=<datadir> --output.csv.file=now.csv --prev.csv.file=before.csv --prefix.len=20",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := debug.SetupCobra(cmd, "integration")
+		db, err := openDB(dbCfg(kv.ChainDB, chaindata), false, logger)
+		if err != nil {
+			logger.Error("Opening DB", "error", err)
+			return
+		}
+		defer db.Close()
+
+		if err := dbInspect(cmd.Context(), db, logger); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+	},
+}
+
+func init() {
+	withDataDir(cmdDbInspect)
+	withBucket(cmdDbInspect)
+	withOutputCsvFile(cmdDbInspect)
+	cmdDbInspect.Flags().StringVar(&inspectPrevCsvFile, "prev.csv.file", "", "csv produced by previous run of this command, to report growth")
+	cmdDbInspect.Flags().IntVar(&inspectPrefixLen, "prefix.len", 0, "if > 0: scan tables and report largest key prefixes of this length (slow)")
+	cmdDbInspect.Flags().IntVar(&inspectTopPrefixes, "prefix.top", 10, "amount of largest key prefixes to report per table")
+	rootCmd.AddCommand(cmdDbInspect)
+}
+
+type tableInspection struct {
+	table    string
+	size     uint64
+	keys     uint64
+	prefixes []prefixCount
+}
+
+type prefixCount struct {
+	prefix []byte
+	count  uint64
+}
+
+func dbInspect(ctx context.Context, db kv.RoDB, logger log.Logger) error {
+	prev, err := readTableInspections(inspectPrevCsvFile)
+	if err != nil {
+		return err
+	}
+
+	tables := make([]string, 0, len(db.AllTables()))
+	for table, cfg := range db.AllTables() {
+		if cfg.IsDeprecated {
+			continue
+		}
+		if bucket != "" && table != bucket {
+			continue
+		}
+		tables = append(tables, table)
+	}
+	slices.Sort(tables)
+
+	res := make([]tableInspection, 0, len(tables))
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		for _, table := range tables {
+			size, err := tx.BucketSize(table)
+			if err != nil {
+				return err
+			}
+			keys, err := tx.Count(table)
+			if err != nil {
+				return err
+			}
+			ti := tableInspection{table: table, size: size, keys: keys}
+			if inspectPrefixLen > 0 && keys > 0 {
+				if ti.prefixes, err = largestPrefixes(ctx, tx, table, inspectPrefixLen, inspectTopPrefixes, logger); err != nil {
+					return err
+				}
+			}
+			res = append(res, ti)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Table,SizeBytes,Size,Keys,SizeGrowthBytes,KeysGrowth\n")
+	for _, ti := range res {
+		var sizeGrowth, keysGrowth int64
+		if p, ok := prev[ti.table]; ok {
+			sizeGrowth, keysGrowth = int64(ti.size)-int64(p.size), int64(ti.keys)-int64(p.keys)
+		}
+		sb.WriteString(fmt.Sprintf("%s,%d,%s,%d,%d,%d\n", ti.table, ti.size, libcommon.ByteCount(ti.size), ti.keys, sizeGrowth, keysGrowth))
+	}
+	for _, ti := range res {
+		for _, p := range ti.prefixes {
+			logger.Info("largest prefix", "table", ti.table, "prefix", fmt.Sprintf("%x", p.prefix), "keys", p.count)
+		}
+	}
+
+	if outputCsvFile == "" {
+		logger.Info("db inspect", "csv", sb.String())
+		return nil
+	}
+	if err := os.WriteFile(outputCsvFile, []byte(sb.String()), 0644); err != nil { //nolint:gosec
+		return fmt.Errorf("issue writing output to file %s: %w", outputCsvFile, err)
+	}
+	logger.Info("wrote db inspect to csv output file", "file", outputCsvFile)
+	return nil
+}
+
+// largestPrefixes - keys are sorted, so all keys with same prefix are adjacent: count them in one pass and keep `top` largest groups
+func largestPrefixes(ctx context.Context, tx kv.Tx, table string, prefixLen, top int, logger log.Logger) ([]prefixCount, error) {
+	logEvery := time.NewTicker(30 * time.Second)
+	defer logEvery.Stop()
+
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var res []prefixCount
+	var cur prefixCount
+	flush := func() {
+		if cur.count == 0 {
+			return
+		}
+		res = append(res, cur)
+		if len(res) > 2*top {
+			slices.SortFunc(res, func(a, b prefixCount) int { return cmp.Compare(b.count, a.count) })
+			res = res[:top]
+		}
+	}
+	for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		p := k
+		if len(p) > prefixLen {
+			p = p[:prefixLen]
+		}
+		if cur.count > 0 && bytes.Equal(cur.prefix, p) {
+			cur.count++
+			continue
+		}
+		flush()
+		cur = prefixCount{prefix: libcommon.CopyBytes(p), count: 1}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-logEvery.C:
+			logger.Info("[db_inspect] scanning", "table", table, "prefix", fmt.Sprintf("%x", p))
+		default:
+		}
+	}
+	flush()
+	slices.SortFunc(res, func(a, b prefixCount) int { return cmp.Compare(b.count, a.count) })
+	if len(res) > top {
+		res = res[:top]
+	}
+	return res, nil
+}
+
+// readTableInspections - parses csv produced by db_inspect. Empty fileName means: no previous run.
+func readTableInspections(fileName string) (map[string]tableInspection, error) {
+	res := map[string]tableInspection{}
+	if fileName == "" {
+		return res, nil
+	}
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for i, line := range lines {
+		if i == 0 { // header
+			continue
+		}
+		fields := strings.Split(line, ",")
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("%s:%d: unexpected amount of fields: %d", fileName, i+1, len(fields))
+		}
+		size, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", fileName, i+1, err)
+		}
+		keys, err := strconv.ParseUint(fields[3], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", fileName, i+1, err)
+		}
+		res[fields[0]] = tableInspection{table: fields[0], size: size, keys: keys}
+	}
+	return res, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/memdb"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+// putKeys - puts `amount` keys of given prefix: prefix + 1 byte of index
+func putKeys(t *testing.T, db kv.RwDB, table string, prefix string, amount int) {
+	t.Helper()
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for i := 0; i < amount; i++ {
+			if err := tx.Put(table, append([]byte(prefix), byte(i)), []byte{1}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func TestLargestPrefixes(t *testing.T) {
+	db := memdb.NewTestDB(t, kv.ChainDB)
+	// more groups than 2*top: intermediate trimming must not drop largest ones
+	for i, amount := range []int{3, 7, 1, 9, 2, 5, 4, 8, 6} {
+		putKeys(t, db, kv.HeaderNumber, string(rune('a'+i))+"-", amount)
+	}
+	// 1-byte key is shorter than prefix: is own group
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error { return tx.Put(kv.HeaderNumber, []byte("d"), []byte{1}) }))
+
+	tx, err := db.BeginRo(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+	res, err := largestPrefixes(context.Background(), tx, kv.HeaderNumber, 2, 3, log.New())
+	require.NoError(t, err)
+	require.Equal(t, []prefixCount{{prefix: []byte("d-"), count: 9}, {prefix: []byte("h-"), count: 8}, {prefix: []byte("b-"), count: 7}}, res)
+
+	res, err = largestPrefixes(context.Background(), tx, kv.HeaderNumber, 1, 1, log.New())
+	require.NoError(t, err)
+	require.Equal(t, []prefixCount{{prefix: []byte("d"), count: 10}}, res)
+}
+
+func TestDbInspectGrowth(t *testing.T) {
+	defer func(b, out, prev string) { bucket, outputCsvFile, inspectPrevCsvFile = b, out, prev }(bucket, outputCsvFile, inspectPrevCsvFile)
+	dir := t.TempDir()
+	db := memdb.NewTestDB(t, kv.ChainDB)
+	bucket = kv.HeaderNumber
+
+	putKeys(t, db, kv.HeaderNumber, "a", 10)
+	outputCsvFile, inspectPrevCsvFile = filepath.Join(dir, "before.csv"), ""
+	require.NoError(t, dbInspect(context.Background(), db, log.New()))
+	before, err := readTableInspections(outputCsvFile)
+	require.NoError(t, err)
+	require.Len(t, before, 1)
+	require.Equal(t, uint64(10), before[kv.HeaderNumber].keys)
+	require.Positive(t, before[kv.HeaderNumber].size)
+
+	putKeys(t, db, kv.HeaderNumber, "b", 15)
+	outputCsvFile, inspectPrevCsvFile = filepath.Join(dir, "now.csv"), outputCsvFile
+	require.NoError(t, dbInspect(context.Background(), db, log.New()))
+	now, err := readTableInspections(outputCsvFile)
+	require.NoError(t, err)
+	require.Equal(t, uint64(25), now[kv.HeaderNumber].keys)
+
+	data, err := os.ReadFile(outputCsvFile)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	fields := strings.Split(lines[1], ",")
+	require.Equal(t, kv.HeaderNumber, fields[0])
+	require.Equal(t, "15", fields[5]) // KeysGrowth
+}
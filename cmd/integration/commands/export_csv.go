@@ -0,0 +1,289 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/types/accounts"
+
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/debug"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+var (
+	exportDomain     string
+	exportChangeSets bool
+	exportFrom       uint64
+	exportTo         uint64
+)
+
+var cmdExportCsv = &cobra.Command{
+	Use: "export_csv",
+	Short: `Export state domain (accounts, storage, code) into csv file with header row:
+- state as of end of block --to (default)
+- or with --changesets: every change made by blocks [--from, --to], with previous and new values`,
+	Example: "go run ./cmd/integration export_csv --datadir=<datadir> --domain=accounts --changesets --from=1000000 --to=1001000 --output.csv.file=accounts.csv",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := debug.SetupCobra(cmd, "integration")
+		if outputCsvFile == "" {
+			logger.Error("--output.csv.file is required")
+			return
+		}
+		domain, err := kv.String2Domain(exportDomain)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		db, err := openDB(dbCfg(kv.ChainDB, chaindata), false, logger)
+		if err != nil {
+			logger.Error("Opening DB", "error", err)
+			return
+		}
+		defer db.Close()
+
+		br, _ := blocksIO(db, logger)
+		txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(cmd.Context(), br))
+		if err := exportCsv(cmd.Context(), db, txNumsReader, domain, exportChangeSets, exportFrom, exportTo, outputCsvFile, logger); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+	},
+}
+
+func init() {
+	withDataDir(cmdExportCsv)
+	withOutputCsvFile(cmdExportCsv)
+	cmdExportCsv.Flags().StringVar(&exportDomain, "domain", kv.AccountsDomain.String(), "one of: accounts, storage, code")
+	cmdExportCsv.Flags().BoolVar(&exportChangeSets, "changesets", false, "export changes of each block in range, instead of state")
+	cmdExportCsv.Flags().Uint64Var(&exportFrom, "from", 0, "first block of range (only for --changesets)")
+	cmdExportCsv.Flags().Uint64Var(&exportTo, "to", 0, "last block of range (inclusive). 0 means: last executed block")
+	rootCmd.AddCommand(cmdExportCsv)
+}
+
+// exportCsvHeader - schema of exported rows: key columns, then value columns (twice for changesets: prev and new)
+func exportCsvHeader(domain kv.Domain, changeSets bool) ([]string, error) {
+	var keyCols, valCols []string
+	switch domain {
+	case kv.AccountsDomain:
+		keyCols, valCols = []string{"address"}, []string{"nonce", "balance", "code_hash", "incarnation"}
+	case kv.StorageDomain:
+		keyCols, valCols = []string{"address", "location"}, []string{"value"}
+	case kv.CodeDomain:
+		keyCols, valCols = []string{"address"}, []string{"code_hash", "code_size"}
+	default:
+		return nil, fmt.Errorf("export of domain %s is not supported", domain)
+	}
+	if !changeSets {
+		return append(keyCols, valCols...), nil
+	}
+	header := append([]string{"block_number"}, keyCols...)
+	for _, c := range valCols {
+		header = append(header, "prev_"+c)
+	}
+	for _, c := range valCols {
+		header = append(header, "new_"+c)
+	}
+	return header, nil
+}
+
+// exportCsvKey - key columns of row. Empty value columns mean: no value (account deleted, storage slot empty)
+func exportCsvKey(domain kv.Domain, k []byte) []string {
+	if domain == kv.StorageDomain {
+		return []string{fmt.Sprintf("0x%x", k[:length.Addr]), fmt.Sprintf("0x%x", k[length.Addr:])}
+	}
+	return []string{fmt.Sprintf("0x%x", k)}
+}
+
+func exportCsvValue(domain kv.Domain, v []byte) ([]string, error) {
+	switch domain {
+	case kv.AccountsDomain:
+		if len(v) == 0 {
+			return []string{"", "", "", ""}, nil
+		}
+		var a accounts.Account
+		if err := accounts.DeserialiseV3(&a, v); err != nil {
+			return nil, err
+		}
+		return []string{strconv.FormatUint(a.Nonce, 10), a.Balance.Dec(), a.CodeHash.Hex(), strconv.FormatUint(a.Incarnation, 10)}, nil
+	case kv.StorageDomain:
+		if len(v) == 0 {
+			return []string{""}, nil
+		}
+		return []string{fmt.Sprintf("0x%x", v)}, nil
+	case kv.CodeDomain:
+		if len(v) == 0 {
+			return []string{"", "0"}, nil
+		}
+		return []string{fmt.Sprintf("0x%x", crypto.Keccak256(v)), strconv.Itoa(len(v))}, nil
+	default:
+		return nil, fmt.Errorf("export of domain %s is not supported", domain)
+	}
+}
+
+func exportCsv(ctx context.Context, db kv.TemporalRwDB, txNumsReader rawdbv3.TxNumsReader, domain kv.Domain, changeSets bool, from, to uint64, fileName string, logger log.Logger) error {
+	header, err := exportCsvHeader(domain, changeSets)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if to == 0 {
+		if to, err = stages.GetStageProgress(tx, stages.Execution); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	bufWriter := bufio.NewWriterSize(f, 4*1024*1024)
+	w := csv.NewWriter(bufWriter)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+	var rows uint64
+	writeRow := func(row []string) error {
+		rows++
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-logEvery.C:
+			logger.Info("[export_csv]", "domain", domain, "rows", rows)
+		default:
+		}
+		return w.Write(row)
+	}
+
+	if changeSets {
+		if err := exportCsvChangeSets(tx, txNumsReader, domain, from, to, writeRow); err != nil {
+			return err
+		}
+	} else {
+		maxTxNum, err := txNumsReader.Max(tx, to)
+		if err != nil {
+			return err
+		}
+		it, err := tx.RangeAsOf(domain, nil, nil, maxTxNum+1, order.Asc, -1)
+		if err != nil {
+			return err
+		}
+		defer it.Close()
+		for it.HasNext() {
+			k, v, err := it.Next()
+			if err != nil {
+				return err
+			}
+			if len(v) == 0 {
+				continue
+			}
+			vals, err := exportCsvValue(domain, v)
+			if err != nil {
+				return err
+			}
+			if err := writeRow(append(exportCsvKey(domain, k), vals...)); err != nil {
+				return err
+			}
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	if err := bufWriter.Flush(); err != nil {
+		return err
+	}
+	logger.Info("[export_csv] done", "domain", domain, "rows", rows, "file", fileName)
+	return nil
+}
+
+// exportCsvChangeSets - stored history of block has previous values of all keys changed by block, new values are read as of next block
+func exportCsvChangeSets(tx kv.TemporalTx, txNumsReader rawdbv3.TxNumsReader, domain kv.Domain, from, to uint64, writeRow func([]string) error) error {
+	for blockNum := from; blockNum <= to; blockNum++ {
+		minTxNum, err := txNumsReader.Min(tx, blockNum)
+		if err != nil {
+			return err
+		}
+		maxTxNum, err := txNumsReader.Max(tx, blockNum)
+		if err != nil {
+			return err
+		}
+		it, err := tx.HistoryRange(domain, int(minTxNum), int(maxTxNum+1), order.Asc, -1)
+		if err != nil {
+			return err
+		}
+		for it.HasNext() {
+			k, prev, err := it.Next()
+			if err != nil {
+				it.Close()
+				return err
+			}
+			next, _, err := tx.GetAsOf(domain, k, maxTxNum+1)
+			if err != nil {
+				it.Close()
+				return err
+			}
+			prevVals, err := exportCsvValue(domain, prev)
+			if err != nil {
+				it.Close()
+				return err
+			}
+			nextVals, err := exportCsvValue(domain, next)
+			if err != nil {
+				it.Close()
+				return err
+			}
+			row := append([]string{strconv.FormatUint(blockNum, 10)}, exportCsvKey(domain, k)...)
+			row = append(append(row, prevVals...), nextVals...)
+			if err := writeRow(row); err != nil {
+				it.Close()
+				return err
+			}
+		}
+		it.Close()
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+func TestExportCsv(t *testing.T) {
+	ctx := context.Background()
+	m, contract := selfDestructChain(t)
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, m.BlockReader))
+	export := func(domain kv.Domain, changeSets bool, from, to uint64) string {
+		fileName := filepath.Join(t.TempDir(), domain.String()+".csv")
+		require.NoError(t, exportCsv(ctx, m.DB, txNumsReader, domain, changeSets, from, to, fileName, m.Log))
+		data, err := os.ReadFile(fileName)
+		require.NoError(t, err)
+		return string(data)
+	}
+	addr, location := fmt.Sprintf("0x%x", contract), "0x"+strings.Repeat("00", 32)
+
+	// hex fields are written as is: without quotes, with 0x prefix and leading zeros of fixed-size keys
+	require.Equal(t, "address,location,value\n"+
+		addr+","+location+",0x01\n",
+		export(kv.StorageDomain, false, 0, 1))
+	// slot of self-destructed contract has no value in state of block 2
+	require.Equal(t, "address,location,value\n", export(kv.StorageDomain, false, 0, 2))
+
+	// no value is empty field, not "0x"
+	require.Equal(t, "block_number,address,location,prev_value,new_value\n"+
+		"1,"+addr+","+location+",,0x01\n"+
+		"2,"+addr+","+location+",0x01,\n",
+		export(kv.StorageDomain, true, 1, 2))
+
+	// code is not exported: only its hash and size
+	rows, err := csv.NewReader(strings.NewReader(export(kv.CodeDomain, false, 0, 1))).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"address", "code_hash", "code_size"},
+		{addr, fmt.Sprintf("0x%x", crypto.Keccak256([]byte{0x33, 0xff})), "2"},
+	}, rows)
+
+	rows, err = csv.NewReader(strings.NewReader(export(kv.AccountsDomain, true, 2, 2))).ReadAll()
+	require.NoError(t, err)
+	require.Equal(t, []string{"block_number", "address",
+		"prev_nonce", "prev_balance", "prev_code_hash", "prev_incarnation",
+		"new_nonce", "new_balance", "new_code_hash", "new_incarnation"}, rows[0])
+	var deleted []string
+	for _, row := range rows[1:] {
+		require.Len(t, row, 10)
+		if row[1] == addr {
+			deleted = row
+		}
+	}
+	// all value columns of deleted account are empty
+	require.Equal(t, []string{"2", addr, "1", "0", fmt.Sprintf("0x%x", crypto.Keccak256([]byte{0x33, 0xff})), "1", "", "", "", ""}, deleted)
+}
@@ -0,0 +1,332 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	chain2 "github.com/erigontech/erigon-lib/chain"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/config3"
+	"github.com/erigontech/erigon-lib/kv"
+	kv2 "github.com/erigontech/erigon-lib/kv/mdbx"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/kv/temporal"
+	"github.com/erigontech/erigon-lib/log/v3"
+	libstate "github.com/erigontech/erigon-lib/state"
+
+	"github.com/erigontech/erigon/cmd/hack/tool/fromdb"
+	"github.com/erigontech/erigon/core/rawdb"
+	"github.com/erigontech/erigon/core/state"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/turbo/debug"
+	"github.com/erigontech/erigon/turbo/services"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+var (
+	fixtureAddresses []string
+	fixtureDatadir   string
+)
+
+// blockhashWindow - how many ancestors of block are reachable by BLOCKHASH opcode
+const blockhashWindow = 256
+
+var cmdExtractFixture = &cobra.Command{
+	Use: "extract_fixture",
+	Short: `Extract minimal datadir which is enough to re-execute or trace --block:
+- genesis header, chain config, block and headers of its 256 ancestors (for BLOCKHASH)
+- state as of beginning of block for --addresses, block's coinbase, senders, recipients, access lists, withdrawals
+  and system contracts of active forks (accounts, storage, code)
+Commitment is not extracted, so state root of block can't be checked on such datadir`,
+	Example: "go run ./cmd/integration extract_fixture --datadir=<datadir> --block=1000000 --addresses=0x...,0x... --output.datadir=/tmp/fixture",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := debug.SetupCobra(cmd, "integration")
+		if fixtureDatadir == "" {
+			logger.Error("--output.datadir is required")
+			return
+		}
+		if block == 0 {
+			logger.Error("--block is required")
+			return
+		}
+		addrs := make([]libcommon.Address, 0, len(fixtureAddresses))
+		for _, a := range fixtureAddresses {
+			if !libcommon.IsHexAddress(a) {
+				logger.Error("invalid address", "addr", a)
+				return
+			}
+			addrs = append(addrs, libcommon.HexToAddress(a))
+		}
+		db, err := openDB(dbCfg(kv.ChainDB, chaindata), false, logger)
+		if err != nil {
+			logger.Error("Opening DB", "error", err)
+			return
+		}
+		defer db.Close()
+		br, _ := blocksIO(db, logger)
+
+		if err := extractFixture(cmd.Context(), db, br, block, addrs, fixtureDatadir, logger); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+	},
+}
+
+func init() {
+	withDataDir(cmdExtractFixture)
+	withBlock(cmdExtractFixture)
+	cmdExtractFixture.Flags().StringSliceVar(&fixtureAddresses, "addresses", nil, "accounts to extract in addition to coinbase, senders and recipients of block")
+	cmdExtractFixture.Flags().StringVar(&fixtureDatadir, "output.datadir", "", "datadir to create, must not exist")
+	rootCmd.AddCommand(cmdExtractFixture)
+}
+
+func extractFixture(ctx context.Context, db kv.TemporalRwDB, br services.FullBlockReader, blockNum uint64, addrs []libcommon.Address, outDir string, logger log.Logger) error {
+	if _, err := os.Stat(outDir); err == nil {
+		return fmt.Errorf("output datadir %s already exists", outDir)
+	}
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, br))
+	chainConfig := fromdb.ChainConfig(db)
+
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	hash, ok, err := br.CanonicalHash(ctx, tx, blockNum)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("block %d not found", blockNum)
+	}
+	blk, senders, err := br.BlockWithSenders(ctx, tx, hash, blockNum)
+	if err != nil {
+		return err
+	}
+	if blk == nil {
+		return fmt.Errorf("block %d not found", blockNum)
+	}
+	genesis, err := br.HeaderByNumber(ctx, tx, 0)
+	if err != nil {
+		return err
+	}
+
+	// state before first tx of block is state as of last tx of previous block
+	minTxNum, err := txNumsReader.Min(tx, blockNum)
+	if err != nil {
+		return err
+	}
+	maxTxNum, err := txNumsReader.Max(tx, blockNum)
+	if err != nil {
+		return err
+	}
+
+	readSet := state.NewBlockReadSet(blk, senders)
+	for _, a := range systemContractsOfBlock(chainConfig, blk.HeaderNoCopy()) {
+		readSet.AddAccount(a)
+	}
+	for _, a := range addrs {
+		readSet.AddAccount(a)
+	}
+	addrs = readSet.Accounts
+
+	dirs := datadir.New(outDir)
+	outRawDB, err := kv2.New(kv.ChainDB, logger).Path(dirs.Chaindata).Open(ctx)
+	if err != nil {
+		return err
+	}
+	defer outRawDB.Close()
+	agg, err := libstate.NewAggregator2(ctx, dirs, config3.DefaultStepSize, outRawDB, logger)
+	if err != nil {
+		return err
+	}
+	defer agg.Close()
+	outDB, err := temporal.New(outRawDB, agg)
+	if err != nil {
+		return err
+	}
+	outTx, err := outDB.BeginTemporalRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer outTx.Rollback()
+
+	if err := extractFixtureBlocks(ctx, tx, br, outTx, genesis, blk, senders); err != nil {
+		return err
+	}
+	if err := rawdb.WriteChainConfig(outTx, genesis.Hash(), chainConfig); err != nil {
+		return err
+	}
+	genesisMaxTxNum, err := txNumsReader.Max(tx, 0)
+	if err != nil {
+		return err
+	}
+	if err := rawdbv3.TxNums.Append(outTx, 0, genesisMaxTxNum); err != nil {
+		return err
+	}
+	if blockNum > 1 {
+		if err := rawdbv3.TxNums.Append(outTx, blockNum-1, minTxNum-1); err != nil {
+			return err
+		}
+	}
+	if err := rawdbv3.TxNums.Append(outTx, blockNum, maxTxNum); err != nil {
+		return err
+	}
+
+	keys, err := extractFixtureState(ctx, tx, outTx, addrs, minTxNum, blockNum)
+	if err != nil {
+		return err
+	}
+
+	for _, stage := range []stages.SyncStage{stages.Headers, stages.BlockHashes, stages.Bodies, stages.Senders} {
+		if err := stages.SaveStageProgress(outTx, stage, blockNum); err != nil {
+			return err
+		}
+	}
+	if err := stages.SaveStageProgress(outTx, stages.Execution, blockNum-1); err != nil {
+		return err
+	}
+	if err := outTx.Commit(); err != nil {
+		return err
+	}
+	logger.Info("[extract_fixture] done", "block", blockNum, "accounts", len(addrs), "keys", keys, "datadir", dirs.DataDir)
+	return nil
+}
+
+// systemContractsOfBlock - accounts read and written by system calls of consensus engine before and after transactions of block
+func systemContractsOfBlock(chainConfig *chain2.Config, header *types.Header) []libcommon.Address {
+	var res []libcommon.Address
+	if chainConfig.IsCancun(header.Time) {
+		res = append(res, params.BeaconRootsAddress) // EIP-4788
+	}
+	if chainConfig.IsPrague(header.Time) {
+		res = append(res, params.HistoryStorageAddress, params.WithdrawalRequestAddress, params.ConsolidationRequestAddress) // EIP-2935, EIP-7002, EIP-7251
+	}
+	return res
+}
+
+// extractFixtureBlocks - copies genesis, block with senders and headers of ancestors, which BLOCKHASH may read
+func extractFixtureBlocks(ctx context.Context, tx kv.Tx, br services.FullBlockReader, outTx kv.RwTx, genesis *types.Header, blk *types.Block, senders []libcommon.Address) error {
+	writeHeader := func(h *types.Header) error {
+		if err := rawdb.WriteHeader(outTx, h); err != nil {
+			return err
+		}
+		if err := rawdb.WriteCanonicalHash(outTx, h.Hash(), h.Number.Uint64()); err != nil {
+			return err
+		}
+		td, err := rawdb.ReadTd(tx, h.Hash(), h.Number.Uint64())
+		if err != nil {
+			return err
+		}
+		if td == nil {
+			return nil
+		}
+		return rawdb.WriteTd(outTx, h.Hash(), h.Number.Uint64(), td)
+	}
+
+	if err := writeHeader(genesis); err != nil {
+		return err
+	}
+	blockNum := blk.NumberU64()
+	for n := max(blockNum, blockhashWindow) - blockhashWindow; n < blockNum; n++ {
+		if n == 0 {
+			continue
+		}
+		h, err := br.HeaderByNumber(ctx, tx, n)
+		if err != nil {
+			return err
+		}
+		if h == nil {
+			return fmt.Errorf("header %d not found", n)
+		}
+		if err := writeHeader(h); err != nil {
+			return err
+		}
+	}
+	if err := writeHeader(blk.HeaderNoCopy()); err != nil {
+		return err
+	}
+	if err := rawdb.WriteBody(outTx, blk.Hash(), blockNum, blk.Body()); err != nil {
+		return err
+	}
+	return rawdb.WriteSenders(outTx, blk.Hash(), blockNum, senders)
+}
+
+// extractFixtureState - copies accounts, code and storage of addrs as of txNum. All values are written at txNum-1,
+// so they are visible both as latest state and as history of block
+func extractFixtureState(ctx context.Context, tx kv.TemporalTx, outTx kv.TemporalRwTx, addrs []libcommon.Address, txNum, blockNum uint64) (keys int, err error) {
+	domains, err := libstate.NewSharedDomains(outTx, log.New())
+	if err != nil {
+		return 0, err
+	}
+	defer domains.Close()
+	domains.SetTxNum(txNum - 1)
+	domains.SetBlockNum(blockNum - 1)
+
+	for _, addr := range addrs {
+		for _, d := range []kv.Domain{kv.AccountsDomain, kv.CodeDomain} {
+			v, _, err := tx.GetAsOf(d, addr[:], txNum)
+			if err != nil {
+				return keys, err
+			}
+			if len(v) == 0 {
+				continue
+			}
+			if err := domains.DomainPut(d, addr[:], nil, v, nil, 0); err != nil {
+				return keys, err
+			}
+			keys++
+		}
+
+		to, _ := kv.NextSubtree(addr[:])
+		it, err := tx.RangeAsOf(kv.StorageDomain, addr[:], to, txNum, order.Asc, -1)
+		if err != nil {
+			return keys, err
+		}
+		for it.HasNext() {
+			k, v, err := it.Next()
+			if err != nil {
+				it.Close()
+				return keys, err
+			}
+			if len(v) == 0 {
+				continue
+			}
+			if err := domains.DomainPut(kv.StorageDomain, k[:length.Addr], k[length.Addr:], v, nil, 0); err != nil {
+				it.Close()
+				return keys, err
+			}
+			keys++
+		}
+		it.Close()
+	}
+	return keys, domains.Flush(ctx, outTx)
+}
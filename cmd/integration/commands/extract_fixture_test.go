@@ -0,0 +1,124 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/config3"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/mdbx"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/kv/temporal"
+	"github.com/erigontech/erigon-lib/log/v3"
+	libstate "github.com/erigontech/erigon-lib/state"
+
+	"github.com/erigontech/erigon/cmd/hack/tool/fromdb"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/ethconfig"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/polygon/heimdall"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+	"github.com/erigontech/erigon/turbo/stages/mock"
+)
+
+// TestExtractFixtureRoundTrip - block re-executed on extracted fixture writes the same state as on full datadir
+func TestExtractFixtureRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		signer   = types.LatestSignerForChainID(nil)
+		gspec    = &types.Genesis{Config: params.TestChainConfig, Alloc: types.GenesisAlloc{address: {Balance: big.NewInt(1e18)}}}
+		initCode = hexutil.MustDecode("0x6001600055" + "68" + "600054600101600055" + "600052" + "60096017f3") // SSTORE(0, 1); runtime code: SSTORE(0, SLOAD(0)+1)
+		contract libcommon.Address
+	)
+	m := mock.MockWithGenesis(t, gspec, key, false)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 3, func(i int, b *core.BlockGen) {
+		nonce := b.TxNonce(address)
+		var txn types.Transaction
+		if i == 0 {
+			txn = types.NewContractCreation(nonce, new(uint256.Int), 100_000, new(uint256.Int), initCode)
+			contract = crypto.CreateAddress(address, nonce)
+		} else {
+			txn = types.NewTransaction(nonce, contract, new(uint256.Int), 100_000, new(uint256.Int), nil)
+		}
+		txn, err := types.SignTx(txn, *signer, key)
+		require.NoError(t, err)
+		b.AddTx(txn)
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(chain))
+
+	const blockNum = 3
+	outDir := filepath.Join(t.TempDir(), "fixture")
+	require.NoError(t, extractFixture(ctx, m.DB, m.BlockReader, blockNum, nil, outDir, m.Log))
+
+	tx, err := m.DB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, m.BlockReader))
+	want, _, _, err := reExecuteBlock(ctx, tx, m.BlockReader, txNumsReader, m.Engine, m.ChainConfig, blockNum, m.Log)
+	require.NoError(t, err)
+	counter := want.storage[verifyStorageKey{addr: contract}]
+	require.Equal(t, uint64(3), counter.Uint64()) // 1 by constructor, incremented by blocks 2 and 3
+
+	dirs := datadir.New(outDir)
+	fixtureRawDB := mdbx.New(kv.ChainDB, log.New()).Path(dirs.Chaindata).MustOpen()
+	defer fixtureRawDB.Close()
+	agg, err := libstate.NewAggregator2(ctx, dirs, config3.DefaultStepSize, fixtureRawDB, log.New())
+	require.NoError(t, err)
+	defer agg.Close()
+	fixtureDB, err := temporal.New(fixtureRawDB, agg)
+	require.NoError(t, err)
+	fixtureTx, err := fixtureDB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer fixtureTx.Rollback()
+	freezingCfg := ethconfig.Defaults.Snapshot
+	fixtureBr := freezeblocks.NewBlockReader(freezeblocks.NewRoSnapshots(freezingCfg, dirs.Snap, 0, log.New()), heimdall.NewRoSnapshots(freezingCfg, dirs.Snap, 0, log.New()), nil, nil)
+	fixtureTxNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, fixtureBr))
+	got, _, _, err := reExecuteBlock(ctx, fixtureTx, fixtureBr, fixtureTxNumsReader, m.Engine, fromdb.ChainConfig(fixtureDB), blockNum, m.Log)
+	require.NoError(t, err)
+
+	require.Equal(t, want.accounts, got.accounts)
+	require.Equal(t, want.code, got.code)
+	require.Equal(t, want.storage, got.storage)
+	require.Equal(t, want.deleted, got.deleted)
+}
+
+func TestSystemContractsOfBlock(t *testing.T) {
+	cancun, prague := *params.TestChainConfig, *params.TestChainConfig
+	cancun.ShanghaiTime, cancun.CancunTime = big.NewInt(0), big.NewInt(0)
+	prague.ShanghaiTime, prague.CancunTime, prague.PragueTime = big.NewInt(0), big.NewInt(0), big.NewInt(0)
+	header := &types.Header{Number: big.NewInt(1), Time: 10}
+
+	require.Empty(t, systemContractsOfBlock(params.TestChainConfig, header))
+	require.Equal(t, []libcommon.Address{params.BeaconRootsAddress}, systemContractsOfBlock(&cancun, header))
+	require.Equal(t, []libcommon.Address{params.BeaconRootsAddress, params.HistoryStorageAddress, params.WithdrawalRequestAddress, params.ConsolidationRequestAddress},
+		systemContractsOfBlock(&prague, header))
+}
@@ -29,6 +29,7 @@ var (
 	chaindata                                string
 	databaseVerbosity                        int
 	referenceChaindata                       string
+	diffLimit, diffSample                    uint64
 	block, pruneTo, unwind                   uint64
 	unwindEvery                              uint64
 	batchSizeStr                             string
@@ -85,6 +86,11 @@ func withFile(cmd *cobra.Command) {
 	must(cmd.MarkFlagRequired("file"))
 }
 
+func withDiffLimits(cmd *cobra.Command) {
+	cmd.Flags().Uint64Var(&diffLimit, "limit", 0, "print at most this amount of differences per bucket, 0 - no limit")
+	cmd.Flags().Uint64Var(&diffSample, "sample", 1, "print only every N-th difference")
+}
+
 func withReferenceChaindata(cmd *cobra.Command) {
 	cmd.Flags().StringVar(&referenceChaindata, "chaindata.reference", "", "path to the 2nd (reference/etalon) db")
 	must(cmd.MarkFlagDirname("chaindata.reference"))
@@ -33,6 +33,7 @@ import (
 	libcommon "github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/backup"
+	"github.com/erigontech/erigon-lib/kv/kvbench"
 	mdbx2 "github.com/erigontech/erigon-lib/kv/mdbx"
 	"github.com/erigontech/erigon-lib/log/v3"
 
@@ -70,7 +71,7 @@ var cmdMdbxTopDup = &cobra.Command{
 }
 var cmdCompareBucket = &cobra.Command{
 	Use:   "compare_bucket",
-	Short: "compare bucket to the same bucket in '--chaindata.reference'",
+	Short: "compare bucket (all buckets if not set) to the same bucket in '--chaindata.reference'",
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx, _ := libcommon.RootContext()
 		logger := debug.SetupCobra(cmd, "integration")
@@ -139,10 +140,163 @@ var cmdFToMdbx = &cobra.Command{
 	},
 }
 
+var cmdMdbxToStream = &cobra.Command{
+	Use:   "mdbx_to_stream",
+	Short: "write consistent snapshot of '--chaindata' tables (or '--bucket') into '--file' ('-' for stdout) in db-independent format, node may keep running",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, _ := libcommon.RootContext()
+		logger := debug.SetupCobra(cmd, "integration")
+		err := mdbxToStream(ctx, chaindata, bucket, file, logger)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error(err.Error())
+			return
+		}
+	},
+}
+
+var cmdStreamToMdbx = &cobra.Command{
+	Use:   "stream_to_mdbx",
+	Short: "restore tables written by mdbx_to_stream from '--file' ('-' for stdin) into '--chaindata.to'",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, _ := libcommon.RootContext()
+		logger := debug.SetupCobra(cmd, "integration")
+		err := streamToMdbx(ctx, file, toChaindata, logger)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error(err.Error())
+			return
+		}
+	},
+}
+
+var (
+	deleteFromKey string
+	deleteToKey   string
+)
+
+var cmdMdbxDeleteRange = &cobra.Command{
+	Use:     "mdbx_delete_range",
+	Short:   "delete keys of '--bucket' in ['--from', '--to') (hex) by small transactions, so node's writes are not blocked for long",
+	Example: "go run ./cmd/integration mdbx_delete_range --datadir=<datadir> --bucket=Receipt --from=0000000000000000 --to=0000000000989680",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, _ := libcommon.RootContext()
+		logger := debug.SetupCobra(cmd, "integration")
+		from, err := hex.DecodeString(deleteFromKey)
+		if err != nil {
+			logger.Error("invalid --from", "err", err)
+			return
+		}
+		var to []byte
+		if deleteToKey != "" {
+			if to, err = hex.DecodeString(deleteToKey); err != nil {
+				logger.Error("invalid --to", "err", err)
+				return
+			}
+		}
+		db := mdbx2.New(kv.ChainDB, logger).Path(chaindata).Accede(true).WriteMap(dbWriteMap).MustOpen()
+		defer db.Close()
+
+		logEvery := time.NewTicker(20 * time.Second)
+		defer logEvery.Stop()
+		deleted, err := kv.DeleteRange(ctx, db, bucket, from, to, 100_000, func(deleted uint64, lastKey []byte) {
+			select {
+			case <-logEvery.C:
+				logger.Info("[mdbx_delete_range] progress", "table", bucket, "deleted", libcommon.PrettyCounter(deleted), "key", hex.EncodeToString(lastKey))
+			default:
+			}
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			logger.Error(err.Error())
+			return
+		}
+		logger.Info("[mdbx_delete_range] done", "table", bucket, "deleted", libcommon.PrettyCounter(deleted))
+	},
+}
+
+var compressCodec string
+
+var cmdMdbxCompress = &cobra.Command{
+	Use:     "mdbx_compress",
+	Short:   "rewrite all values of '--bucket' compressed by '--codec' in one tx. Node must be stopped",
+	Example: "go run ./cmd/integration mdbx_compress --datadir=<datadir> --bucket=BlockBody --codec=zstd",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, _ := libcommon.RootContext()
+		logger := debug.SetupCobra(cmd, "integration")
+		codec, err := mdbx2.ParseCodec(compressCodec)
+		if err != nil {
+			logger.Error(err.Error())
+			return
+		}
+		db := mdbx2.New(kv.ChainDB, logger).Path(chaindata).WriteMap(dbWriteMap).MustOpen()
+		defer db.Close()
+		if err := mdbx2.CompressTable(ctx, db, bucket, codec); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+		logger.Info("[mdbx_compress] done", "table", bucket, "codec", codec)
+	},
+}
+
+var cmdMdbxVerify = &cobra.Command{
+	Use:     "mdbx_verify",
+	Short:   "read every record of '--bucket' (all tables if empty) and print damaged key ranges: checksum mismatches, unsorted records, unreadable pages",
+	Example: "go run ./cmd/integration mdbx_verify --datadir=<datadir>",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, _ := libcommon.RootContext()
+		logger := debug.SetupCobra(cmd, "integration")
+		db := mdbx2.New(kv.ChainDB, logger).Path(chaindata).Accede(true).MustOpen()
+		defer db.Close()
+		var tables []string
+		if bucket != "" {
+			tables = []string{bucket}
+		}
+		damages, err := backup.Verify(ctx, db, tables, logger)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+		for _, d := range damages {
+			fmt.Printf("%s\n", d)
+		}
+		logger.Info("[mdbx_verify] done", "damaged_ranges", len(damages))
+	},
+}
+
+var benchOps int
+var benchSeed int64
+
+var cmdMdbxBench = &cobra.Command{
+	Use:     "mdbx_bench",
+	Short:   "run read workloads (sequential scan, random seek, random get) on '--bucket' and print results as json lines",
+	Example: "go run ./cmd/integration mdbx_bench --datadir=<datadir> --bucket=AccountChangeSet --ops=1000000",
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, _ := libcommon.RootContext()
+		logger := debug.SetupCobra(cmd, "integration")
+		db := mdbx2.New(kv.ChainDB, logger).Path(chaindata).Accede(true).MustOpen()
+		defer db.Close()
+		cfg := kvbench.DefaultConfig()
+		cfg.Ops, cfg.Seed = benchOps, benchSeed
+		results, err := kvbench.Run(ctx, db, []string{bucket}, cfg)
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+		if err := kvbench.WriteJSON(os.Stdout, results); err != nil {
+			logger.Error(err.Error())
+		}
+	},
+}
+
 func init() {
 	withDataDir(cmdCompareBucket)
 	withReferenceChaindata(cmdCompareBucket)
 	withBucket(cmdCompareBucket)
+	withDiffLimits(cmdCompareBucket)
 
 	rootCmd.AddCommand(cmdCompareBucket)
 
@@ -154,6 +308,7 @@ func init() {
 	withDataDir(cmdCompareStates)
 	withReferenceChaindata(cmdCompareStates)
 	withBucket(cmdCompareStates)
+	withDiffLimits(cmdCompareStates)
 
 	rootCmd.AddCommand(cmdCompareStates)
 
@@ -168,6 +323,82 @@ func init() {
 	withBucket(cmdFToMdbx)
 
 	rootCmd.AddCommand(cmdFToMdbx)
+
+	withDataDir(cmdMdbxToStream)
+	withFile(cmdMdbxToStream)
+	withBucket(cmdMdbxToStream)
+
+	rootCmd.AddCommand(cmdMdbxToStream)
+
+	withToChaindata(cmdStreamToMdbx)
+	withFile(cmdStreamToMdbx)
+
+	rootCmd.AddCommand(cmdStreamToMdbx)
+
+	withDataDir(cmdMdbxDeleteRange)
+	withBucket(cmdMdbxDeleteRange)
+	must(cmdMdbxDeleteRange.MarkFlagRequired("bucket"))
+	cmdMdbxDeleteRange.Flags().StringVar(&deleteFromKey, "from", "", "first key to delete, hex")
+	cmdMdbxDeleteRange.Flags().StringVar(&deleteToKey, "to", "", "key to stop at (not deleted), hex. Empty means end of table")
+
+	rootCmd.AddCommand(cmdMdbxDeleteRange)
+
+	withDataDir(cmdMdbxCompress)
+	withBucket(cmdMdbxCompress)
+	must(cmdMdbxCompress.MarkFlagRequired("bucket"))
+	cmdMdbxCompress.Flags().StringVar(&compressCodec, "codec", "zstd", "none, snappy or zstd")
+
+	rootCmd.AddCommand(cmdMdbxCompress)
+
+	withDataDir(cmdMdbxVerify)
+	withBucket(cmdMdbxVerify)
+
+	rootCmd.AddCommand(cmdMdbxVerify)
+
+	withDataDir(cmdMdbxBench)
+	withBucket(cmdMdbxBench)
+	must(cmdMdbxBench.MarkFlagRequired("bucket"))
+	cmdMdbxBench.Flags().IntVar(&benchOps, "ops", kvbench.DefaultConfig().Ops, "operations of each workload")
+	cmdMdbxBench.Flags().Int64Var(&benchSeed, "seed", kvbench.DefaultConfig().Seed, "seed of random workloads: same seed and same data - same operations")
+
+	rootCmd.AddCommand(cmdMdbxBench)
+}
+
+func mdbxToStream(ctx context.Context, chaindata, bucket, file string, logger log.Logger) error {
+	db := mdbx2.New(kv.ChainDB, logger).Path(chaindata).Accede(true).MustOpen()
+	defer db.Close()
+
+	var tables []string
+	if bucket != "" {
+		tables = []string{bucket}
+	}
+	if file == "-" {
+		return backup.Stream(ctx, db, tables, os.Stdout, logger)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := backup.Stream(ctx, db, tables, f, logger); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func streamToMdbx(ctx context.Context, file, toChaindata string, logger log.Logger) error {
+	db := mdbx2.New(kv.ChainDB, logger).Path(toChaindata).WriteMap(dbWriteMap).MustOpen()
+	defer db.Close()
+
+	if file == "-" {
+		return backup.Restore(ctx, db, os.Stdin, logger)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return backup.Restore(ctx, db, f, logger)
 }
 
 func mdbxTopDup(ctx context.Context, chaindata string, bucket string, logger log.Logger) error {
@@ -214,121 +445,44 @@ func mdbxTopDup(ctx context.Context, chaindata string, bucket string, logger log
 }
 
 func compareStates(ctx context.Context, chaindata string, referenceChaindata string) error {
-	db := mdbx2.MustOpen(chaindata)
-	defer db.Close()
-
-	refDB := mdbx2.MustOpen(referenceChaindata)
-	defer refDB.Close()
+	return compareDatabases(ctx, chaindata, referenceChaindata, stateBuckets)
+}
 
-	if err := db.View(context.Background(), func(tx kv.Tx) error {
-		if err := refDB.View(context.Background(), func(refTX kv.Tx) error {
-			for _, bucket := range stateBuckets {
-				fmt.Printf("\nBucket: %s\n", bucket)
-				if err := compareBuckets(ctx, tx, bucket, refTX, bucket); err != nil {
-					return err
-				}
-			}
-			return nil
-		}); err != nil {
-			return err
-		}
-		return nil
-	}); err != nil {
-		return err
+func compareBucketBetweenDatabases(ctx context.Context, chaindata string, referenceChaindata string, bucket string) error {
+	var tables []string // all tables if bucket is not set
+	if bucket != "" {
+		tables = []string{bucket}
 	}
-
-	return nil
+	return compareDatabases(ctx, chaindata, referenceChaindata, tables)
 }
-func compareBucketBetweenDatabases(ctx context.Context, chaindata string, referenceChaindata string, bucket string) error {
+
+// compareDatabases - prints each `--sample`-th difference, at most `--limit` per table, then exact counts per table
+func compareDatabases(ctx context.Context, chaindata string, referenceChaindata string, tables []string) error {
 	db := mdbx2.MustOpen(chaindata)
 	defer db.Close()
 
 	refDB := mdbx2.MustOpen(referenceChaindata)
 	defer refDB.Close()
 
-	if err := db.View(context.Background(), func(tx kv.Tx) error {
-		return refDB.View(context.Background(), func(refTX kv.Tx) error {
-			return compareBuckets(ctx, tx, bucket, refTX, bucket)
-		})
-	}); err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func compareBuckets(ctx context.Context, tx kv.Tx, b string, refTx kv.Tx, refB string) error {
-	count := 0
-	c, err := tx.Cursor(b)
-	if err != nil {
-		return err
-	}
-	defer c.Close()
-	k, v, e := c.First()
-	if e != nil {
-		return e
-	}
-	refC, err := refTx.Cursor(refB)
+	var lastTable string
+	var seen, printed uint64
+	stats, err := backup.Diff(ctx, db, refDB, tables, func(table string, kind backup.DiffKind, k, v, refV []byte) error {
+		if table != lastTable {
+			lastTable, seen, printed = table, 0, 0
+		}
+		seen++
+		if (diffSample > 1 && (seen-1)%diffSample != 0) || (diffLimit > 0 && printed >= diffLimit) {
+			return nil
+		}
+		printed++
+		fmt.Printf("%s: %s %x. db: [%x], refDB: [%x]\n", table, kind, k, v, refV)
+		return nil
+	}, log.Root())
 	if err != nil {
 		return err
 	}
-	defer refC.Close()
-	refK, refV, revErr := refC.First()
-	if revErr != nil {
-		return revErr
-	}
-	for k != nil || refK != nil {
-		count++
-		if count%10_000_000 == 0 {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
-			fmt.Printf("Compared %d records\n", count)
-		}
-		if k == nil {
-			fmt.Printf("Missing in db: %x [%x]\n", refK, refV)
-			refK, refV, revErr = refC.Next()
-			if revErr != nil {
-				return revErr
-			}
-		} else if refK == nil {
-			fmt.Printf("Missing refDB: %x [%x]\n", k, v)
-			k, v, e = c.Next()
-			if e != nil {
-				return e
-			}
-		} else {
-			switch bytes.Compare(k, refK) {
-			case -1:
-				fmt.Printf("Missing refDB: %x [%x]\n", k, v)
-				k, v, e = c.Next()
-				if e != nil {
-					return e
-				}
-			case 1:
-				fmt.Printf("Missing in db: %x [%x]\n", refK, refV)
-				refK, refV, revErr = refC.Next()
-				if revErr != nil {
-					return revErr
-				}
-			case 0:
-				if !bytes.Equal(v, refV) {
-					fmt.Printf("Different values for %x. db: [%x], refDB: [%x]\n", k, v, refV)
-				}
-				k, v, e = c.Next()
-				if e != nil {
-					return e
-				}
-				refK, refV, revErr = refC.Next()
-				if revErr != nil {
-					return revErr
-				}
-			default:
-				fmt.Printf("Unexpected result of bytes.Compare: %d\n", bytes.Compare(k, refK))
-			}
-		}
+	for _, s := range stats {
+		fmt.Printf("%s: equal=%d, missing=%d, extra=%d, different=%d\n", s.Table, s.Equal, s.Missing, s.Extra, s.Different)
 	}
 	return nil
 }
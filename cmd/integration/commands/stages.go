@@ -19,6 +19,7 @@ package commands
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"os"
@@ -405,7 +406,7 @@ var cmdPrintTableSizes = &cobra.Command{
 
 var cmdPrintMigrations = &cobra.Command{
 	Use:   "print_migrations",
-	Short: "",
+	Short: "print applied migrations and pending ones - which will be applied on next start (dry-run)",
 	Run: func(cmd *cobra.Command, args []string) {
 		logger := debug.SetupCobra(cmd, "integration")
 		db, err := openDB(dbCfg(kv.ChainDB, chaindata), false, logger)
@@ -414,7 +415,7 @@ var cmdPrintMigrations = &cobra.Command{
 			return
 		}
 		defer db.Close()
-		if err := printAppliedMigrations(db, cmd.Context(), logger); err != nil {
+		if err := printMigrations(db, cmd.Context(), logger); err != nil {
 			if !errors.Is(err, context.Canceled) {
 				logger.Error(err.Error())
 			}
@@ -1290,7 +1291,7 @@ func printAllStages(db kv.RoDB, ctx context.Context, logger log.Logger) error {
 	return db.View(ctx, func(tx kv.Tx) error { return printStages(tx, sn, borSn, agg) })
 }
 
-func printAppliedMigrations(db kv.RwDB, ctx context.Context, logger log.Logger) error {
+func printMigrations(db kv.RwDB, ctx context.Context, logger log.Logger) error {
 	return db.View(ctx, func(tx kv.Tx) error {
 		applied, err := migrations.AppliedMigrations(tx, false /* withPayload */)
 		if err != nil {
@@ -1304,6 +1305,18 @@ func printAppliedMigrations(db kv.RwDB, ctx context.Context, logger log.Logger)
 		}
 		slices.Sort(appliedStrs)
 		logger.Info("Applied", "migrations", strings.Join(appliedStrs, " "))
+
+		pending, err := migrations.NewMigrator(kv.ChainDB).PendingMigrations(tx)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending {
+			progress, err := migrations.MigrationProgress(tx, m.Name)
+			if err != nil {
+				return err
+			}
+			logger.Info("Pending", "migration", m.Name, "resume_from", hex.EncodeToString(progress))
+		}
 		return nil
 	})
 }
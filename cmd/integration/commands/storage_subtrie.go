@@ -0,0 +1,285 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/erigontech/erigon-lib/commitment"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+	libstate "github.com/erigontech/erigon-lib/state"
+	"github.com/erigontech/erigon-lib/trie"
+
+	"github.com/erigontech/erigon/turbo/debug"
+)
+
+var (
+	storageAddress string
+	storageFile    string
+)
+
+var cmdExportStorage = &cobra.Command{
+	Use: "export_storage",
+	Short: `Export storage subtrie of single contract as of latest state into json file:
+- account, code and storage keys with values
+- commitment branches of the storage subtrie
+- storage root, recomputed from commitment`,
+	Example: "go run ./cmd/integration export_storage --datadir=<datadir> --address=0x... --file=/tmp/storage.json",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := debug.SetupCobra(cmd, "integration")
+		if !libcommon.IsHexAddress(storageAddress) {
+			logger.Error("invalid address", "addr", storageAddress)
+			return
+		}
+		db, err := openDB(dbCfg(kv.ChainDB, chaindata), false, logger)
+		if err != nil {
+			logger.Error("Opening DB", "error", err)
+			return
+		}
+		defer db.Close()
+
+		if err := exportStorage(cmd.Context(), db, libcommon.HexToAddress(storageAddress), storageFile, logger); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+	},
+}
+
+var cmdImportStorage = &cobra.Command{
+	Use: "import_storage",
+	Short: `Import storage subtrie exported by export_storage into latest state of --datadir. Existing storage of the contract is replaced.
+Commitment is recomputed and import fails if the storage root of the account doesn't match the exported one.
+State root of latest block will not match its header anymore, so it's meant for test environments and targeted repairs`,
+	Example: "go run ./cmd/integration import_storage --datadir=<datadir> --file=/tmp/storage.json",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := debug.SetupCobra(cmd, "integration")
+		db, err := openDB(dbCfg(kv.ChainDB, chaindata), false, logger)
+		if err != nil {
+			logger.Error("Opening DB", "error", err)
+			return
+		}
+		defer db.Close()
+
+		if err := importStorage(cmd.Context(), db, storageFile, logger); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+	},
+}
+
+func init() {
+	withDataDir(cmdExportStorage)
+	cmdExportStorage.Flags().StringVar(&storageAddress, "address", "", "contract to export")
+	must(cmdExportStorage.MarkFlagRequired("address"))
+	cmdExportStorage.Flags().StringVar(&storageFile, "file", "", "json file to write")
+	must(cmdExportStorage.MarkFlagRequired("file"))
+	rootCmd.AddCommand(cmdExportStorage)
+
+	withDataDir(cmdImportStorage)
+	cmdImportStorage.Flags().StringVar(&storageFile, "file", "", "json file written by export_storage")
+	must(cmdImportStorage.MarkFlagRequired("file"))
+	rootCmd.AddCommand(cmdImportStorage)
+}
+
+type storageExport struct {
+	Address     libcommon.Address `json:"address"`
+	BlockNum    uint64            `json:"blockNum"`
+	StorageRoot libcommon.Hash    `json:"storageRoot"`
+	Account     hexutility.Bytes  `json:"account,omitempty"`
+	Code        hexutility.Bytes  `json:"code,omitempty"`
+	Storage     []storageKV       `json:"storage"`
+	Branches    []storageKV       `json:"branches"` // commitment domain keys and values
+}
+
+type storageKV struct {
+	Key   hexutility.Bytes `json:"key"`
+	Value hexutility.Bytes `json:"value"`
+}
+
+// flatStorageRoot - storage root computed from exported storage keys only
+func (e *storageExport) flatStorageRoot() libcommon.Hash {
+	tr := trie.New(libcommon.Hash{})
+	for _, s := range e.Storage {
+		tr.Update(crypto.Keccak256(s.Key[length.Addr:]), s.Value)
+	}
+	return tr.Hash()
+}
+
+func exportStorage(ctx context.Context, db kv.TemporalRwDB, addr libcommon.Address, file string, logger log.Logger) error {
+	// storage prefix iteration of SharedDomains requires RwTx, nothing is written
+	tx, err := db.BeginTemporalRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	domains, err := libstate.NewSharedDomains(tx, logger)
+	if err != nil {
+		return err
+	}
+	defer domains.Close()
+
+	e := &storageExport{Address: addr, BlockNum: domains.BlockNum()}
+	if e.Account, _, err = domains.GetLatest(kv.AccountsDomain, addr[:]); err != nil {
+		return err
+	}
+	if len(e.Account) == 0 {
+		return fmt.Errorf("account %x not found", addr)
+	}
+	if e.Code, _, err = domains.GetLatest(kv.CodeDomain, addr[:]); err != nil {
+		return err
+	}
+	if err := domains.IterateStoragePrefix(addr[:], func(k, v []byte, _ uint64) error {
+		if len(v) > 0 {
+			e.Storage = append(e.Storage, storageKV{Key: libcommon.Copy(k), Value: libcommon.Copy(v)})
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	root, found, err := storageSubtrie(domains, addr, func(key, branch []byte) error {
+		e.Branches = append(e.Branches, storageKV{Key: libcommon.Copy(key), Value: libcommon.Copy(branch)})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("account %x not found in commitment", addr)
+	}
+	e.StorageRoot = root
+	if flatRoot := e.flatStorageRoot(); flatRoot != root {
+		return fmt.Errorf("storage root of %x in commitment %x doesn't match root of flat storage %x", addr, root, flatRoot)
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		return err
+	}
+	logger.Info("[export_storage] done", "addr", addr, "block", e.BlockNum, "keys", len(e.Storage), "branches", len(e.Branches), "storageRoot", root, "file", file)
+	return nil
+}
+
+func importStorage(ctx context.Context, db kv.TemporalRwDB, file string, logger log.Logger) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	var e storageExport
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+	for _, s := range e.Storage {
+		if len(s.Key) != length.Addr+length.Hash || !bytes.Equal(s.Key[:length.Addr], e.Address[:]) {
+			return fmt.Errorf("storage key %x doesn't belong to %x", s.Key, e.Address)
+		}
+	}
+	if flatRoot := e.flatStorageRoot(); flatRoot != e.StorageRoot {
+		return fmt.Errorf("exported storage root %x doesn't match root of exported storage %x", e.StorageRoot, flatRoot)
+	}
+
+	tx, err := db.BeginTemporalRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	domains, err := libstate.NewSharedDomains(tx, logger)
+	if err != nil {
+		return err
+	}
+	defer domains.Close()
+
+	if len(e.Account) > 0 {
+		if err := domains.DomainPut(kv.AccountsDomain, e.Address[:], nil, e.Account, nil, 0); err != nil {
+			return err
+		}
+	}
+	if len(e.Code) > 0 {
+		if err := domains.DomainPut(kv.CodeDomain, e.Address[:], nil, e.Code, nil, 0); err != nil {
+			return err
+		}
+	}
+	if err := domains.DomainDelPrefix(kv.StorageDomain, e.Address[:]); err != nil {
+		return err
+	}
+	for _, b := range e.Branches {
+		if err := domains.DomainPut(kv.CommitmentDomain, b.Key, nil, b.Value, nil, 0); err != nil {
+			return err
+		}
+	}
+	for _, s := range e.Storage {
+		if err := domains.DomainPut(kv.StorageDomain, s.Key[:length.Addr], s.Key[length.Addr:], s.Value, nil, 0); err != nil {
+			return err
+		}
+	}
+	stateRoot, err := domains.ComputeCommitment(ctx, true, domains.BlockNum(), "import_storage")
+	if err != nil {
+		return err
+	}
+
+	root, found, err := storageSubtrie(domains, e.Address, nil)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("account %x not found in commitment after import", e.Address)
+	}
+	if root != e.StorageRoot {
+		return fmt.Errorf("storage root of %x after import %x doesn't match exported %x", e.Address, root, e.StorageRoot)
+	}
+	if err := domains.Flush(ctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	logger.Info("[import_storage] done", "addr", e.Address, "keys", len(e.Storage), "branches", len(e.Branches), "storageRoot", root, "stateRoot", fmt.Sprintf("%x", stateRoot))
+	return nil
+}
+
+// storageSubtrie - storage root of addr recomputed from commitment of domains, fn is called for branches of its storage subtrie
+func storageSubtrie(domains *libstate.SharedDomains, addr libcommon.Address, fn func(key, branch []byte) error) (libcommon.Hash, bool, error) {
+	sdCtx := libstate.NewSharedDomainsCommitmentContext(domains, commitment.ModeDirect, commitment.VariantHexPatriciaTrie)
+	hph, ok := sdCtx.Trie().(*commitment.HexPatriciaHashed)
+	if !ok {
+		return libcommon.Hash{}, false, errors.New("casting to HexPatriciaTrieHashed failed")
+	}
+	root, found, err := hph.StorageSubtrie(addr[:], fn)
+	if err != nil || !found {
+		return libcommon.Hash{}, found, err
+	}
+	return libcommon.BytesToHash(root), true, nil
+}
@@ -0,0 +1,282 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/spf13/cobra"
+
+	chain2 "github.com/erigontech/erigon-lib/chain"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/types/accounts"
+
+	"github.com/erigontech/erigon/cmd/hack/tool/fromdb"
+	"github.com/erigontech/erigon/consensus"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/state"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/eth/stagedsync"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/debug"
+	"github.com/erigontech/erigon/turbo/services"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+var (
+	verifyFrom, verifyTo uint64
+	verifyFailFast       bool
+)
+
+var cmdVerifyBlocks = &cobra.Command{
+	Use: "verify",
+	Short: `Re-execute blocks on historical state and compare results with stored data:
+receipts root, bloom and gas used - with headers; state changes of each block - with state history (which is what state roots are built from).`,
+	Example: "go run ./cmd/integration verify --datadir=<datadir> --from=1000000 --to=1001000",
+	Run: func(cmd *cobra.Command, args []string) {
+		logger := debug.SetupCobra(cmd, "integration")
+		db, err := openDB(dbCfg(kv.ChainDB, chaindata), false, logger)
+		if err != nil {
+			logger.Error("Opening DB", "error", err)
+			return
+		}
+		defer db.Close()
+
+		if err := verifyBlocks(cmd.Context(), db, verifyFrom, verifyTo, verifyFailFast, logger); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				logger.Error(err.Error())
+			}
+			return
+		}
+	},
+}
+
+func init() {
+	withDataDir(cmdVerifyBlocks)
+	withChain(cmdVerifyBlocks)
+	withHeimdall(cmdVerifyBlocks)
+	cmdVerifyBlocks.Flags().Uint64Var(&verifyFrom, "from", 1, "first block to verify")
+	cmdVerifyBlocks.Flags().Uint64Var(&verifyTo, "to", 0, "last block to verify (inclusive). 0 means: until last executed block")
+	cmdVerifyBlocks.Flags().BoolVar(&verifyFailFast, "failFast", true, "stop after 1st problem or print WARN log and continue")
+	rootCmd.AddCommand(cmdVerifyBlocks)
+}
+
+func verifyBlocks(ctx context.Context, db kv.TemporalRwDB, from, to uint64, failFast bool, logger log.Logger) error {
+	if from == 0 {
+		from = 1 // genesis is not executed
+	}
+	chainConfig := fromdb.ChainConfig(db)
+	br, _ := blocksIO(db, logger)
+	engine, _ := initConsensusEngine(ctx, chainConfig, datadir.New(datadirCli).DataDir, db, br, logger)
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, br))
+
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if to == 0 {
+		if to, err = stages.GetStageProgress(tx, stages.Execution); err != nil {
+			return err
+		}
+	}
+
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	var mismatches int
+	for blockNum := from; blockNum <= to; blockNum++ {
+		err := verifyBlock(ctx, tx, br, txNumsReader, engine, chainConfig, blockNum, logger)
+		if err != nil {
+			if errors.Is(err, context.Canceled) || failFast {
+				return err
+			}
+			mismatches++
+			logger.Warn("[verify] " + err.Error())
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-logEvery.C:
+			logger.Info("[verify]", "block", blockNum, "to", to, "mismatches", mismatches)
+		default:
+		}
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("verify: %d blocks of [%d, %d] have mismatches", mismatches, from, to)
+	}
+	logger.Info("[verify] done", "from", from, "to", to)
+	return nil
+}
+
+// verifyBlock - re-executes block (ExecuteBlockEphemerally checks receipts root, bloom and gas used),
+// then compares all state changes made by block with state as of txNum after block's last txNum.
+// Commitment (state root) history is not stored, but roots are built from exactly this state.
+func verifyBlock(ctx context.Context, tx kv.TemporalTx, br services.FullBlockReader, txNumsReader rawdbv3.TxNumsReader, engine consensus.Engine, chainConfig *chain2.Config, blockNum uint64, logger log.Logger) error {
+	stateWriter, _, maxTxNum, err := reExecuteBlock(ctx, tx, br, txNumsReader, engine, chainConfig, blockNum, logger)
+	if err != nil {
+		return err
+	}
+	afterReader := state.NewHistoryReaderV3()
+	afterReader.SetTx(tx)
+	afterReader.SetTxNum(maxTxNum + 1)
+	if err := stateWriter.compare(afterReader); err != nil {
+		return fmt.Errorf("block %d: %w", blockNum, err)
+	}
+	return nil
+}
+
+// reExecuteBlock - executes block on top of state as of block's first txNum, returns all state changes made by block
+func reExecuteBlock(ctx context.Context, tx kv.TemporalTx, br services.FullBlockReader, txNumsReader rawdbv3.TxNumsReader, engine consensus.Engine, chainConfig *chain2.Config, blockNum uint64, logger log.Logger) (stateWriter *verifyStateWriter, minTxNum, maxTxNum uint64, err error) {
+	block, err := br.BlockByNumber(ctx, tx, blockNum)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if block == nil {
+		return nil, 0, 0, fmt.Errorf("block %d not found", blockNum)
+	}
+	if minTxNum, err = txNumsReader.Min(tx, blockNum); err != nil {
+		return nil, 0, 0, err
+	}
+	if maxTxNum, err = txNumsReader.Max(tx, blockNum); err != nil {
+		return nil, 0, 0, err
+	}
+
+	stateReader := state.NewHistoryReaderV3()
+	stateReader.SetTx(tx)
+	stateReader.SetTxNum(minTxNum)
+	if minTxNum < stateReader.StateHistoryStartFrom() {
+		return nil, 0, 0, fmt.Errorf("block %d: %w", blockNum, state.PrunedError)
+	}
+	stateWriter = newVerifyStateWriter()
+
+	getHeader := func(hash libcommon.Hash, number uint64) *types.Header {
+		h, _ := br.Header(ctx, tx, hash, number)
+		return h
+	}
+	chainReader := stagedsync.NewChainReaderImpl(chainConfig, tx, br, logger)
+	vmConfig := &vm.Config{}
+	if _, err = core.ExecuteBlockEphemerally(chainConfig, vmConfig, core.GetHashFn(block.Header(), getHeader), engine, block, stateReader, stateWriter, chainReader, nil, logger); err != nil {
+		return nil, 0, 0, fmt.Errorf("block %d: %w", blockNum, err)
+	}
+	return stateWriter, minTxNum, maxTxNum, nil
+}
+
+type verifyStorageKey struct {
+	addr libcommon.Address
+	key  libcommon.Hash
+}
+
+// verifyStateWriter - remembers last written value of each account/storage/code, to compare them with stored state
+type verifyStateWriter struct {
+	accounts map[libcommon.Address]*accounts.Account // nil value means: deleted
+	code     map[libcommon.Address][]byte
+	storage  map[verifyStorageKey]uint256.Int
+	deleted  map[libcommon.Address]struct{} // accounts deleted at least once, with all their storage
+}
+
+func newVerifyStateWriter() *verifyStateWriter {
+	return &verifyStateWriter{
+		accounts: map[libcommon.Address]*accounts.Account{},
+		code:     map[libcommon.Address][]byte{},
+		storage:  map[verifyStorageKey]uint256.Int{},
+		deleted:  map[libcommon.Address]struct{}{},
+	}
+}
+
+func (w *verifyStateWriter) UpdateAccountData(address libcommon.Address, original, account *accounts.Account) error {
+	w.accounts[address] = account.SelfCopy()
+	return nil
+}
+
+func (w *verifyStateWriter) UpdateAccountCode(address libcommon.Address, incarnation uint64, codeHash libcommon.Hash, code []byte) error {
+	w.code[address] = libcommon.CopyBytes(code)
+	return nil
+}
+
+func (w *verifyStateWriter) DeleteAccount(address libcommon.Address, original *accounts.Account) error {
+	w.accounts[address] = nil
+	w.deleted[address] = struct{}{}
+	delete(w.code, address)
+	for k := range w.storage {
+		if k.addr == address {
+			delete(w.storage, k)
+		}
+	}
+	return nil
+}
+
+func (w *verifyStateWriter) WriteAccountStorage(address libcommon.Address, incarnation uint64, key *libcommon.Hash, original, value *uint256.Int) error {
+	w.storage[verifyStorageKey{addr: address, key: *key}] = *value
+	return nil
+}
+
+func (w *verifyStateWriter) CreateContract(address libcommon.Address) error { return nil }
+func (w *verifyStateWriter) WriteChangeSets() error                         { return nil }
+func (w *verifyStateWriter) WriteHistory() error                            { return nil }
+
+func (w *verifyStateWriter) compare(r state.StateReader) error {
+	for addr, acc := range w.accounts {
+		stored, err := r.ReadAccountData(addr)
+		if err != nil {
+			return err
+		}
+		if acc == nil || stored == nil {
+			if acc != stored {
+				return fmt.Errorf("account %x: executed=%+v, stored=%+v", addr, acc, stored)
+			}
+			continue
+		}
+		// incarnation is not part of state root
+		if acc.Nonce != stored.Nonce || acc.CodeHash != stored.CodeHash || !acc.Balance.Eq(&stored.Balance) {
+			return fmt.Errorf("account %x: executed=%+v, stored=%+v", addr, acc, stored)
+		}
+	}
+	for addr, code := range w.code {
+		stored, err := r.ReadAccountCode(addr, 0)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(code, stored) {
+			return fmt.Errorf("code %x: executed len=%d, stored len=%d", addr, len(code), len(stored))
+		}
+	}
+	for k, v := range w.storage {
+		stored, err := r.ReadAccountStorage(k.addr, 0, &k.key)
+		if err != nil {
+			return err
+		}
+		var storedV uint256.Int
+		storedV.SetBytes(stored)
+		if !v.Eq(&storedV) {
+			return fmt.Errorf("storage %x %x: executed=%s, stored=%s", k.addr, k.key, v.Hex(), storedV.Hex())
+		}
+	}
+	return nil
+}
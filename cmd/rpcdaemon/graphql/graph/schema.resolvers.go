@@ -13,6 +13,7 @@ import (
 
 	"github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/length"
 	"github.com/erigontech/erigon/cmd/rpcdaemon/graphql/graph/model"
 	"github.com/erigontech/erigon/core/types"
 	"github.com/erigontech/erigon/rpc"
@@ -25,9 +26,12 @@ func (r *mutationResolver) SendRawTransaction(ctx context.Context, data string)
 
 // Block is the resolver for the block field.
 func (r *queryResolver) Block(ctx context.Context, number *string, hash *string) (*model.Block, error) {
-	var blockNumber rpc.BlockNumber
+	var res map[string]interface{}
+	var err error
 
-	if number != nil {
+	switch {
+	case number != nil:
+		var blockNumber rpc.BlockNumber
 		// Block number is not null, test for a positive long integer
 		bNum, err := strconv.ParseUint(*number, 10, 64)
 		if err == nil {
@@ -35,23 +39,29 @@ func (r *queryResolver) Block(ctx context.Context, number *string, hash *string)
 			blockNumber = rpc.BlockNumber(bNum)
 		} else {
 			bNum, err := hexutil.DecodeUint64(*number)
-			if err == nil {
-				// Hexadecimal, 0x prefixed
-				blockNumber = rpc.BlockNumber(bNum)
-			} else {
-				var err error
+			if err != nil {
 				return nil, err
 			}
+			// Hexadecimal, 0x prefixed
+			blockNumber = rpc.BlockNumber(bNum)
 		}
-	} else {
-		if hash != nil {
-			blockHash, _ := hexutil.DecodeBig(*hash)
-			fmt.Println("TODO/GraphQL/Implement me, get Block by hash=", blockHash)
-			hash = nil
+		res, err = r.GraphQLAPI.GetBlockDetails(ctx, blockNumber)
+		if err != nil {
+			return nil, err
 		}
-	}
-
-	if number == nil && hash == nil {
+	case hash != nil:
+		blockHash, err := hexutil.Decode(*hash)
+		if err != nil {
+			return nil, err
+		}
+		if len(blockHash) != length.Hash {
+			return nil, fmt.Errorf("invalid block hash length: %d", len(blockHash))
+		}
+		res, err = r.GraphQLAPI.GetBlockDetailsByHash(ctx, common.BytesToHash(blockHash))
+		if err != nil {
+			return nil, err
+		}
+	default:
 		// If neither number or hash is specified (nil), we should deliver "latest" block
 		/*
 			rpc.LatestExecutedBlockNumber = BlockNumber(-5)
@@ -60,12 +70,14 @@ func (r *queryResolver) Block(ctx context.Context, number *string, hash *string)
 			rpc.PendingBlockNumber        = BlockNumber(-2)
 			rpc.LatestBlockNumber         = BlockNumber(-1)
 		*/
-		blockNumber = rpc.LatestBlockNumber
+		res, err = r.GraphQLAPI.GetBlockDetails(ctx, rpc.LatestBlockNumber)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	res, err := r.GraphQLAPI.GetBlockDetails(ctx, blockNumber)
-	if err != nil {
-		return nil, err
+	if res == nil { // block not found
+		return nil, nil
 	}
 
 	block := &model.Block{}
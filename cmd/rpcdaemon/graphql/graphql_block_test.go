@@ -0,0 +1,73 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/kvcache"
+	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/rpc/rpccfg"
+	"github.com/erigontech/erigon/turbo/jsonrpc"
+)
+
+func TestGraphQLBlockByNumberAndHash(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	base := jsonrpc.NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), m.BlockReader, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, nil)
+	server := httptest.NewServer(CreateHandler([]rpc.API{{Namespace: "graphql", Service: jsonrpc.GraphQLAPI(jsonrpc.NewGraphQLAPI(base, m.DB))}}))
+	defer server.Close()
+
+	query := func(q string) string {
+		t.Helper()
+		body := fmt.Sprintf(`{"query": %q, "variables": null}`, q)
+		resp, err := http.Post(server.URL, "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		res, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(res)
+	}
+
+	var hash string
+	require.NoError(t, m.DB.View(context.Background(), func(tx kv.Tx) error {
+		h, ok, err := m.BlockReader.CanonicalHash(context.Background(), tx, 1)
+		require.True(t, ok)
+		hash = h.Hex()
+		return err
+	}))
+
+	const fields = "{number,hash,gasUsed,transactionCount,transactions{hash,logs{index}}}"
+	byNumber := query(`{block(number:1)` + fields + `}`)
+	require.Contains(t, byNumber, fmt.Sprintf(`"number":1,"hash":"%s"`, hash))
+	require.Contains(t, byNumber, `"transactions":[{"hash":"0x`)
+
+	require.Equal(t, byNumber, query(fmt.Sprintf(`{block(hash:"%s")`, hash)+fields+`}`))
+
+	require.Equal(t, `{"data":{"block":null}}`, query(`{block(hash:"0x`+strings.Repeat("ab", 32)+`"){number}}`))
+	require.Equal(t, `{"data":{"block":null}}`, query(`{block(number:1000){number}}`))
+	require.Contains(t, query(`{block(hash:"0xabcd"){number}}`), "invalid block hash length: 2")
+}
@@ -56,6 +56,11 @@ func TestGraphQLQueryBlock(t *testing.T) {
 			want: `{"data":{"block":{"number":0,"gasUsed":0,"gasLimit":5000}}}`,
 			code: 200,
 		},
+		{ // Should return info about genesis block by its hash
+			body: `{"query": "{block(hash:\"0xd4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3\"){number,gasUsed,gasLimit}}","variables": null}`,
+			want: `{"data":{"block":{"number":0,"gasUsed":0,"gasLimit":5000}}}`,
+			code: 200,
+		},
 		{
 			body: `{"query": "{block(number:-1){number,gasUsed,gasLimit}}","variables": null}`,
 			want: `{"data":{"block":null}}`,
@@ -0,0 +1,53 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package health
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/erigontech/erigon-lib/common/hexutil"
+)
+
+var (
+	errTooManyBlocksBehind = errors.New("too many blocks behind")
+)
+
+// checkBlocksBehind - node is ready if it's not syncing, or if its current block is within `blocks` of highest seen block
+func checkBlocksBehind(r *http.Request, blocks uint64, ethAPI EthAPI) error {
+	i, err := ethAPI.Syncing(r.Context())
+	if err != nil {
+		return err
+	}
+	if i == nil || i == false {
+		return nil
+	}
+	syncing, ok := i.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected syncing response type: %T", i)
+	}
+	current, ok1 := syncing["currentBlock"].(hexutil.Uint64)
+	highest, ok2 := syncing["highestBlock"].(hexutil.Uint64)
+	if !ok1 || !ok2 {
+		return errNotSynced
+	}
+	if highest > current && uint64(highest-current) > blocks {
+		return fmt.Errorf("%w: current: %d, highest: %d, allowed: %d", errTooManyBlocksBehind, current, highest, blocks)
+	}
+	return nil
+}
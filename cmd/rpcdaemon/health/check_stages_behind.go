@@ -0,0 +1,73 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/erigontech/erigon-lib/common/hexutil"
+)
+
+var (
+	errStagesBehind = errors.New("sync stages behind")
+)
+
+// syncingStages - eth_syncing response of syncing node. Decoded from json: type of stages is local to eth_syncing
+type syncingStages struct {
+	HighestBlock hexutil.Uint64 `json:"highestBlock"`
+	Stages       []struct {
+		StageName   string         `json:"stage_name"`
+		BlockNumber hexutil.Uint64 `json:"block_number"`
+	} `json:"stages"`
+}
+
+// checkStagesBehind - node is ready if it's not syncing, or if each sync stage is within `blocks` of highest seen block.
+// Status of each stage is returned by stage name (not syncing node has no stages to report: all of them are at tip).
+func checkStagesBehind(r *http.Request, blocks uint64, ethAPI EthAPI) (map[string]error, error) {
+	i, err := ethAPI.Syncing(r.Context())
+	if err != nil {
+		return nil, err
+	}
+	if i == nil || i == false {
+		return nil, nil
+	}
+	data, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	var syncing syncingStages
+	if err := json.Unmarshal(data, &syncing); err != nil {
+		return nil, fmt.Errorf("unexpected syncing response: %w", err)
+	}
+	stages := make(map[string]error, len(syncing.Stages))
+	var behind int
+	for _, s := range syncing.Stages {
+		if syncing.HighestBlock > s.BlockNumber && uint64(syncing.HighestBlock-s.BlockNumber) > blocks {
+			stages[s.StageName] = fmt.Errorf("block: %d, highest: %d, allowed: %d", s.BlockNumber, syncing.HighestBlock, blocks)
+			behind++
+			continue
+		}
+		stages[s.StageName] = nil
+	}
+	if behind > 0 {
+		return stages, fmt.Errorf("%w: %d of %d", errStagesBehind, behind, len(syncing.Stages))
+	}
+	return stages, nil
+}
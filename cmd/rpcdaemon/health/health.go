@@ -43,6 +43,10 @@ const (
 	minPeerCount     = "min_peer_count"
 	checkBlock       = "check_block"
 	maxSecondsBehind = "max_seconds_behind"
+	maxBlocksBehind  = "max_blocks_behind"
+	maxStagesBehind  = "max_stage_blocks_behind"
+	stagePrefix      = "stage_"
+	dbReadable       = "db"
 )
 
 var (
@@ -77,6 +81,10 @@ func processFromHeaders(headers []string, ethAPI EthAPI, netAPI NetAPI, w http.R
 		errCheckPeer    = errCheckDisabled
 		errCheckBlock   = errCheckDisabled
 		errCheckSeconds = errCheckDisabled
+		errCheckBlocks  = errCheckDisabled
+		errCheckStages  = errCheckDisabled
+		errCheckDB      = errCheckDisabled
+		stages          map[string]error
 	)
 
 	for _, header := range headers {
@@ -113,9 +121,28 @@ func processFromHeaders(headers []string, ethAPI EthAPI, netAPI NetAPI, w http.R
 			now := time.Now().Unix()
 			errCheckSeconds = checkTime(r, int(now)-seconds, ethAPI)
 		}
+		if strings.HasPrefix(lHeader, maxBlocksBehind) {
+			blocks, err := strconv.ParseUint(strings.TrimPrefix(lHeader, maxBlocksBehind), 10, 64)
+			if err != nil {
+				errCheckBlocks = err
+				break
+			}
+			errCheckBlocks = checkBlocksBehind(r, blocks, ethAPI)
+		}
+		if strings.HasPrefix(lHeader, maxStagesBehind) {
+			blocks, err := strconv.ParseUint(strings.TrimPrefix(lHeader, maxStagesBehind), 10, 64)
+			if err != nil {
+				errCheckStages = err
+				break
+			}
+			stages, errCheckStages = checkStagesBehind(r, blocks, ethAPI)
+		}
+		if lHeader == dbReadable {
+			errCheckDB = checkDB(r, ethAPI)
+		}
 	}
 
-	reportHealthFromHeaders(errCheckSynced, errCheckPeer, errCheckBlock, errCheckSeconds, w)
+	reportHealthFromHeaders(errCheckSynced, errCheckPeer, errCheckBlock, errCheckSeconds, errCheckBlocks, errCheckStages, stages, errCheckDB, w)
 }
 
 func processFromBody(w http.ResponseWriter, r *http.Request, netAPI NetAPI, ethAPI EthAPI) {
@@ -183,7 +210,7 @@ func reportHealthFromBody(errParse, errMinPeerCount, errCheckBlock error, w http
 	return writeResponse(w, errors, statusCode)
 }
 
-func reportHealthFromHeaders(errCheckSynced, errCheckPeer, errCheckBlock, errCheckSeconds error, w http.ResponseWriter) error {
+func reportHealthFromHeaders(errCheckSynced, errCheckPeer, errCheckBlock, errCheckSeconds, errCheckBlocks, errCheckStages error, stages map[string]error, errCheckDB error, w http.ResponseWriter) error {
 	statusCode := http.StatusOK
 	errs := make(map[string]string)
 
@@ -207,6 +234,24 @@ func reportHealthFromHeaders(errCheckSynced, errCheckPeer, errCheckBlock, errChe
 	}
 	errs[maxSecondsBehind] = errorStringOrOK(errCheckSeconds)
 
+	if shouldChangeStatusCode(errCheckBlocks) {
+		statusCode = http.StatusInternalServerError
+	}
+	errs[maxBlocksBehind] = errorStringOrOK(errCheckBlocks)
+
+	if shouldChangeStatusCode(errCheckStages) {
+		statusCode = http.StatusInternalServerError
+	}
+	errs[maxStagesBehind] = errorStringOrOK(errCheckStages)
+	for stage, err := range stages {
+		errs[stagePrefix+stage] = errorStringOrOK(err)
+	}
+
+	if shouldChangeStatusCode(errCheckDB) {
+		statusCode = http.StatusInternalServerError
+	}
+	errs[dbReadable] = errorStringOrOK(errCheckDB)
+
 	return writeResponse(w, errs, statusCode)
 }
 
@@ -27,6 +27,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
+
 	"github.com/erigontech/erigon-lib/common/hexutil"
 
 	"github.com/erigontech/erigon/rpc"
@@ -46,6 +48,8 @@ type ethApiStub struct {
 	blockError    error
 	syncingResult interface{}
 	syncingError  error
+	blockNumber   hexutil.Uint64
+	blockNumError error
 }
 
 func (e *ethApiStub) GetBlockByNumber(_ context.Context, _ rpc.BlockNumber, _ bool) (map[string]interface{}, error) {
@@ -56,6 +60,10 @@ func (e *ethApiStub) Syncing(_ context.Context) (interface{}, error) {
 	return e.syncingResult, e.syncingError
 }
 
+func (e *ethApiStub) BlockNumber(_ context.Context) (hexutil.Uint64, error) {
+	return e.blockNumber, e.blockNumError
+}
+
 func TestProcessHealthcheckIfNeeded_HeadersTests(t *testing.T) {
 	cases := []struct {
 		headers             []string
@@ -577,3 +585,149 @@ func TestProcessHealthcheckIfNeeded_RequestBody(t *testing.T) {
 		}
 	}
 }
+
+// syncingStub - response of eth_syncing of syncing node, with stages of same json shape
+func syncingStub(current, highest uint64, stages map[string]uint64) map[string]interface{} {
+	type S struct {
+		StageName   string         `json:"stage_name"`
+		BlockNumber hexutil.Uint64 `json:"block_number"`
+	}
+	stagesList := make([]S, 0, len(stages))
+	for name, block := range stages {
+		stagesList = append(stagesList, S{name, hexutil.Uint64(block)})
+	}
+	return map[string]interface{}{
+		"startingBlock": "0x0",
+		"currentBlock":  hexutil.Uint64(current),
+		"highestBlock":  hexutil.Uint64(highest),
+		"stages":        stagesList,
+	}
+}
+
+func TestProcessHealthcheckIfNeeded_ReadinessHeaders(t *testing.T) {
+	disabled := func(body map[string]string) map[string]string {
+		for _, k := range []string{synced, minPeerCount, checkBlock, maxSecondsBehind, maxBlocksBehind, maxStagesBehind, dbReadable} {
+			if _, ok := body[k]; !ok {
+				body[k] = "DISABLED"
+			}
+		}
+		return body
+	}
+	cases := []struct {
+		name               string
+		headers            []string
+		syncingResult      interface{}
+		blockNumError      error
+		expectedStatusCode int
+		expectedBody       map[string]string
+	}{
+		{
+			name:               "blocks behind - within allowed lag",
+			headers:            []string{"max_blocks_behind5"},
+			syncingResult:      syncingStub(100, 104, nil),
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       disabled(map[string]string{maxBlocksBehind: "HEALTHY"}),
+		},
+		{
+			name:               "blocks behind - too far behind",
+			headers:            []string{"max_blocks_behind5"},
+			syncingResult:      syncingStub(100, 200, nil),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       disabled(map[string]string{maxBlocksBehind: "ERROR: too many blocks behind: current: 100, highest: 200, allowed: 5"}),
+		},
+		{
+			name:               "blocks behind - not syncing",
+			headers:            []string{"max_blocks_behind0"},
+			syncingResult:      false,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       disabled(map[string]string{maxBlocksBehind: "HEALTHY"}),
+		},
+		{
+			name:               "blocks behind - badly formed request",
+			headers:            []string{"max_blocks_behind-1"},
+			syncingResult:      false,
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       disabled(map[string]string{maxBlocksBehind: `ERROR: strconv.ParseUint: parsing "-1": invalid syntax`}),
+		},
+		{
+			name:               "stages behind - all stages within allowed lag",
+			headers:            []string{"max_stage_blocks_behind10"},
+			syncingResult:      syncingStub(195, 200, map[string]uint64{"Headers": 200, "Execution": 195}),
+			expectedStatusCode: http.StatusOK,
+			expectedBody: disabled(map[string]string{
+				maxStagesBehind:   "HEALTHY",
+				"stage_Headers":   "HEALTHY",
+				"stage_Execution": "HEALTHY",
+			}),
+		},
+		{
+			name:               "stages behind - one stage too far behind",
+			headers:            []string{"max_stage_blocks_behind10"},
+			syncingResult:      syncingStub(150, 200, map[string]uint64{"Headers": 200, "Execution": 150}),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody: disabled(map[string]string{
+				maxStagesBehind:   "ERROR: sync stages behind: 1 of 2",
+				"stage_Headers":   "HEALTHY",
+				"stage_Execution": "ERROR: block: 150, highest: 200, allowed: 10",
+			}),
+		},
+		{
+			name:               "stages behind - not syncing",
+			headers:            []string{"max_stage_blocks_behind0"},
+			syncingResult:      false,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       disabled(map[string]string{maxStagesBehind: "HEALTHY"}),
+		},
+		{
+			name:               "db - readable",
+			headers:            []string{"db"},
+			syncingResult:      false,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       disabled(map[string]string{dbReadable: "HEALTHY"}),
+		},
+		{
+			name:               "db - can't be opened",
+			headers:            []string{"db"},
+			syncingResult:      false,
+			blockNumError:      errors.New("mdbx_txn_begin: MDBX_PANIC"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       disabled(map[string]string{dbReadable: "ERROR: mdbx_txn_begin: MDBX_PANIC"}),
+		},
+		{
+			name:               "db and stages - ready and live",
+			headers:            []string{"db", "max_blocks_behind10", "max_stage_blocks_behind10"},
+			syncingResult:      syncingStub(199, 200, map[string]uint64{"Headers": 200}),
+			expectedStatusCode: http.StatusOK,
+			expectedBody: disabled(map[string]string{
+				maxBlocksBehind: "HEALTHY",
+				maxStagesBehind: "HEALTHY",
+				"stage_Headers": "HEALTHY",
+				dbReadable:      "HEALTHY",
+			}),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodGet, "http://localhost:9090/health", nil)
+			require.NoError(t, err)
+			for _, header := range c.headers {
+				r.Header.Add("X-ERIGON-HEALTHCHECK", header)
+			}
+			apis := []rpc.API{
+				{Service: &netApiStub{response: hexutil.Uint(1)}},
+				{Service: &ethApiStub{syncingResult: c.syncingResult, blockNumber: hexutil.Uint64(200), blockNumError: c.blockNumError}},
+			}
+
+			ProcessHealthcheckIfNeeded(w, r, apis)
+
+			result := w.Result()
+			defer result.Body.Close()
+			require.Equal(t, c.expectedStatusCode, result.StatusCode)
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(result.Body).Decode(&body))
+			require.Equal(t, c.expectedBody, body)
+		})
+	}
+}
@@ -31,4 +31,5 @@ type NetAPI interface {
 type EthAPI interface {
 	GetBlockByNumber(_ context.Context, number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error)
 	Syncing(ctx context.Context) (interface{}, error)
+	BlockNumber(ctx context.Context) (hexutil.Uint64, error)
 }
@@ -0,0 +1,87 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon/core/types"
+)
+
+// BlockReadSet - accounts and storage slots which execution of a block is known to read before the block is executed:
+// coinbase, senders, recipients, withdrawal recipients and access lists of transactions.
+// It's a lower bound: slots read by contract code itself are found only by execution.
+type BlockReadSet struct {
+	Accounts []common.Address                 // in order of first appearance, without duplicates
+	Storage  map[common.Address][]common.Hash // slots from access lists
+	seen     map[common.Address]struct{}
+}
+
+// NewBlockReadSet - senders may be nil, then senders cached in transactions are used
+func NewBlockReadSet(block *types.Block, senders []common.Address) *BlockReadSet {
+	rs := &BlockReadSet{Storage: map[common.Address][]common.Hash{}, seen: map[common.Address]struct{}{}}
+	rs.AddAccount(block.Coinbase())
+	for i, txn := range block.Transactions() {
+		if i < len(senders) {
+			rs.AddAccount(senders[i])
+		} else if sender, ok := txn.GetSender(); ok {
+			rs.AddAccount(sender)
+		}
+		if to := txn.GetTo(); to != nil {
+			rs.AddAccount(*to)
+		}
+		for _, tuple := range txn.GetAccessList() {
+			rs.AddAccount(tuple.Address)
+			rs.Storage[tuple.Address] = append(rs.Storage[tuple.Address], tuple.StorageKeys...)
+		}
+	}
+	for _, w := range block.Withdrawals() {
+		rs.AddAccount(w.Address)
+	}
+	return rs
+}
+
+// AddAccount - adds account known to be read by other means, for example touched by recent blocks
+func (rs *BlockReadSet) AddAccount(addr common.Address) {
+	if _, ok := rs.seen[addr]; ok {
+		return
+	}
+	rs.seen[addr] = struct{}{}
+	rs.Accounts = append(rs.Accounts, addr)
+}
+
+// Prefetch - reads accounts, code and storage of the set, so pages they are in get cached before execution needs them
+func (rs *BlockReadSet) Prefetch(tx kv.TemporalGetter) error {
+	for _, addr := range rs.Accounts {
+		acc, _, err := tx.GetLatest(kv.AccountsDomain, addr[:])
+		if err != nil {
+			return err
+		}
+		if len(acc) == 0 {
+			continue
+		}
+		if _, _, err := tx.GetLatest(kv.CodeDomain, addr[:]); err != nil {
+			return err
+		}
+		for _, slot := range rs.Storage[addr] {
+			if _, _, err := tx.GetLatest(kv.StorageDomain, append(addr[:], slot[:]...)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon/core/types"
+)
+
+type recordingGetter struct {
+	values map[string][]byte
+	reads  []string
+}
+
+func (g *recordingGetter) GetLatest(name kv.Domain, k []byte) ([]byte, uint64, error) {
+	key := name.String() + string(k)
+	g.reads = append(g.reads, key)
+	return g.values[key], 0, nil
+}
+
+func TestBlockReadSet(t *testing.T) {
+	var (
+		coinbase  = common.HexToAddress("0x01")
+		sender    = common.HexToAddress("0x02")
+		to        = common.HexToAddress("0x03")
+		listed    = common.HexToAddress("0x04")
+		withdrawn = common.HexToAddress("0x05")
+		slot      = common.HexToHash("0x06")
+	)
+	txs := []types.Transaction{
+		types.NewTransaction(0, to, uint256.NewInt(1), 21000, uint256.NewInt(1), nil),
+		&types.AccessListTx{
+			LegacyTx:   *types.NewTransaction(1, to, uint256.NewInt(1), 50000, uint256.NewInt(1), nil),
+			ChainID:    uint256.NewInt(1),
+			AccessList: types.AccessList{{Address: listed, StorageKeys: []common.Hash{slot}}},
+		},
+	}
+	header := &types.Header{Number: big.NewInt(1), Coinbase: coinbase}
+	block := types.NewBlock(header, txs, nil, nil, []*types.Withdrawal{{Address: withdrawn}})
+
+	rs := NewBlockReadSet(block, []common.Address{sender, sender})
+	require.Equal(t, []common.Address{coinbase, sender, to, listed, withdrawn}, rs.Accounts)
+	require.Equal(t, map[common.Address][]common.Hash{listed: {slot}}, rs.Storage)
+	rs.AddAccount(sender)
+	require.Len(t, rs.Accounts, 5)
+
+	g := &recordingGetter{values: map[string][]byte{
+		kv.AccountsDomain.String() + string(listed[:]): {1},
+	}}
+	require.NoError(t, rs.Prefetch(g))
+	// code and storage are read only for existing accounts
+	require.Contains(t, g.reads, kv.CodeDomain.String()+string(listed[:]))
+	require.Contains(t, g.reads, kv.StorageDomain.String()+string(append(listed[:], slot[:]...)))
+	require.NotContains(t, g.reads, kv.CodeDomain.String()+string(to[:]))
+	require.Len(t, g.reads, 5+2)
+}
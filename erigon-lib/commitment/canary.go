@@ -0,0 +1,229 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commitment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/metrics"
+	"github.com/erigontech/erigon-lib/rlp"
+)
+
+var (
+	mxCanaryChecked    = metrics.GetOrCreateCounter("domain_commitment_canary_checked")
+	mxCanaryMismatches = metrics.GetOrCreateCounter("domain_commitment_canary_mismatches")
+)
+
+// CanaryMismatch describes a hash memoized in the commitment branches which does not match
+// the hash recomputed from flat state.
+type CanaryMismatch struct {
+	Prefix   []byte // nibbles of the branch holding the cell
+	Nibble   int    // cell of the branch, -1 if the whole branch hashes to a different value than its parent holds
+	Stored   []byte
+	Computed []byte
+}
+
+func (m CanaryMismatch) String() string {
+	if m.Nibble < 0 {
+		return fmt.Sprintf("branch [%x]: parent holds %x, computed %x", m.Prefix, m.Stored, m.Computed)
+	}
+	return fmt.Sprintf("branch [%x] cell %x: stored %x, computed %x", m.Prefix, m.Nibble, m.Stored, m.Computed)
+}
+
+// HashCanary spot-checks hashes memoized in the stored branches: it walks from the root branch along
+// random children and, for every branch on the way, recomputes leaf hashes from flat state and the branch
+// hash from its cells. Recomputed values are compared to memoized leaf hashes and to the hash
+// held by the parent cell. It never writes anything, so it is safe to run next to the trie over the same state.
+type HashCanary struct {
+	hph *HexPatriciaHashed
+	rnd *rand.Rand
+	row [16]cell
+	buf []byte
+}
+
+func NewHashCanary(accountKeyLen int, ctx PatriciaContext, tmpdir string, seed int64) *HashCanary {
+	return &HashCanary{
+		hph: NewHexPatriciaHashed(accountKeyLen, ctx, tmpdir),
+		rnd: rand.New(rand.NewSource(seed)),
+		buf: make([]byte, 0, 1024),
+	}
+}
+
+func (hc *HashCanary) ResetContext(ctx PatriciaContext) { hc.hph.ResetContext(ctx) }
+
+type canaryChild struct {
+	prefix []byte // nibbles of the child branch
+	hash   []byte // hash of the child branch held by the cell
+}
+
+// Sample verifies branches on a single random path from the root and returns the number of branches checked.
+func (hc *HashCanary) Sample() (checked int, mismatches []CanaryMismatch, err error) {
+	var prefix, expected []byte
+	for {
+		children, found, mm, err := hc.verifyBranch(prefix, expected)
+		if err != nil {
+			return checked, mismatches, err
+		}
+		if !found {
+			break
+		}
+		checked++
+		mismatches = append(mismatches, mm...)
+		if len(children) == 0 {
+			break
+		}
+		next := children[hc.rnd.Intn(len(children))]
+		prefix, expected = next.prefix, next.hash
+	}
+	mxCanaryChecked.AddInt(checked)
+	mxCanaryMismatches.AddInt(len(mismatches))
+	return checked, mismatches, nil
+}
+
+// verifyBranch recomputes hashes of the branch stored at prefix. expected is the branch hash held by the parent
+// cell, nil for the root branch.
+func (hc *HashCanary) verifyBranch(prefix, expected []byte) (children []canaryChild, found bool, mismatches []CanaryMismatch, err error) {
+	depth := len(prefix) + 1
+	enc, found, err := hc.hph.encodeStoredBranch(prefix, &hc.row, hc.buf[:0], func(nibble int, c *cell, storedStateHash []byte) {
+		switch {
+		case c.accountAddrLen == 0 && c.storageAddrLen == 0 && c.hashLen > 0:
+			childPrefix := append(append(append(make([]byte, 0, depth+c.extLen), prefix...), byte(nibble)), c.extension[:c.extLen]...)
+			children = append(children, canaryChild{prefix: childPrefix, hash: append([]byte{}, c.hash[:c.hashLen]...)})
+		case c.accountAddrLen > 0 && c.storageAddrLen == 0 && c.hashLen > 0:
+			storagePrefix := append(hc.hph.HashAndNibblizeKey(c.accountAddr[:c.accountAddrLen]), c.extension[:c.extLen]...)
+			children = append(children, canaryChild{prefix: storagePrefix, hash: append([]byte{}, c.hash[:c.hashLen]...)})
+		}
+		if len(storedStateHash) == length.Hash && !bytes.Equal(storedStateHash, c.stateHash[:c.stateHashLen]) {
+			mismatches = append(mismatches, CanaryMismatch{
+				Prefix:   prefix,
+				Nibble:   nibble,
+				Stored:   append([]byte{}, storedStateHash...),
+				Computed: append([]byte{}, c.stateHash[:c.stateHashLen]...),
+			})
+		}
+	})
+	if err != nil || !found {
+		return nil, found, nil, err
+	}
+
+	if expected != nil {
+		branchHash := hc.hph.branchNodeHash(enc)
+		if !bytes.Equal(expected, branchHash) {
+			mismatches = append(mismatches, CanaryMismatch{Prefix: prefix, Nibble: -1, Stored: expected, Computed: branchHash})
+		}
+	}
+	return children, true, mismatches, nil
+}
+
+// BranchHash recomputes the hash of the branch node stored at prefix (nibbles) from the branch cells,
+// reloading leaves from flat state, so memoized leaf hashes are not trusted. node is the RLP encoding
+// of the branch node and can be served as a witness of the returned hash. found is false if there is
+// no branch stored at prefix.
+func (hph *HexPatriciaHashed) BranchHash(prefix []byte) (hash, node []byte, found bool, err error) {
+	var row [16]cell
+	enc, found, err := hph.encodeStoredBranch(prefix, &row, nil, nil)
+	if err != nil || !found {
+		return nil, nil, found, err
+	}
+	var lenPrefix [4]byte
+	pt := rlp.GenerateStructLen(lenPrefix[:], len(enc))
+	node = append(append(make([]byte, 0, pt+len(enc)), lenPrefix[:pt]...), enc...)
+	return hph.branchNodeHash(enc), node, true, nil
+}
+
+// encodeStoredBranch reads the branch stored at prefix into row and returns the RLP payload of the branch node
+// (without the list prefix). Memoized leaf hashes are dropped and leaves are reloaded from flat state before hashing.
+// visit, if set, is called for every present cell after its hash was computed, with the leaf hash the branch held.
+func (hph *HexPatriciaHashed) encodeStoredBranch(prefix []byte, row *[16]cell, buf []byte, visit func(nibble int, c *cell, storedStateHash []byte)) (enc []byte, found bool, err error) {
+	branchData, _, err := hph.ctx.Branch(hexToCompact(prefix))
+	if err != nil {
+		return nil, false, err
+	}
+	if len(branchData) >= 2 {
+		branchData = branchData[2:] // skip touch map
+	}
+	if len(branchData) < 2 {
+		return nil, false, nil
+	}
+
+	depth := len(prefix) + 1
+	afterMap := binary.BigEndian.Uint16(branchData)
+	pos := 2
+	var storedStateHash [length.Hash]byte
+	enc = make([]byte, 0, 16*33+1)
+	for nibble := 0; nibble < 16; nibble++ {
+		if afterMap&(uint16(1)<<nibble) == 0 {
+			enc = append(enc, 0x80)
+			continue
+		}
+		c := &row[nibble]
+		c.reset()
+		fieldBits := branchData[pos]
+		pos++
+		if pos, err = c.fillFromFields(branchData, pos, cellFields(fieldBits)); err != nil {
+			return nil, true, fmt.Errorf("prefix [%x] branchData[%x]: %w", prefix, branchData, err)
+		}
+
+		// drop memoized hash and reload leaf from flat state
+		storedStateHashLen := c.stateHashLen
+		copy(storedStateHash[:], c.stateHash[:c.stateHashLen])
+		c.stateHashLen = 0
+		if c.accountAddrLen > 0 {
+			upd, err := hph.ctx.Account(c.accountAddr[:c.accountAddrLen])
+			if err != nil {
+				return nil, true, fmt.Errorf("failed to get account: %w", err)
+			}
+			c.setFromUpdate(upd)
+			c.loaded = c.loaded.addFlag(cellLoadAccount)
+		}
+		if c.storageAddrLen > 0 {
+			upd, err := hph.ctx.Storage(c.storageAddr[:c.storageAddrLen])
+			if err != nil {
+				return nil, true, fmt.Errorf("failed to get storage: %w", err)
+			}
+			c.setFromUpdate(upd)
+			c.loaded = c.loaded.addFlag(cellLoadStorage)
+		}
+
+		cellHash, err := hph.computeCellHash(c, depth, buf[:0])
+		if err != nil {
+			return nil, true, err
+		}
+		enc = append(enc, cellHash...)
+		if visit != nil {
+			visit(nibble, c, storedStateHash[:storedStateHashLen])
+		}
+	}
+	enc = append(enc, 0x80) // no value in branch nodes
+	return enc, true, nil
+}
+
+// branchNodeHash returns keccak of the branch node with RLP payload enc.
+func (hph *HexPatriciaHashed) branchNodeHash(enc []byte) []byte {
+	var lenPrefix [4]byte
+	pt := rlp.GenerateStructLen(lenPrefix[:], len(enc))
+	branchHash := make([]byte, length.Hash)
+	hph.keccak2.Reset()
+	hph.keccak2.Write(lenPrefix[:pt])
+	hph.keccak2.Write(enc)
+	hph.keccak2.Read(branchHash)
+	return branchHash
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commitment
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/crypto"
+)
+
+func Test_HashCanary(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMockState(t)
+
+	ub := NewUpdateBuilder()
+	for i := 0; i < 300; i++ {
+		addr := fmt.Sprintf("%040x", i)
+		ub.Balance(addr, uint64(i+1))
+		if i%10 == 0 {
+			for j := 0; j < 20; j++ {
+				ub.Storage(addr, fmt.Sprintf("%064x", j), fmt.Sprintf("%04x", i*j+1))
+			}
+		}
+	}
+	plainKeys, updates := ub.Build()
+
+	hph := NewHexPatriciaHashed(length.Addr, ms, ms.TempDir())
+	require.NoError(t, ms.applyPlainUpdates(plainKeys, updates))
+	upds := WrapKeyUpdates(t, ModeDirect, hph.HashAndNibblizeKey, plainKeys, updates)
+	_, err := hph.Process(ctx, upds, "")
+	require.NoError(t, err)
+	upds.Close()
+
+	canary := NewHashCanary(length.Addr, ms, ms.TempDir(), 1)
+	var checked int
+	var sawStorage bool
+	for i := 0; i < 200; i++ {
+		n, mismatches, err := canary.Sample()
+		require.NoError(t, err)
+		require.Empty(t, mismatches)
+		require.Positive(t, n)
+		checked += n
+		sawStorage = sawStorage || n > 2
+	}
+	require.True(t, sawStorage, "canary never descended into storage tries")
+
+	// change flat state behind the trie's back
+	corrupted, corruptedUpdates := NewUpdateBuilder().
+		Balance(fmt.Sprintf("%040x", 10), 100500).
+		Storage(fmt.Sprintf("%040x", 20), fmt.Sprintf("%064x", 3), "ffff").
+		Build()
+	require.NoError(t, ms.applyPlainUpdates(corrupted, corruptedUpdates))
+
+	var found []CanaryMismatch
+	for i := 0; i < 5000 && len(found) == 0; i++ {
+		_, mismatches, err := canary.Sample()
+		require.NoError(t, err)
+		found = mismatches
+	}
+	require.NotEmpty(t, found)
+	t.Logf("%d branches checked, mismatch: %s", checked, found[0])
+}
+
+func Test_HexPatriciaHashed_BranchHash(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMockState(t)
+
+	ub := NewUpdateBuilder()
+	for i := 0; i < 100; i++ {
+		ub.Balance(fmt.Sprintf("%040x", i), uint64(i+1))
+	}
+	plainKeys, updates := ub.Build()
+
+	hph := NewHexPatriciaHashed(length.Addr, ms, ms.TempDir())
+	require.NoError(t, ms.applyPlainUpdates(plainKeys, updates))
+	upds := WrapKeyUpdates(t, ModeDirect, hph.HashAndNibblizeKey, plainKeys, updates)
+	rootHash, err := hph.Process(ctx, upds, "")
+	require.NoError(t, err)
+	upds.Close()
+
+	resolver := NewHexPatriciaHashed(length.Addr, ms, ms.TempDir())
+	hash, node, found, err := resolver.BranchHash(nil)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, rootHash, hash)
+	require.Equal(t, hash, crypto.Keccak256(node))
+
+	_, _, found, err = resolver.BranchHash([]byte{0xf, 0xf, 0xf, 0xf, 0xf, 0xf})
+	require.NoError(t, err)
+	require.False(t, found)
+}
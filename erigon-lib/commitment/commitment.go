@@ -42,6 +42,8 @@ import (
 	"github.com/erigontech/erigon-lib/etl"
 )
 
+var logger = log.Subsystem("trie.commitment")
+
 var (
 	mxTrieProcessedKeys   = metrics.GetOrCreateCounter("domain_commitment_keys")
 	mxTrieBranchesUpdated = metrics.GetOrCreateCounter("domain_commitment_updates_applied")
@@ -1044,7 +1046,7 @@ func (t *Updates) TouchPlainKey(key string, val []byte, fn func(c *KeyUpdate, va
 		if _, ok := t.keys[key]; !ok {
 			keyBytes := toBytesZeroCopy(key)
 			if err := t.etl.Collect(t.hasher(keyBytes), keyBytes); err != nil {
-				log.Warn("failed to collect updated key", "key", key, "err", err)
+				logger.Warn("failed to collect updated key", "key", key, "err", err)
 			}
 			t.keys[key] = struct{}{}
 		}
@@ -27,6 +27,7 @@ import (
 	"testing"
 
 	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/length"
 
 	"github.com/stretchr/testify/require"
 )
@@ -400,3 +401,23 @@ func TestUpdates_TouchPlainKey(t *testing.T) {
 	require.NoError(t, err)
 	require.EqualValues(t, len(uniqUpds), i)
 }
+
+func TestProcessRecord_KeyRanges(t *testing.T) {
+	acc1, acc2 := bytes.Repeat([]byte{1}, length.Addr), bytes.Repeat([]byte{2}, length.Addr)
+	r := &ProcessRecord{}
+	r.addKey(append(common.Copy(acc2), bytes.Repeat([]byte{9}, length.Hash)...))
+	r.addKey(acc1)
+	r.addKey(append(common.Copy(acc2), bytes.Repeat([]byte{3}, length.Hash)...))
+
+	ranges := r.KeyRanges(length.Addr)
+	require.Len(t, ranges, 2)
+	require.Equal(t, KeyRange{Account: acc1, Touched: true}, ranges[0])
+	require.Equal(t, acc2, ranges[1].Account)
+	require.False(t, ranges[1].Touched)
+	require.Equal(t, 2, ranges[1].Slots)
+	require.Equal(t, bytes.Repeat([]byte{3}, length.Hash), ranges[1].FirstSlot)
+	require.Equal(t, bytes.Repeat([]byte{9}, length.Hash), ranges[1].LastSlot)
+
+	r.Reset()
+	require.Empty(t, r.KeyRanges(length.Addr))
+}
@@ -34,12 +34,12 @@ import (
 	"time"
 
 	"github.com/erigontech/erigon-lib/etl"
-	"github.com/erigontech/erigon-lib/log/v3"
 	"github.com/erigontech/erigon-lib/trie"
 	"github.com/erigontech/erigon-lib/types/accounts"
 	witnesstypes "github.com/erigontech/erigon-lib/types/witness"
 
 	"github.com/erigontech/erigon-lib/common/dbg"
+	"github.com/erigontech/erigon-lib/common/featureflags"
 
 	"github.com/erigontech/erigon-lib/common"
 	libcommon "github.com/erigontech/erigon-lib/common"
@@ -50,6 +50,10 @@ import (
 	"golang.org/x/crypto/sha3"
 )
 
+// memoizedHashesFlag - when disabled, hashes of leaves stored in branch nodes are not reused
+// on unfold: accounts and storage are re-read from state and hashed again (slower, but doesn't trust stored hashes).
+var memoizedHashesFlag = featureflags.New("commitment_memoized_hashes", "reuse leaf hashes memoized in commitment branches. Affects: erigon (execution), rpcdaemon (eth_getProof)", true)
+
 // keccakState wraps sha3.state. In addition to the usual hash methods, it also supports
 // Read to get a variable amount of data from the hash state. Read is faster than Sum
 // because it doesn't copy the internal state, but also modifies the internal state.
@@ -90,6 +94,7 @@ type HexPatriciaHashed struct {
 
 	depthsToTxNum [129]uint64 // endTxNum of file with branch data for that depth
 	hadToLoadL    map[uint64]skipStat
+	record        *ProcessRecord // if set, Process collects touched keys and timings into it
 
 	//temp buffers
 	accValBuf rlp.RlpEncodedBytes
@@ -1056,7 +1061,7 @@ func (hph *HexPatriciaHashed) needUnfolding(hashedKey []byte) int {
 		if hph.root.hashedExtLen == 64 && hph.root.accountAddrLen > 0 && hph.root.storageAddrLen > 0 {
 			// in case if root is a leaf node with storage and account, we need to derive storage part of a key
 			if err := hph.root.deriveHashedKeys(depth, hph.keccak, hph.accountKeyLen); err != nil {
-				log.Warn("deriveHashedKeys for root with storage", "err", err, "cell", hph.root.FullString())
+				logger.Warn("deriveHashedKeys for root with storage", "err", err, "cell", hph.root.FullString())
 				return 0
 			}
 			//copy(hph.currentKey[:], hph.root.hashedExtension[:])
@@ -1299,7 +1304,7 @@ func (hph *HexPatriciaHashed) ToTrie(hashedKey []byte, codeReads map[libcommon.H
 			}
 			fullNode.Children[currentNibble] = nextNode // ready to expand next nibble in the path
 		} else if accNode, ok := currentNode.(*trie.AccountNode); ok {
-			if len(hashedKey) <= 64 { // no storage, stop here
+			if len(hashedKey) <= 64 || accNode.Storage == nil { // no storage, stop here
 				nextNode = nil // nolint:ineffassign, wastedassign
 				break
 			}
@@ -1316,8 +1321,9 @@ func (hph *HexPatriciaHashed) ToTrie(hashedKey []byte, codeReads map[libcommon.H
 			break // break if currentNode is nil
 		}
 		// we need to check if we are dealing with the next node being an account node and we have a storage key,
-		// in that case start a new tree for the storage
-		if nextAccNode, ok := nextNode.(*trie.AccountNode); ok && len(hashedKey) > 64 {
+		// in that case start a new tree for the storage. Account without storage keeps empty storage root,
+		// the storage key is then proven absent by the account node itself
+		if nextAccNode, ok := nextNode.(*trie.AccountNode); ok && len(hashedKey) > 64 && nextAccNode.Storage != nil {
 			nextNode = &trie.FullNode{}
 			nextAccNode.Storage = nextNode
 		}
@@ -1334,6 +1340,9 @@ func (hph *HexPatriciaHashed) unfoldBranchNode(row, depth int, deleted bool) (bo
 	if err != nil {
 		return false, err
 	}
+	if hph.record != nil {
+		hph.record.addBranchPrefix(key)
+	}
 	hph.depthsToTxNum[depth] = fileEndTxNum
 	if len(branchData) >= 2 {
 		branchData = branchData[2:] // skip touch map and keep the rest
@@ -1347,7 +1356,7 @@ func (hph *HexPatriciaHashed) unfoldBranchNode(row, depth int, deleted bool) (bo
 		return false, nil
 	}
 	if len(branchData) == 0 {
-		log.Warn("got empty branch data during unfold", "key", hex.EncodeToString(key), "row", row, "depth", depth, "deleted", deleted)
+		logger.Warn("got empty branch data during unfold", "key", hex.EncodeToString(key), "row", row, "depth", depth, "deleted", deleted)
 		return false, fmt.Errorf("empty branch data read during unfold, prefix %x", hexToCompact(hph.currentKey[:hph.currentKeyLen]))
 	}
 	hph.branchBefore[row] = true
@@ -1372,6 +1381,9 @@ func (hph *HexPatriciaHashed) unfoldBranchNode(row, depth int, deleted bool) (bo
 		if pos, err = cell.fillFromFields(branchData, pos, cellFields(fieldBits)); err != nil {
 			return false, fmt.Errorf("prefix [%x] branchData[%x]: %w", hph.currentKey[:hph.currentKeyLen], branchData, err)
 		}
+		if !memoizedHashesFlag.Enabled() && (cell.accountAddrLen > 0 || cell.storageAddrLen > 0) {
+			cell.stateHashLen = 0
+		}
 		if hph.trace {
 			fmt.Printf("cell (%d, %x, depth=%d) %s\n", row, nibble, depth, cell.FullString())
 		}
@@ -1514,11 +1526,11 @@ func (hph *HexPatriciaHashed) createCellGetter(b []byte, updateKey []byte, row,
 		cell := &hph.grid[row][nibble]
 		if cell.accountAddrLen > 0 && cell.stateHashLen == 0 && !cell.loaded.account() && !cell.Deleted() {
 			//panic("account not loaded" + fmt.Sprintf("%x", cell.accountAddr[:cell.accountAddrLen]))
-			log.Warn("account not loaded", "pref", updateKey, "c", fmt.Sprintf("(%d, %x, depth=%d", row, nibble, depth), "cell", cell.String())
+			logger.Warn("account not loaded", "pref", updateKey, "c", fmt.Sprintf("(%d, %x, depth=%d", row, nibble, depth), "cell", cell.String())
 		}
 		if cell.storageAddrLen > 0 && cell.stateHashLen == 0 && !cell.loaded.storage() && !cell.Deleted() {
 			//panic("storage not loaded" + fmt.Sprintf("%x", cell.storageAddr[:cell.storageAddrLen]))
-			log.Warn("storage not loaded", "pref", updateKey, "c", fmt.Sprintf("(%d, %x, depth=%d", row, nibble, depth), "cell", cell.String())
+			logger.Warn("storage not loaded", "pref", updateKey, "c", fmt.Sprintf("(%d, %x, depth=%d", row, nibble, depth), "cell", cell.String())
 		}
 
 		loadedBefore := cell.loaded
@@ -1897,7 +1909,7 @@ func (hph *HexPatriciaHashed) GenerateWitness(ctx context.Context, updates *Upda
 		select {
 		case <-logEvery.C:
 			dbg.ReadMemStats(&m)
-			log.Info(fmt.Sprintf("[%s][agg] computing trie", logPrefix),
+			logger.Info(fmt.Sprintf("[%s][agg] computing trie", logPrefix),
 				"progress", fmt.Sprintf("%s/%s", common.PrettyCounter(ki), common.PrettyCounter(updatesCount)),
 				"alloc", common.ByteCount(m.Alloc), "sys", common.ByteCount(m.Sys))
 
@@ -2010,7 +2022,7 @@ func (hph *HexPatriciaHashed) Process(ctx context.Context, updates *Updates, log
 		select {
 		case <-logEvery.C:
 			dbg.ReadMemStats(&m)
-			log.Info(fmt.Sprintf("[%s][agg] computing trie", logPrefix),
+			logger.Info(fmt.Sprintf("[%s][agg] computing trie", logPrefix),
 				"progress", fmt.Sprintf("%s/%s", common.PrettyCounter(ki), common.PrettyCounter(updatesCount)),
 				"alloc", common.ByteCount(m.Alloc), "sys", common.ByteCount(m.Sys))
 
@@ -2020,17 +2032,39 @@ func (hph *HexPatriciaHashed) Process(ctx context.Context, updates *Updates, log
 		if hph.trace {
 			fmt.Printf("\n%d/%d) plainKey [%x] hashedKey [%x] currentKey [%x]\n", ki+1, updatesCount, plainKey, hashedKey, hph.currentKey[:hph.currentKeyLen])
 		}
+		var stepStart time.Time
+		if hph.record != nil {
+			hph.record.addKey(plainKey)
+			stepStart = time.Now()
+		}
+		allocs := hph.record.startAllocs()
 		// Keep folding until the currentKey is the prefix of the key we modify
 		for hph.needFolding(hashedKey) {
 			if err := hph.fold(); err != nil {
 				return fmt.Errorf("fold: %w", err)
 			}
+			if hph.record != nil {
+				hph.record.Folds++
+			}
+		}
+		if hph.record != nil {
+			hph.record.FoldTook += time.Since(stepStart)
+			allocs.addTo(&hph.record.FoldAllocs)
+			stepStart, allocs = time.Now(), hph.record.startAllocs()
 		}
 		// Now unfold until we step on an empty cell
 		for unfolding := hph.needUnfolding(hashedKey); unfolding > 0; unfolding = hph.needUnfolding(hashedKey) {
 			if err := hph.unfold(hashedKey, unfolding); err != nil {
 				return fmt.Errorf("unfold: %w", err)
 			}
+			if hph.record != nil {
+				hph.record.Unfolds++
+			}
+		}
+		if hph.record != nil {
+			hph.record.UnfoldTook += time.Since(stepStart)
+			allocs.addTo(&hph.record.UnfoldAllocs)
+			stepStart, allocs = time.Now(), hph.record.startAllocs()
 		}
 
 		if stateUpdate == nil {
@@ -2055,6 +2089,10 @@ func (hph *HexPatriciaHashed) Process(ctx context.Context, updates *Updates, log
 			}
 		}
 		hph.updateCell(plainKey, hashedKey, update)
+		if hph.record != nil {
+			hph.record.UpdateTook += time.Since(stepStart)
+			allocs.addTo(&hph.record.UpdateAllocs)
+		}
 
 		mxTrieProcessedKeys.Inc()
 		ki++
@@ -2065,25 +2103,43 @@ func (hph *HexPatriciaHashed) Process(ctx context.Context, updates *Updates, log
 	}
 
 	// Folding everything up to the root
+	foldStart, allocs := time.Now(), hph.record.startAllocs()
 	for hph.activeRows > 0 {
 		if err := hph.fold(); err != nil {
 			return nil, fmt.Errorf("final fold: %w", err)
 		}
+		if hph.record != nil {
+			hph.record.Folds++
+		}
+	}
+	if hph.record != nil {
+		hph.record.FoldTook += time.Since(foldStart)
+		allocs.addTo(&hph.record.FoldAllocs)
 	}
 
+	allocs = hph.record.startAllocs()
 	rootHash, err = hph.RootHash()
 	if err != nil {
 		return nil, fmt.Errorf("root hash evaluation failed: %w", err)
 	}
+	if hph.record != nil {
+		allocs.addTo(&hph.record.RootHashAllocs)
+	}
 	if hph.trace {
 		fmt.Printf("root hash %x updates %d\n", rootHash, updatesCount)
 	}
+	branchWriteStart, allocs := time.Now(), hph.record.startAllocs()
 	err = hph.branchEncoder.Load(hph.ctx, etl.TransformArgs{Quit: ctx.Done()})
 	if err != nil {
 		return nil, fmt.Errorf("branch update failed: %w", err)
 	}
+	if hph.record != nil {
+		hph.record.BranchWriteTook = time.Since(branchWriteStart)
+		allocs.addTo(&hph.record.BranchWriteAllocs)
+		hph.record.Took = time.Since(start)
+	}
 	if dbg.KVReadLevelledMetrics {
-		log.Debug("commitment finished, counters updated (no reset)",
+		logger.Debug("commitment finished, counters updated (no reset)",
 			//"hadToLoad", common.PrettyCounter(hadToLoad.Load()), "skippedLoad", common.PrettyCounter(skippedLoad.Load()),
 			//"hadToReset", common.PrettyCounter(hadToReset.Load()),
 			"skip ratio", fmt.Sprintf("%.1f%%", 100*(float64(skippedLoad.Load())/float64(hadToLoad.Load()+skippedLoad.Load()))),
@@ -2101,9 +2157,9 @@ func (hph *HexPatriciaHashed) Process(ctx context.Context, updates *Updates, log
 			accs := fmt.Sprintf("load=%s skip=%s (%.1f%%) reset %.1f%%", common.PrettyCounter(v.accLoaded), common.PrettyCounter(v.accSkipped), 100*(float64(v.accSkipped)/float64(v.accLoaded+v.accSkipped)), 100*(float64(v.accReset)/float64(v.accReset+v.accSkipped)))
 			stors := fmt.Sprintf("load=%s skip=%s (%.1f%%) reset %.1f%%", common.PrettyCounter(v.storLoaded), common.PrettyCounter(v.storSkipped), 100*(float64(v.storSkipped)/float64(v.storLoaded+v.storSkipped)), 100*(float64(v.storReset)/float64(v.storReset+v.storSkipped)))
 			if k == 0 {
-				log.Debug("branchData memoization, new branches", "endStep", k, "accounts", accs, "storages", stors)
+				logger.Debug("branchData memoization, new branches", "endStep", k, "accounts", accs, "storages", stors)
 			} else {
-				log.Debug("branchData memoization", "L", Li, "endStep", k, "accounts", accs, "storages", stors)
+				logger.Debug("branchData memoization", "L", Li, "endStep", k, "accounts", accs, "storages", stors)
 				Li++
 
 				mxTrieStateLevelledSkipRatesAccount[min(Li, 5)].Add(float64(v.accSkipped))
@@ -2119,6 +2175,10 @@ func (hph *HexPatriciaHashed) Process(ctx context.Context, updates *Updates, log
 
 func (hph *HexPatriciaHashed) SetTrace(trace bool) { hph.trace = trace }
 
+// SetProcessRecord - record keys, branches and timings of next Process calls into r (nil - stop recording).
+// Caller is responsible to Reset r between calls.
+func (hph *HexPatriciaHashed) SetProcessRecord(r *ProcessRecord) { hph.record = r }
+
 func (hph *HexPatriciaHashed) Variant() TrieVariant { return VariantHexPatriciaTrie }
 
 // Reset allows HexPatriciaHashed instance to be reused for the new commitment calculation
@@ -2366,7 +2426,7 @@ func (cell *cell) Decode(buf []byte) error {
 		pos += cell.extLen //nolint
 	}
 	if flags&cellFlagDelete != 0 {
-		log.Warn("deleted cell should not be encoded", "cell", cell.String())
+		logger.Warn("deleted cell should not be encoded", "cell", cell.String())
 		cell.Update.Flags = DeleteUpdate
 	}
 	return nil
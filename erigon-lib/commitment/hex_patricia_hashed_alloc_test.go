@@ -0,0 +1,126 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commitment
+
+import (
+	"context"
+	"encoding/hex"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/length"
+)
+
+// processAllocs - runs Process with allocations counted by ProcessRecord: fold and unfold per updated key,
+// root hash per Process. Hashing of cells below root is done by fold and attributed to it.
+func processAllocs(tb testing.TB, hph *HexPatriciaHashed, pk [][]byte, upds []Update, runs int) (fold, unfold, hash float64, record *ProcessRecord) {
+	tb.Helper()
+	record = &ProcessRecord{CountAllocs: true}
+	hph.SetProcessRecord(record)
+	defer hph.SetProcessRecord(nil)
+	var foldAllocs, unfoldAllocs, hashAllocs uint64
+	var keys int
+	for i := 0; i < runs; i++ {
+		hph.Reset()
+		record.Reset()
+		updates := WrapKeyUpdates(tb, ModeDirect, hph.HashAndNibblizeKey, pk, upds)
+		_, err := hph.Process(context.Background(), updates, "")
+		updates.Close()
+		require.NoError(tb, err)
+		foldAllocs, unfoldAllocs, hashAllocs = foldAllocs+record.FoldAllocs.Objects, unfoldAllocs+record.UnfoldAllocs.Objects, hashAllocs+record.RootHashAllocs.Objects
+		keys += len(record.PlainKeys)
+	}
+	return float64(foldAllocs) / float64(keys), float64(unfoldAllocs) / float64(keys), float64(hashAllocs) / float64(runs), record
+}
+
+// sectionsFixture - trie of `accounts` accounts (some with storage) committed to mock state,
+// and updates of `updated` random accounts of it
+func sectionsFixture(tb testing.TB, accounts, updated int) (*HexPatriciaHashed, *MockState, [][]byte, []Update) {
+	tb.Helper()
+	rnd := rand.New(rand.NewSource(42))
+	builder := NewUpdateBuilder()
+	addrs := make([]string, accounts)
+	for i := range addrs {
+		key := make([]byte, length.Addr)
+		rnd.Read(key)
+		addrs[i] = hex.EncodeToString(key)
+		builder.Balance(addrs[i], rnd.Uint64())
+		if i%10 == 0 {
+			for j := 0; j < 10; j++ {
+				builder.Storage(addrs[i], hex.EncodeToString([]byte{byte(j)}), hex.EncodeToString([]byte{byte(i), byte(j + 1)}))
+			}
+		}
+	}
+	pk, upds := builder.Build()
+	ms := NewMockState(tb)
+	require.NoError(tb, ms.applyPlainUpdates(pk, upds))
+	hph := NewHexPatriciaHashed(length.Addr, ms, ms.TempDir())
+	updates := WrapKeyUpdates(tb, ModeDirect, hph.HashAndNibblizeKey, pk, upds)
+	_, err := hph.Process(context.Background(), updates, "")
+	updates.Close()
+	require.NoError(tb, err)
+
+	builder = NewUpdateBuilder()
+	for i := 0; i < updated; i++ {
+		builder.Balance(addrs[rnd.Intn(len(addrs))], rnd.Uint64())
+	}
+	pk, upds = builder.Build()
+	require.NoError(tb, ms.applyPlainUpdates(pk, upds))
+	return hph, ms, pk, upds
+}
+
+// TestHexPatriciaHashed_SectionAllocs - allocation regression gate of fold/unfold/root hash of HexPatriciaHashed.
+// Limits are ~2x of measured values: raise them only together with understanding of where new allocations come from.
+func TestHexPatriciaHashed_SectionAllocs(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	hph, _, pk, upds := sectionsFixture(t, 10_000, 100)
+	fold, unfold, hash, record := processAllocs(t, hph, pk, upds, 5)
+	t.Logf("allocs per key: fold=%.1f unfold=%.1f, per root hash=%.1f; last run: fold=%dB unfold=%dB update=%dB branch write=%dB",
+		fold, unfold, hash, record.FoldAllocs.Bytes, record.UnfoldAllocs.Bytes, record.UpdateAllocs.Bytes, record.BranchWriteAllocs.Bytes)
+	require.LessOrEqual(t, fold, 35.0)
+	require.LessOrEqual(t, unfold, 5.0)
+	require.LessOrEqual(t, hash, 5.0)
+	require.NotZero(t, record.FoldAllocs.Objects)
+	require.NotZero(t, record.UpdateAllocs.Objects)
+
+	// not counted unless asked
+	record = &ProcessRecord{}
+	hph.SetProcessRecord(record)
+	defer hph.SetProcessRecord(nil)
+	hph.Reset()
+	updates := WrapKeyUpdates(t, ModeDirect, hph.HashAndNibblizeKey, pk, upds)
+	defer updates.Close()
+	_, err := hph.Process(context.Background(), updates, "")
+	require.NoError(t, err)
+	require.NotZero(t, record.Folds)
+	require.Zero(t, record.FoldAllocs)
+	require.Zero(t, record.UnfoldAllocs)
+}
+
+func BenchmarkHexPatriciaHashed_Sections(b *testing.B) {
+	hph, _, pk, upds := sectionsFixture(b, 10_000, 100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	fold, unfold, hash, _ := processAllocs(b, hph, pk, upds, b.N)
+	b.ReportMetric(fold, "fold-allocs/key")
+	b.ReportMetric(unfold, "unfold-allocs/key")
+	b.ReportMetric(hash, "roothash-allocs/op")
+}
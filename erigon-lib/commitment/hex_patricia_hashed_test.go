@@ -29,6 +29,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/featureflags"
 	"github.com/erigontech/erigon-lib/common/length"
 )
 
@@ -1338,3 +1339,38 @@ func Test_HexPatriciaHashed_ProcessWithDozensOfStorageKeys(t *testing.T) {
 	}
 	require.EqualValues(t, rBatch, rSeq, "sequential and batch root should match")
 }
+
+func Test_HexPatriciaHashed_MemoizedHashesDisabled(t *testing.T) {
+	ctx := context.Background()
+	plainKeys, updates := NewUpdateBuilder().
+		Balance("68ee6c0e9cdc73b2b2d52dbd79f19d24fe25e2f9", 4).
+		Balance("18f4dcf2d94402019d5b00f71d5f9d02e4f70e40", 900234).
+		Storage("8e5476fc5990638a4fb0b5fd3f61bb4b5c5f395e", "24f3a02dc65eda502dbf75919e795458413d3c45b38bb35b51235432707900ed", "0401").
+		Storage("ba7a3b7b095d3370c022ca655c790f0c0ead66f5", "0fa41642c48ecf8f2059c275353ce4fee173b3a8ce5480f040c4d2901603d14e", "050505").
+		Nonce("18f4dcf2d94402019d5b00f71d5f9d02e4f70e40", 169356).
+		Storage("8e5476fc5990638a4fb0b5fd3f61bb4b5c5f395e", "24f3a02dc65eda502dbf75919e795458413d3c45b38bb35b51235432707900ed", "0402").
+		Balance("14c4d3bba7f5009599257d3701785d34c7f2aa27", 6*1e18).
+		Build()
+
+	sequentialRoot := func() []byte {
+		ms := NewMockState(t)
+		hph := NewHexPatriciaHashed(length.Addr, ms, ms.TempDir())
+		var root []byte
+		for i := range updates {
+			require.NoError(t, ms.applyPlainUpdates(plainKeys[i:i+1], updates[i:i+1]))
+			upds := WrapKeyUpdates(t, ModeDirect, hph.HashAndNibblizeKey, plainKeys[i:i+1], updates[i:i+1])
+			rh, err := hph.Process(ctx, upds, "")
+			require.NoError(t, err)
+			upds.Close()
+			root = common.Copy(rh)
+		}
+		return root
+	}
+
+	withMemoized := sequentialRoot()
+
+	_, err := featureflags.Set(memoizedHashesFlag.Name(), false)
+	require.NoError(t, err)
+	defer featureflags.Set(memoizedHashesFlag.Name(), true) //nolint:errcheck
+	require.Equal(t, withMemoized, sequentialRoot())
+}
@@ -33,13 +33,13 @@ import (
 
 // In memory commitment and state to use with the tests
 type MockState struct {
-	t      *testing.T
+	t      testing.TB
 	sm     map[string][]byte     // backbone of the state
 	cm     map[string]BranchData // backbone of the commitments
 	numBuf [binary.MaxVarintLen64]byte
 }
 
-func NewMockState(t *testing.T) *MockState {
+func NewMockState(t testing.TB) *MockState {
 	t.Helper()
 	return &MockState{
 		t:  t,
@@ -0,0 +1,137 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commitment
+
+import (
+	"bytes"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/erigontech/erigon-lib/common"
+)
+
+// ProcessRecord - what HexPatriciaHashed.Process touched and where it spent time.
+// Collected only when set by SetProcessRecord: enough to understand (and reproduce) slow commitment computation
+// without re-running it with trace.
+type ProcessRecord struct {
+	PlainKeys      [][]byte // updated keys in order of processing (by hashed key)
+	BranchPrefixes [][]byte // compacted prefixes of branches read by unfold
+
+	Folds, Unfolds int
+
+	FoldTook        time.Duration
+	UnfoldTook      time.Duration // includes branch reads
+	UpdateTook      time.Duration // reads of updated accounts/storage and cell updates
+	BranchWriteTook time.Duration // load of collected branch updates into domain
+	Took            time.Duration
+
+	// Heap allocations of same parts of Process, counted only if CountAllocs is set. Each measurement
+	// stops the world (runtime.ReadMemStats), so it's for allocation regression tests and short diagnostics.
+	CountAllocs       bool
+	FoldAllocs        AllocStats // includes hashing of folded cells
+	UnfoldAllocs      AllocStats // includes branch reads and key buffers
+	UpdateAllocs      AllocStats // includes reads and decode of updated accounts/storage
+	RootHashAllocs    AllocStats
+	BranchWriteAllocs AllocStats
+}
+
+// Reset - clears collected data, keeps CountAllocs
+func (r *ProcessRecord) Reset() {
+	r.PlainKeys, r.BranchPrefixes = r.PlainKeys[:0], r.BranchPrefixes[:0]
+	r.Folds, r.Unfolds = 0, 0
+	r.FoldTook, r.UnfoldTook, r.UpdateTook, r.BranchWriteTook, r.Took = 0, 0, 0, 0, 0
+	r.FoldAllocs, r.UnfoldAllocs, r.UpdateAllocs, r.RootHashAllocs, r.BranchWriteAllocs = AllocStats{}, AllocStats{}, AllocStats{}, AllocStats{}, AllocStats{}
+}
+
+// AllocStats - heap allocations of part of Process. Allocations of other goroutines done meanwhile are counted too.
+type AllocStats struct {
+	Objects uint64
+	Bytes   uint64
+}
+
+// allocMeter - allocations counters at start of measured part, zero if allocations are not counted
+type allocMeter struct {
+	on             bool
+	objects, bytes uint64
+}
+
+func (r *ProcessRecord) startAllocs() allocMeter {
+	if r == nil || !r.CountAllocs {
+		return allocMeter{}
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return allocMeter{on: true, objects: m.Mallocs, bytes: m.TotalAlloc}
+}
+
+func (a allocMeter) addTo(s *AllocStats) {
+	if !a.on {
+		return
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	s.Objects += m.Mallocs - a.objects
+	s.Bytes += m.TotalAlloc - a.bytes
+}
+
+// KeyRange - updated keys of one account: account itself and/or range of its storage slots
+type KeyRange struct {
+	Account   []byte
+	Touched   bool // account key itself was updated
+	Slots     int
+	FirstSlot []byte
+	LastSlot  []byte
+}
+
+// KeyRanges - updated keys grouped by account, in order of accounts
+func (r *ProcessRecord) KeyRanges(accountKeyLen int) []KeyRange {
+	keys := make([][]byte, len(r.PlainKeys))
+	copy(keys, r.PlainKeys)
+	slices.SortFunc(keys, bytes.Compare)
+
+	var res []KeyRange
+	for _, k := range keys {
+		if len(k) < accountKeyLen {
+			continue
+		}
+		acc := k[:accountKeyLen]
+		if len(res) == 0 || !bytes.Equal(res[len(res)-1].Account, acc) {
+			res = append(res, KeyRange{Account: acc})
+		}
+		kr := &res[len(res)-1]
+		if len(k) == accountKeyLen {
+			kr.Touched = true
+			continue
+		}
+		slot := k[accountKeyLen:]
+		if kr.Slots == 0 {
+			kr.FirstSlot = slot
+		}
+		kr.LastSlot = slot
+		kr.Slots++
+	}
+	return res
+}
+
+func (r *ProcessRecord) addKey(plainKey []byte) {
+	r.PlainKeys = append(r.PlainKeys, common.Copy(plainKey))
+}
+
+func (r *ProcessRecord) addBranchPrefix(prefix []byte) {
+	r.BranchPrefixes = append(r.BranchPrefixes, common.Copy(prefix))
+}
@@ -0,0 +1,140 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commitment
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// StorageSubtrie finds the cell of account plainKey by descending stored branches from the root and returns
+// the storage root of the account recomputed from that cell, with leaves reloaded from flat state. Then it calls
+// fn for every branch of the account's storage subtrie, parents before children. key is the commitment domain key
+// of the branch and branch is the data stored under it, both are only valid during the call. fn may be nil if only
+// the storage root is needed. found is false if the account is not in the trie.
+func (hph *HexPatriciaHashed) StorageSubtrie(plainKey []byte, fn func(key, branch []byte) error) (storageRoot []byte, found bool, err error) {
+	hashedKey := hph.HashAndNibblizeKey(plainKey)
+	var row [16]cell
+	var prefix []byte
+	for len(prefix) < len(hashedKey) {
+		afterMap, _, err := hph.storedBranchCells(prefix, &row)
+		if err != nil {
+			return nil, false, err
+		}
+		nibble := int(hashedKey[len(prefix)])
+		if afterMap&(uint16(1)<<nibble) == 0 {
+			return nil, false, nil
+		}
+		c := &row[nibble]
+		if c.accountAddrLen == 0 {
+			if c.storageAddrLen > 0 || c.hashLen == 0 {
+				return nil, false, nil
+			}
+			next := append(append(append(make([]byte, 0, len(prefix)+1+c.extLen), prefix...), byte(nibble)), c.extension[:c.extLen]...)
+			if !bytes.HasPrefix(hashedKey, next) {
+				return nil, false, nil
+			}
+			prefix = next
+			continue
+		}
+		if !bytes.Equal(c.accountAddr[:c.accountAddrLen], plainKey) {
+			return nil, false, nil
+		}
+
+		// drop memoized hash, so storage root is recomputed from the cell and flat state
+		c.stateHashLen = 0
+		upd, err := hph.ctx.Account(c.accountAddr[:c.accountAddrLen])
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to get account: %w", err)
+		}
+		c.setFromUpdate(upd)
+		c.loaded = c.loaded.addFlag(cellLoadAccount)
+		if c.storageAddrLen > 0 {
+			upd, err := hph.ctx.Storage(c.storageAddr[:c.storageAddrLen])
+			if err != nil {
+				return nil, true, fmt.Errorf("failed to get storage: %w", err)
+			}
+			c.setFromUpdate(upd)
+			c.loaded = c.loaded.addFlag(cellLoadStorage)
+		}
+		_, _, root, err := hph.computeCellHashWithStorage(c, len(prefix)+1, nil)
+		if err != nil {
+			return nil, true, err
+		}
+		storageRoot = append([]byte{}, root...)
+		if fn == nil || c.storageAddrLen > 0 || c.hashLen == 0 {
+			return storageRoot, true, nil // no storage branches
+		}
+		return storageRoot, true, hph.walkStoredBranches(append(hashedKey, c.extension[:c.extLen]...), &row, fn)
+	}
+	return nil, false, nil
+}
+
+// walkStoredBranches calls fn for the branch stored at prefix and all its descendant branches, parents before children.
+func (hph *HexPatriciaHashed) walkStoredBranches(prefix []byte, row *[16]cell, fn func(key, branch []byte) error) error {
+	queue := [][]byte{prefix}
+	for len(queue) > 0 {
+		prefix, queue = queue[0], queue[1:]
+		afterMap, branch, err := hph.storedBranchCells(prefix, row)
+		if err != nil {
+			return err
+		}
+		if branch == nil {
+			return fmt.Errorf("branch [%x] is referenced by its parent but not stored", prefix)
+		}
+		if err := fn(hexToCompact(prefix), branch); err != nil {
+			return err
+		}
+		for nibble := 0; nibble < 16; nibble++ {
+			c := &row[nibble]
+			if afterMap&(uint16(1)<<nibble) == 0 || c.accountAddrLen > 0 || c.storageAddrLen > 0 || c.hashLen == 0 {
+				continue
+			}
+			child := append(append(append(make([]byte, 0, len(prefix)+1+c.extLen), prefix...), byte(nibble)), c.extension[:c.extLen]...)
+			queue = append(queue, child)
+		}
+	}
+	return nil
+}
+
+// storedBranchCells decodes cells of the branch stored at prefix into row. Returns bitmap of present cells and
+// the branch data as stored (with touch map), branch is nil if nothing is stored at prefix.
+func (hph *HexPatriciaHashed) storedBranchCells(prefix []byte, row *[16]cell) (afterMap uint16, branch []byte, err error) {
+	branch, _, err = hph.ctx.Branch(hexToCompact(prefix))
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(branch) < 4 {
+		return 0, nil, nil
+	}
+	afterMap = binary.BigEndian.Uint16(branch[2:])
+	pos := 4
+	for nibble := 0; nibble < 16; nibble++ {
+		if afterMap&(uint16(1)<<nibble) == 0 {
+			continue
+		}
+		c := &row[nibble]
+		c.reset()
+		fieldBits := branch[pos]
+		pos++
+		if pos, err = c.fillFromFields(branch, pos, cellFields(fieldBits)); err != nil {
+			return 0, nil, fmt.Errorf("prefix [%x] branchData[%x]: %w", prefix, branch, err)
+		}
+	}
+	return afterMap, branch, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package commitment
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/trie"
+)
+
+func Test_HexPatriciaHashed_StorageSubtrie(t *testing.T) {
+	ctx := context.Background()
+	ms := NewMockState(t)
+
+	ub := NewUpdateBuilder()
+	for i := 0; i < 50; i++ {
+		addr := fmt.Sprintf("%040x", i)
+		ub.Balance(addr, uint64(i+1))
+		switch i {
+		case 7: // single slot is folded into account cell
+			ub.Storage(addr, fmt.Sprintf("%064x", 1), "0100")
+		case 10:
+			for j := 0; j < 40; j++ {
+				ub.Storage(addr, fmt.Sprintf("%064x", j), fmt.Sprintf("%04x", 0x100+j))
+			}
+		}
+	}
+	plainKeys, updates := ub.Build()
+
+	hph := NewHexPatriciaHashed(length.Addr, ms, ms.TempDir())
+	require.NoError(t, ms.applyPlainUpdates(plainKeys, updates))
+	upds := WrapKeyUpdates(t, ModeDirect, hph.HashAndNibblizeKey, plainKeys, updates)
+	_, err := hph.Process(ctx, upds, "")
+	require.NoError(t, err)
+	upds.Close()
+
+	storageRoot := func(slots int, value func(j int) []byte) []byte {
+		tr := trie.New(libcommon.Hash{})
+		for j := 0; j < slots; j++ {
+			loc := libcommon.FromHex(fmt.Sprintf("%064x", j))
+			if slots == 1 {
+				loc = libcommon.FromHex(fmt.Sprintf("%064x", 1))
+			}
+			tr.Update(crypto.Keccak256(loc), value(j))
+		}
+		h := tr.Hash()
+		return h[:]
+	}
+
+	resolver := NewHexPatriciaHashed(length.Addr, ms, ms.TempDir())
+	var prefixes [][]byte
+	root, found, err := resolver.StorageSubtrie(libcommon.FromHex(fmt.Sprintf("%040x", 10)), func(key, branch []byte) error {
+		prefixes = append(prefixes, CompactedKeyToHex(key))
+		return nil
+	})
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, storageRoot(40, func(j int) []byte { return libcommon.FromHex(fmt.Sprintf("%04x", 0x100+j)) }), root)
+	require.NotEmpty(t, prefixes)
+	for _, p := range prefixes {
+		require.GreaterOrEqual(t, len(p), 64)
+	}
+
+	root, found, err = resolver.StorageSubtrie(libcommon.FromHex(fmt.Sprintf("%040x", 7)), func(key, branch []byte) error {
+		return fmt.Errorf("unexpected branch [%x]", key)
+	})
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, storageRoot(1, func(int) []byte { return []byte{1, 0} }), root)
+
+	root, found, err = resolver.StorageSubtrie(libcommon.FromHex(fmt.Sprintf("%040x", 3)), nil)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, EmptyRootHash, root)
+
+	_, found, err = resolver.StorageSubtrie(libcommon.FromHex(fmt.Sprintf("%040x", 1000)), nil)
+	require.NoError(t, err)
+	require.False(t, found)
+}
@@ -21,6 +21,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 
 	"github.com/gofrs/flock"
@@ -109,18 +111,56 @@ func TryFlock(dirs Dirs) (*flock.Flock, bool, error) {
 	l := flock.New(filepath.Join(dirs.DataDir, "LOCK"))
 	locked, err := l.TryLock()
 	if err != nil {
-		return nil, false, convertFileLockError(err)
+		if err = convertFileLockError(err); errors.Is(err, ErrDataDirLocked) {
+			err = LockedError(dirs)
+		}
+		return nil, false, err
+	}
+	if locked {
+		// pid of owner - for error of next process. Separate file: on Windows locked file is not writable by other handle.
+		// Lock is released by OS when process dies, but pid file stays if process was killed: pid of dead process may be
+		// reported if datadir is locked by process which doesn't write pid (older version) or didn't write it yet.
+		// Unflock removes pid file.
+		if err := os.WriteFile(lockPidFile(dirs), []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+			_ = l.Unlock()
+			return nil, false, err
+		}
 	}
 	return l, locked, nil
 }
 
+// LockedError - ErrDataDirLocked with pid of process which holds lock (if known)
+func LockedError(dirs Dirs) error {
+	b, err := os.ReadFile(lockPidFile(dirs))
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrDataDirLocked, dirs.DataDir)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil || pid <= 0 {
+		return fmt.Errorf("%w: %s", ErrDataDirLocked, dirs.DataDir)
+	}
+	return fmt.Errorf("%w: %s, pid: %d", ErrDataDirLocked, dirs.DataDir, pid)
+}
+
+// Unflock - removes pid file of owner, then releases lock taken by TryFlock/MustFlock. Pid file is removed while
+// lock is still held: not to remove pid of next owner.
+func Unflock(dirs Dirs, l *flock.Flock) error {
+	if err := os.Remove(lockPidFile(dirs)); err != nil && !os.IsNotExist(err) {
+		_ = l.Unlock()
+		return err
+	}
+	return l.Unlock()
+}
+
+func lockPidFile(dirs Dirs) string { return filepath.Join(dirs.DataDir, "LOCK.pid") }
+
 func (dirs Dirs) MustFlock() (Dirs, *flock.Flock, error) {
 	l, locked, err := TryFlock(dirs)
 	if err != nil {
 		return dirs, l, err
 	}
 	if !locked {
-		return dirs, l, ErrDataDirLocked
+		return dirs, l, LockedError(dirs)
 	}
 	return dirs, l, nil
 }
@@ -142,7 +182,7 @@ func ApplyMigrations(dirs Dirs) error { //nolint
 	if !locked {
 		return nil
 	}
-	defer lock.Unlock()
+	defer Unflock(dirs, lock)
 
 	// add your migration here
 
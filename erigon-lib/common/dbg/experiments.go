@@ -54,6 +54,30 @@ var (
 	// allows to collect reading metrics for kv by file level
 	KVReadLevelledMetrics = EnvBool("KV_READ_METRICS", false)
 
+	// allows to collect latency histograms of Get/Seek/Next for each table of chaindata
+	KVOpLatencyMetrics = EnvBool("KV_OP_LATENCY_METRICS", false)
+
+	// allows to attribute bytes read/written by txs of chaindata to subsystems, see kv.WithSubsystem
+	KVIOAmplification = EnvBool("KV_IO_AMPLIFICATION", false)
+
+	// store values of critical tables of new chaindata with checksum, see kv.ChaindataValueChecksumTables
+	KVValueChecksums = EnvBool("KV_VALUE_CHECKSUMS", false)
+
+	// commit chaindata txs without fsync and fsync them in background once per given period. If 0, every commit is durable
+	MdbxSyncPeriod = EnvDuration("MDBX_SYNC_PERIOD", time.Duration(0))
+
+	// re-derive a random path of memoized commitment hashes from flat state every given interval. If interval is 0, canary is off
+	CommitmentCanaryInterval = EnvDuration("COMMITMENT_CANARY_INTERVAL", time.Duration(0))
+
+	// check given number of invariants (state root, changeset, history index, state cache) of each new block, rotating over them. If 0, no checks
+	BlockInvariantChecks = EnvInt("BLOCK_INVARIANT_CHECKS", 0)
+
+	// dump touched keys, branches and timings of commitment computation to <datadir>/slow_commitment if it took longer than given duration. If 0, no dumps
+	SlowCommitmentDump = EnvDuration("SLOW_COMMITMENT_DUMP", time.Duration(0))
+
+	// read accounts and storage which blocks are known to touch (senders, recipients, access lists) ahead of their execution
+	ReadAheadState = EnvBool("READ_AHEAD_STATE", false)
+
 	// run prune on flush with given timeout. If timeout is 0, no prune on flush will be performed
 	PruneOnFlushTimeout = EnvDuration("PRUNE_ON_FLUSH_TIMEOUT", time.Duration(0))
 
@@ -0,0 +1,108 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package dbg
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync"
+)
+
+// DetectTxMisuse - db tx and cursors panic on concurrent use from 2 goroutines, on use of RwTx from not-creator goroutine
+// and on use after end of tx. Instead of silent data corruption or segfault in C code.
+var DetectTxMisuse = EnvBool("DETECT_TX_MISUSE", false)
+
+// UseGuard - detects misuse of not thread-safe resource (like db tx and it's cursors). Panics with stacks of both sides.
+// Nested use by same goroutine is allowed. nil *UseGuard does nothing.
+type UseGuard struct {
+	name string
+
+	mu         sync.Mutex
+	boundTo    int64 // if not 0 - only this goroutine can use resource
+	boundStack []byte
+	owner      int64 // goroutine which is using resource now
+	depth      int
+	ownerStack []byte
+	endStack   []byte
+}
+
+func NewUseGuard(name string, boundToCurrentGoroutine bool) *UseGuard {
+	g := &UseGuard{name: name}
+	if boundToCurrentGoroutine {
+		g.boundTo, g.boundStack = goroutineID(), debug.Stack()
+	}
+	return g
+}
+
+var noopExit = func() {}
+
+// Use - usage: `defer g.Use()()`
+func (g *UseGuard) Use() (exit func()) {
+	if g == nil {
+		return noopExit
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	id := goroutineID()
+	if g.endStack != nil {
+		panic(fmt.Sprintf("%s: use after end\n--- use:\n%s\n--- end:\n%s", g.name, debug.Stack(), g.endStack))
+	}
+	if g.boundTo != 0 && g.boundTo != id {
+		panic(fmt.Sprintf("%s: use from goroutine %d, but it belongs to goroutine %d\n--- use:\n%s\n--- created:\n%s", g.name, id, g.boundTo, debug.Stack(), g.boundStack))
+	}
+	switch g.owner {
+	case 0:
+		g.owner, g.depth, g.ownerStack = id, 1, debug.Stack()
+	case id:
+		g.depth++
+	default:
+		panic(fmt.Sprintf("%s: concurrent use from goroutines %d and %d\n--- use:\n%s\n--- concurrent use:\n%s", g.name, id, g.owner, debug.Stack(), g.ownerStack))
+	}
+	return g.exit
+}
+
+func (g *UseGuard) exit() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.depth--
+	if g.depth == 0 {
+		g.owner, g.ownerStack = 0, nil
+	}
+}
+
+// End - resource is released, any next Use will panic
+func (g *UseGuard) End() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.endStack = debug.Stack()
+}
+
+func goroutineID() int64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	id, err := strconv.ParseInt(string(b[:bytes.IndexByte(b, ' ')]), 10, 64)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
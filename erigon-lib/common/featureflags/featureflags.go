@@ -0,0 +1,186 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package featureflags - runtime switches for cache/resolver behavior which can be
+// flipped without restart (for example by admin_setFeatureFlag) and survive restarts
+// through a small json file in datadir.
+//
+// Flags are process-local: Erigon node and standalone rpcdaemon share datadir, but each process
+// has own file (see FileName) - so admin_setFeatureFlag of rpcdaemon doesn't change flags of node
+// and processes don't overwrite each other's settings. Usage of each flag names processes it affects.
+//
+// Flags are declared by the packages they control, as package-level variables:
+//
+//	var stateCacheFlag = featureflags.New("state_cache", "serve reads from kvcache", true)
+//
+// and checked in hot paths by `stateCacheFlag.Enabled()` - it's a single atomic load.
+package featureflags
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Processes which persist flags in datadir. Rpcdaemon embedded into node is part of ProcessErigon.
+const (
+	ProcessErigon    = "erigon"
+	ProcessRpcDaemon = "rpcdaemon"
+)
+
+// FileName - name of file in datadir where explicitly set flags of process are persisted
+func FileName(process string) string { return "feature_flags_" + process + ".json" }
+
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+type Flag struct {
+	name    string
+	usage   string
+	def     bool
+	enabled atomic.Bool
+}
+
+// Info - snapshot of flag state, returned by All and Set
+type Info struct {
+	Name    string `json:"name"`
+	Usage   string `json:"usage"`
+	Default bool   `json:"default"`
+	Enabled bool   `json:"enabled"`
+}
+
+var (
+	lock      sync.Mutex
+	flags     = map[string]*Flag{}
+	overrides = map[string]bool{} // explicitly set values, including flags not registered in this binary
+	path      string              // where overrides are persisted, empty - in-memory only
+)
+
+// New registers flag. Must be called from package-level var declarations, panics on duplicated names.
+func New(name, usage string, def bool) *Flag {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := flags[name]; ok {
+		panic(fmt.Sprintf("feature flag %q registered twice", name))
+	}
+	f := &Flag{name: name, usage: usage, def: def}
+	f.enabled.Store(def)
+	if v, ok := overrides[name]; ok {
+		f.enabled.Store(v)
+	}
+	flags[name] = f
+	return f
+}
+
+func (f *Flag) Name() string  { return f.name }
+func (f *Flag) Enabled() bool { return f.enabled.Load() }
+
+func (f *Flag) info() Info {
+	return Info{Name: f.name, Usage: f.usage, Default: f.def, Enabled: f.Enabled()}
+}
+
+// All - state of all registered flags, sorted by name
+func All() []Info {
+	lock.Lock()
+	defer lock.Unlock()
+	res := make([]Info, 0, len(flags))
+	for _, f := range flags {
+		res = append(res, f.info())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Set - changes flag value at runtime and persists it (if Load was called)
+func Set(name string, enabled bool) (Info, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	f, ok := flags[name]
+	if !ok {
+		return Info{}, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	prev, hadPrev := overrides[name]
+	overrides[name] = enabled
+	if err := save(); err != nil {
+		if hadPrev {
+			overrides[name] = prev
+		} else {
+			delete(overrides, name)
+		}
+		return Info{}, err
+	}
+	f.enabled.Store(enabled)
+	return f.info(), nil
+}
+
+// Load - reads persisted values of process from `<dataDir>/feature_flags_<process>.json` and applies them.
+// All further Set calls are persisted to this file. Missing file is not an error.
+func Load(dataDir, process string) error {
+	lock.Lock()
+	defer lock.Unlock()
+	path = filepath.Join(dataDir, FileName(process))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	loaded := map[string]bool{}
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for name, v := range loaded {
+		overrides[name] = v
+		if f, ok := flags[name]; ok {
+			f.enabled.Store(v)
+		}
+	}
+	return nil
+}
+
+// save - writes overrides to unique temp file and renames it: readers never see partially written file,
+// and concurrent writers (several rpcdaemons over one datadir) don't corrupt each other's temp file
+func save() error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op after successful rename
+	if _, err = f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
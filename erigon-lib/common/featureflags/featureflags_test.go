@@ -0,0 +1,103 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package featureflags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func resetForTest(t *testing.T) {
+	t.Helper()
+	lock.Lock()
+	defer lock.Unlock()
+	flags, overrides, path = map[string]*Flag{}, map[string]bool{}, ""
+}
+
+func TestSetAndPersist(t *testing.T) {
+	resetForTest(t)
+	datadir := t.TempDir()
+	a := New("a", "flag a", true)
+	b := New("b", "flag b", false)
+	require.NoError(t, Load(datadir, ProcessErigon))
+	require.True(t, a.Enabled())
+	require.False(t, b.Enabled())
+
+	info, err := Set("a", false)
+	require.NoError(t, err)
+	require.Equal(t, Info{Name: "a", Usage: "flag a", Default: true, Enabled: false}, info)
+	require.False(t, a.Enabled())
+
+	_, err = Set("unknown", true)
+	require.ErrorIs(t, err, ErrUnknownFlag)
+
+	require.Equal(t, []Info{
+		{Name: "a", Usage: "flag a", Default: true, Enabled: false},
+		{Name: "b", Usage: "flag b", Default: false, Enabled: false},
+	}, All())
+
+	// restart: persisted value overrides default, untouched flags keep default
+	resetForTest(t)
+	require.NoError(t, Load(datadir, ProcessErigon))
+	a = New("a", "flag a", true)
+	b = New("b", "flag b", false)
+	require.False(t, a.Enabled())
+	require.False(t, b.Enabled())
+}
+
+func TestLoadBrokenFile(t *testing.T) {
+	resetForTest(t)
+	datadir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(datadir, FileName(ProcessErigon)), []byte("{"), 0644))
+	require.Error(t, Load(datadir, ProcessErigon))
+}
+
+func TestDuplicatedName(t *testing.T) {
+	resetForTest(t)
+	New("a", "", true)
+	require.Panics(t, func() { New("a", "", true) })
+}
+
+func TestFilePerProcess(t *testing.T) {
+	resetForTest(t)
+	datadir := t.TempDir()
+	a := New("a", "flag a", true)
+	require.NoError(t, Load(datadir, ProcessRpcDaemon))
+	_, err := Set("a", false)
+	require.NoError(t, err)
+
+	// other process over same datadir doesn't see it
+	resetForTest(t)
+	require.NoError(t, Load(datadir, ProcessErigon))
+	a = New("a", "flag a", true)
+	require.True(t, a.Enabled())
+	_, err = Set("a", true)
+	require.NoError(t, err)
+
+	// and doesn't overwrite it
+	resetForTest(t)
+	require.NoError(t, Load(datadir, ProcessRpcDaemon))
+	a = New("a", "flag a", true)
+	require.False(t, a.Enabled())
+
+	files, err := filepath.Glob(filepath.Join(datadir, "*"))
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{filepath.Join(datadir, FileName(ProcessErigon)), filepath.Join(datadir, FileName(ProcessRpcDaemon))}, files) // no temp files left
+}
@@ -0,0 +1,70 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package tracing - spans linked by context: from rpc call, through state reads, down to db operations and
+// commitment hashing - to break down slow call into time of its parts.
+//
+// Tracer is no-op by default: instrumented code checks `tracing.Enabled()` - a single atomic load - and doesn't
+// create spans. Embedders install real tracer by SetTracer, for example adapter to OpenTelemetry SDK:
+// otel's trace.Tracer.Start has the same shape as Tracer.Start, and trace.Span has End and SetAttributes.
+package tracing
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+type Span interface {
+	// SetAttributes - key-value pairs, keys are strings: "table", "accounts"
+	SetAttributes(kv ...any)
+	End()
+}
+
+type Tracer interface {
+	// Start - creates span which is child of span of ctx (if any), and ctx carrying new span
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type tracerHolder struct{ t Tracer }
+
+var tracer atomic.Pointer[tracerHolder]
+
+// SetTracer - installs tracer for whole process, nil - back to no-op
+func SetTracer(t Tracer) {
+	if t == nil {
+		tracer.Store(nil)
+		return
+	}
+	tracer.Store(&tracerHolder{t: t})
+}
+
+// Enabled - if false, Start returns NoopSpan: callers check it before building span name or attributes in hot paths
+func Enabled() bool { return tracer.Load() != nil }
+
+func Start(ctx context.Context, name string) (context.Context, Span) {
+	h := tracer.Load()
+	if h == nil {
+		return ctx, NoopSpan
+	}
+	return h.t.Start(ctx, name)
+}
+
+var NoopSpan Span = noopSpan{}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...any) {}
+func (noopSpan) End()                 {}
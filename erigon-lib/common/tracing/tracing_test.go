@@ -0,0 +1,71 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type parentKey struct{}
+
+type testSpan struct {
+	name, parent string
+	attrs        []any
+	ended        bool
+}
+
+func (s *testSpan) SetAttributes(kv ...any) { s.attrs = append(s.attrs, kv...) }
+func (s *testSpan) End()                    { s.ended = true }
+
+type testTracer struct{ spans []*testSpan }
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(parentKey{}).(string)
+	s := &testSpan{name: name, parent: parent}
+	t.spans = append(t.spans, s)
+	return context.WithValue(ctx, parentKey{}, name), s
+}
+
+func TestTracer(t *testing.T) {
+	require.False(t, Enabled())
+	ctx := context.Background()
+	ctx2, span := Start(ctx, "noop")
+	require.Equal(t, ctx, ctx2)
+	require.Equal(t, NoopSpan, span)
+
+	tr := &testTracer{}
+	SetTracer(tr)
+	defer SetTracer(nil)
+	require.True(t, Enabled())
+	ctx, root := Start(ctx, "root")
+	_, child := Start(ctx, "child")
+	child.SetAttributes("table", "accounts")
+	child.End()
+	root.End()
+	require.Len(t, tr.spans, 2)
+	require.Equal(t, &testSpan{name: "root", ended: true}, tr.spans[0])
+	require.Equal(t, &testSpan{name: "child", parent: "root", attrs: []any{"table", "accounts"}, ended: true}, tr.spans[1])
+
+	SetTracer(nil)
+	require.False(t, Enabled())
+	_, span = Start(ctx, "noop")
+	require.Equal(t, NoopSpan, span)
+	require.Len(t, tr.spans, 2)
+}
@@ -18,7 +18,6 @@ package backup
 
 import (
 	"context"
-	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"maps"
@@ -35,6 +34,7 @@ import (
 	"github.com/erigontech/erigon-lib/common/dbg"
 	"github.com/erigontech/erigon-lib/kv"
 	mdbx2 "github.com/erigontech/erigon-lib/kv/mdbx"
+	"github.com/erigontech/erigon-lib/kv/order"
 	"github.com/erigontech/erigon-lib/log/v3"
 )
 
@@ -183,9 +183,11 @@ const ReadAheadThreads = 2048
 func WarmupTable(ctx context.Context, db kv.RoDB, bucket string, lvl log.Lvl, readAheadThreads int) {
 	var ThreadsLimit = readAheadThreads
 	var total uint64
-	db.View(ctx, func(tx kv.Tx) error {
+	var ranges [][2][]byte
+	db.View(ctx, func(tx kv.Tx) (err error) {
 		total, _ = tx.Count(bucket)
-		return nil
+		ranges, err = kv.SplitRange(tx, bucket, 4*ThreadsLimit)
+		return err
 	})
 	if total < 10_000 {
 		return
@@ -197,54 +199,10 @@ func WarmupTable(ctx context.Context, db kv.RoDB, bucket string, lvl log.Lvl, re
 
 	g, ctx := errgroup.WithContext(ctx)
 	g.SetLimit(ThreadsLimit)
-	for i := 0; i < 256; i++ {
-		for j := 0; j < 256; j++ {
-			i := i
-			j := j
-			g.Go(func() error {
-				return db.View(ctx, func(tx kv.Tx) error {
-					it, err := tx.Prefix(bucket, []byte{byte(i), byte(j)})
-					if err != nil {
-						return err
-					}
-					defer it.Close()
-					kNum := 0
-					for it.HasNext() {
-						k, v, err := it.Next()
-						if err != nil {
-							return err
-						}
-						if len(k) > 0 {
-							_, _ = k[0], k[len(k)-1]
-						}
-						if len(v) > 0 {
-							_, _ = v[0], v[len(v)-1]
-						}
-						progress.Add(1)
-					}
-
-					kNum++
-					if kNum%1024 == 0 { // a bit reduce runtime cost
-						select {
-						case <-ctx.Done():
-							return ctx.Err()
-						case <-logEvery.C:
-							log.Log(lvl, fmt.Sprintf("[warmup] Progress: %s %.2f%%", bucket, 100*float64(progress.Load())/float64(total)))
-						default:
-						}
-					}
-					return nil
-				})
-			})
-		}
-	}
-	for i := 0; i < 1_000; i++ {
-		i := i
+	for _, r := range ranges {
 		g.Go(func() error {
 			return db.View(ctx, func(tx kv.Tx) error {
-				seek := make([]byte, 8)
-				binary.BigEndian.PutUint64(seek, uint64(i*100_000))
-				it, err := tx.Prefix(bucket, seek)
+				it, err := tx.Range(bucket, r[0], r[1], order.Asc, -1)
 				if err != nil {
 					return err
 				}
@@ -261,9 +219,10 @@ func WarmupTable(ctx context.Context, db kv.RoDB, bucket string, lvl log.Lvl, re
 					if len(v) > 0 {
 						_, _ = v[0], v[len(v)-1]
 					}
+					progress.Add(1)
 
 					kNum++
-					if kNum%1024 == 0 {
+					if kNum%1024 == 0 { // a bit reduce runtime cost
 						select {
 						case <-ctx.Done():
 							return ctx.Err()
@@ -273,7 +232,6 @@ func WarmupTable(ctx context.Context, db kv.RoDB, bucket string, lvl log.Lvl, re
 						}
 					}
 				}
-
 				return nil
 			})
 		})
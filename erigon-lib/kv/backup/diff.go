@@ -0,0 +1,174 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+type DiffKind uint8
+
+const (
+	DiffMissing   DiffKind = iota // record is in ref, but not in db
+	DiffExtra                     // record is in db, but not in ref
+	DiffDifferent                 // key is in both, but values are different. DupSort tables don't have it: different dups are Missing+Extra
+)
+
+func (k DiffKind) String() string {
+	switch k {
+	case DiffMissing:
+		return "missing"
+	case DiffExtra:
+		return "extra"
+	default:
+		return "different"
+	}
+}
+
+type DiffStat struct {
+	Table                            string
+	Equal, Missing, Extra, Different uint64
+}
+
+func (s DiffStat) Diffs() uint64 { return s.Missing + s.Extra + s.Different }
+
+// Diff - compares tables (all not deprecated tables of db if empty) of db and ref: walks both by cursors in key order,
+// each db in 1 read tx - so both are consistent snapshots. Calls onDiff (if not nil) on each difference:
+// v - value in db, refV - value in ref. Sampling and limits of reporting are up to onDiff: counting is always exact.
+func Diff(ctx context.Context, db, ref kv.RoDB, tables []string, onDiff func(table string, kind DiffKind, k, v, refV []byte) error, logger log.Logger) ([]DiffStat, error) {
+	if len(tables) == 0 {
+		for name, cfg := range db.AllTables() {
+			if !cfg.IsDeprecated {
+				tables = append(tables, name)
+			}
+		}
+	}
+	slices.Sort(tables)
+
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	stats := make([]DiffStat, 0, len(tables))
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		return ref.View(ctx, func(refTx kv.Tx) error {
+			for _, table := range tables {
+				isDupSort := db.AllTables()[table].Flags&kv.DupSort != 0
+				stat, err := diffTable(ctx, tx, refTx, table, isDupSort, onDiff, logEvery, logger)
+				if err != nil {
+					return err
+				}
+				stats = append(stats, stat)
+			}
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func diffTable(ctx context.Context, tx, refTx kv.Tx, table string, isDupSort bool, onDiff func(table string, kind DiffKind, k, v, refV []byte) error, logEvery *time.Ticker, logger log.Logger) (DiffStat, error) {
+	stat := DiffStat{Table: table}
+	report := func(kind DiffKind, k, v, refV []byte) error {
+		switch kind {
+		case DiffMissing:
+			stat.Missing++
+		case DiffExtra:
+			stat.Extra++
+		case DiffDifferent:
+			stat.Different++
+		}
+		if onDiff == nil {
+			return nil
+		}
+		return onDiff(table, kind, k, v, refV)
+	}
+
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return stat, err
+	}
+	defer c.Close()
+	refC, err := refTx.Cursor(table)
+	if err != nil {
+		return stat, err
+	}
+	defer refC.Close()
+
+	k, v, err := c.First()
+	if err != nil {
+		return stat, err
+	}
+	refK, refV, err := refC.First()
+	if err != nil {
+		return stat, err
+	}
+	for k != nil || refK != nil {
+		var cmp int
+		switch {
+		case k == nil:
+			cmp = 1
+		case refK == nil:
+			cmp = -1
+		default:
+			cmp = bytes.Compare(k, refK)
+			if cmp == 0 && isDupSort {
+				cmp = bytes.Compare(v, refV)
+			}
+		}
+
+		switch {
+		case cmp < 0:
+			err = report(DiffExtra, k, v, nil)
+		case cmp > 0:
+			err = report(DiffMissing, refK, nil, refV)
+		case bytes.Equal(v, refV):
+			stat.Equal++
+		default:
+			err = report(DiffDifferent, k, v, refV)
+		}
+		if err != nil {
+			return stat, err
+		}
+		if cmp <= 0 {
+			if k, v, err = c.Next(); err != nil {
+				return stat, err
+			}
+		}
+		if cmp >= 0 {
+			if refK, refV, err = refC.Next(); err != nil {
+				return stat, err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return stat, ctx.Err()
+		case <-logEvery.C:
+			logger.Info("[diff] progress", "table", table, "key", fmt.Sprintf("%x", k), "equal", stat.Equal, "diffs", stat.Diffs())
+		default:
+		}
+	}
+	return stat, nil
+}
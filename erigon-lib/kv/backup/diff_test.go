@@ -0,0 +1,84 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/memdb"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	fill := func(db kv.RwDB, f func(tx kv.RwTx) error) {
+		require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+			for i := 0; i < 10; i++ {
+				if err := tx.Put(kv.Headers, []byte(fmt.Sprintf("key%d", i)), []byte("v")); err != nil {
+					return err
+				}
+				if err := tx.Put(kv.TblAccountVals, []byte("acc"), []byte(fmt.Sprintf("v%d", i))); err != nil {
+					return err
+				}
+			}
+			return f(tx)
+		}))
+	}
+	db, ref := memdb.NewTestDB(t, kv.ChainDB), memdb.NewTestDB(t, kv.ChainDB)
+	fill(db, func(tx kv.RwTx) error {
+		if err := tx.Put(kv.Headers, []byte("key3"), []byte("changed")); err != nil {
+			return err
+		}
+		if err := tx.Delete(kv.Headers, []byte("key5")); err != nil {
+			return err
+		}
+		return tx.Put(kv.TblAccountVals, []byte("acc"), []byte("v99"))
+	})
+	fill(ref, func(tx kv.RwTx) error { return tx.Put(kv.Headers, []byte("key99"), []byte("v")) })
+
+	type diff struct {
+		table   string
+		kind    DiffKind
+		k       string
+		v, refV string
+	}
+	var diffs []diff
+	stats, err := Diff(ctx, db, ref, []string{kv.TblAccountVals, kv.Headers}, func(table string, kind DiffKind, k, v, refV []byte) error {
+		diffs = append(diffs, diff{table, kind, string(k), string(v), string(refV)})
+		return nil
+	}, log.New())
+	require.NoError(t, err)
+	require.Equal(t, []DiffStat{
+		{Table: kv.TblAccountVals, Equal: 10, Extra: 1},
+		{Table: kv.Headers, Equal: 8, Missing: 2, Different: 1},
+	}, stats)
+	require.Equal(t, []diff{
+		{kv.TblAccountVals, DiffExtra, "acc", "v99", ""},
+		{kv.Headers, DiffDifferent, "key3", "changed", "v"},
+		{kv.Headers, DiffMissing, "key5", "", "v"},
+		{kv.Headers, DiffMissing, "key99", "", "v"},
+	}, diffs)
+
+	stats, err = Diff(ctx, ref, ref, []string{kv.Headers}, nil, log.New())
+	require.NoError(t, err)
+	require.Zero(t, stats[0].Diffs())
+}
@@ -0,0 +1,268 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	common2 "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+// Stream format - independent of db engine and page size:
+//
+//	magic
+//	'T' table_name            - following records belong to this table
+//	'R' key value             - records of table, in cursor order (sorted, with dups sorted)
+//	'E' records_count         - end of stream, guards against truncation
+//
+// name, key and value are uvarint length followed by bytes, records_count is uvarint.
+const streamMagic = "erigon-kv-stream-v1"
+
+const (
+	streamTable  = 'T'
+	streamRecord = 'R'
+	streamEnd    = 'E'
+)
+
+var ErrStreamCorrupted = errors.New("kv stream is corrupted")
+
+// Stream - writes consistent snapshot of tables (all not deprecated tables if empty) of src into w. Snapshot is read
+// in one read transaction, so writers are not blocked.
+func Stream(ctx context.Context, src kv.RoDB, tables []string, w io.Writer, logger log.Logger) error {
+	if len(tables) == 0 {
+		for name, cfg := range src.AllTables() {
+			if !cfg.IsDeprecated {
+				tables = append(tables, name)
+			}
+		}
+	}
+	slices.Sort(tables)
+
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	bw := bufio.NewWriterSize(w, 1024*1024)
+	sw := &streamWriter{w: bw}
+	sw.writeString(streamMagic)
+	var records uint64
+	if err := src.View(ctx, func(tx kv.Tx) error {
+		for _, table := range tables {
+			sw.writeByte(streamTable)
+			sw.writeBytes([]byte(table))
+			c, err := tx.Cursor(table)
+			if err != nil {
+				return err
+			}
+			for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+				if err != nil {
+					c.Close()
+					return err
+				}
+				sw.writeByte(streamRecord)
+				sw.writeBytes(k)
+				sw.writeBytes(v)
+				if sw.err != nil {
+					c.Close()
+					return sw.err
+				}
+				records++
+				if records%100_000 == 0 {
+					select {
+					case <-ctx.Done():
+						c.Close()
+						return ctx.Err()
+					case <-logEvery.C:
+						logger.Info("[kv_stream] writing", "table", table, "records", common2.PrettyCounter(records))
+					default:
+					}
+				}
+			}
+			c.Close()
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	sw.writeByte(streamEnd)
+	sw.writeUvarint(records)
+	if sw.err != nil {
+		return sw.err
+	}
+	return bw.Flush()
+}
+
+// Restore - loads tables from stream written by Stream into dst. Tables present in stream are cleared first, tables
+// not present in stream are left untouched. Data is committed periodically, so on error dst may be partially restored.
+func Restore(ctx context.Context, dst kv.RwDB, r io.Reader, logger log.Logger) error {
+	sr := &streamReader{r: bufio.NewReaderSize(r, 1024*1024)}
+	if magic := sr.readN(len(streamMagic)); sr.err != nil || string(magic) != streamMagic {
+		return fmt.Errorf("%w: not a kv stream", ErrStreamCorrupted)
+	}
+
+	commitEvery := time.NewTicker(5 * time.Minute)
+	defer commitEvery.Stop()
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	tx, err := dst.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { tx.Rollback() }()
+
+	var table string
+	var c kv.RwCursor
+	var records uint64
+	for {
+		switch recType := sr.readByte(); {
+		case sr.err != nil:
+			return fmt.Errorf("%w: %w", ErrStreamCorrupted, sr.err)
+		case recType == streamTable:
+			table = string(sr.readBytes())
+			if sr.err != nil {
+				return fmt.Errorf("%w: %w", ErrStreamCorrupted, sr.err)
+			}
+			if _, ok := dst.AllTables()[table]; !ok {
+				return fmt.Errorf("table %s from stream is unknown to db", table)
+			}
+			if err := tx.ClearBucket(table); err != nil {
+				return err
+			}
+			if c, err = tx.RwCursor(table); err != nil {
+				return err
+			}
+		case recType == streamRecord:
+			k, v := sr.readBytes(), sr.readBytes()
+			if sr.err != nil {
+				return fmt.Errorf("%w: %w", ErrStreamCorrupted, sr.err)
+			}
+			if c == nil {
+				return fmt.Errorf("%w: record before table", ErrStreamCorrupted)
+			}
+			if dc, ok := c.(kv.RwCursorDupSort); ok {
+				err = dc.AppendDup(k, v)
+			} else {
+				err = c.Append(k, v)
+			}
+			if err != nil {
+				return fmt.Errorf("table %s: %w", table, err)
+			}
+			records++
+			if records%100_000 != 0 {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-logEvery.C:
+				logger.Info("[kv_stream] restoring", "table", table, "records", common2.PrettyCounter(records))
+			case <-commitEvery.C:
+				if err := tx.Commit(); err != nil {
+					return err
+				}
+				if tx, err = dst.BeginRw(ctx); err != nil {
+					return err
+				}
+				if c, err = tx.RwCursor(table); err != nil {
+					return err
+				}
+			default:
+			}
+		case recType == streamEnd:
+			if expected := sr.readUvarint(); sr.err != nil || expected != records {
+				return fmt.Errorf("%w: stream has %d records, restored %d", ErrStreamCorrupted, expected, records)
+			}
+			return tx.Commit()
+		default:
+			return fmt.Errorf("%w: unknown record type %x", ErrStreamCorrupted, recType)
+		}
+	}
+}
+
+type streamWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (sw *streamWriter) writeByte(b byte) {
+	if sw.err == nil {
+		sw.err = sw.w.WriteByte(b)
+	}
+}
+func (sw *streamWriter) writeString(s string) {
+	if sw.err == nil {
+		_, sw.err = sw.w.WriteString(s)
+	}
+}
+func (sw *streamWriter) writeUvarint(n uint64) {
+	if sw.err == nil {
+		_, sw.err = sw.w.Write(sw.buf[:binary.PutUvarint(sw.buf[:], n)])
+	}
+}
+func (sw *streamWriter) writeBytes(b []byte) {
+	sw.writeUvarint(uint64(len(b)))
+	if sw.err == nil {
+		_, sw.err = sw.w.Write(b)
+	}
+}
+
+type streamReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (sr *streamReader) readByte() byte {
+	if sr.err != nil {
+		return 0
+	}
+	var b byte
+	b, sr.err = sr.r.ReadByte()
+	return b
+}
+func (sr *streamReader) readUvarint() uint64 {
+	if sr.err != nil {
+		return 0
+	}
+	var n uint64
+	n, sr.err = binary.ReadUvarint(sr.r)
+	return n
+}
+func (sr *streamReader) readN(n int) []byte {
+	if sr.err != nil {
+		return nil
+	}
+	b := make([]byte, n)
+	_, sr.err = io.ReadFull(sr.r, b)
+	return b
+}
+func (sr *streamReader) readBytes() []byte {
+	n := sr.readUvarint()
+	if sr.err == nil && n > 1<<32 {
+		sr.err = fmt.Errorf("length %d is too big", n)
+	}
+	return sr.readN(int(n))
+}
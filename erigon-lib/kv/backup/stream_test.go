@@ -0,0 +1,76 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/memdb"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+func TestStreamRestore(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New()
+	src := memdb.NewTestDB(t, kv.ChainDB)
+	require.NoError(t, src.Update(ctx, func(tx kv.RwTx) error {
+		for i := 0; i < 1000; i++ {
+			if err := tx.Put(kv.Headers, []byte(fmt.Sprintf("key%04d", i)), []byte(fmt.Sprintf("value%d", i))); err != nil {
+				return err
+			}
+			for j := 0; j < 3; j++ {
+				if err := tx.Put(kv.TblAccountVals, []byte(fmt.Sprintf("acc%03d", i%100)), []byte(fmt.Sprintf("v%04d-%d", i, j))); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, Stream(ctx, src, []string{kv.TblAccountVals, kv.Headers}, &buf, logger))
+
+	dst := memdb.NewTestDB(t, kv.ChainDB)
+	require.NoError(t, dst.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.Headers, []byte("stale"), []byte("stale")) // cleared by restore
+	}))
+	require.NoError(t, Restore(ctx, dst, bytes.NewReader(buf.Bytes()), logger))
+
+	dump := func(db kv.RoDB, table string) (res []string) {
+		require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+			return tx.ForEach(table, nil, func(k, v []byte) error {
+				res = append(res, string(k)+"="+string(v))
+				return nil
+			})
+		}))
+		return res
+	}
+	for _, table := range []string{kv.Headers, kv.TblAccountVals} {
+		require.Equal(t, dump(src, table), dump(dst, table), table)
+	}
+	require.Len(t, dump(dst, kv.TblAccountVals), 3000)
+
+	truncated := buf.Bytes()[:buf.Len()-10]
+	require.ErrorIs(t, Restore(ctx, memdb.NewTestDB(t, kv.ChainDB), bytes.NewReader(truncated), logger), ErrStreamCorrupted)
+	require.ErrorIs(t, Restore(ctx, memdb.NewTestDB(t, kv.ChainDB), bytes.NewReader([]byte("garbage")), logger), ErrStreamCorrupted)
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+var ErrKeyOrder = errors.New("records are not in ascending order")
+
+// Damage - records of Table between From and To can't be trusted: From is last good key (nil - start of table),
+// To is first good key after damage (nil - end of table). Err is first error of range.
+type Damage struct {
+	Table    string
+	From, To []byte
+	Err      error
+}
+
+func (d Damage) String() string {
+	return fmt.Sprintf("table=%s, from=%x, to=%x: %s", d.Table, d.From, d.To, d.Err)
+}
+
+// Verify - reads every record of tables (all not deprecated tables of db if empty) in 1 read tx, and reports key ranges
+// which can't be read or are not sorted - instead of failing on first error:
+//   - value which doesn't match checksum (see kv.ValueCorruptionError) - walk continues after it
+//   - records out of order (keys, or values of 1 key in DupSort table)
+//   - page-level error of db (for example mdbx.Corrupted) - rest of table is reported as damaged
+//
+// Returned error is only about walk itself (ctx cancel, can't open tx). Empty result means: db is fine.
+func Verify(ctx context.Context, db kv.RoDB, tables []string, logger log.Logger) ([]Damage, error) {
+	if len(tables) == 0 {
+		for name, cfg := range db.AllTables() {
+			if !cfg.IsDeprecated {
+				tables = append(tables, name)
+			}
+		}
+	}
+	slices.Sort(tables)
+
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+
+	var damages []Damage
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		for _, table := range tables {
+			isDupSort := db.AllTables()[table].Flags&kv.DupSort != 0
+			tableDamages, err := verifyTable(ctx, tx, table, isDupSort, logEvery, logger)
+			if err != nil {
+				return err
+			}
+			damages = append(damages, tableDamages...)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return damages, nil
+}
+
+func verifyTable(ctx context.Context, tx kv.Tx, table string, isDupSort bool, logEvery *time.Ticker, logger log.Logger) ([]Damage, error) {
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	var damages []Damage
+	var open *Damage // damage which is not closed by good record yet
+	var prevK, prevV []byte
+	var records uint64
+	k, v, err := c.First()
+	for {
+		if err != nil {
+			if open == nil {
+				open = &Damage{Table: table, From: prevK, Err: err}
+			}
+			if !errors.Is(err, kv.ErrValueCorrupted) { // cursor is lost on damaged page, can't go further
+				break
+			}
+			k, v, err = c.Next()
+			continue
+		}
+		if k == nil {
+			break
+		}
+
+		cmp := 1
+		if prevK != nil {
+			if cmp = bytes.Compare(k, prevK); cmp == 0 && isDupSort {
+				cmp = bytes.Compare(v, prevV)
+			}
+		}
+		if cmp <= 0 {
+			damages = append(damages, Damage{Table: table, From: prevK, To: common.CopyBytes(k), Err: fmt.Errorf("%w: %x after %x", ErrKeyOrder, k, prevK)})
+		} else if open != nil {
+			open.To = common.CopyBytes(k)
+			damages = append(damages, *open)
+			open = nil
+		}
+		prevK, prevV = common.CopyBytes(k), common.CopyBytes(v)
+		records++
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-logEvery.C:
+			logger.Info("[verify] progress", "table", table, "key", fmt.Sprintf("%x", k), "records", records, "damages", len(damages))
+		default:
+		}
+		k, v, err = c.Next()
+	}
+	if open != nil {
+		damages = append(damages, *open)
+	}
+	return damages, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package backup
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/mdbx"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+func TestVerify(t *testing.T) {
+	ctx, path, logger := context.Background(), t.TempDir(), log.New()
+	withChecksum := func(v string) []byte {
+		return binary.BigEndian.AppendUint32([]byte(v), crc32.Checksum([]byte(v), crc32.MakeTable(crc32.Castagnoli)))
+	}
+
+	// values written without checksums, then table is marked as checksummed: "x" is too short to be valid
+	db := mdbx.New(kv.ChainDB, logger).Path(path).MapSize(128 * datasize.MB).MustOpen()
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for k, v := range map[string][]byte{"k1": withChecksum("v1"), "k2": []byte("x"), "k3": []byte("x"), "k4": withChecksum("v4"), "k5": []byte("x")} {
+			if err := tx.Put(kv.Headers, []byte(k), v); err != nil {
+				return err
+			}
+		}
+		if err := tx.Put(kv.BlockBody, []byte("k1"), []byte("x")); err != nil {
+			return err
+		}
+		return tx.Put(kv.DatabaseInfo, []byte("valueChecksums/"+kv.Headers), []byte{1})
+	}))
+	db.Close()
+
+	db = mdbx.New(kv.ChainDB, logger).Path(path).MapSize(128 * datasize.MB).MustOpen()
+	defer db.Close()
+	damages, err := Verify(ctx, db, []string{kv.Headers, kv.BlockBody}, logger)
+	require.NoError(t, err)
+	require.Len(t, damages, 2)
+	require.Equal(t, kv.Headers, damages[0].Table)
+	require.Equal(t, []byte("k1"), damages[0].From)
+	require.Equal(t, []byte("k4"), damages[0].To)
+	require.ErrorIs(t, damages[0].Err, kv.ErrValueCorrupted)
+	require.Equal(t, []byte("k4"), damages[1].From)
+	require.Nil(t, damages[1].To)
+
+	damages, err = Verify(ctx, db, []string{kv.BlockBody}, logger)
+	require.NoError(t, err)
+	require.Empty(t, damages)
+}
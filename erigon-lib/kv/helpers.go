@@ -17,6 +17,7 @@
 package kv
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"errors"
@@ -114,6 +115,54 @@ func BigChunks(db RoDB, table string, from []byte, walker func(tx Tx, k, v []byt
 	return nil
 }
 
+// DeleteRange - deletes records of `table` with keys in [from, to), to=nil means EndOfTable. Records are deleted by chunks
+// of `chunkSize` records, each chunk in own write transaction - to keep FreeList and amount of dirty pages small and to not
+// block other writers for long. progress (if not nil) is called after each committed chunk. For DupSort tables all values
+// of a key are deleted.
+func DeleteRange(ctx context.Context, db RwDB, table string, from, to []byte, chunkSize int, progress func(deleted uint64, lastKey []byte)) (deleted uint64, err error) {
+	if chunkSize <= 0 {
+		return 0, errors.New("DeleteRange: chunkSize must be positive")
+	}
+	from = common.Copy(from)
+	for done := false; !done; {
+		var lastKey []byte
+		var n int
+		if err := db.Update(ctx, func(tx RwTx) error {
+			c, err := tx.RwCursor(table)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+
+			for k, _, err := c.Seek(from); ; k, _, err = c.Next() {
+				if err != nil {
+					return err
+				}
+				if k == nil || (to != nil && bytes.Compare(k, to) >= 0) {
+					done = true
+					return nil
+				}
+				if n == chunkSize {
+					from = common.Copy(k) // next transaction will start from this key
+					return nil
+				}
+				lastKey = append(lastKey[:0], k...)
+				if err := c.DeleteCurrent(); err != nil {
+					return err
+				}
+				n++
+			}
+		}); err != nil {
+			return deleted, err
+		}
+		deleted += uint64(n)
+		if progress != nil && n > 0 {
+			progress(deleted, lastKey)
+		}
+	}
+	return deleted, nil
+}
+
 var (
 	bytesTrue  = []byte{1}
 	bytesFalse = []byte{0}
@@ -229,6 +278,47 @@ func LastKey(tx Tx, table string) ([]byte, error) {
 	return k, nil
 }
 
+// SplitRange - splits keys of table to at most n ranges [from, to) for concurrent scans, nil means: table start/end.
+// Boundaries are interpolated between first and last keys (after their common prefix), so ranges are roughly equal
+// for uniformly distributed keys: hashes, addresses, big-endian numbers.
+func SplitRange(tx Tx, table string, n int) ([][2][]byte, error) {
+	first, err := FirstKey(tx, table)
+	if err != nil || first == nil {
+		return nil, err
+	}
+	first = common.Copy(first)
+	last, err := LastKey(tx, table)
+	if err != nil {
+		return nil, err
+	}
+	p := 0
+	for p < len(first) && p < len(last) && first[p] == last[p] {
+		p++
+	}
+	toUint64 := func(k []byte) uint64 {
+		var b [8]byte
+		if p < len(k) {
+			copy(b[:], k[p:])
+		}
+		return binary.BigEndian.Uint64(b[:])
+	}
+	lo, hi := toUint64(first), toUint64(last)
+	if n <= 1 || hi-lo < uint64(n) {
+		return [][2][]byte{{nil, nil}}, nil
+	}
+	step := (hi - lo) / uint64(n)
+	ranges := make([][2][]byte, 0, n)
+	var from []byte
+	for i := 1; i < n; i++ {
+		to := make([]byte, p+8)
+		copy(to, first[:p])
+		binary.BigEndian.PutUint64(to[p:], lo+uint64(i)*step)
+		ranges = append(ranges, [2][]byte{from, to})
+		from = to
+	}
+	return append(ranges, [2][]byte{from, nil}), nil
+}
+
 // NextSubtree does []byte++. Returns false if overflow.
 // nil is marker of the table end, while []byte{} is in the table beginning
 func NextSubtree(in []byte) ([]byte, bool) {
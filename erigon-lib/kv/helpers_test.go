@@ -0,0 +1,124 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package kv_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/memdb"
+	"github.com/erigontech/erigon-lib/kv/order"
+)
+
+func TestDeleteRange(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewTestDB(t, kv.ChainDB)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for i := 0; i < 100; i++ {
+			if err := tx.Put(kv.Headers, []byte(fmt.Sprintf("%03d", i)), []byte{1}); err != nil {
+				return err
+			}
+			for j := 0; j < 3; j++ {
+				if err := tx.Put(kv.TblAccountVals, []byte(fmt.Sprintf("%03d", i)), []byte{byte(j)}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}))
+	count := func(table string) (n uint64) {
+		require.NoError(t, db.View(ctx, func(tx kv.Tx) (err error) {
+			n, err = tx.Count(table)
+			return err
+		}))
+		return n
+	}
+
+	var chunks int
+	var last []byte
+	deleted, err := kv.DeleteRange(ctx, db, kv.Headers, []byte("010"), []byte("050"), 7, func(deleted uint64, lastKey []byte) {
+		chunks++
+		last = lastKey
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint64(40), deleted)
+	require.Equal(t, 6, chunks)
+	require.Equal(t, "049", string(last))
+	require.Equal(t, uint64(60), count(kv.Headers))
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		for _, k := range []string{"009", "050"} {
+			v, err := tx.GetOne(kv.Headers, []byte(k))
+			require.NoError(t, err)
+			require.NotNil(t, v, k)
+		}
+		return nil
+	}))
+
+	deleted, err = kv.DeleteRange(ctx, db, kv.TblAccountVals, []byte("090"), nil, 10, nil)
+	require.NoError(t, err)
+	require.Equal(t, uint64(30), deleted)
+	require.Equal(t, uint64(270), count(kv.TblAccountVals))
+}
+
+func TestSplitRange(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewTestDB(t, kv.ChainDB)
+	tx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	ranges, err := kv.SplitRange(tx, kv.Headers, 4)
+	require.NoError(t, err)
+	require.Empty(t, ranges)
+
+	// block-number keys with common prefix: split after it
+	for i := uint64(0); i < 1000; i++ {
+		require.NoError(t, tx.Put(kv.Headers, hexutility.EncodeTs(1_000_000+i), []byte{1}))
+	}
+	ranges, err = kv.SplitRange(tx, kv.Headers, 4)
+	require.NoError(t, err)
+	require.Len(t, ranges, 4)
+	require.Nil(t, ranges[0][0])
+	require.Nil(t, ranges[3][1])
+	total := 0
+	for i, r := range ranges {
+		if i > 0 {
+			require.Equal(t, ranges[i-1][1], r[0])
+		}
+		cnt := 0
+		it, err := tx.Range(kv.Headers, r[0], r[1], order.Asc, -1)
+		require.NoError(t, err)
+		for it.HasNext() {
+			_, _, err := it.Next()
+			require.NoError(t, err)
+			cnt++
+		}
+		it.Close()
+		require.InDelta(t, 250, cnt, 2)
+		total += cnt
+	}
+	require.Equal(t, 1000, total)
+
+	ranges, err = kv.SplitRange(tx, kv.Headers, 1)
+	require.NoError(t, err)
+	require.Equal(t, [][2][]byte{{nil, nil}}, ranges)
+}
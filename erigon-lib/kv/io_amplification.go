@@ -0,0 +1,133 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package kv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+)
+
+// Subsystems - tags of DB users. Tag is carried by ctx passed to BeginRo/BeginRw: all bytes read/written by tx
+// are attributed to subsystem of its ctx. Untagged txs are attributed to SubsystemOther.
+const (
+	SubsystemSync  = "sync" // staged sync loop, logical work: gas of executed blocks
+	SubsystemRPC   = "rpc"  // logical work: rpc calls
+	SubsystemOther = "other"
+)
+
+type subsystemCtxKey struct{}
+
+func WithSubsystem(ctx context.Context, subsystem string) context.Context {
+	return context.WithValue(ctx, subsystemCtxKey{}, subsystem)
+}
+
+func SubsystemOf(ctx context.Context) string {
+	if s, ok := ctx.Value(subsystemCtxKey{}).(string); ok {
+		return s
+	}
+	return SubsystemOther
+}
+
+// SubsystemIO - bytes passed through DB by txs of 1 subsystem. Bytes of data files (snapshots) are not counted:
+// they are not read through DB txs.
+type SubsystemIO struct {
+	Txs          atomic.Uint64
+	BytesRead    atomic.Uint64 // keys and values returned to caller
+	BytesWritten atomic.Uint64 // keys and values passed by caller to Put/Append/Delete
+	BytesDirty   atomic.Uint64 // pages modified by RwTx at time of commit - what really will be written to disk
+	LogicalWork  atomic.Uint64 // units of useful work done by subsystem, see AddLogicalWork
+}
+
+// AddRead - nil-safe: DB implementations keep nil if counters are disabled
+func (s *SubsystemIO) AddRead(k, v []byte) {
+	if s != nil {
+		s.BytesRead.Add(uint64(len(k) + len(v)))
+	}
+}
+
+func (s *SubsystemIO) AddWrite(k, v []byte) {
+	if s != nil {
+		s.BytesWritten.Add(uint64(len(k) + len(v)))
+	}
+}
+
+var (
+	subsystemIOLock sync.Mutex
+	subsystemIO     sync.Map // subsystem -> *SubsystemIO
+)
+
+// IOOf - counters of given subsystem, created on first use
+func IOOf(subsystem string) *SubsystemIO {
+	if s, ok := subsystemIO.Load(subsystem); ok {
+		return s.(*SubsystemIO)
+	}
+	subsystemIOLock.Lock()
+	defer subsystemIOLock.Unlock()
+	if s, ok := subsystemIO.Load(subsystem); ok {
+		return s.(*SubsystemIO)
+	}
+	s := &SubsystemIO{}
+	subsystemIO.Store(subsystem, s)
+	return s
+}
+
+// AddLogicalWork - subsystem of ctx did `units` of useful work (unit is subsystem-specific). Amplification is reported
+// as DB bytes per unit.
+func AddLogicalWork(ctx context.Context, units uint64) {
+	IOOf(SubsystemOf(ctx)).LogicalWork.Add(units)
+}
+
+// DumpIOAmplification - prints counters of all subsystems and ratios: bytes read and written per unit of logical work,
+// and dirty/written - write amplification of DB itself (page-level COW)
+func DumpIOAmplification(w io.Writer) error {
+	var subsystems []string
+	subsystemIO.Range(func(s, _ any) bool {
+		subsystems = append(subsystems, s.(string))
+		return true
+	})
+	sort.Strings(subsystems)
+
+	ratio := func(a, b uint64) string {
+		if b == 0 {
+			return "-"
+		}
+		return fmt.Sprintf("%.2f", float64(a)/float64(b))
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "subsystem\ttxs\tread\twritten\tdirty\tlogical\tread/logical\twritten/logical\tdirty/written")
+	for _, name := range subsystems {
+		s := IOOf(name)
+		read, written, dirty, logical := s.BytesRead.Load(), s.BytesWritten.Load(), s.BytesDirty.Load(), s.LogicalWork.Load()
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\t%s\t%s\t%s\n", name, s.Txs.Load(), read, written, dirty, logical,
+			ratio(read, logical), ratio(written, logical), ratio(dirty, written))
+	}
+	return tw.Flush()
+}
+
+// IOAmplificationHandler - dump of counters on demand: `curl http://<metrics.addr>/debug/metrics/db_io_amplification`
+func IOAmplificationHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	if err := DumpIOAmplification(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -86,6 +86,9 @@ var (
 	TxUnspill = metrics.GetOrCreateGauge(`tx_unspill`) //nolint
 	TxDirty   = metrics.GetOrCreateGauge(`tx_dirty`)   //nolint
 
+	// committed txs since start of oldest read tx, see HasOldestReader. Growing lag - some read tx is too long and holds freed pages
+	TxReaderLag = metrics.GetOrCreateGauge(`tx_reader_lag`) //nolint
+
 	DbCommitPreparation = metrics.GetOrCreateSummary(`db_commit_seconds{phase="preparation"}`) //nolint
 	//DbGCWallClock       = metrics.GetOrCreateSummary(`db_commit_seconds{phase="gc_wall_clock"}`) //nolint
 	//DbGCCpuTime         = metrics.GetOrCreateSummary(`db_commit_seconds{phase="gc_cpu_time"}`)   //nolint
@@ -539,6 +542,16 @@ type HasSpaceDirty interface {
 	SpaceDirty() (uint64, uint64, error)
 }
 
+type HasForceSync interface {
+	ForceSync() error
+}
+
+// HasOldestReader - how far back concurrent read txs (of all processes) reach: data deleted by RwTx is still
+// visible to read txs with ViewID >= OldestReaderViewID until they end. Returns ViewID of RwTx itself if no readers.
+type HasOldestReader interface {
+	OldestReaderViewID() (uint64, error)
+}
+
 // BucketMigrator used for buckets migration, don't use it in usual app code
 type BucketMigrator interface {
 	ListBuckets() ([]string, error)
@@ -0,0 +1,218 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package kvbench - reproducible read workloads over tables of any kv.RoDB (mdbx, memdb, remote):
+// to compare providers, options and their versions on same data (for example on copy of real chaindata).
+// Results are json lines - to diff them by scripts.
+package kvbench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+)
+
+const (
+	SeqScan    = "seq_scan"    // First + Next: changeset/history scans
+	RandomSeek = "random_seek" // Seek by random prefix of existing key + few Next: trie rebuild pattern
+	RandomGet  = "random_get"  // GetOne of random existing keys and (MissRatio of) absent keys: state reads
+)
+
+var Workloads = []string{SeqScan, RandomSeek, RandomGet}
+
+type Config struct {
+	Seed       int64
+	Ops        int     // operations of each workload on each table. SeqScan sample keys for other workloads, so it's limited by it too
+	SeekNexts  int     // Next calls after each Seek of RandomSeek
+	MissRatio  float64 // share of absent keys in RandomGet
+	SampleSize int     // keys sampled by SeqScan for random workloads
+}
+
+func DefaultConfig() Config {
+	return Config{Seed: 1, Ops: 1_000_000, SeekNexts: 3, MissRatio: 0.1, SampleSize: 100_000}
+}
+
+type Result struct {
+	Workload  string        `json:"workload"`
+	Table     string        `json:"table"`
+	Ops       int           `json:"ops"`
+	Bytes     uint64        `json:"bytes"` // sum of lengths of keys and values read
+	Duration  time.Duration `json:"duration_ns"`
+	OpsPerSec float64       `json:"ops_per_sec"`
+	P50       time.Duration `json:"p50_ns"`
+	P99       time.Duration `json:"p99_ns"`
+}
+
+// WriteJSON - 1 line per result
+func WriteJSON(w io.Writer, results []Result) error {
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run - runs all workloads on each table, each table in own read tx. Same Config and same data - same operations.
+func Run(ctx context.Context, db kv.RoDB, tables []string, cfg Config) ([]Result, error) {
+	var results []Result
+	for _, table := range tables {
+		if err := db.View(ctx, func(tx kv.Tx) error {
+			rnd := rand.New(rand.NewSource(cfg.Seed))
+			r, sample, err := seqScan(ctx, tx, table, cfg, rnd)
+			if err != nil {
+				return err
+			}
+			results = append(results, r)
+			if len(sample) == 0 {
+				return nil
+			}
+			if r, err = randomSeek(ctx, tx, table, cfg, rnd, sample); err != nil {
+				return err
+			}
+			results = append(results, r)
+			if r, err = randomGet(ctx, tx, table, cfg, rnd, sample); err != nil {
+				return err
+			}
+			results = append(results, r)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// recorder - latency of each operation, to get percentiles
+type recorder struct {
+	res       Result
+	latencies []time.Duration
+	start     time.Time
+}
+
+func newRecorder(workload, table string, ops int) *recorder {
+	return &recorder{res: Result{Workload: workload, Table: table}, latencies: make([]time.Duration, 0, ops)}
+}
+
+func (r *recorder) begin() { r.start = time.Now() }
+func (r *recorder) end(k, v []byte) {
+	d := time.Since(r.start)
+	r.latencies = append(r.latencies, d)
+	r.res.Duration += d
+	r.res.Bytes += uint64(len(k) + len(v))
+}
+
+func (r *recorder) result() Result {
+	r.res.Ops = len(r.latencies)
+	if r.res.Ops == 0 {
+		return r.res
+	}
+	if r.res.Duration > 0 {
+		r.res.OpsPerSec = float64(r.res.Ops) / r.res.Duration.Seconds()
+	}
+	slices.Sort(r.latencies)
+	r.res.P50 = r.latencies[len(r.latencies)/2]
+	r.res.P99 = r.latencies[len(r.latencies)*99/100]
+	return r.res
+}
+
+func seqScan(ctx context.Context, tx kv.Tx, table string, cfg Config, rnd *rand.Rand) (Result, [][]byte, error) {
+	rec := newRecorder(SeqScan, table, cfg.Ops)
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return rec.res, nil, err
+	}
+	defer c.Close()
+
+	var sample [][]byte // reservoir sampling: each key has equal chance
+	rec.begin()
+	k, v, err := c.First()
+	for i := 0; i < cfg.Ops; i++ {
+		if err != nil {
+			return rec.res, nil, err
+		}
+		if k == nil {
+			break
+		}
+		rec.end(k, v)
+		if len(sample) < cfg.SampleSize {
+			sample = append(sample, common.CopyBytes(k))
+		} else if j := rnd.Intn(i + 1); j < cfg.SampleSize {
+			sample[j] = common.CopyBytes(k)
+		}
+		if err := ctx.Err(); err != nil {
+			return rec.res, nil, err
+		}
+		rec.begin()
+		k, v, err = c.Next()
+	}
+	return rec.result(), sample, nil
+}
+
+func randomSeek(ctx context.Context, tx kv.Tx, table string, cfg Config, rnd *rand.Rand, sample [][]byte) (Result, error) {
+	rec := newRecorder(RandomSeek, table, cfg.Ops)
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return rec.res, err
+	}
+	defer c.Close()
+
+	for i := 0; i < cfg.Ops; i++ {
+		key := sample[rnd.Intn(len(sample))]
+		prefix := key[:rnd.Intn(len(key)+1)]
+		rec.begin()
+		k, v, err := c.Seek(prefix)
+		for j := 0; j < cfg.SeekNexts && err == nil && k != nil; j++ {
+			k, v, err = c.Next()
+		}
+		rec.end(k, v)
+		if err != nil {
+			return rec.res, err
+		}
+		if err := ctx.Err(); err != nil {
+			return rec.res, err
+		}
+	}
+	return rec.result(), nil
+}
+
+func randomGet(ctx context.Context, tx kv.Tx, table string, cfg Config, rnd *rand.Rand, sample [][]byte) (Result, error) {
+	rec := newRecorder(RandomGet, table, cfg.Ops)
+	for i := 0; i < cfg.Ops; i++ {
+		key := sample[rnd.Intn(len(sample))]
+		if rnd.Float64() < cfg.MissRatio {
+			key = append(bytes.Clone(key), 0xff, 0xff) // most likely absent
+		}
+		rec.begin()
+		v, err := tx.GetOne(table, key)
+		rec.end(key, v)
+		if err != nil {
+			return rec.res, err
+		}
+		if err := ctx.Err(); err != nil {
+			return rec.res, err
+		}
+	}
+	return rec.result(), nil
+}
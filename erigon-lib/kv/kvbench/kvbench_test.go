@@ -0,0 +1,73 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package kvbench
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/memdb"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.NewTestDB(t, kv.ChainDB)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for i := uint64(0); i < 1000; i++ {
+			if err := tx.Put(kv.Headers, binary.BigEndian.AppendUint64(nil, i), []byte("value")); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	cfg := DefaultConfig()
+	cfg.Ops, cfg.SampleSize = 500, 100
+	results, err := Run(ctx, db, []string{kv.Headers, kv.BlockBody}, cfg)
+	require.NoError(t, err)
+	require.Len(t, results, 4) // empty table has only seq_scan
+	for i, workload := range append(Workloads, SeqScan) {
+		require.Equal(t, workload, results[i].Workload)
+	}
+	require.Equal(t, kv.BlockBody, results[3].Table)
+	require.Equal(t, 0, results[3].Ops)
+	for _, r := range results[:3] {
+		require.Equal(t, 500, r.Ops)
+		require.Positive(t, r.Bytes)
+		require.LessOrEqual(t, r.P50, r.P99)
+	}
+
+	// same seed - same operations
+	again, err := Run(ctx, db, []string{kv.Headers}, cfg)
+	require.NoError(t, err)
+	for i := range again {
+		require.Equal(t, results[i].Bytes, again[i].Bytes)
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, results[:1]))
+	var r Result
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &r))
+	require.Equal(t, SeqScan, r.Workload)
+	require.Equal(t, results[0].Ops, r.Ops)
+}
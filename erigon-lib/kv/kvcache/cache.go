@@ -32,12 +32,17 @@ import (
 	"golang.org/x/crypto/sha3"
 
 	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/featureflags"
 	"github.com/erigontech/erigon-lib/gointerfaces"
 	remote "github.com/erigontech/erigon-lib/gointerfaces/remoteproto"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/metrics"
 )
 
+// stateCacheFlag - when disabled, Get/GetCode bypass cache and read directly from db.
+// OnNewBlock keeps cache up-to-date anyway - so it can be enabled back without restart.
+var stateCacheFlag = featureflags.New("state_cache", "serve state and code reads of rpc from kvcache. Affects: rpcdaemon, erigon (embedded rpc)", true)
+
 type CacheValidationResult struct {
 	RequestCancelled   bool
 	Enabled            bool
@@ -393,7 +398,30 @@ func (c *Coherent) getFromCache(k []byte, id uint64, code bool) (*Element, *Cohe
 	}
 	return it, r, nil
 }
+func (c *Coherent) readState(k []byte, tx kv.Tx) (v []byte, err error) {
+	if c.cfg.StateV3 {
+		if len(k) == 20 {
+			v, _, err = tx.(kv.TemporalTx).GetLatest(kv.AccountsDomain, k)
+		} else {
+			v, _, err = tx.(kv.TemporalTx).GetLatest(kv.StorageDomain, k)
+		}
+		return v, err
+	}
+	return tx.GetOne(kv.PlainState, k)
+}
+
+func (c *Coherent) readCode(k []byte, tx kv.Tx) (v []byte, err error) {
+	if c.cfg.StateV3 {
+		v, _, err = tx.(kv.TemporalTx).GetLatest(kv.CodeDomain, k)
+		return v, err
+	}
+	return tx.GetOne(kv.Code, k)
+}
+
 func (c *Coherent) Get(k []byte, tx kv.Tx, id uint64) (v []byte, err error) {
+	if !stateCacheFlag.Enabled() {
+		return c.readState(k, tx)
+	}
 	it, r, err := c.getFromCache(k, id, false)
 	if err != nil {
 		return nil, err
@@ -406,15 +434,7 @@ func (c *Coherent) Get(k []byte, tx kv.Tx, id uint64) (v []byte, err error) {
 	}
 	c.miss.Inc()
 
-	if c.cfg.StateV3 {
-		if len(k) == 20 {
-			v, _, err = tx.(kv.TemporalTx).GetLatest(kv.AccountsDomain, k)
-		} else {
-			v, _, err = tx.(kv.TemporalTx).GetLatest(kv.StorageDomain, k)
-		}
-	} else {
-		v, err = tx.GetOne(kv.PlainState, k)
-	}
+	v, err = c.readState(k, tx)
 	if err != nil {
 		return nil, err
 	}
@@ -430,6 +450,9 @@ func (c *Coherent) Get(k []byte, tx kv.Tx, id uint64) (v []byte, err error) {
 }
 
 func (c *Coherent) GetCode(k []byte, tx kv.Tx, id uint64) (v []byte, err error) {
+	if !stateCacheFlag.Enabled() {
+		return c.readCode(k, tx)
+	}
 	it, r, err := c.getFromCache(k, id, true)
 	if err != nil {
 		return nil, err
@@ -442,11 +465,7 @@ func (c *Coherent) GetCode(k []byte, tx kv.Tx, id uint64) (v []byte, err error)
 	}
 	c.codeMiss.Inc()
 
-	if c.cfg.StateV3 {
-		v, _, err = tx.(kv.TemporalTx).GetLatest(kv.CodeDomain, k)
-	} else {
-		v, err = tx.GetOne(kv.Code, k)
-	}
+	v, err = c.readCode(k, tx)
 	if err != nil {
 		return nil, err
 	}
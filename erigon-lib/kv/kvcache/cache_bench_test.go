@@ -0,0 +1,157 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package kvcache
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/datadir"
+	remote "github.com/erigontech/erigon-lib/gointerfaces/remoteproto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/temporal/temporaltest"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/state"
+	"github.com/erigontech/erigon-lib/types"
+)
+
+// go test -run=XXX -bench=BenchmarkCachedVsUncached -kvcache.report=report.jsonl ./kv/kvcache
+var benchReport = flag.String("kvcache.report", "", "append results of BenchmarkCachedVsUncached to this file, 1 json object per line")
+
+const benchAccounts = 20_000
+
+type benchResult struct {
+	Name     string  `json:"name"`
+	Coverage int     `json:"coverage_percent"` // size of cache as percent of size of all accounts
+	HitRate  float64 `json:"hit_rate"`
+	N        int     `json:"n"`
+	NsPerOp  float64 `json:"ns_per_op"`
+}
+
+func benchAccountKey(i int) []byte {
+	k := make([]byte, 20)
+	binary.BigEndian.PutUint64(k[12:], uint64(i)+1)
+	return k
+}
+
+func newBenchDB(b *testing.B) (kv.TemporalRwDB, int) {
+	b.Helper()
+	ctx := context.Background()
+	db, _ := temporaltest.NewTestDB(b, datadir.New(b.TempDir()))
+	var size int
+	require.NoError(b, db.Update(ctx, func(tx kv.RwTx) error {
+		d, err := state.NewSharedDomains(tx, log.New())
+		if err != nil {
+			return err
+		}
+		defer d.Close()
+		for i := 0; i < benchAccounts; i++ {
+			k, v := benchAccountKey(i), types.EncodeAccountBytesV3(uint64(i), uint256.NewInt(uint64(i)), nil, 0)
+			if err := d.DomainPut(kv.AccountsDomain, k, nil, v, nil, 0); err != nil {
+				return err
+			}
+			size += len(k) + len(v)
+		}
+		return d.Flush(ctx, tx)
+	}))
+	return db, size
+}
+
+// BenchmarkCachedVsUncached - same random account reads: directly from db, and through Coherent cache
+// which has size of given percent of all accounts (LRU with uniform reads, so coverage is also expected hit rate)
+func BenchmarkCachedVsUncached(b *testing.B) {
+	ctx := context.Background()
+	db, size := newBenchDB(b)
+	tx, err := db.BeginTemporalRo(ctx)
+	require.NoError(b, err)
+	defer tx.Rollback()
+
+	var names []string
+	results := map[string]benchResult{} // last run of each sub-benchmark has final b.N
+	report := func(b *testing.B, name string, coverage int, hits, misses uint64) {
+		hitRate := float64(hits) / float64(max(hits+misses, 1))
+		b.ReportMetric(hitRate, "hit-rate")
+		if _, ok := results[name]; !ok {
+			names = append(names, name)
+		}
+		results[name] = benchResult{Name: name, Coverage: coverage, HitRate: hitRate, N: b.N, NsPerOp: float64(b.Elapsed().Nanoseconds()) / float64(b.N)}
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		rnd := rand.New(rand.NewSource(1))
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := tx.GetLatest(kv.AccountsDomain, benchAccountKey(rnd.Intn(benchAccounts))); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.StopTimer()
+		report(b, "uncached", 0, 0, uint64(b.N))
+	})
+	for _, coverage := range []int{0, 10, 50, 90, 100} {
+		name := fmt.Sprintf("cached coverage=%d%%", coverage)
+		b.Run(name, func(b *testing.B) {
+			cfg := DefaultCoherentConfig
+			cfg.WaitForNewBlock = false
+			cfg.MetricsLabel = fmt.Sprintf("bench_%d", coverage)
+			cfg.CacheSize = datasize.ByteSize(size * coverage / 100)
+			c := New(cfg)
+			view, err := c.View(ctx, tx)
+			require.NoError(b, err)
+			id := view.(*CoherentView).stateVersionID
+			c.OnNewBlock(&remote.StateChangeBatch{StateVersionId: id})
+			for i := 0; i < benchAccounts; i++ { // warmup: fill cache up to its size
+				_, err := c.Get(benchAccountKey(i), tx, id)
+				require.NoError(b, err)
+			}
+			hits, misses := c.hits.GetValueUint64(), c.miss.GetValueUint64()
+			rnd := rand.New(rand.NewSource(1))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Get(benchAccountKey(rnd.Intn(benchAccounts)), tx, id); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			report(b, name, coverage, c.hits.GetValueUint64()-hits, c.miss.GetValueUint64()-misses)
+		})
+	}
+
+	if *benchReport == "" {
+		return
+	}
+	f, err := os.OpenFile(*benchReport, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(b, err)
+	defer f.Close()
+	enc := json.NewEncoder(f)
+	for _, name := range names {
+		require.NoError(b, enc.Encode(results[name]))
+	}
+}
@@ -0,0 +1,516 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package kvchaos - kv.RwDB wrapper which injects faults: latency, transient errors, short reads
+// (iteration ends before end of data) and failures of transaction begin/commit.
+// Faults are pseudo-random with given seed - to harden code against real-world storage misbehavior.
+//
+// Only plain kv.RwDB can be wrapped: temporal.DB requires underlying *mdbx.MdbxTx.
+package kvchaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/stream"
+)
+
+var ErrInjected = errors.New("kvchaos: injected fault")
+
+type Config struct {
+	Seed int64
+
+	LatencyProb float64       // probability of delay of operation
+	MaxLatency  time.Duration // delay is random in [0, MaxLatency]
+
+	ErrorProb      float64 // probability of ErrInjected returned by read/write operation
+	ShortReadProb  float64 // probability of end of iteration at each step (of cursor, stream, ForEach)
+	BeginFailProb  float64 // probability of failure to begin transaction
+	CommitFailProb float64 // probability of Commit failure: transaction is rolled back, all its changes lost
+}
+
+type DB struct {
+	kv.RwDB
+	cfg Config
+
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+func New(db kv.RwDB, cfg Config) *DB {
+	return &DB{RwDB: db, cfg: cfg, rnd: rand.New(rand.NewSource(cfg.Seed))}
+}
+
+func (db *DB) chance(prob float64) bool {
+	if prob <= 0 {
+		return false
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return db.rnd.Float64() < prob
+}
+
+// fault - called before each operation: maybe sleeps, maybe returns error
+func (db *DB) fault(prob float64) error {
+	if db.chance(db.cfg.LatencyProb) {
+		db.mu.Lock()
+		d := time.Duration(db.rnd.Int63n(int64(db.cfg.MaxLatency) + 1))
+		db.mu.Unlock()
+		time.Sleep(d)
+	}
+	if db.chance(prob) {
+		return ErrInjected
+	}
+	return nil
+}
+
+func (db *DB) BeginRo(ctx context.Context) (kv.Tx, error) {
+	if err := db.fault(db.cfg.BeginFailProb); err != nil {
+		return nil, err
+	}
+	tx, err := db.RwDB.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &roTx{Tx: tx, db: db}, nil
+}
+
+func (db *DB) BeginRw(ctx context.Context) (kv.RwTx, error) {
+	if err := db.fault(db.cfg.BeginFailProb); err != nil {
+		return nil, err
+	}
+	tx, err := db.RwDB.BeginRw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rwTx{RwTx: tx, ro: &roTx{Tx: tx, db: db}}, nil
+}
+
+func (db *DB) BeginRwNosync(ctx context.Context) (kv.RwTx, error) {
+	if err := db.fault(db.cfg.BeginFailProb); err != nil {
+		return nil, err
+	}
+	tx, err := db.RwDB.BeginRwNosync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rwTx{RwTx: tx, ro: &roTx{Tx: tx, db: db}}, nil
+}
+
+func (db *DB) View(ctx context.Context, f func(tx kv.Tx) error) error {
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return f(tx)
+}
+
+func (db *DB) Update(ctx context.Context, f func(tx kv.RwTx) error) error {
+	tx, err := db.BeginRw(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (db *DB) UpdateNosync(ctx context.Context, f func(tx kv.RwTx) error) error {
+	tx, err := db.BeginRwNosync(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type roTx struct {
+	kv.Tx
+	db *DB
+}
+
+func (tx *roTx) GetOne(table string, key []byte) ([]byte, error) {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return nil, err
+	}
+	return tx.Tx.GetOne(table, key)
+}
+
+func (tx *roTx) Has(table string, key []byte) (bool, error) {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return false, err
+	}
+	return tx.Tx.Has(table, key)
+}
+
+func (tx *roTx) ReadSequence(table string) (uint64, error) {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return 0, err
+	}
+	return tx.Tx.ReadSequence(table)
+}
+
+var errStop = errors.New("stop")
+
+func (tx *roTx) ForEach(table string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return err
+	}
+	err := tx.Tx.ForEach(table, fromPrefix, func(k, v []byte) error {
+		if tx.db.chance(tx.db.cfg.ShortReadProb) {
+			return errStop
+		}
+		return walker(k, v)
+	})
+	if errors.Is(err, errStop) {
+		return nil
+	}
+	return err
+}
+
+func (tx *roTx) ForAmount(table string, prefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return err
+	}
+	return tx.Tx.ForAmount(table, prefix, amount, walker)
+}
+
+func (tx *roTx) newCursor(c kv.Cursor) *cursor {
+	res := &cursor{db: tx.db, c: c}
+	res.dup, _ = c.(kv.CursorDupSort)
+	res.rw, _ = c.(kv.RwCursor)
+	res.rwDup, _ = c.(kv.RwCursorDupSort)
+	return res
+}
+
+func (tx *roTx) Cursor(table string) (kv.Cursor, error) {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return nil, err
+	}
+	c, err := tx.Tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.newCursor(c), nil
+}
+
+func (tx *roTx) CursorDupSort(table string) (kv.CursorDupSort, error) {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return nil, err
+	}
+	c, err := tx.Tx.CursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.newCursor(c), nil
+}
+
+func (tx *roTx) newStream(s stream.KV, err error) (stream.KV, error) {
+	if err != nil {
+		return nil, err
+	}
+	return &kvStream{KV: s, db: tx.db}, nil
+}
+
+func (tx *roTx) Range(table string, fromPrefix, toPrefix []byte, asc order.By, limit int) (stream.KV, error) {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return nil, err
+	}
+	return tx.newStream(tx.Tx.Range(table, fromPrefix, toPrefix, asc, limit))
+}
+
+func (tx *roTx) Prefix(table string, prefix []byte) (stream.KV, error) {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return nil, err
+	}
+	return tx.newStream(tx.Tx.Prefix(table, prefix))
+}
+
+func (tx *roTx) RangeDupSort(table string, key []byte, fromPrefix, toPrefix []byte, asc order.By, limit int) (stream.KV, error) {
+	if err := tx.db.fault(tx.db.cfg.ErrorProb); err != nil {
+		return nil, err
+	}
+	return tx.newStream(tx.Tx.RangeDupSort(table, key, fromPrefix, toPrefix, asc, limit))
+}
+
+type rwTx struct {
+	kv.RwTx
+	ro *roTx
+}
+
+// Commit - on injected failure transaction is rolled back, like on real commit failure
+func (tx *rwTx) Commit() error {
+	if err := tx.ro.db.fault(tx.ro.db.cfg.CommitFailProb); err != nil {
+		tx.RwTx.Rollback()
+		return err
+	}
+	return tx.RwTx.Commit()
+}
+
+func (tx *rwTx) GetOne(table string, key []byte) ([]byte, error) { return tx.ro.GetOne(table, key) }
+func (tx *rwTx) Has(table string, key []byte) (bool, error)      { return tx.ro.Has(table, key) }
+func (tx *rwTx) ReadSequence(table string) (uint64, error)       { return tx.ro.ReadSequence(table) }
+func (tx *rwTx) ForEach(table string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	return tx.ro.ForEach(table, fromPrefix, walker)
+}
+func (tx *rwTx) ForAmount(table string, prefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	return tx.ro.ForAmount(table, prefix, amount, walker)
+}
+func (tx *rwTx) Cursor(table string) (kv.Cursor, error) { return tx.ro.Cursor(table) }
+func (tx *rwTx) CursorDupSort(table string) (kv.CursorDupSort, error) {
+	return tx.ro.CursorDupSort(table)
+}
+func (tx *rwTx) Range(table string, fromPrefix, toPrefix []byte, asc order.By, limit int) (stream.KV, error) {
+	return tx.ro.Range(table, fromPrefix, toPrefix, asc, limit)
+}
+func (tx *rwTx) Prefix(table string, prefix []byte) (stream.KV, error) {
+	return tx.ro.Prefix(table, prefix)
+}
+func (tx *rwTx) RangeDupSort(table string, key []byte, fromPrefix, toPrefix []byte, asc order.By, limit int) (stream.KV, error) {
+	return tx.ro.RangeDupSort(table, key, fromPrefix, toPrefix, asc, limit)
+}
+
+func (tx *rwTx) RwCursor(table string) (kv.RwCursor, error) {
+	if err := tx.ro.db.fault(tx.ro.db.cfg.ErrorProb); err != nil {
+		return nil, err
+	}
+	c, err := tx.RwTx.RwCursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.ro.newCursor(c), nil
+}
+
+func (tx *rwTx) RwCursorDupSort(table string) (kv.RwCursorDupSort, error) {
+	if err := tx.ro.db.fault(tx.ro.db.cfg.ErrorProb); err != nil {
+		return nil, err
+	}
+	c, err := tx.RwTx.RwCursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.ro.newCursor(c), nil
+}
+
+func (tx *rwTx) Put(table string, k, v []byte) error {
+	if err := tx.ro.db.fault(tx.ro.db.cfg.ErrorProb); err != nil {
+		return err
+	}
+	return tx.RwTx.Put(table, k, v)
+}
+
+func (tx *rwTx) Delete(table string, k []byte) error {
+	if err := tx.ro.db.fault(tx.ro.db.cfg.ErrorProb); err != nil {
+		return err
+	}
+	return tx.RwTx.Delete(table, k)
+}
+
+func (tx *rwTx) Append(table string, k, v []byte) error {
+	if err := tx.ro.db.fault(tx.ro.db.cfg.ErrorProb); err != nil {
+		return err
+	}
+	return tx.RwTx.Append(table, k, v)
+}
+
+func (tx *rwTx) AppendDup(table string, k, v []byte) error {
+	if err := tx.ro.db.fault(tx.ro.db.cfg.ErrorProb); err != nil {
+		return err
+	}
+	return tx.RwTx.AppendDup(table, k, v)
+}
+
+func (tx *rwTx) IncrementSequence(table string, amount uint64) (uint64, error) {
+	if err := tx.ro.db.fault(tx.ro.db.cfg.ErrorProb); err != nil {
+		return 0, err
+	}
+	return tx.RwTx.IncrementSequence(table, amount)
+}
+
+// cursor - implements all cursor interfaces, but created only with interface supported by underlying cursor
+type cursor struct {
+	db    *DB
+	c     kv.Cursor
+	dup   kv.CursorDupSort
+	rw    kv.RwCursor
+	rwDup kv.RwCursorDupSort
+}
+
+func (c *cursor) fault() error { return c.db.fault(c.db.cfg.ErrorProb) }
+
+// step - fault or short read (end of table) before moving cursor
+func (c *cursor) step(f func() ([]byte, []byte, error)) ([]byte, []byte, error) {
+	if err := c.fault(); err != nil {
+		return []byte{}, nil, err
+	}
+	if c.db.chance(c.db.cfg.ShortReadProb) {
+		return nil, nil, nil
+	}
+	return f()
+}
+
+func (c *cursor) First() ([]byte, []byte, error) {
+	if err := c.fault(); err != nil {
+		return []byte{}, nil, err
+	}
+	return c.c.First()
+}
+func (c *cursor) Seek(seek []byte) ([]byte, []byte, error) {
+	if err := c.fault(); err != nil {
+		return []byte{}, nil, err
+	}
+	return c.c.Seek(seek)
+}
+func (c *cursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	if err := c.fault(); err != nil {
+		return []byte{}, nil, err
+	}
+	return c.c.SeekExact(key)
+}
+func (c *cursor) Next() ([]byte, []byte, error) { return c.step(c.c.Next) }
+func (c *cursor) Prev() ([]byte, []byte, error) { return c.step(c.c.Prev) }
+func (c *cursor) Last() ([]byte, []byte, error) {
+	if err := c.fault(); err != nil {
+		return []byte{}, nil, err
+	}
+	return c.c.Last()
+}
+func (c *cursor) Current() ([]byte, []byte, error) {
+	if err := c.fault(); err != nil {
+		return []byte{}, nil, err
+	}
+	return c.c.Current()
+}
+func (c *cursor) Close() { c.c.Close() }
+
+func (c *cursor) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
+	if err := c.fault(); err != nil {
+		return []byte{}, nil, err
+	}
+	return c.dup.SeekBothExact(key, value)
+}
+func (c *cursor) SeekBothRange(key, value []byte) ([]byte, error) {
+	if err := c.fault(); err != nil {
+		return nil, err
+	}
+	return c.dup.SeekBothRange(key, value)
+}
+func (c *cursor) FirstDup() ([]byte, error) {
+	if err := c.fault(); err != nil {
+		return nil, err
+	}
+	return c.dup.FirstDup()
+}
+func (c *cursor) NextDup() ([]byte, []byte, error)   { return c.step(c.dup.NextDup) }
+func (c *cursor) NextNoDup() ([]byte, []byte, error) { return c.step(c.dup.NextNoDup) }
+func (c *cursor) PrevDup() ([]byte, []byte, error)   { return c.step(c.dup.PrevDup) }
+func (c *cursor) PrevNoDup() ([]byte, []byte, error) { return c.step(c.dup.PrevNoDup) }
+func (c *cursor) LastDup() ([]byte, error) {
+	if err := c.fault(); err != nil {
+		return nil, err
+	}
+	return c.dup.LastDup()
+}
+func (c *cursor) CountDuplicates() (uint64, error) {
+	if err := c.fault(); err != nil {
+		return 0, err
+	}
+	return c.dup.CountDuplicates()
+}
+
+func (c *cursor) Put(k, v []byte) error {
+	if err := c.fault(); err != nil {
+		return err
+	}
+	return c.rw.Put(k, v)
+}
+func (c *cursor) Append(k, v []byte) error {
+	if err := c.fault(); err != nil {
+		return err
+	}
+	return c.rw.Append(k, v)
+}
+func (c *cursor) Delete(k []byte) error {
+	if err := c.fault(); err != nil {
+		return err
+	}
+	return c.rw.Delete(k)
+}
+func (c *cursor) DeleteCurrent() error {
+	if err := c.fault(); err != nil {
+		return err
+	}
+	return c.rw.DeleteCurrent()
+}
+func (c *cursor) PutNoDupData(k, v []byte) error {
+	if err := c.fault(); err != nil {
+		return err
+	}
+	return c.rwDup.PutNoDupData(k, v)
+}
+func (c *cursor) DeleteCurrentDuplicates() error {
+	if err := c.fault(); err != nil {
+		return err
+	}
+	return c.rwDup.DeleteCurrentDuplicates()
+}
+func (c *cursor) DeleteExact(k1, k2 []byte) error {
+	if err := c.fault(); err != nil {
+		return err
+	}
+	return c.rwDup.DeleteExact(k1, k2)
+}
+func (c *cursor) AppendDup(k, v []byte) error {
+	if err := c.fault(); err != nil {
+		return err
+	}
+	return c.rwDup.AppendDup(k, v)
+}
+
+type kvStream struct {
+	stream.KV
+	db    *DB
+	ended bool // short read happened
+}
+
+func (s *kvStream) HasNext() bool {
+	if s.ended {
+		return false
+	}
+	if s.db.chance(s.db.cfg.ShortReadProb) {
+		s.ended = true
+		return false
+	}
+	return s.KV.HasNext()
+}
+
+func (s *kvStream) Next() ([]byte, []byte, error) {
+	if err := s.db.fault(s.db.cfg.ErrorProb); err != nil {
+		return nil, nil, err
+	}
+	return s.KV.Next()
+}
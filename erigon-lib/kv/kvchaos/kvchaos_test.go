@@ -0,0 +1,121 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package kvchaos_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/kvchaos"
+	"github.com/erigontech/erigon-lib/kv/memdb"
+	"github.com/erigontech/erigon-lib/kv/order"
+)
+
+func fill(t *testing.T, db kv.RwDB, n int) {
+	t.Helper()
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for i := 0; i < n; i++ {
+			if err := tx.Put(kv.HeaderNumber, []byte{byte(i)}, []byte{byte(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+func count(t *testing.T, db kv.RoDB) (n int) {
+	t.Helper()
+	require.NoError(t, db.View(context.Background(), func(tx kv.Tx) error {
+		it, err := tx.Range(kv.HeaderNumber, nil, nil, order.Asc, kv.Unlim)
+		if err != nil {
+			return err
+		}
+		defer it.Close()
+		for it.HasNext() {
+			if _, _, err := it.Next(); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	}))
+	return n
+}
+
+func TestNoFaultsIsTransparent(t *testing.T) {
+	db := kvchaos.New(memdb.NewTestDB(t, kv.ChainDB), kvchaos.Config{Seed: 1})
+	fill(t, db, 100)
+	require.Equal(t, 100, count(t, db))
+}
+
+func TestCommitFailure(t *testing.T) {
+	raw := memdb.NewTestDB(t, kv.ChainDB)
+	db := kvchaos.New(raw, kvchaos.Config{Seed: 1, CommitFailProb: 1})
+	err := db.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(kv.HeaderNumber, []byte{1}, []byte{1})
+	})
+	require.ErrorIs(t, err, kvchaos.ErrInjected)
+	require.Equal(t, 0, count(t, raw))
+}
+
+func TestShortReads(t *testing.T) {
+	raw := memdb.NewTestDB(t, kv.ChainDB)
+	fill(t, raw, 200)
+	db := kvchaos.New(raw, kvchaos.Config{Seed: 1, ShortReadProb: 0.05})
+	require.Less(t, count(t, db), 200)
+
+	require.NoError(t, db.View(context.Background(), func(tx kv.Tx) error {
+		c, err := tx.Cursor(kv.HeaderNumber)
+		require.NoError(t, err)
+		defer c.Close()
+		var n int
+		for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+			require.NoError(t, err)
+			n++
+		}
+		require.Less(t, n, 200)
+		return nil
+	}))
+}
+
+func TestErrorsAreDeterministic(t *testing.T) {
+	raw := memdb.NewTestDB(t, kv.ChainDB)
+	fill(t, raw, 10)
+
+	failures := func() (res []bool) {
+		db := kvchaos.New(raw, kvchaos.Config{Seed: 42, ErrorProb: 0.3})
+		tx, err := db.BeginRo(context.Background())
+		require.NoError(t, err)
+		defer tx.Rollback()
+		for i := 0; i < 100; i++ {
+			_, err := tx.GetOne(kv.HeaderNumber, []byte{byte(i % 10)})
+			if err != nil && !errors.Is(err, kvchaos.ErrInjected) {
+				require.NoError(t, err)
+			}
+			res = append(res, err != nil)
+		}
+		return res
+	}
+	first := failures()
+	require.Equal(t, first, failures())
+	require.Contains(t, first, true)
+	require.Contains(t, first, false)
+}
@@ -0,0 +1,498 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package kvrecorder - records all operations of kv.RwDB session (order, keys, values of writes, sizes of reads)
+// into text trace, and replays such trace on any kv.RwDB. Allows to reproduce "block N was slow on machine X"
+// without copying of whole datadir.
+//
+// Trace format - 1 operation per line: `<Method> <txID|cursorID> <args...> [-> <result key> <result value len>]`.
+// Bytes are hex-encoded with `0x` prefix, nil is `nil`.
+//
+// Only plain kv.RwDB can be wrapped: temporal.DB requires underlying *mdbx.MdbxTx.
+package kvrecorder
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/stream"
+)
+
+// DB - kv.RwDB which writes all operations of all transactions into trace
+type DB struct {
+	kv.RwDB
+
+	mu  sync.Mutex
+	w   *bufio.Writer
+	err error // first error of trace writing
+
+	ids atomic.Uint64 // tx, cursor and stream ids
+}
+
+func New(db kv.RwDB, trace io.Writer) *DB {
+	return &DB{RwDB: db, w: bufio.NewWriterSize(trace, 1024*1024)}
+}
+
+func (db *DB) record(op string, id uint64, args ...string) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.err != nil {
+		return
+	}
+	line := op + " " + strconv.FormatUint(id, 10)
+	if len(args) > 0 {
+		line += " " + strings.Join(args, " ")
+	}
+	_, db.err = db.w.WriteString(line + "\n")
+}
+
+// Flush - writes buffered trace. Returns first error of trace writing, if any.
+func (db *DB) Flush() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if db.err != nil {
+		return db.err
+	}
+	return db.w.Flush()
+}
+
+func (db *DB) Close() {
+	db.Flush() //nolint:errcheck
+	db.RwDB.Close()
+}
+
+func (db *DB) newTx(tx kv.Tx, op string) *roTx {
+	t := &roTx{Tx: tx, db: db, id: db.ids.Add(1)}
+	db.record(op, t.id)
+	return t
+}
+
+func (db *DB) BeginRo(ctx context.Context) (kv.Tx, error) {
+	tx, err := db.RwDB.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return db.newTx(tx, "BeginRo"), nil
+}
+
+func (db *DB) BeginRw(ctx context.Context) (kv.RwTx, error) {
+	tx, err := db.RwDB.BeginRw(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rwTx{RwTx: tx, ro: db.newTx(tx, "BeginRw")}, nil
+}
+
+func (db *DB) BeginRwNosync(ctx context.Context) (kv.RwTx, error) {
+	tx, err := db.RwDB.BeginRwNosync(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &rwTx{RwTx: tx, ro: db.newTx(tx, "BeginRw")}, nil
+}
+
+func (db *DB) View(ctx context.Context, f func(tx kv.Tx) error) error {
+	return db.RwDB.View(ctx, func(tx kv.Tx) error {
+		t := db.newTx(tx, "BeginRo")
+		defer t.end("Rollback")
+		return f(t)
+	})
+}
+
+func (db *DB) Update(ctx context.Context, f func(tx kv.RwTx) error) error {
+	return db.RwDB.Update(ctx, func(tx kv.RwTx) (err error) {
+		t := &rwTx{RwTx: tx, ro: db.newTx(tx, "BeginRw")}
+		defer func() { t.ro.end(commitOrRollback(err)) }()
+		return f(t)
+	})
+}
+
+func (db *DB) UpdateNosync(ctx context.Context, f func(tx kv.RwTx) error) error {
+	return db.RwDB.UpdateNosync(ctx, func(tx kv.RwTx) (err error) {
+		t := &rwTx{RwTx: tx, ro: db.newTx(tx, "BeginRw")}
+		defer func() { t.ro.end(commitOrRollback(err)) }()
+		return f(t)
+	})
+}
+
+func commitOrRollback(err error) string {
+	if err != nil {
+		return "Rollback"
+	}
+	return "Commit"
+}
+
+type roTx struct {
+	kv.Tx
+	db    *DB
+	id    uint64
+	ended bool
+}
+
+// end - records end of tx only once: Rollback after Commit is common pattern
+func (tx *roTx) end(op string) {
+	if tx.ended {
+		return
+	}
+	tx.ended = true
+	tx.db.record(op, tx.id)
+}
+
+func (tx *roTx) Rollback() {
+	tx.end("Rollback")
+	tx.Tx.Rollback()
+}
+
+func (tx *roTx) GetOne(table string, key []byte) ([]byte, error) {
+	v, err := tx.Tx.GetOne(table, key)
+	tx.db.record("GetOne", tx.id, table, enc(key), "->", valLen(v))
+	return v, err
+}
+
+func (tx *roTx) Has(table string, key []byte) (bool, error) {
+	has, err := tx.Tx.Has(table, key)
+	tx.db.record("Has", tx.id, table, enc(key), "->", strconv.FormatBool(has))
+	return has, err
+}
+
+func (tx *roTx) ReadSequence(table string) (uint64, error) {
+	tx.db.record("ReadSequence", tx.id, table)
+	return tx.Tx.ReadSequence(table)
+}
+
+func (tx *roTx) ForEach(table string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	var n int
+	err := tx.Tx.ForEach(table, fromPrefix, func(k, v []byte) error {
+		n++
+		return walker(k, v)
+	})
+	tx.db.record("ForEach", tx.id, table, enc(fromPrefix), "->", strconv.Itoa(n))
+	return err
+}
+
+func (tx *roTx) ForAmount(table string, prefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	tx.db.record("ForAmount", tx.id, table, enc(prefix), strconv.FormatUint(uint64(amount), 10))
+	return tx.Tx.ForAmount(table, prefix, amount, walker)
+}
+
+func (tx *roTx) newCursor(op, table string, c kv.Cursor) *cursor {
+	res := &cursor{db: tx.db, id: tx.db.ids.Add(1), c: c}
+	res.dup, _ = c.(kv.CursorDupSort)
+	res.rw, _ = c.(kv.RwCursor)
+	res.rwDup, _ = c.(kv.RwCursorDupSort)
+	tx.db.record(op, tx.id, table, strconv.FormatUint(res.id, 10))
+	return res
+}
+
+func (tx *roTx) Cursor(table string) (kv.Cursor, error) {
+	c, err := tx.Tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.newCursor("Cursor", table, c), nil
+}
+
+func (tx *roTx) CursorDupSort(table string) (kv.CursorDupSort, error) {
+	c, err := tx.Tx.CursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.newCursor("CursorDupSort", table, c), nil
+}
+
+func (tx *roTx) newStream(s stream.KV, args ...string) *kvStream {
+	res := &kvStream{KV: s, db: tx.db, id: tx.db.ids.Add(1)}
+	tx.db.record(args[0], tx.id, append(args[1:], strconv.FormatUint(res.id, 10))...)
+	return res
+}
+
+func (tx *roTx) Range(table string, fromPrefix, toPrefix []byte, asc order.By, limit int) (stream.KV, error) {
+	s, err := tx.Tx.Range(table, fromPrefix, toPrefix, asc, limit)
+	if err != nil {
+		return nil, err
+	}
+	return tx.newStream(s, "Range", table, enc(fromPrefix), enc(toPrefix), strconv.FormatBool(bool(asc)), strconv.Itoa(limit)), nil
+}
+
+func (tx *roTx) Prefix(table string, prefix []byte) (stream.KV, error) {
+	s, err := tx.Tx.Prefix(table, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return tx.newStream(s, "Prefix", table, enc(prefix)), nil
+}
+
+func (tx *roTx) RangeDupSort(table string, key []byte, fromPrefix, toPrefix []byte, asc order.By, limit int) (stream.KV, error) {
+	s, err := tx.Tx.RangeDupSort(table, key, fromPrefix, toPrefix, asc, limit)
+	if err != nil {
+		return nil, err
+	}
+	return tx.newStream(s, "RangeDupSort", table, enc(key), enc(fromPrefix), enc(toPrefix), strconv.FormatBool(bool(asc)), strconv.Itoa(limit)), nil
+}
+
+type rwTx struct {
+	kv.RwTx
+	ro *roTx
+}
+
+func (tx *rwTx) Commit() error {
+	tx.ro.end("Commit")
+	return tx.RwTx.Commit()
+}
+func (tx *rwTx) Rollback() { tx.ro.Rollback() }
+
+func (tx *rwTx) GetOne(table string, key []byte) ([]byte, error) { return tx.ro.GetOne(table, key) }
+func (tx *rwTx) Has(table string, key []byte) (bool, error)      { return tx.ro.Has(table, key) }
+func (tx *rwTx) ReadSequence(table string) (uint64, error)       { return tx.ro.ReadSequence(table) }
+func (tx *rwTx) ForEach(table string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	return tx.ro.ForEach(table, fromPrefix, walker)
+}
+func (tx *rwTx) ForAmount(table string, prefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	return tx.ro.ForAmount(table, prefix, amount, walker)
+}
+func (tx *rwTx) Cursor(table string) (kv.Cursor, error) { return tx.ro.Cursor(table) }
+func (tx *rwTx) CursorDupSort(table string) (kv.CursorDupSort, error) {
+	return tx.ro.CursorDupSort(table)
+}
+func (tx *rwTx) Range(table string, fromPrefix, toPrefix []byte, asc order.By, limit int) (stream.KV, error) {
+	return tx.ro.Range(table, fromPrefix, toPrefix, asc, limit)
+}
+func (tx *rwTx) Prefix(table string, prefix []byte) (stream.KV, error) {
+	return tx.ro.Prefix(table, prefix)
+}
+func (tx *rwTx) RangeDupSort(table string, key []byte, fromPrefix, toPrefix []byte, asc order.By, limit int) (stream.KV, error) {
+	return tx.ro.RangeDupSort(table, key, fromPrefix, toPrefix, asc, limit)
+}
+
+func (tx *rwTx) RwCursor(table string) (kv.RwCursor, error) {
+	c, err := tx.RwTx.RwCursor(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.ro.newCursor("RwCursor", table, c), nil
+}
+
+func (tx *rwTx) RwCursorDupSort(table string) (kv.RwCursorDupSort, error) {
+	c, err := tx.RwTx.RwCursorDupSort(table)
+	if err != nil {
+		return nil, err
+	}
+	return tx.ro.newCursor("RwCursorDupSort", table, c), nil
+}
+
+func (tx *rwTx) Put(table string, k, v []byte) error {
+	tx.ro.db.record("Put", tx.ro.id, table, enc(k), enc(v))
+	return tx.RwTx.Put(table, k, v)
+}
+
+func (tx *rwTx) Delete(table string, k []byte) error {
+	tx.ro.db.record("Delete", tx.ro.id, table, enc(k))
+	return tx.RwTx.Delete(table, k)
+}
+
+func (tx *rwTx) Append(table string, k, v []byte) error {
+	tx.ro.db.record("Append", tx.ro.id, table, enc(k), enc(v))
+	return tx.RwTx.Append(table, k, v)
+}
+
+func (tx *rwTx) AppendDup(table string, k, v []byte) error {
+	tx.ro.db.record("AppendDup", tx.ro.id, table, enc(k), enc(v))
+	return tx.RwTx.AppendDup(table, k, v)
+}
+
+func (tx *rwTx) IncrementSequence(table string, amount uint64) (uint64, error) {
+	tx.ro.db.record("IncrementSequence", tx.ro.id, table, strconv.FormatUint(amount, 10))
+	return tx.RwTx.IncrementSequence(table, amount)
+}
+
+func (tx *rwTx) ClearBucket(table string) error {
+	tx.ro.db.record("ClearBucket", tx.ro.id, table)
+	return tx.RwTx.ClearBucket(table)
+}
+
+// cursor - implements all cursor interfaces, but created only with interface supported by underlying cursor
+type cursor struct {
+	db    *DB
+	id    uint64
+	c     kv.Cursor
+	dup   kv.CursorDupSort
+	rw    kv.RwCursor
+	rwDup kv.RwCursorDupSort
+}
+
+func (c *cursor) recordKV(op string, k, v []byte, err error, args ...string) ([]byte, []byte, error) {
+	c.db.record(op, c.id, append(args, "->", enc(k), valLen(v))...)
+	return k, v, err
+}
+
+func (c *cursor) recordV(op string, v []byte, err error, args ...string) ([]byte, error) {
+	c.db.record(op, c.id, append(args, "->", valLen(v))...)
+	return v, err
+}
+
+func (c *cursor) First() ([]byte, []byte, error) {
+	k, v, err := c.c.First()
+	return c.recordKV("First", k, v, err)
+}
+func (c *cursor) Seek(seek []byte) ([]byte, []byte, error) {
+	k, v, err := c.c.Seek(seek)
+	return c.recordKV("Seek", k, v, err, enc(seek))
+}
+func (c *cursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	k, v, err := c.c.SeekExact(key)
+	return c.recordKV("SeekExact", k, v, err, enc(key))
+}
+func (c *cursor) Next() ([]byte, []byte, error) {
+	k, v, err := c.c.Next()
+	return c.recordKV("Next", k, v, err)
+}
+func (c *cursor) Prev() ([]byte, []byte, error) {
+	k, v, err := c.c.Prev()
+	return c.recordKV("Prev", k, v, err)
+}
+func (c *cursor) Last() ([]byte, []byte, error) {
+	k, v, err := c.c.Last()
+	return c.recordKV("Last", k, v, err)
+}
+func (c *cursor) Current() ([]byte, []byte, error) {
+	k, v, err := c.c.Current()
+	return c.recordKV("Current", k, v, err)
+}
+func (c *cursor) Close() {
+	c.db.record("Close", c.id)
+	c.c.Close()
+}
+
+func (c *cursor) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
+	k, v, err := c.dup.SeekBothExact(key, value)
+	return c.recordKV("SeekBothExact", k, v, err, enc(key), enc(value))
+}
+func (c *cursor) SeekBothRange(key, value []byte) ([]byte, error) {
+	v, err := c.dup.SeekBothRange(key, value)
+	return c.recordV("SeekBothRange", v, err, enc(key), enc(value))
+}
+func (c *cursor) FirstDup() ([]byte, error) {
+	v, err := c.dup.FirstDup()
+	return c.recordV("FirstDup", v, err)
+}
+func (c *cursor) NextDup() ([]byte, []byte, error) {
+	k, v, err := c.dup.NextDup()
+	return c.recordKV("NextDup", k, v, err)
+}
+func (c *cursor) NextNoDup() ([]byte, []byte, error) {
+	k, v, err := c.dup.NextNoDup()
+	return c.recordKV("NextNoDup", k, v, err)
+}
+func (c *cursor) PrevDup() ([]byte, []byte, error) {
+	k, v, err := c.dup.PrevDup()
+	return c.recordKV("PrevDup", k, v, err)
+}
+func (c *cursor) PrevNoDup() ([]byte, []byte, error) {
+	k, v, err := c.dup.PrevNoDup()
+	return c.recordKV("PrevNoDup", k, v, err)
+}
+func (c *cursor) LastDup() ([]byte, error) {
+	v, err := c.dup.LastDup()
+	return c.recordV("LastDup", v, err)
+}
+func (c *cursor) CountDuplicates() (uint64, error) {
+	c.db.record("CountDuplicates", c.id)
+	return c.dup.CountDuplicates()
+}
+
+func (c *cursor) Put(k, v []byte) error {
+	c.db.record("Put", c.id, enc(k), enc(v))
+	return c.rw.Put(k, v)
+}
+func (c *cursor) Append(k, v []byte) error {
+	c.db.record("Append", c.id, enc(k), enc(v))
+	return c.rw.Append(k, v)
+}
+func (c *cursor) Delete(k []byte) error {
+	c.db.record("Delete", c.id, enc(k))
+	return c.rw.Delete(k)
+}
+func (c *cursor) DeleteCurrent() error {
+	c.db.record("DeleteCurrent", c.id)
+	return c.rw.DeleteCurrent()
+}
+
+func (c *cursor) PutNoDupData(k, v []byte) error {
+	c.db.record("PutNoDupData", c.id, enc(k), enc(v))
+	return c.rwDup.PutNoDupData(k, v)
+}
+func (c *cursor) DeleteCurrentDuplicates() error {
+	c.db.record("DeleteCurrentDuplicates", c.id)
+	return c.rwDup.DeleteCurrentDuplicates()
+}
+func (c *cursor) DeleteExact(k1, k2 []byte) error {
+	c.db.record("DeleteExact", c.id, enc(k1), enc(k2))
+	return c.rwDup.DeleteExact(k1, k2)
+}
+func (c *cursor) AppendDup(k, v []byte) error {
+	c.db.record("AppendDup", c.id, enc(k), enc(v))
+	return c.rwDup.AppendDup(k, v)
+}
+
+type kvStream struct {
+	stream.KV
+	db *DB
+	id uint64
+}
+
+func (s *kvStream) Next() ([]byte, []byte, error) {
+	k, v, err := s.KV.Next()
+	s.db.record("Next", s.id, "->", enc(k), valLen(v))
+	return k, v, err
+}
+
+func (s *kvStream) Close() {
+	s.db.record("Close", s.id)
+	s.KV.Close()
+}
+
+func enc(b []byte) string {
+	if b == nil {
+		return "nil"
+	}
+	return "0x" + hex.EncodeToString(b)
+}
+
+func dec(s string) ([]byte, error) {
+	if s == "nil" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "0x") {
+		return nil, fmt.Errorf("bytes must have 0x prefix: %s", s)
+	}
+	return hex.DecodeString(s[2:])
+}
+
+func valLen(v []byte) string {
+	if v == nil {
+		return "-1"
+	}
+	return strconv.Itoa(len(v))
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package kvrecorder_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/kvrecorder"
+	"github.com/erigontech/erigon-lib/kv/memdb"
+	"github.com/erigontech/erigon-lib/kv/order"
+)
+
+func session(t *testing.T, db kv.RwDB) {
+	t.Helper()
+	ctx := context.Background()
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		require.NoError(t, tx.Put(kv.HeaderNumber, []byte{1}, []byte{1}))
+		require.NoError(t, tx.Put(kv.HeaderNumber, []byte{2}, []byte{}))
+		require.NoError(t, tx.Put(kv.HeaderNumber, []byte{3}, []byte{3, 3}))
+		c, err := tx.RwCursorDupSort(kv.TblAccountVals)
+		require.NoError(t, err)
+		defer c.Close()
+		require.NoError(t, c.Put([]byte{1}, []byte{1}))
+		require.NoError(t, c.Put([]byte{1}, []byte{2}))
+		require.NoError(t, c.Put([]byte{2}, []byte{1}))
+		return tx.Delete(kv.HeaderNumber, []byte{3})
+	}))
+
+	tx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	v, err := tx.GetOne(kv.HeaderNumber, []byte{1})
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, v)
+	c, err := tx.CursorDupSort(kv.TblAccountVals)
+	require.NoError(t, err)
+	defer c.Close()
+	k, _, err := c.Seek([]byte{1})
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, k)
+	_, v, err = c.NextDup()
+	require.NoError(t, err)
+	require.Equal(t, []byte{2}, v)
+	v, err = c.SeekBothRange([]byte{2}, []byte{0})
+	require.NoError(t, err)
+	require.Equal(t, []byte{1}, v)
+
+	it, err := tx.Range(kv.HeaderNumber, nil, nil, order.Asc, kv.Unlim)
+	require.NoError(t, err)
+	var n int
+	for it.HasNext() {
+		_, _, err := it.Next()
+		require.NoError(t, err)
+		n++
+	}
+	it.Close()
+	require.Equal(t, 2, n)
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	ctx := context.Background()
+	var trace bytes.Buffer
+	db := kvrecorder.New(memdb.NewTestDB(t, kv.ChainDB), &trace)
+	session(t, db)
+	require.NoError(t, db.Flush())
+
+	lines := strings.Split(strings.TrimSpace(trace.String()), "\n")
+	require.Equal(t, "BeginRw 1", lines[0])
+	require.Equal(t, "Put 1 HeaderNumber 0x01 0x01", lines[1])
+	require.Contains(t, lines, "GetOne 3 HeaderNumber 0x01 -> 1")
+
+	// replay on empty db: writes of trace produce same data, so reads must return same results
+	stats, err := kvrecorder.Replay(ctx, memdb.NewTestDB(t, kv.ChainDB), bytes.NewReader(trace.Bytes()))
+	require.NoError(t, err)
+	require.Zero(t, stats.Mismatches, stats.String())
+	require.Equal(t, 6, stats.Ops["Put"].Count) // 3 by tx, 3 by cursor
+	require.Equal(t, 1, stats.Ops["Commit"].Count)
+	require.Equal(t, 2, stats.Ops["Next"].Count)
+
+	// replay of reads only on db with other data - must detect differences
+	other := memdb.NewTestDB(t, kv.ChainDB)
+	require.NoError(t, other.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.HeaderNumber, []byte{1}, []byte{1, 1})
+	}))
+	readTrace := trace.String()[strings.Index(trace.String(), "BeginRo"):]
+	stats, err = kvrecorder.Replay(ctx, other, strings.NewReader(readTrace))
+	require.NoError(t, err)
+	require.NotZero(t, stats.Mismatches)
+}
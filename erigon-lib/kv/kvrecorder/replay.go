@@ -0,0 +1,463 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package kvrecorder
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/stream"
+)
+
+type OpStats struct {
+	Count    int
+	Duration time.Duration
+}
+
+type ReplayStats struct {
+	Ops map[string]*OpStats
+
+	// Mismatches - amount of operations which returned different result than recorded.
+	// Expected if trace is replayed on db with different data.
+	Mismatches    int
+	FirstMismatch int // line number
+}
+
+func (s *ReplayStats) String() string {
+	ops := make([]string, 0, len(s.Ops))
+	for op := range s.Ops {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return s.Ops[ops[i]].Duration > s.Ops[ops[j]].Duration })
+	var sb strings.Builder
+	for _, op := range ops {
+		st := s.Ops[op]
+		fmt.Fprintf(&sb, "%-24s count=%-10d total=%-14s avg=%s\n", op, st.Count, st.Duration, st.Duration/time.Duration(st.Count))
+	}
+	fmt.Fprintf(&sb, "mismatches=%d, first mismatch at line %d\n", s.Mismatches, s.FirstMismatch)
+	return sb.String()
+}
+
+type replayer struct {
+	db      kv.RwDB
+	txs     map[uint64]kv.Tx
+	cursors map[uint64]kv.Cursor
+	streams map[uint64]stream.KV
+}
+
+// Replay - re-executes recorded trace on given db, sequentially in one goroutine.
+// All transactions which are open at the end of trace are rolled back.
+func Replay(ctx context.Context, db kv.RwDB, trace io.Reader) (*ReplayStats, error) {
+	r := &replayer{db: db, txs: map[uint64]kv.Tx{}, cursors: map[uint64]kv.Cursor{}, streams: map[uint64]stream.KV{}}
+	defer func() {
+		for _, tx := range r.txs {
+			tx.Rollback()
+		}
+	}()
+
+	stats := &ReplayStats{Ops: map[string]*OpStats{}}
+	scanner := bufio.NewScanner(trace)
+	scanner.Buffer(make([]byte, 1024*1024), 256*1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if lineNum%100_000 == 0 {
+			select {
+			case <-ctx.Done():
+				return stats, ctx.Err()
+			default:
+			}
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			return stats, fmt.Errorf("line %d: malformed", lineNum)
+		}
+		op := fields[0]
+		id, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return stats, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		args, expected := fields[2:], []string(nil)
+		for i, a := range args {
+			if a == "->" {
+				args, expected = args[:i], args[i+1:]
+				break
+			}
+		}
+
+		start := time.Now()
+		result, err := r.exec(ctx, op, id, args)
+		if err != nil {
+			return stats, fmt.Errorf("line %d: %s: %w", lineNum, op, err)
+		}
+		st, ok := stats.Ops[op]
+		if !ok {
+			st = &OpStats{}
+			stats.Ops[op] = st
+		}
+		st.Count++
+		st.Duration += time.Since(start)
+
+		if expected != nil && strings.Join(result, " ") != strings.Join(expected, " ") {
+			if stats.Mismatches == 0 {
+				stats.FirstMismatch = lineNum
+			}
+			stats.Mismatches++
+		}
+	}
+	return stats, scanner.Err()
+}
+
+func (r *replayer) tx(id uint64) (kv.Tx, error) {
+	tx, ok := r.txs[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown tx %d", id)
+	}
+	return tx, nil
+}
+
+func (r *replayer) rwTx(id uint64) (kv.RwTx, error) {
+	tx, err := r.tx(id)
+	if err != nil {
+		return nil, err
+	}
+	rwTx, ok := tx.(kv.RwTx)
+	if !ok {
+		return nil, fmt.Errorf("tx %d is read-only", id)
+	}
+	return rwTx, nil
+}
+
+func kvResult(k, v []byte, err error) ([]string, error) {
+	return []string{enc(k), valLen(v)}, err
+}
+
+func vResult(v []byte, err error) ([]string, error) {
+	return []string{valLen(v)}, err
+}
+
+func decArgs(args []string, from, n int) ([][]byte, error) {
+	if len(args) < from+n {
+		return nil, fmt.Errorf("expected %d args, got %d", from+n, len(args))
+	}
+	res := make([][]byte, n)
+	for i := range res {
+		var err error
+		if res[i], err = dec(args[from+i]); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (r *replayer) exec(ctx context.Context, op string, id uint64, args []string) ([]string, error) {
+	if c, ok := r.cursors[id]; ok {
+		return r.execCursor(c, op, id, args)
+	}
+	if s, ok := r.streams[id]; ok {
+		switch op {
+		case "Next":
+			if !s.HasNext() {
+				return []string{"nil", "-1"}, nil
+			}
+			return kvResult(s.Next())
+		case "Close":
+			s.Close()
+			delete(r.streams, id)
+			return nil, nil
+		default:
+			return nil, fmt.Errorf("unknown stream operation")
+		}
+	}
+
+	switch op {
+	case "BeginRo":
+		tx, err := r.db.BeginRo(ctx)
+		r.txs[id] = tx
+		return nil, err
+	case "BeginRw":
+		tx, err := r.db.BeginRw(ctx)
+		r.txs[id] = tx
+		return nil, err
+	case "Commit":
+		tx, err := r.rwTx(id)
+		if err != nil {
+			return nil, err
+		}
+		delete(r.txs, id)
+		return nil, tx.Commit()
+	case "Rollback":
+		tx, err := r.tx(id)
+		if err != nil {
+			return nil, err
+		}
+		delete(r.txs, id)
+		tx.Rollback()
+		return nil, nil
+	}
+
+	if len(args) < 1 {
+		return nil, fmt.Errorf("table is not set")
+	}
+	table := args[0]
+	switch op {
+	case "GetOne", "Has", "ReadSequence", "ForEach", "ForAmount", "Cursor", "CursorDupSort", "Range", "Prefix", "RangeDupSort":
+		tx, err := r.tx(id)
+		if err != nil {
+			return nil, err
+		}
+		return r.execRead(tx, op, args)
+	case "RwCursor", "RwCursorDupSort":
+		tx, err := r.rwTx(id)
+		if err != nil {
+			return nil, err
+		}
+		cid, err := strconv.ParseUint(args[len(args)-1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if op == "RwCursor" {
+			r.cursors[cid], err = tx.RwCursor(table)
+		} else {
+			r.cursors[cid], err = tx.RwCursorDupSort(table)
+		}
+		return nil, err
+	case "IncrementSequence":
+		tx, err := r.rwTx(id)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < 2 {
+			return nil, fmt.Errorf("amount is not set")
+		}
+		amount, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		_, err = tx.IncrementSequence(table, amount)
+		return nil, err
+	case "ClearBucket":
+		tx, err := r.rwTx(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, tx.ClearBucket(table)
+	case "Put", "Append", "AppendDup", "Delete":
+		tx, err := r.rwTx(id)
+		if err != nil {
+			return nil, err
+		}
+		n := 2
+		if op == "Delete" {
+			n = 1
+		}
+		kvs, err := decArgs(args, 1, n)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "Put":
+			return nil, tx.Put(table, kvs[0], kvs[1])
+		case "Append":
+			return nil, tx.Append(table, kvs[0], kvs[1])
+		case "AppendDup":
+			return nil, tx.AppendDup(table, kvs[0], kvs[1])
+		default:
+			return nil, tx.Delete(table, kvs[0])
+		}
+	default:
+		return nil, fmt.Errorf("unknown operation")
+	}
+}
+
+func (r *replayer) execRead(tx kv.Tx, op string, args []string) ([]string, error) {
+	table := args[0]
+	switch op {
+	case "GetOne":
+		k, err := decArgs(args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return vResult(tx.GetOne(table, k[0]))
+	case "Has":
+		k, err := decArgs(args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		has, err := tx.Has(table, k[0])
+		return []string{strconv.FormatBool(has)}, err
+	case "ReadSequence":
+		_, err := tx.ReadSequence(table)
+		return nil, err
+	case "ForEach":
+		k, err := decArgs(args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		var n int
+		err = tx.ForEach(table, k[0], func(k, v []byte) error { n++; return nil })
+		return []string{strconv.Itoa(n)}, err
+	case "ForAmount":
+		k, err := decArgs(args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < 3 {
+			return nil, fmt.Errorf("amount is not set")
+		}
+		amount, err := strconv.ParseUint(args[2], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		return nil, tx.ForAmount(table, k[0], uint32(amount), func(k, v []byte) error { return nil })
+	}
+
+	// operations which create cursor or stream: last arg is id of it
+	newID, err := strconv.ParseUint(args[len(args)-1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	args = args[:len(args)-1]
+	switch op {
+	case "Cursor":
+		r.cursors[newID], err = tx.Cursor(table)
+		return nil, err
+	case "CursorDupSort":
+		r.cursors[newID], err = tx.CursorDupSort(table)
+		return nil, err
+	case "Prefix":
+		k, err := decArgs(args, 1, 1)
+		if err != nil {
+			return nil, err
+		}
+		r.streams[newID], err = tx.Prefix(table, k[0])
+		return nil, err
+	case "Range", "RangeDupSort":
+		n := 2
+		if op == "RangeDupSort" {
+			n = 3
+		}
+		k, err := decArgs(args, 1, n)
+		if err != nil {
+			return nil, err
+		}
+		if len(args) < 1+n+2 {
+			return nil, fmt.Errorf("order and limit are not set")
+		}
+		asc, err := strconv.ParseBool(args[1+n])
+		if err != nil {
+			return nil, err
+		}
+		limit, err := strconv.Atoi(args[2+n])
+		if err != nil {
+			return nil, err
+		}
+		if op == "Range" {
+			r.streams[newID], err = tx.Range(table, k[0], k[1], order.By(asc), limit)
+		} else {
+			r.streams[newID], err = tx.RangeDupSort(table, k[0], k[1], k[2], order.By(asc), limit)
+		}
+		return nil, err
+	default:
+		return nil, fmt.Errorf("unknown operation")
+	}
+}
+
+func (r *replayer) execCursor(c kv.Cursor, op string, id uint64, args []string) ([]string, error) {
+	dup, _ := c.(kv.CursorDupSort)
+	rw, _ := c.(kv.RwCursor)
+	rwDup, _ := c.(kv.RwCursorDupSort)
+	nArgs := map[string]int{"Seek": 1, "SeekExact": 1, "SeekBothExact": 2, "SeekBothRange": 2, "Put": 2, "Append": 2, "Delete": 1,
+		"PutNoDupData": 2, "DeleteExact": 2, "AppendDup": 2}[op]
+	a, err := decArgs(args, 0, nArgs)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case dup == nil && (op == "SeekBothExact" || op == "SeekBothRange" || op == "FirstDup" || op == "NextDup" || op == "NextNoDup" ||
+		op == "PrevDup" || op == "PrevNoDup" || op == "LastDup" || op == "CountDuplicates"):
+		return nil, fmt.Errorf("cursor %d is not dupsort", id)
+	case rw == nil && (op == "Put" || op == "Append" || op == "Delete" || op == "DeleteCurrent"):
+		return nil, fmt.Errorf("cursor %d is read-only", id)
+	case rwDup == nil && (op == "PutNoDupData" || op == "DeleteCurrentDuplicates" || op == "DeleteExact" || op == "AppendDup"):
+		return nil, fmt.Errorf("cursor %d is not read-write dupsort", id)
+	}
+
+	switch op {
+	case "First":
+		return kvResult(c.First())
+	case "Seek":
+		return kvResult(c.Seek(a[0]))
+	case "SeekExact":
+		return kvResult(c.SeekExact(a[0]))
+	case "Next":
+		return kvResult(c.Next())
+	case "Prev":
+		return kvResult(c.Prev())
+	case "Last":
+		return kvResult(c.Last())
+	case "Current":
+		return kvResult(c.Current())
+	case "Close":
+		c.Close()
+		delete(r.cursors, id)
+		return nil, nil
+	case "SeekBothExact":
+		return kvResult(dup.SeekBothExact(a[0], a[1]))
+	case "SeekBothRange":
+		return vResult(dup.SeekBothRange(a[0], a[1]))
+	case "FirstDup":
+		return vResult(dup.FirstDup())
+	case "NextDup":
+		return kvResult(dup.NextDup())
+	case "NextNoDup":
+		return kvResult(dup.NextNoDup())
+	case "PrevDup":
+		return kvResult(dup.PrevDup())
+	case "PrevNoDup":
+		return kvResult(dup.PrevNoDup())
+	case "LastDup":
+		return vResult(dup.LastDup())
+	case "CountDuplicates":
+		_, err := dup.CountDuplicates()
+		return nil, err
+	case "Put":
+		return nil, rw.Put(a[0], a[1])
+	case "Append":
+		return nil, rw.Append(a[0], a[1])
+	case "Delete":
+		return nil, rw.Delete(a[0])
+	case "DeleteCurrent":
+		return nil, rw.DeleteCurrent()
+	case "PutNoDupData":
+		return nil, rwDup.PutNoDupData(a[0], a[1])
+	case "DeleteCurrentDuplicates":
+		return nil, rwDup.DeleteCurrentDuplicates()
+	case "DeleteExact":
+		return nil, rwDup.DeleteExact(a[0], a[1])
+	case "AppendDup":
+		return nil, rwDup.AppendDup(a[0], a[1])
+	default:
+		return nil, fmt.Errorf("unknown cursor operation")
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package mdbx
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"strings"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+)
+
+// Values of tables with checksums are stored with trailing CRC32C (hardware-accelerated) of value.
+// Checksum is verified on each read and stripped - so bit rot is reported as kv.ErrValueCorrupted
+// with table and key, instead of root mismatch far from damaged key.
+//
+// In DupSort tables checksum is part of sorted value: methods which take full value (SeekBothExact, DeleteExact)
+// append checksum, methods which take value prefix (SeekBothRange) don't. Values of 1 key must not be prefixes of each other.
+
+const checksumLen = 4
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// valueChecksumMarker - key in kv.DatabaseInfo, presence means: all values of table have checksums
+const valueChecksumMarker = "valueChecksums/"
+
+func withChecksum(v []byte) []byte {
+	res := make([]byte, len(v)+checksumLen)
+	copy(res, v)
+	binary.BigEndian.PutUint32(res[len(v):], crc32.Checksum(v, castagnoli))
+	return res
+}
+
+// verifyChecksum - returns value without checksum. nil value means: not found
+func verifyChecksum(table string, k, v []byte) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if len(v) < checksumLen {
+		return nil, &kv.ValueCorruptionError{Table: table, Key: common.Copy(k), ValueLen: len(v)}
+	}
+	val, stored := v[:len(v)-checksumLen], binary.BigEndian.Uint32(v[len(v)-checksumLen:])
+	if calculated := crc32.Checksum(val, castagnoli); calculated != stored {
+		return nil, &kv.ValueCorruptionError{Table: table, Key: common.Copy(k), Stored: stored, Calculated: calculated, ValueLen: len(v)}
+	}
+	return val, nil
+}
+
+// initValueChecksums - table with checksums is marked in kv.DatabaseInfo. Checksums can be enabled only on empty
+// table, and once enabled they are verified (and stripped) even if not requested by opts.
+func (db *MdbxKV) initValueChecksums(ctx context.Context) error {
+	if _, ok := db.buckets[kv.DatabaseInfo]; !ok {
+		if len(db.opts.valueChecksums) > 0 && !db.opts.valueChecksumsDefault {
+			return fmt.Errorf("value checksums require table %s, label: %s", kv.DatabaseInfo, db.opts.label)
+		}
+		return nil
+	}
+	db.checksums = map[string]bool{}
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		return tx.ForEach(kv.DatabaseInfo, []byte(valueChecksumMarker), func(k, _ []byte) error {
+			if table, ok := strings.CutPrefix(string(k), valueChecksumMarker); ok {
+				db.checksums[table] = true
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	var toEnable []string
+	for _, table := range db.opts.valueChecksums {
+		if _, ok := db.buckets[table]; !ok && db.opts.valueChecksumsDefault {
+			continue
+		}
+		if !db.checksums[table] {
+			toEnable = append(toEnable, table)
+		}
+	}
+	if len(toEnable) == 0 {
+		return nil
+	}
+	if db.ReadOnly() || db.Accede() {
+		return fmt.Errorf("can't enable value checksums of %s: db is opened read-only, label: %s", toEnable, db.opts.label)
+	}
+	if err := db.Update(ctx, func(tx kv.RwTx) error {
+		for _, table := range toEnable {
+			cfg, ok := db.buckets[table]
+			if !ok || cfg.IsDeprecated {
+				return fmt.Errorf("can't enable value checksums: unknown table %s", table)
+			}
+			if cfg.AutoDupSortKeysConversion {
+				return fmt.Errorf("can't enable value checksums: table %s has AutoDupSortKeysConversion", table)
+			}
+			cnt, err := tx.(*MdbxTx).Count(table)
+			if err != nil {
+				return err
+			}
+			if cnt > 0 {
+				return fmt.Errorf("can't enable value checksums: table %s has %d values without checksums", table, cnt)
+			}
+			if err := tx.Put(kv.DatabaseInfo, []byte(valueChecksumMarker+table), []byte{1}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, table := range toEnable {
+		db.checksums[table] = true
+	}
+	return nil
+}
+
+func (c *MdbxCursor) verifyValue(k, v []byte) ([]byte, error) {
+	_, v, err := c.verify(k, v)
+	return v, err
+}
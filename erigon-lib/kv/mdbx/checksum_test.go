@@ -0,0 +1,161 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package mdbx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+const (
+	checksumTable    = "ChecksumTable"
+	checksumDupTable = "ChecksumDupTable"
+)
+
+func openChecksumDB(t *testing.T, path string, tables ...string) (kv.RwDB, error) {
+	t.Helper()
+	return New(kv.ChainDB, log.New()).Path(path).WithTableCfg(func(defaultBuckets kv.TableCfg) kv.TableCfg {
+		return kv.TableCfg{
+			checksumTable:    kv.TableCfgItem{},
+			checksumDupTable: kv.TableCfgItem{Flags: kv.DupSort},
+			kv.DatabaseInfo:  kv.TableCfgItem{},
+		}
+	}).MapSize(128 * datasize.MB).ValueChecksums(tables...).Open(context.Background())
+}
+
+func TestValueChecksums(t *testing.T) {
+	ctx, path := context.Background(), t.TempDir()
+	db, err := openChecksumDB(t, path, checksumTable, checksumDupTable)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		require.NoError(t, tx.Put(checksumTable, []byte("k1"), []byte("v1")))
+		require.NoError(t, tx.Put(checksumTable, []byte("k2"), []byte{}))
+		c, err := tx.RwCursorDupSort(checksumDupTable)
+		require.NoError(t, err)
+		defer c.Close()
+		for _, v := range []string{"a1", "b2", "c3"} {
+			require.NoError(t, c.Put([]byte("k"), []byte(v)))
+		}
+		return nil
+	}))
+
+	check := func(tx kv.Tx) {
+		v, err := tx.GetOne(checksumTable, []byte("k1"))
+		require.NoError(t, err)
+		require.Equal(t, "v1", string(v))
+		v, err = tx.GetOne(checksumTable, []byte("k2"))
+		require.NoError(t, err)
+		require.NotNil(t, v)
+		require.Empty(t, v)
+		v, err = tx.GetOne(checksumTable, []byte("k3"))
+		require.NoError(t, err)
+		require.Nil(t, v)
+
+		it, err := tx.Range(checksumTable, nil, nil, order.Asc, -1)
+		require.NoError(t, err)
+		var vals []string
+		for it.HasNext() {
+			_, v, err := it.Next()
+			require.NoError(t, err)
+			vals = append(vals, string(v))
+		}
+		require.Equal(t, []string{"v1", ""}, vals)
+
+		c, err := tx.CursorDupSort(checksumDupTable)
+		require.NoError(t, err)
+		defer c.Close()
+		v, err = c.SeekBothRange([]byte("k"), []byte("b"))
+		require.NoError(t, err)
+		require.Equal(t, "b2", string(v))
+		_, v, err = c.NextDup()
+		require.NoError(t, err)
+		require.Equal(t, "c3", string(v))
+		_, v, err = c.SeekBothExact([]byte("k"), []byte("a1"))
+		require.NoError(t, err)
+		require.Equal(t, "a1", string(v))
+	}
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error { check(tx); return nil }))
+
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		c, err := tx.RwCursorDupSort(checksumDupTable)
+		require.NoError(t, err)
+		defer c.Close()
+		require.NoError(t, c.DeleteExact([]byte("k"), []byte("b2")))
+		cnt, err := c.CountDuplicates()
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), cnt)
+		return nil
+	}))
+	db.Close()
+
+	// marker persisted: checksums are stripped even if not requested by opts
+	db, err = openChecksumDB(t, path)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(checksumTable, []byte("k1"))
+		require.NoError(t, err)
+		require.Equal(t, "v1", string(v))
+		return nil
+	}))
+
+	// bit rot
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		dbi := mdbx.DBI(tx.(*MdbxTx).db.buckets[checksumTable].DBI)
+		return tx.(*MdbxTx).tx.Put(dbi, []byte("k1"), append([]byte("v0"), withChecksum([]byte("v1"))[2:]...), 0)
+	}))
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		_, err := tx.GetOne(checksumTable, []byte("k1"))
+		require.ErrorIs(t, err, kv.ErrValueCorrupted)
+		var corruption *kv.ValueCorruptionError
+		require.True(t, errors.As(err, &corruption))
+		require.Equal(t, checksumTable, corruption.Table)
+		require.Equal(t, []byte("k1"), corruption.Key)
+
+		c, err := tx.Cursor(checksumTable)
+		require.NoError(t, err)
+		defer c.Close()
+		_, _, err = c.First()
+		require.ErrorIs(t, err, kv.ErrValueCorrupted)
+		return nil
+	}))
+}
+
+func TestValueChecksumsOnlyOnEmptyTable(t *testing.T) {
+	ctx, path := context.Background(), t.TempDir()
+	db, err := openChecksumDB(t, path)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error { return tx.Put(checksumTable, []byte("k"), []byte("v")) }))
+	db.Close()
+
+	_, err = openChecksumDB(t, path, checksumTable)
+	require.ErrorContains(t, err, "values without checksums")
+
+	db, err = openChecksumDB(t, path, checksumDupTable)
+	require.NoError(t, err)
+	db.Close()
+}
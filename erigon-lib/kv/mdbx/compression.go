@@ -0,0 +1,286 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package mdbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+)
+
+// Values of compressed tables are stored with 1-byte header - codec of value. Value is stored raw (CodecNone header)
+// if compression didn't make it smaller. Header allows change codec of table without rewriting existing values.
+// Compression is applied before checksum: checksum covers stored bytes.
+//
+// Only non-DupSort tables can be compressed: compressed values don't preserve order of values.
+
+type Codec uint8
+
+const (
+	CodecNone Codec = iota
+	CodecSnappy
+	CodecZstd
+)
+
+func (c Codec) String() string {
+	switch c {
+	case CodecNone:
+		return "none"
+	case CodecSnappy:
+		return "snappy"
+	case CodecZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("codec(%d)", uint8(c))
+	}
+}
+
+func ParseCodec(s string) (Codec, error) {
+	for _, c := range []Codec{CodecNone, CodecSnappy, CodecZstd} {
+		if c.String() == s {
+			return c, nil
+		}
+	}
+	return CodecNone, fmt.Errorf("unknown codec: %s, supported: none, snappy, zstd", s)
+}
+
+// valueCompressionMarker - key in kv.DatabaseInfo, value is Codec: all values of table have codec header
+const valueCompressionMarker = "valueCompression/"
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+)
+
+func compressValue(codec Codec, v []byte) []byte {
+	res := make([]byte, 1, 1+len(v))
+	switch codec {
+	case CodecSnappy:
+		res = append(res, s2.EncodeSnappy(nil, v)...)
+	case CodecZstd:
+		res = zstdEncoder.EncodeAll(v, res)
+	}
+	if len(res) >= 1+len(v) { // compression didn't help
+		res = append(res[:0], byte(CodecNone))
+		return append(res, v...)
+	}
+	res[0] = byte(codec)
+	return res
+}
+
+// decompressValue - returns value without header. nil value means: not found
+func decompressValue(table string, k, v []byte) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if len(v) == 0 {
+		return nil, fmt.Errorf("%w: table=%s, key=%x: no codec header", kv.ErrValueCorrupted, table, k)
+	}
+	var res []byte
+	var err error
+	switch codec := Codec(v[0]); codec {
+	case CodecNone:
+		return v[1:], nil
+	case CodecSnappy:
+		res, err = s2.Decode(nil, v[1:])
+	case CodecZstd:
+		res, err = zstdDecoder.DecodeAll(v[1:], nil)
+	default:
+		err = fmt.Errorf("unknown %s", codec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: table=%s, key=%x: %w", kv.ErrValueCorrupted, table, common.Copy(k), err)
+	}
+	if res == nil {
+		res = []byte{}
+	}
+	return res, nil
+}
+
+func (tx *MdbxTx) encodeValue(table string, v []byte) []byte {
+	if codec, ok := tx.db.codecs[table]; ok {
+		v = compressValue(codec, v)
+	}
+	if tx.db.checksums[table] {
+		v = withChecksum(v)
+	}
+	return v
+}
+
+func (tx *MdbxTx) decodeValue(table string, k, v []byte) (_ []byte, err error) {
+	if tx.db.checksums[table] {
+		if v, err = verifyChecksum(table, k, v); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := tx.db.codecs[table]; ok {
+		return decompressValue(table, k, v)
+	}
+	return v, nil
+}
+
+// initValueCompression - compressed table is marked in kv.DatabaseInfo. Compression can be enabled by opts only
+// on empty table, non-empty table must be converted by CompressTable. Once enabled, values are decompressed
+// even if compression is not requested by opts.
+func (db *MdbxKV) initValueCompression(ctx context.Context) error {
+	if _, ok := db.buckets[kv.DatabaseInfo]; !ok {
+		if len(db.opts.compression) > 0 {
+			return fmt.Errorf("value compression requires table %s, label: %s", kv.DatabaseInfo, db.opts.label)
+		}
+		return nil
+	}
+	db.codecs = map[string]Codec{}
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		return tx.ForEach(kv.DatabaseInfo, []byte(valueCompressionMarker), func(k, v []byte) error {
+			if table, ok := strings.CutPrefix(string(k), valueCompressionMarker); ok && len(v) == 1 {
+				db.codecs[table] = Codec(v[0])
+			}
+			return nil
+		})
+	}); err != nil {
+		return err
+	}
+
+	toSet := map[string]Codec{}
+	for table, codec := range db.opts.compression {
+		if current, ok := db.codecs[table]; !ok || current != codec {
+			toSet[table] = codec
+		}
+	}
+	if len(toSet) == 0 {
+		return nil
+	}
+	if db.ReadOnly() || db.Accede() {
+		return fmt.Errorf("can't enable value compression of %v: db is opened read-only, label: %s", toSet, db.opts.label)
+	}
+	if err := db.Update(ctx, func(tx kv.RwTx) error {
+		for table, codec := range toSet {
+			if err := db.checkCompressible(table); err != nil {
+				return err
+			}
+			if _, ok := db.codecs[table]; !ok { // codec of already compressed table can be changed: each value has own codec header
+				cnt, err := tx.(*MdbxTx).Count(table)
+				if err != nil {
+					return err
+				}
+				if cnt > 0 {
+					return fmt.Errorf("can't enable value compression: table %s has %d uncompressed values, use CompressTable", table, cnt)
+				}
+			}
+			if err := tx.Put(kv.DatabaseInfo, []byte(valueCompressionMarker+table), []byte{byte(codec)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for table, codec := range toSet {
+		db.codecs[table] = codec
+	}
+	return nil
+}
+
+func (db *MdbxKV) checkCompressible(table string) error {
+	cfg, ok := db.buckets[table]
+	if !ok || cfg.IsDeprecated {
+		return fmt.Errorf("can't enable value compression: unknown table %s", table)
+	}
+	if cfg.Flags&mdbx.DupSort != 0 {
+		return fmt.Errorf("can't enable value compression: table %s is DupSort", table)
+	}
+	return nil
+}
+
+// CompressTable - migration: rewrites all values of table with codec in one tx. Table can be uncompressed
+// or compressed by another codec. Db must not be used by other goroutines until CompressTable returns.
+func CompressTable(ctx context.Context, db kv.RwDB, table string, codec Codec) error {
+	mdbxDB, ok := db.(*MdbxKV)
+	if !ok {
+		return fmt.Errorf("CompressTable: %T is not mdbx db", db)
+	}
+	if mdbxDB.codecs == nil {
+		return errors.New("CompressTable: value compression requires table " + kv.DatabaseInfo)
+	}
+	if err := mdbxDB.checkCompressible(table); err != nil {
+		return err
+	}
+	if err := db.Update(ctx, func(tx kv.RwTx) error {
+		src, err := tx.(*MdbxTx).stdCursor(table) // decodes with current codec of table
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		dst, err := tx.(*MdbxTx).stdCursor(table)
+		if err != nil {
+			return err
+		}
+		defer dst.Close()
+		dst.(*MdbxCursor).codec, dst.(*MdbxCursor).compressed = codec, true
+
+		for k, v, err := src.First(); k != nil; k, v, err = src.Next() {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := dst.Put(common.Copy(k), common.Copy(v)); err != nil {
+				return err
+			}
+		}
+		return tx.Put(kv.DatabaseInfo, []byte(valueCompressionMarker+table), []byte{byte(codec)})
+	}); err != nil {
+		return err
+	}
+	mdbxDB.codecs[table] = codec
+	return nil
+}
+
+// seal - compresses and appends checksum if table has them
+func (c *MdbxCursor) seal(v []byte) []byte {
+	if c.compressed {
+		v = compressValue(c.codec, v)
+	}
+	if c.checksum {
+		v = withChecksum(v)
+	}
+	return v
+}
+
+func (c *MdbxCursor) verify(k, v []byte) ([]byte, []byte, error) {
+	var err error
+	if c.checksum {
+		if v, err = verifyChecksum(c.bucketName, k, v); err != nil {
+			return []byte{}, nil, err
+		}
+	}
+	if c.compressed {
+		if v, err = decompressValue(c.bucketName, k, v); err != nil {
+			return []byte{}, nil, err
+		}
+	}
+	return k, v, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package mdbx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/erigontech/mdbx-go/mdbx"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+func openCompressionDB(t *testing.T, path string, codec Codec, checksums bool) (kv.RwDB, error) {
+	t.Helper()
+	opts := New(kv.ChainDB, log.New()).Path(path).WithTableCfg(func(defaultBuckets kv.TableCfg) kv.TableCfg {
+		return kv.TableCfg{
+			checksumTable:    kv.TableCfgItem{},
+			checksumDupTable: kv.TableCfgItem{Flags: kv.DupSort},
+			kv.DatabaseInfo:  kv.TableCfgItem{},
+		}
+	}).MapSize(128 * datasize.MB)
+	if codec != CodecNone {
+		opts = opts.Compression(checksumTable, codec)
+	}
+	if checksums {
+		opts = opts.ValueChecksums(checksumTable)
+	}
+	return opts.Open(context.Background())
+}
+
+func TestValueCompression(t *testing.T) {
+	ctx := context.Background()
+	big := bytes.Repeat([]byte("block body "), 100)
+	for _, codec := range []Codec{CodecSnappy, CodecZstd} {
+		for _, checksums := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s checksums=%t", codec, checksums), func(t *testing.T) {
+				path := t.TempDir()
+				db, err := openCompressionDB(t, path, codec, checksums)
+				require.NoError(t, err)
+				require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+					require.NoError(t, tx.Put(checksumTable, []byte("k1"), big))
+					require.NoError(t, tx.Put(checksumTable, []byte("k2"), []byte("v2")))
+					c, err := tx.RwCursor(checksumTable)
+					require.NoError(t, err)
+					defer c.Close()
+					return c.Append([]byte("k3"), []byte{})
+				}))
+				db.Close()
+
+				// marker persisted: values are decompressed even if not requested by opts
+				db, err = openCompressionDB(t, path, CodecNone, false)
+				require.NoError(t, err)
+				defer db.Close()
+				require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+					v, err := tx.GetOne(checksumTable, []byte("k1"))
+					require.NoError(t, err)
+					require.Equal(t, big, v)
+
+					c, err := tx.Cursor(checksumTable)
+					require.NoError(t, err)
+					defer c.Close()
+					var vals [][]byte
+					for k, v, err := c.First(); k != nil; k, v, err = c.Next() {
+						require.NoError(t, err)
+						vals = append(vals, v)
+					}
+					require.Equal(t, [][]byte{big, []byte("v2"), {}}, vals)
+
+					stored, err := tx.(*MdbxTx).tx.Get(mdbx.DBI(tx.(*MdbxTx).db.buckets[checksumTable].DBI), []byte("k1"))
+					require.NoError(t, err)
+					require.Less(t, len(stored), len(big)/4)
+					return nil
+				}))
+			})
+		}
+	}
+}
+
+func TestCompressTable(t *testing.T) {
+	ctx, path := context.Background(), t.TempDir()
+	big := bytes.Repeat([]byte("receipt "), 100)
+	db, err := openCompressionDB(t, path, CodecNone, false)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for _, k := range []string{"a", "b", "c"} {
+			require.NoError(t, tx.Put(checksumTable, []byte(k), big))
+		}
+		return nil
+	}))
+	db.Close()
+
+	_, err = openCompressionDB(t, path, CodecSnappy, false)
+	require.ErrorContains(t, err, "use CompressTable")
+
+	db, err = openCompressionDB(t, path, CodecNone, false)
+	require.NoError(t, err)
+	require.ErrorContains(t, CompressTable(ctx, db, checksumDupTable, CodecSnappy), "DupSort")
+	require.NoError(t, CompressTable(ctx, db, checksumTable, CodecSnappy))
+	require.NoError(t, CompressTable(ctx, db, checksumTable, CodecZstd))
+	db.Close()
+
+	db, err = openCompressionDB(t, path, CodecZstd, false)
+	require.NoError(t, err)
+	defer db.Close()
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		cnt := 0
+		return tx.ForEach(checksumTable, nil, func(k, v []byte) error {
+			cnt++
+			require.Equal(t, big, v)
+			stored, err := tx.(*MdbxTx).tx.Get(mdbx.DBI(tx.(*MdbxTx).db.buckets[checksumTable].DBI), k)
+			require.NoError(t, err)
+			require.Equal(t, byte(CodecZstd), stored[0])
+			return nil
+		})
+	}))
+}
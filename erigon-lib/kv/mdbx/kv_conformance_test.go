@@ -0,0 +1,327 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package mdbx_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/erigontech/erigon-lib/gointerfaces"
+	remote "github.com/erigontech/erigon-lib/gointerfaces/remoteproto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/mdbx"
+	"github.com/erigontech/erigon-lib/kv/memdb"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/remotedb"
+	"github.com/erigontech/erigon-lib/kv/remotedbserver"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+// conformanceProvider - opens db of some kind: writes go to `writeDB`, reads and checks are done on `readDB`
+type conformanceProvider struct {
+	name string
+	open func(t *testing.T) (writeDB kv.RwDB, readDB kv.RoDB)
+}
+
+func conformanceProviders() []conformanceProvider {
+	return []conformanceProvider{
+		{name: "mdbx", open: func(t *testing.T) (kv.RwDB, kv.RoDB) {
+			db := mdbx.New(kv.ChainDB, log.New()).Path(t.TempDir()).MustOpen()
+			t.Cleanup(db.Close)
+			return db, db
+		}},
+		{name: "memdb", open: func(t *testing.T) (kv.RwDB, kv.RoDB) {
+			db := memdb.NewTestDB(t, kv.ChainDB)
+			return db, db
+		}},
+		{name: "remote", open: func(t *testing.T) (kv.RwDB, kv.RoDB) {
+			logger := log.New()
+			db := memdb.NewTestDB(t, kv.ChainDB)
+			grpcServer, conn := grpc.NewServer(), bufconn.Listen(1024*1024)
+			remote.RegisterKVServer(grpcServer, remotedbserver.NewKvServer(context.Background(), db, nil, nil, nil, logger))
+			go grpcServer.Serve(conn) //nolint:errcheck
+			cc, err := grpc.NewClient("passthrough:///bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+				grpc.WithContextDialer(func(ctx context.Context, url string) (net.Conn, error) { return conn.Dial() }))
+			require.NoError(t, err)
+			rdb, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), logger, remote.NewKVClient(cc)).Open()
+			require.NoError(t, err)
+			t.Cleanup(func() {
+				rdb.Close()
+				cc.Close()
+				grpcServer.Stop()
+			})
+			return db, rdb
+		}},
+	}
+}
+
+// conformanceScenario - fills db and returns what was observed by reading. All providers must observe the same.
+type conformanceScenario struct {
+	name string
+	fill func(tx kv.RwTx) error
+	read func(ctx context.Context, writeDB kv.RwDB, readDB kv.RoDB) ([]string, error)
+}
+
+const (
+	conformanceTable    = kv.HeaderNumber   // plain table
+	conformanceDupTable = kv.TblAccountVals // dupsort table
+)
+
+// observe - text form of cursor/get result: nil and empty values are different
+func observe(k, v []byte, err error) string {
+	if err != nil {
+		return "err"
+	}
+	if k == nil && v == nil {
+		return "<nil>"
+	}
+	val := fmt.Sprintf("%x", v)
+	if v == nil {
+		val = "<nil>"
+	} else if len(v) == 0 {
+		val = "<empty>"
+	}
+	return fmt.Sprintf("%x=%s", k, val)
+}
+
+func observeRange(it interface {
+	HasNext() bool
+	Next() ([]byte, []byte, error)
+}, err error) []string {
+	if err != nil {
+		return []string{"err"}
+	}
+	var res []string
+	for it.HasNext() {
+		k, v, err := it.Next()
+		res = append(res, observe(k, v, err))
+		if err != nil {
+			break
+		}
+	}
+	return res
+}
+
+func conformanceScenarios() []conformanceScenario {
+	putAll := func(table string, kvs ...[]byte) func(tx kv.RwTx) error {
+		return func(tx kv.RwTx) error {
+			for i := 0; i < len(kvs); i += 2 {
+				if err := tx.Put(table, kvs[i], kvs[i+1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	return []conformanceScenario{
+		{
+			name: "cursor",
+			fill: putAll(conformanceTable, []byte{1}, []byte{1}, []byte{1, 1}, []byte{2}, []byte{3}, []byte{3}),
+			read: func(ctx context.Context, _ kv.RwDB, db kv.RoDB) (res []string, err error) {
+				err = db.View(ctx, func(tx kv.Tx) error {
+					c, err := tx.Cursor(conformanceTable)
+					if err != nil {
+						return err
+					}
+					defer c.Close()
+					res = append(res, observe(c.First()))
+					res = append(res, observe(c.Prev())) // before first
+					res = append(res, observe(c.Next()))
+					res = append(res, observe(c.Seek([]byte{2}))) // not exact
+					res = append(res, observe(c.Seek([]byte{4}))) // after last
+					res = append(res, observe(c.SeekExact([]byte{2})))
+					res = append(res, observe(c.SeekExact([]byte{1, 1})))
+					res = append(res, observe(c.Last()))
+					res = append(res, observe(c.Next())) // after last
+					res = append(res, observe(c.Current()))
+					res = append(res, observe(c.Seek(nil)))
+					return nil
+				})
+				return res, err
+			},
+		},
+		{
+			name: "dupsort cursor",
+			fill: putAll(conformanceDupTable, []byte{1}, []byte{1}, []byte{1}, []byte{3}, []byte{1}, []byte{5}, []byte{2}, []byte{1}),
+			read: func(ctx context.Context, _ kv.RwDB, db kv.RoDB) (res []string, err error) {
+				err = db.View(ctx, func(tx kv.Tx) error {
+					c, err := tx.CursorDupSort(conformanceDupTable)
+					if err != nil {
+						return err
+					}
+					defer c.Close()
+					res = append(res, observe(c.First()))
+					res = append(res, observe(c.NextDup()))
+					v, err := c.LastDup()
+					res = append(res, observe([]byte{1}, v, err))
+					res = append(res, observe(c.NextDup())) // no more dups
+					res = append(res, observe(c.NextNoDup()))
+					res = append(res, observe(c.PrevNoDup()))
+					v, err = c.SeekBothRange([]byte{1}, []byte{4})
+					res = append(res, observe([]byte{1}, v, err))
+					v, err = c.SeekBothRange([]byte{1}, []byte{6}) // after last dup
+					res = append(res, observe([]byte{1}, v, err))
+					res = append(res, observe(c.SeekBothExact([]byte{1}, []byte{3})))
+					res = append(res, observe(c.SeekBothExact([]byte{1}, []byte{4})))
+					res = append(res, observe(c.Last()))
+					res = append(res, observe(c.PrevDup())) // single dup
+					return nil
+				})
+				return res, err
+			},
+		},
+		{
+			name: "prefix",
+			fill: putAll(conformanceTable, []byte{0, 1}, []byte{1}, []byte{1}, []byte{1}, []byte{1, 1}, []byte{1}, []byte{1, 0xff}, []byte{1}, []byte{2}, []byte{1}, []byte{0xff}, []byte{1}, []byte{0xff, 0xff}, []byte{1}),
+			read: func(ctx context.Context, _ kv.RwDB, db kv.RoDB) (res []string, err error) {
+				err = db.View(ctx, func(tx kv.Tx) error {
+					for _, prefix := range [][]byte{nil, {1}, {0xff}, {0xff, 0xff}, {3}} {
+						res = append(res, fmt.Sprintf("prefix %x", prefix))
+						res = append(res, observeRange(tx.Prefix(conformanceTable, prefix))...)
+					}
+					res = append(res, "range [1, 2)")
+					res = append(res, observeRange(tx.Range(conformanceTable, []byte{1}, []byte{2}, order.Asc, kv.Unlim))...)
+					res = append(res, "range desc [0xff, 1)")
+					res = append(res, observeRange(tx.Range(conformanceTable, []byte{0xff}, []byte{1}, order.Desc, kv.Unlim))...)
+					res = append(res, "foreach from 1")
+					return tx.ForEach(conformanceTable, []byte{1}, func(k, v []byte) error {
+						res = append(res, observe(k, v, nil))
+						return nil
+					})
+				})
+				return res, err
+			},
+		},
+		{
+			name: "empty values",
+			fill: putAll(conformanceTable, []byte{1}, []byte{}, []byte{2}, nil, []byte{3}, []byte{3}),
+			read: func(ctx context.Context, _ kv.RwDB, db kv.RoDB) (res []string, err error) {
+				err = db.View(ctx, func(tx kv.Tx) error {
+					for _, k := range [][]byte{{1}, {2}, {4}} {
+						v, err := tx.GetOne(conformanceTable, k)
+						res = append(res, observe(k, v, err))
+						has, err := tx.Has(conformanceTable, k)
+						res = append(res, fmt.Sprintf("has %x=%t,%v", k, has, err))
+					}
+					res = append(res, observeRange(tx.Range(conformanceTable, nil, nil, order.Asc, kv.Unlim))...)
+					c, err := tx.Cursor(conformanceTable)
+					if err != nil {
+						return err
+					}
+					defer c.Close()
+					res = append(res, observe(c.First()))
+					res = append(res, observe(c.Next()))
+					res = append(res, observe(c.SeekExact([]byte{2})))
+					res = append(res, observe(c.Seek([]byte{1})))
+					res = append(res, observe(c.Current()))
+					res = append(res, observe(c.Prev()))
+					res = append(res, observe(c.Last()))
+					return nil
+				})
+				return res, err
+			},
+		},
+		{
+			name: "tx isolation",
+			fill: putAll(conformanceTable, []byte{1}, []byte{1}),
+			read: func(ctx context.Context, writeDB kv.RwDB, db kv.RoDB) (res []string, err error) {
+				err = db.View(ctx, func(tx kv.Tx) error {
+					c, err := tx.Cursor(conformanceTable)
+					if err != nil {
+						return err
+					}
+					defer c.Close()
+					res = append(res, observe(c.First()))
+					// new updates are not visible for old readers
+					if err := writeDB.Update(ctx, func(tx kv.RwTx) error {
+						if err := tx.Put(conformanceTable, []byte{1}, []byte{2}); err != nil {
+							return err
+						}
+						return tx.Put(conformanceTable, []byte{2}, []byte{2})
+					}); err != nil {
+						return err
+					}
+					res = append(res, observe(c.Next()))
+					v, err := tx.GetOne(conformanceTable, []byte{1})
+					res = append(res, observe([]byte{1}, v, err))
+					return nil
+				})
+				if err != nil {
+					return nil, err
+				}
+				err = db.View(ctx, func(tx kv.Tx) error {
+					res = append(res, observeRange(tx.Range(conformanceTable, nil, nil, order.Asc, kv.Unlim))...)
+					return nil
+				})
+				return res, err
+			},
+		},
+		{
+			name: "errors",
+			fill: putAll(conformanceTable),
+			read: func(ctx context.Context, _ kv.RwDB, db kv.RoDB) (res []string, err error) {
+				err = db.View(ctx, func(tx kv.Tx) error {
+					_, err := tx.GetOne("NotExistingTable", []byte{1})
+					res = append(res, fmt.Sprintf("get from unknown table: err=%t", err != nil))
+					return nil
+				})
+				if err != nil {
+					return nil, err
+				}
+				viewErr := errors.New("view failed")
+				err = db.View(ctx, func(tx kv.Tx) error { return viewErr })
+				res = append(res, fmt.Sprintf("error of View callback returned as is: %t", errors.Is(err, viewErr)))
+				return res, nil
+			},
+		},
+	}
+}
+
+// TestProvidersConformance - runs same scenarios on all kv providers, fails on any difference of observed behavior
+func TestProvidersConformance(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fix me on win please")
+	}
+	ctx := context.Background()
+	for _, s := range conformanceScenarios() {
+		s := s
+		t.Run(s.name, func(t *testing.T) {
+			var refName string
+			var ref []string
+			for _, p := range conformanceProviders() {
+				writeDB, readDB := p.open(t)
+				require.NoError(t, writeDB.Update(ctx, s.fill), p.name)
+				observed, err := s.read(ctx, writeDB, readDB)
+				require.NoError(t, err, p.name)
+				if ref == nil {
+					refName, ref = p.name, observed
+					continue
+				}
+				require.Equal(t, ref, observed, "%s diverges from %s", p.name, refName)
+			}
+		})
+	}
+}
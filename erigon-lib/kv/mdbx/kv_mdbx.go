@@ -73,26 +73,43 @@ type MdbxOpts struct {
 	verbosity       kv.DBVerbosityLvl
 	label           kv.Label // marker to distinct db instances - one process may open many databases. for example to collect metrics of only 1 database
 	inMem           bool
+	ctxCheckEvery   int // ForEach/ForAmount check ctx of tx once per this amount of iterations
 
-	metrics bool
+	metrics   bool
+	opLatency bool // histograms of Get/Seek/Next/Put latency and bytes read/written per table, see kv.TableOpLatency
+	ioAmp     bool // attribute bytes read/written to subsystem of tx, see kv.WithSubsystem
+
+	valueChecksums        []string         // tables where values stored with checksum, see initValueChecksums
+	valueChecksumsDefault bool             // valueChecksums set by env: tables which are not in table cfg (of tests, tools) are skipped
+	compression           map[string]Codec // tables where values stored compressed, see initValueCompression
 }
 
 const DefaultMapSize = 2 * datasize.TB
 const DefaultGrowthStep = 1 * datasize.GB
 
-func New(label kv.Label, log log.Logger) MdbxOpts {
+func New(label kv.Label, logger log.Logger) MdbxOpts {
 	opts := MdbxOpts{
 		bucketsCfg: WithChaindataTables,
 		flags:      mdbx.NoReadahead | mdbx.Coalesce | mdbx.Durable,
-		log:        log,
+		log:        log.WithSubsystem(logger, "ethdb"),
 		pageSize:   kv.DefaultPageSize(),
 
 		mapSize:         DefaultMapSize,
 		growthStep:      DefaultGrowthStep,
 		mergeThreshold:  2 * 8192,
 		shrinkThreshold: -1, // default
+		ctxCheckEvery:   1024,
 		label:           label,
 		metrics:         label == kv.ChainDB,
+		opLatency:       label == kv.ChainDB && dbg.KVOpLatencyMetrics,
+		ioAmp:           label == kv.ChainDB && dbg.KVIOAmplification,
+	}
+	if label == kv.ChainDB && dbg.KVValueChecksums {
+		opts.valueChecksums, opts.valueChecksumsDefault = kv.ChaindataValueChecksumTables, true
+	}
+	if label == kv.ChainDB && dbg.MdbxSyncPeriod > 0 {
+		// group commit: fsync committed txs in background once per period, crash loses at most this period but db stays consistent
+		opts = opts.RemoveFlags(mdbx.Durable).AddFlags(mdbx.SafeNoSync).SyncPeriod(dbg.MdbxSyncPeriod)
 	}
 	if label == kv.ChainDB {
 		opts = opts.RemoveFlags(mdbx.NoReadahead) // enable readahead for chaindata by default. Erigon3 require fast updates and prune. Also it's chaindata is small (doesen GB)
@@ -114,6 +131,22 @@ func (opts MdbxOpts) DBVerbosity(v kv.DBVerbosityLvl) MdbxOpts    { opts.verbosi
 func (opts MdbxOpts) MapSize(sz datasize.ByteSize) MdbxOpts       { opts.mapSize = sz; return opts }
 func (opts MdbxOpts) WriteMergeThreshold(v uint64) MdbxOpts       { opts.mergeThreshold = v; return opts }
 func (opts MdbxOpts) WithTableCfg(f TableCfgFunc) MdbxOpts        { opts.bucketsCfg = f; return opts }
+func (opts MdbxOpts) OpLatencyMetrics(v bool) MdbxOpts            { opts.opLatency = v; return opts }
+func (opts MdbxOpts) IOAmplificationMetrics(v bool) MdbxOpts      { opts.ioAmp = v; return opts }
+func (opts MdbxOpts) CtxCheckEvery(n int) MdbxOpts                { opts.ctxCheckEvery = n; return opts }
+func (opts MdbxOpts) Compression(table string, codec Codec) MdbxOpts {
+	compression := make(map[string]Codec, len(opts.compression)+1)
+	for t, c := range opts.compression {
+		compression[t] = c
+	}
+	compression[table] = codec
+	opts.compression = compression
+	return opts
+}
+func (opts MdbxOpts) ValueChecksums(tables ...string) MdbxOpts {
+	opts.valueChecksums, opts.valueChecksumsDefault = tables, false
+	return opts
+}
 
 // Flags
 func (opts MdbxOpts) HasFlag(flag uint) bool          { return opts.flags&flag != 0 }
@@ -385,6 +418,14 @@ func (opts MdbxOpts) Open(ctx context.Context) (kv.RwDB, error) {
 	}); err != nil {
 		return nil, err
 	}
+	if err := db.initValueChecksums(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := db.initValueCompression(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
 
 	if !opts.inMem {
 		if staleReaders, err := db.env.ReaderCheck(); err != nil {
@@ -428,6 +469,8 @@ type MdbxKV struct {
 	txsAllDoneOnCloseCond *sync.Cond
 
 	leakDetector *dbg.LeakDetector
+	checksums    map[string]bool  // tables where values stored with checksum
+	codecs       map[string]Codec // tables where values stored compressed
 
 	// MaxBatchSize is the maximum size of a batch. Default value is
 	// copied from DefaultMaxBatchSize in Open.
@@ -454,6 +497,9 @@ func (db *MdbxKV) PageSize() datasize.ByteSize { return db.opts.pageSize }
 func (db *MdbxKV) ReadOnly() bool              { return db.opts.HasFlag(mdbx.Readonly) }
 func (db *MdbxKV) Accede() bool                { return db.opts.HasFlag(mdbx.Accede) }
 
+// ForceSync - flush to disk txs committed without fsync (by BeginRwNosync or SafeNoSync mode). Blocks until done.
+func (db *MdbxKV) ForceSync() error { return db.env.Sync(true, false) }
+
 func (db *MdbxKV) CHandle() unsafe.Pointer {
 	return db.env.CHandle()
 }
@@ -586,6 +632,8 @@ func (db *MdbxKV) BeginRo(ctx context.Context) (txn kv.Tx, err error) {
 		tx:       tx,
 		readOnly: true,
 		traceID:  db.leakDetector.Add(),
+		guard:    db.newUseGuard(false),
+		io:       db.subsystemIO(ctx),
 	}, nil
 }
 
@@ -620,6 +668,8 @@ func (db *MdbxKV) beginRw(ctx context.Context, flags uint) (txn kv.RwTx, err err
 		tx:      tx,
 		ctx:     ctx,
 		traceID: db.leakDetector.Add(),
+		guard:   db.newUseGuard(true), // RwTx is bound to OS thread - see runtime.LockOSThread above
+		io:      db.subsystemIO(ctx),
 	}, nil
 }
 
@@ -633,6 +683,25 @@ type MdbxTx struct {
 
 	toCloseMap map[uint64]kv.Closer
 	cursorID   uint64
+
+	guard *dbg.UseGuard   // not nil only if dbg.DetectTxMisuse=true
+	io    *kv.SubsystemIO // not nil only if io amplification metrics are enabled
+}
+
+func (db *MdbxKV) subsystemIO(ctx context.Context) *kv.SubsystemIO {
+	if !db.opts.ioAmp {
+		return nil
+	}
+	s := kv.IOOf(kv.SubsystemOf(ctx))
+	s.Txs.Add(1)
+	return s
+}
+
+func (db *MdbxKV) newUseGuard(rw bool) *dbg.UseGuard {
+	if !dbg.DetectTxMisuse {
+		return nil
+	}
+	return dbg.NewUseGuard(fmt.Sprintf("mdbx tx of %s", db.opts.label), rw)
 }
 
 type MdbxCursor struct {
@@ -641,7 +710,13 @@ type MdbxCursor struct {
 	bucketName string
 	isDupSort  bool
 	id         uint64
-	label      kv.Label // marker to distinct db instances - one process may open many databases. for example to collect metrics of only 1 database
+	label      kv.Label        // marker to distinct db instances - one process may open many databases. for example to collect metrics of only 1 database
+	latency    *kv.OpLatency   // nil if latency metrics are disabled
+	guard      *dbg.UseGuard   // guard of tx
+	io         *kv.SubsystemIO // io counters of tx
+	checksum   bool            // values stored with checksum
+	compressed bool            // values stored with codec header
+	codec      Codec           // codec of new values
 }
 
 func (db *MdbxKV) Env() *mdbx.Env { return db.env }
@@ -704,6 +779,9 @@ func (tx *MdbxTx) CollectMetrics() {
 	kv.TxLimit.SetUint64(tx.db.txSize)
 	kv.TxSpill.SetUint64(txInfo.Spill)
 	kv.TxUnspill.SetUint64(txInfo.Unspill)
+	if !tx.readOnly { // lag of read tx is its own lag
+		kv.TxReaderLag.SetUint64(txInfo.ReadLag)
+	}
 
 	gc, err := tx.BucketStat("gc")
 	if err != nil {
@@ -862,6 +940,8 @@ func (tx *MdbxTx) ExistsBucket(bucket string) (bool, error) {
 }
 
 func (tx *MdbxTx) Commit() error {
+	defer tx.guard.End()
+	defer tx.guard.Use()()
 	if tx.tx == nil {
 		return nil
 	}
@@ -886,6 +966,11 @@ func (tx *MdbxTx) Commit() error {
 	//	tx.PrintDebugInfo()
 	//}
 	tx.CollectMetrics()
+	if tx.io != nil && !tx.readOnly {
+		if txInfo, err := tx.tx.Info(false); err == nil {
+			tx.io.BytesDirty.Add(txInfo.SpaceDirty)
+		}
+	}
 
 	latency, err := tx.tx.Commit()
 	if err != nil {
@@ -916,6 +1001,8 @@ func (tx *MdbxTx) Rollback() {
 	if tx.tx == nil {
 		return
 	}
+	defer tx.guard.End()
+	defer tx.guard.Use()()
 	defer func() {
 		tx.tx = nil
 		tx.db.trackTxEnd()
@@ -940,6 +1027,18 @@ func (tx *MdbxTx) SpaceDirty() (uint64, uint64, error) {
 	return txInfo.SpaceDirty, tx.db.txSize, nil
 }
 
+// OldestReaderViewID - see kv.HasOldestReader. Only for RwTx: read tx of mdbx knows only its own lag
+func (tx *MdbxTx) OldestReaderViewID() (uint64, error) {
+	if tx.readOnly {
+		return 0, errors.New("OldestReaderViewID: not supported by read-only tx")
+	}
+	txInfo, err := tx.tx.Info(true)
+	if err != nil {
+		return 0, err
+	}
+	return txInfo.Id - txInfo.ReadLag, nil
+}
+
 func (tx *MdbxTx) closeCursors() {
 	for _, c := range tx.toCloseMap {
 		if c != nil {
@@ -967,10 +1066,23 @@ func (tx *MdbxTx) statelessCursor(bucket string) (kv.RwCursor, error) {
 }
 
 func (tx *MdbxTx) Put(table string, k, v []byte) error {
+	defer tx.guard.Use()()
+	if tx.db.opts.opLatency {
+		l := kv.TableOpLatency(table)
+		l.AddWrite(k, v)
+		defer l.Put.ObserveDuration(time.Now())
+	}
+	tx.io.AddWrite(k, v)
+	v = tx.encodeValue(table, v)
 	return tx.tx.Put(mdbx.DBI(tx.db.buckets[table].DBI), k, v, 0)
 }
 
 func (tx *MdbxTx) Delete(table string, k []byte) error {
+	defer tx.guard.Use()()
+	if tx.db.opts.opLatency {
+		kv.TableOpLatency(table).AddWrite(k, nil)
+	}
+	tx.io.AddWrite(k, nil)
 	err := tx.tx.Del(mdbx.DBI(tx.db.buckets[table].DBI), k, nil)
 	if mdbx.IsNotFound(err) {
 		return nil
@@ -979,6 +1091,12 @@ func (tx *MdbxTx) Delete(table string, k []byte) error {
 }
 
 func (tx *MdbxTx) GetOne(bucket string, k []byte) ([]byte, error) {
+	defer tx.guard.Use()()
+	var l *kv.OpLatency
+	if tx.db.opts.opLatency {
+		l = kv.TableOpLatency(bucket)
+		defer l.Get.ObserveDuration(time.Now())
+	}
 	v, err := tx.tx.Get(mdbx.DBI(tx.db.buckets[bucket].DBI), k)
 	if mdbx.IsNotFound(err) {
 		return nil, nil
@@ -986,10 +1104,13 @@ func (tx *MdbxTx) GetOne(bucket string, k []byte) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("label: %s, table: %s, %w", tx.db.opts.label, bucket, err)
 	}
-	return v, err
+	tx.io.AddRead(k, v)
+	l.AddRead(k, v)
+	return tx.decodeValue(bucket, k, v)
 }
 
 func (tx *MdbxTx) Has(bucket string, key []byte) (bool, error) {
+	defer tx.guard.Use()()
 	c, err := tx.statelessCursor(bucket)
 	if err != nil {
 		return false, err
@@ -1002,6 +1123,7 @@ func (tx *MdbxTx) Has(bucket string, key []byte) (bool, error) {
 }
 
 func (tx *MdbxTx) Append(bucket string, k, v []byte) error {
+	defer tx.guard.Use()()
 	c, err := tx.statelessCursor(bucket)
 	if err != nil {
 		return err
@@ -1009,6 +1131,7 @@ func (tx *MdbxTx) Append(bucket string, k, v []byte) error {
 	return c.Append(k, v)
 }
 func (tx *MdbxTx) AppendDup(bucket string, k, v []byte) error {
+	defer tx.guard.Use()()
 	c, err := tx.statelessCursor(bucket)
 	if err != nil {
 		return err
@@ -1017,6 +1140,7 @@ func (tx *MdbxTx) AppendDup(bucket string, k, v []byte) error {
 }
 
 func (tx *MdbxTx) IncrementSequence(bucket string, amount uint64) (uint64, error) {
+	defer tx.guard.Use()()
 	c, err := tx.statelessCursor(kv.Sequence)
 	if err != nil {
 		return 0, err
@@ -1041,6 +1165,7 @@ func (tx *MdbxTx) IncrementSequence(bucket string, amount uint64) (uint64, error
 }
 
 func (tx *MdbxTx) ReadSequence(bucket string) (uint64, error) {
+	defer tx.guard.Use()()
 	c, err := tx.statelessCursor(kv.Sequence)
 	if err != nil {
 		return 0, err
@@ -1106,8 +1231,13 @@ func (tx *MdbxTx) Cursor(bucket string) (kv.Cursor, error) {
 }
 
 func (tx *MdbxTx) stdCursor(bucket string) (kv.RwCursor, error) {
-	c := &MdbxCursor{bucketName: bucket, toCloseMap: tx.toCloseMap, label: tx.db.opts.label, isDupSort: tx.db.buckets[bucket].Flags&mdbx.DupSort != 0, id: tx.cursorID}
+	defer tx.guard.Use()()
+	c := &MdbxCursor{bucketName: bucket, toCloseMap: tx.toCloseMap, label: tx.db.opts.label, isDupSort: tx.db.buckets[bucket].Flags&mdbx.DupSort != 0, id: tx.cursorID, guard: tx.guard, io: tx.io, checksum: tx.db.checksums[bucket]}
+	c.codec, c.compressed = tx.db.codecs[bucket]
 	tx.cursorID++
+	if tx.db.opts.opLatency {
+		c.latency = kv.TableOpLatency(bucket)
+	}
 
 	if tx.tx == nil {
 		panic("assert: tx.tx nil. seems this `tx` was Rollback'ed")
@@ -1139,10 +1269,12 @@ func (tx *MdbxTx) CursorDupSort(bucket string) (kv.CursorDupSort, error) {
 }
 
 func (c *MdbxCursor) First() ([]byte, []byte, error) {
+	defer c.guard.Use()()
 	return c.Seek(nil)
 }
 
 func (c *MdbxCursor) Last() ([]byte, []byte, error) {
+	defer c.guard.Use()()
 	k, v, err := c.c.Get(nil, nil, mdbx.Last)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1152,10 +1284,15 @@ func (c *MdbxCursor) Last() ([]byte, []byte, error) {
 		return []byte{}, nil, err
 	}
 
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 func (c *MdbxCursor) Seek(seek []byte) (k, v []byte, err error) {
+	defer c.guard.Use()()
+	if c.latency != nil {
+		defer c.latency.Seek.ObserveDuration(time.Now())
+	}
 	if len(seek) == 0 {
 		k, v, err = c.c.Get(nil, nil, mdbx.First)
 		if err != nil {
@@ -1164,7 +1301,8 @@ func (c *MdbxCursor) Seek(seek []byte) (k, v []byte, err error) {
 			}
 			return []byte{}, nil, fmt.Errorf("cursor.First: %w, bucket: %s, key: %x", err, c.bucketName, seek)
 		}
-		return k, v, nil
+		c.addRead(k, v)
+		return c.verify(k, v)
 	}
 
 	k, v, err = c.c.Get(seek, nil, mdbx.SetRange)
@@ -1174,10 +1312,15 @@ func (c *MdbxCursor) Seek(seek []byte) (k, v []byte, err error) {
 		}
 		return []byte{}, nil, fmt.Errorf("cursor.SetRange: %w, bucket: %s, key: %x", err, c.bucketName, seek)
 	}
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 func (c *MdbxCursor) Next() (k, v []byte, err error) {
+	defer c.guard.Use()()
+	if c.latency != nil {
+		defer c.latency.Next.ObserveDuration(time.Now())
+	}
 	k, v, err = c.c.Get(nil, nil, mdbx.Next)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1185,10 +1328,12 @@ func (c *MdbxCursor) Next() (k, v []byte, err error) {
 		}
 		return []byte{}, nil, fmt.Errorf("failed MdbxKV cursor.Next(): %w", err)
 	}
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 func (c *MdbxCursor) Prev() (k, v []byte, err error) {
+	defer c.guard.Use()()
 	k, v, err = c.c.Get(nil, nil, mdbx.Prev)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1196,11 +1341,13 @@ func (c *MdbxCursor) Prev() (k, v []byte, err error) {
 		}
 		return []byte{}, nil, fmt.Errorf("failed MdbxKV cursor.Prev(): %w", err)
 	}
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 // Current - return key/data at current cursor position
 func (c *MdbxCursor) Current() ([]byte, []byte, error) {
+	defer c.guard.Use()()
 	k, v, err := c.c.Get(nil, nil, mdbx.GetCurrent)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1208,10 +1355,13 @@ func (c *MdbxCursor) Current() ([]byte, []byte, error) {
 		}
 		return []byte{}, nil, err
 	}
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 func (c *MdbxCursor) Delete(k []byte) error {
+	defer c.guard.Use()()
+	c.addWrite(k, nil)
 	_, _, err := c.c.Get(k, nil, mdbx.Set)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1232,17 +1382,33 @@ func (c *MdbxCursor) Delete(k []byte) error {
 // can still be used on it.
 // Both MDB_NEXT and MDB_GET_CURRENT will return the same record after
 // this operation.
-func (c *MdbxCursor) DeleteCurrent() error             { return c.c.Del(mdbx.Current) }
-func (c *MdbxCursor) PutNoOverwrite(k, v []byte) error { return c.c.Put(k, v, mdbx.NoOverwrite) }
+func (c *MdbxCursor) DeleteCurrent() error {
+	defer c.guard.Use()()
+	return c.c.Del(mdbx.Current)
+}
+func (c *MdbxCursor) PutNoOverwrite(k, v []byte) error {
+	defer c.guard.Use()()
+	c.addWrite(k, v)
+	return c.c.Put(k, c.seal(v), mdbx.NoOverwrite)
+}
 
 func (c *MdbxCursor) Put(key []byte, value []byte) error {
-	if err := c.c.Put(key, value, 0); err != nil {
+	defer c.guard.Use()()
+	if c.latency != nil {
+		defer c.latency.Put.ObserveDuration(time.Now())
+	}
+	c.addWrite(key, value)
+	if err := c.c.Put(key, c.seal(value), 0); err != nil {
 		return fmt.Errorf("label: %s, table: %s, err: %w", c.label, c.bucketName, err)
 	}
 	return nil
 }
 
 func (c *MdbxCursor) SeekExact(key []byte) ([]byte, []byte, error) {
+	defer c.guard.Use()()
+	if c.latency != nil {
+		defer c.latency.Seek.ObserveDuration(time.Now())
+	}
 	k, v, err := c.c.Get(key, nil, mdbx.Set)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1250,19 +1416,36 @@ func (c *MdbxCursor) SeekExact(key []byte) ([]byte, []byte, error) {
 		}
 		return []byte{}, nil, err
 	}
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 // Append - speedy feature of mdbx which is not part of KV interface.
 // Cast your cursor to *MdbxCursor to use this method.
 // Return error - if provided data will not sorted (or bucket have old records which mess with new in sorting manner).
 func (c *MdbxCursor) Append(k []byte, v []byte) error {
-	if err := c.c.Put(k, v, mdbx.Append); err != nil {
+	defer c.guard.Use()()
+	if c.latency != nil {
+		defer c.latency.Put.ObserveDuration(time.Now())
+	}
+	c.addWrite(k, v)
+	if err := c.c.Put(k, c.seal(v), mdbx.Append); err != nil {
 		return fmt.Errorf("label: %s, bucket: %s, %w", c.label, c.bucketName, err)
 	}
 	return nil
 }
 
+// addRead, addWrite - count bytes of operation to subsystem of tx and to table
+func (c *MdbxCursor) addRead(k, v []byte) {
+	c.io.AddRead(k, v)
+	c.latency.AddRead(k, v)
+}
+
+func (c *MdbxCursor) addWrite(k, v []byte) {
+	c.io.AddWrite(k, v)
+	c.latency.AddWrite(k, v)
+}
+
 func (c *MdbxCursor) Close() {
 	if c.c != nil {
 		c.c.Close()
@@ -1279,7 +1462,9 @@ type MdbxDupSortCursor struct {
 
 // DeleteExact - does delete
 func (c *MdbxDupSortCursor) DeleteExact(k1, k2 []byte) error {
-	_, _, err := c.c.Get(k1, k2, mdbx.GetBoth)
+	defer c.guard.Use()()
+	c.addWrite(k1, k2)
+	_, _, err := c.c.Get(k1, c.seal(k2), mdbx.GetBoth)
 	if err != nil { // if key not found, or found another one - then nothing to delete
 		if mdbx.IsNotFound(err) {
 			return nil
@@ -1290,17 +1475,20 @@ func (c *MdbxDupSortCursor) DeleteExact(k1, k2 []byte) error {
 }
 
 func (c *MdbxDupSortCursor) SeekBothExact(key, value []byte) ([]byte, []byte, error) {
-	_, v, err := c.c.Get(key, value, mdbx.GetBoth)
+	defer c.guard.Use()()
+	_, v, err := c.c.Get(key, c.seal(value), mdbx.GetBoth)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
 			return nil, nil, nil
 		}
 		return []byte{}, nil, fmt.Errorf("in SeekBothExact: %w", err)
 	}
-	return key, v, nil
+	c.addRead(key, v)
+	return c.verify(key, v)
 }
 
 func (c *MdbxDupSortCursor) SeekBothRange(key, value []byte) ([]byte, error) {
+	defer c.guard.Use()()
 	_, v, err := c.c.Get(key, value, mdbx.GetBothRange)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1308,10 +1496,12 @@ func (c *MdbxDupSortCursor) SeekBothRange(key, value []byte) ([]byte, error) {
 		}
 		return nil, fmt.Errorf("in SeekBothRange, table=%s: %w", c.bucketName, err)
 	}
-	return v, nil
+	c.addRead(nil, v)
+	return c.verifyValue(key, v)
 }
 
 func (c *MdbxDupSortCursor) FirstDup() ([]byte, error) {
+	defer c.guard.Use()()
 	_, v, err := c.c.Get(nil, nil, mdbx.FirstDup)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1319,11 +1509,13 @@ func (c *MdbxDupSortCursor) FirstDup() ([]byte, error) {
 		}
 		return nil, fmt.Errorf("in FirstDup: tbl=%s, %w", c.bucketName, err)
 	}
-	return v, nil
+	c.addRead(nil, v)
+	return c.verifyValue(nil, v)
 }
 
 // NextDup - iterate only over duplicates of current key
 func (c *MdbxDupSortCursor) NextDup() ([]byte, []byte, error) {
+	defer c.guard.Use()()
 	k, v, err := c.c.Get(nil, nil, mdbx.NextDup)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1331,11 +1523,13 @@ func (c *MdbxDupSortCursor) NextDup() ([]byte, []byte, error) {
 		}
 		return []byte{}, nil, fmt.Errorf("in NextDup: %w", err)
 	}
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 // NextNoDup - iterate with skipping all duplicates
 func (c *MdbxDupSortCursor) NextNoDup() ([]byte, []byte, error) {
+	defer c.guard.Use()()
 	k, v, err := c.c.Get(nil, nil, mdbx.NextNoDup)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1343,10 +1537,12 @@ func (c *MdbxDupSortCursor) NextNoDup() ([]byte, []byte, error) {
 		}
 		return []byte{}, nil, fmt.Errorf("in NextNoDup: %w", err)
 	}
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 func (c *MdbxDupSortCursor) PrevDup() ([]byte, []byte, error) {
+	defer c.guard.Use()()
 	k, v, err := c.c.Get(nil, nil, mdbx.PrevDup)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1354,10 +1550,12 @@ func (c *MdbxDupSortCursor) PrevDup() ([]byte, []byte, error) {
 		}
 		return []byte{}, nil, fmt.Errorf("in PrevDup: %w", err)
 	}
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 func (c *MdbxDupSortCursor) PrevNoDup() ([]byte, []byte, error) {
+	defer c.guard.Use()()
 	k, v, err := c.c.Get(nil, nil, mdbx.PrevNoDup)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1365,10 +1563,12 @@ func (c *MdbxDupSortCursor) PrevNoDup() ([]byte, []byte, error) {
 		}
 		return []byte{}, nil, fmt.Errorf("in PrevNoDup: %w", err)
 	}
-	return k, v, nil
+	c.addRead(k, v)
+	return c.verify(k, v)
 }
 
 func (c *MdbxDupSortCursor) LastDup() ([]byte, error) {
+	defer c.guard.Use()()
 	_, v, err := c.c.Get(nil, nil, mdbx.LastDup)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
@@ -1376,25 +1576,32 @@ func (c *MdbxDupSortCursor) LastDup() ([]byte, error) {
 		}
 		return nil, fmt.Errorf("in LastDup: %w", err)
 	}
-	return v, nil
+	c.addRead(nil, v)
+	return c.verifyValue(nil, v)
 }
 
 func (c *MdbxDupSortCursor) Append(k []byte, v []byte) error {
-	if err := c.c.Put(k, v, mdbx.Append|mdbx.AppendDup); err != nil {
+	defer c.guard.Use()()
+	c.addWrite(k, v)
+	if err := c.c.Put(k, c.seal(v), mdbx.Append|mdbx.AppendDup); err != nil {
 		return fmt.Errorf("label: %s, in Append: bucket=%s, %w", c.label, c.bucketName, err)
 	}
 	return nil
 }
 
 func (c *MdbxDupSortCursor) AppendDup(k []byte, v []byte) error {
-	if err := c.c.Put(k, v, mdbx.AppendDup); err != nil {
+	defer c.guard.Use()()
+	c.addWrite(k, v)
+	if err := c.c.Put(k, c.seal(v), mdbx.AppendDup); err != nil {
 		return fmt.Errorf("label: %s, in AppendDup: bucket=%s, %w", c.label, c.bucketName, err)
 	}
 	return nil
 }
 
 func (c *MdbxDupSortCursor) PutNoDupData(k, v []byte) error {
-	if err := c.c.Put(k, v, mdbx.NoDupData); err != nil {
+	defer c.guard.Use()()
+	c.addWrite(k, v)
+	if err := c.c.Put(k, c.seal(v), mdbx.NoDupData); err != nil {
 		return fmt.Errorf("label: %s, in PutNoDupData: %w", c.label, err)
 	}
 
@@ -1403,6 +1610,7 @@ func (c *MdbxDupSortCursor) PutNoDupData(k, v []byte) error {
 
 // DeleteCurrentDuplicates - delete all of the data items for the current key.
 func (c *MdbxDupSortCursor) DeleteCurrentDuplicates() error {
+	defer c.guard.Use()()
 	if err := c.c.Del(mdbx.AllDups); err != nil {
 		return fmt.Errorf("label: %s,in DeleteCurrentDuplicates: %w", c.label, err)
 	}
@@ -1411,6 +1619,7 @@ func (c *MdbxDupSortCursor) DeleteCurrentDuplicates() error {
 
 // CountDuplicates returns the number of duplicates for the current key. See mdb_cursor_count
 func (c *MdbxDupSortCursor) CountDuplicates() (uint64, error) {
+	defer c.guard.Use()()
 	res, err := c.c.Count()
 	if err != nil {
 		return 0, fmt.Errorf("in CountDuplicates: %w", err)
@@ -1430,16 +1639,22 @@ func bucketSlice(b kv.TableCfg) []string {
 }
 
 func (tx *MdbxTx) ForEach(bucket string, fromPrefix []byte, walker func(k, v []byte) error) error {
+	defer tx.guard.Use()()
 	c, err := tx.Cursor(bucket)
 	if err != nil {
 		return err
 	}
 	defer c.Close()
 
+	i := 0
 	for k, v, err := c.Seek(fromPrefix); k != nil; k, v, err = c.Next() {
 		if err != nil {
 			return err
 		}
+		if err := tx.checkCtx(i); err != nil {
+			return err
+		}
+		i++
 		if err := walker(k, v); err != nil {
 			return err
 		}
@@ -1447,6 +1662,14 @@ func (tx *MdbxTx) ForEach(bucket string, fromPrefix []byte, walker func(k, v []b
 	return nil
 }
 
+// checkCtx - checks ctx of tx on every `ctxCheckEvery` iteration of long loop: to not hang shutdown in full-table walks
+func (tx *MdbxTx) checkCtx(i int) error {
+	if tx.ctx == nil || tx.db.opts.ctxCheckEvery <= 0 || i%tx.db.opts.ctxCheckEvery != 0 {
+		return nil
+	}
+	return tx.ctx.Err()
+}
+
 func (tx *MdbxTx) Prefix(table string, prefix []byte) (stream.KV, error) {
 	nextPrefix, ok := kv.NextSubtree(prefix)
 	if !ok {
@@ -1780,6 +2003,7 @@ func (s *cursorDup2iter) Next() (k, v []byte, err error) {
 }
 
 func (tx *MdbxTx) ForAmount(bucket string, fromPrefix []byte, amount uint32, walker func(k, v []byte) error) error {
+	defer tx.guard.Use()()
 	if amount == 0 {
 		return nil
 	}
@@ -1789,10 +2013,15 @@ func (tx *MdbxTx) ForAmount(bucket string, fromPrefix []byte, amount uint32, wal
 	}
 	defer c.Close()
 
+	i := 0
 	for k, v, err := c.Seek(fromPrefix); k != nil && amount > 0; k, v, err = c.Next() {
 		if err != nil {
 			return err
 		}
+		if err := tx.checkCtx(i); err != nil {
+			return err
+		}
+		i++
 		if err := walker(k, v); err != nil {
 			return err
 		}
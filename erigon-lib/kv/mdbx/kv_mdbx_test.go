@@ -17,6 +17,7 @@
 package mdbx
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"errors"
@@ -26,9 +27,12 @@ import (
 	"time"
 
 	"github.com/c2h5oh/datasize"
+	"github.com/erigontech/mdbx-go/mdbx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/erigontech/erigon-lib/common/dbg"
+	"github.com/erigontech/erigon-lib/common/hexutility"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/order"
 	"github.com/erigontech/erigon-lib/kv/stream"
@@ -656,6 +660,103 @@ func TestBeginRwWithDoneContext(t *testing.T) {
 	require.ErrorIs(t, err, context.Canceled)
 }
 
+func TestForEachCancelledContext(t *testing.T) {
+	db := New(kv.ChainDB, log.New()).InMem(t.TempDir()).CtxCheckEvery(2).MustOpen()
+	defer db.Close()
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for i := uint64(0); i < 10; i++ {
+			if err := tx.Put(kv.Headers, hexutility.EncodeTs(i), []byte{1}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var seen int
+	err := db.View(ctx, func(tx kv.Tx) error {
+		return tx.ForEach(kv.Headers, nil, func(k, v []byte) error {
+			seen++
+			if seen == 3 {
+				cancel()
+			}
+			return nil
+		})
+	})
+	require.ErrorIs(t, err, context.Canceled)
+	require.Equal(t, 4, seen)
+}
+
+// callback which succeeded is committed even if ctx was cancelled meanwhile: cancellation only stops long loops inside callback
+func TestUpdateCancelledContextCommitted(t *testing.T) {
+	db := New(kv.ChainDB, log.New()).InMem(t.TempDir()).MustOpen()
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	err := db.Update(ctx, func(tx kv.RwTx) error {
+		cancel()
+		return tx.Put(kv.Headers, []byte{1}, []byte{1})
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.View(context.Background(), func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.Headers, []byte{1})
+		require.Equal(t, []byte{1}, v)
+		return err
+	}))
+}
+
+func TestForceSync(t *testing.T) {
+	path := t.TempDir()
+	opts := New(kv.ChainDB, log.New()).Path(path).MapSize(128 * datasize.MB).
+		RemoveFlags(mdbx.Durable).AddFlags(mdbx.SafeNoSync).SyncPeriod(time.Hour)
+	db := opts.MustOpen()
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(kv.Headers, []byte{1}, []byte{1})
+	}))
+	require.NoError(t, db.(kv.HasForceSync).ForceSync())
+	db.Close()
+
+	db = opts.MustOpen()
+	defer db.Close()
+	require.NoError(t, db.View(context.Background(), func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.Headers, []byte{1})
+		require.Equal(t, []byte{1}, v)
+		return err
+	}))
+}
+
+func TestOldestReaderViewID(t *testing.T) {
+	ctx := context.Background()
+	db := New(kv.ChainDB, log.New()).InMem(t.TempDir()).MustOpen()
+	defer db.Close()
+	put := func() {
+		require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error { return kv.IncrementKey(tx, kv.DatabaseInfo, []byte("k")) }))
+	}
+	put()
+	roTx, err := db.BeginRo(ctx)
+	require.NoError(t, err)
+	defer roTx.Rollback()
+	put()
+	put()
+
+	tx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	oldest, err := tx.(kv.HasOldestReader).OldestReaderViewID()
+	require.NoError(t, err)
+	require.Equal(t, roTx.ViewID(), oldest)
+	require.Less(t, oldest, tx.ViewID())
+	tx.Rollback()
+
+	roTx.Rollback()
+	tx, err = db.BeginRw(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	oldest, err = tx.(kv.HasOldestReader).OldestReaderViewID()
+	require.NoError(t, err)
+	require.Equal(t, tx.ViewID(), oldest)
+}
+
 func testCloseWaitsAfterTxBegin(
 	t *testing.T,
 	count int,
@@ -1042,3 +1143,156 @@ func BenchmarkDB_Delete(b *testing.B) {
 		b.Fatal(err)
 	}
 }
+
+func TestOpLatencyMetrics(t *testing.T) {
+	table := "OpLatencyTable"
+	db := New(kv.ChainDB, log.New()).InMem(t.TempDir()).WithTableCfg(func(defaultBuckets kv.TableCfg) kv.TableCfg {
+		return kv.TableCfg{table: kv.TableCfgItem{}}
+	}).MapSize(128 * datasize.MB).OpLatencyMetrics(true).MustOpen()
+	t.Cleanup(db.Close)
+
+	ctx := context.Background()
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for i := byte(0); i < 10; i++ {
+			if err := tx.Put(table, []byte{i}, []byte{i}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		if _, err := tx.GetOne(table, []byte{1}); err != nil {
+			return err
+		}
+		c, err := tx.Cursor(table)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		for k, _, err := c.Seek([]byte{5}); k != nil; k, _, err = c.Next() {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, kv.DumpOpLatency(&buf))
+	require.Regexp(t, table+`\s+get\s+1\s`, buf.String())
+	require.Regexp(t, table+`\s+seek\s+1\s`, buf.String())
+	require.Regexp(t, table+`\s+next\s+5\s`, buf.String())
+	require.Regexp(t, table+`\s+put\s+10\s`, buf.String())
+
+	l := kv.TableOpLatency(table)
+	require.Equal(t, uint64(20), l.BytesWritten.GetValueUint64())
+	require.Equal(t, uint64(2+2+4*2), l.BytesRead.GetValueUint64()) // get, seek, 4 next
+	require.Regexp(t, table+`\s+12 B\s+20 B`, buf.String())
+}
+
+func TestIOAmplificationMetrics(t *testing.T) {
+	table := "IOAmplificationTable"
+	db := New(kv.ChainDB, log.New()).InMem(t.TempDir()).WithTableCfg(func(defaultBuckets kv.TableCfg) kv.TableCfg {
+		return kv.TableCfg{table: kv.TableCfgItem{}}
+	}).MapSize(128 * datasize.MB).IOAmplificationMetrics(true).MustOpen()
+	t.Cleanup(db.Close)
+
+	subsystem := "test_" + t.Name()
+	ctx := kv.WithSubsystem(context.Background(), subsystem)
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error {
+		for i := byte(0); i < 10; i++ {
+			if err := tx.Put(table, []byte{i}, []byte{i, i, i}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		if _, err := tx.GetOne(table, []byte{1}); err != nil {
+			return err
+		}
+		c, err := tx.Cursor(table)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		for k, _, err := c.Seek([]byte{5}); k != nil; k, _, err = c.Next() {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	kv.AddLogicalWork(ctx, 2)
+
+	s := kv.IOOf(subsystem)
+	require.Equal(t, uint64(2), s.Txs.Load())
+	require.Equal(t, uint64(10*4), s.BytesWritten.Load())
+	require.Equal(t, uint64(4+5*4), s.BytesRead.Load())
+	require.Positive(t, s.BytesDirty.Load())
+	require.Equal(t, uint64(2), s.LogicalWork.Load())
+
+	var buf bytes.Buffer
+	require.NoError(t, kv.DumpIOAmplification(&buf))
+	require.Regexp(t, subsystem+`\s+2\s+24\s+40\s`, buf.String())
+}
+
+func TestDetectTxMisuse(t *testing.T) {
+	dbg.DetectTxMisuse = true
+	defer func() { dbg.DetectTxMisuse = false }()
+	db := BaseCaseDB(t)
+	ctx := context.Background()
+	table := "Table"
+	require.NoError(t, db.Update(ctx, func(tx kv.RwTx) error { return tx.Put(table, []byte{1}, []byte{1}) }))
+
+	panicMsg := func(f func()) (msg string) {
+		defer func() { msg, _ = recover().(string) }()
+		f()
+		return ""
+	}
+
+	t.Run("use after end", func(t *testing.T) {
+		tx, err := db.BeginRo(ctx)
+		require.NoError(t, err)
+		tx.Rollback()
+		require.Contains(t, panicMsg(func() { _, _ = tx.GetOne(table, []byte{1}) }), "use after end")
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		tx, err := db.BeginRo(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		inWalker, release, done := make(chan struct{}), make(chan struct{}), make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = tx.ForEach(table, nil, func(k, v []byte) error {
+				close(inWalker)
+				<-release
+				return nil
+			})
+		}()
+		<-inWalker
+		msg := panicMsg(func() { _, _ = tx.GetOne(table, []byte{1}) })
+		close(release)
+		<-done
+		require.Contains(t, msg, "concurrent use")
+	})
+
+	t.Run("nested use by same goroutine", func(t *testing.T) {
+		require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+			return tx.ForEach(table, nil, func(k, v []byte) error {
+				_, err := tx.GetOne(table, k)
+				return err
+			})
+		}))
+	})
+
+	t.Run("RwTx from other goroutine", func(t *testing.T) {
+		tx, err := db.BeginRw(ctx)
+		require.NoError(t, err)
+		defer tx.Rollback()
+		msg := make(chan string)
+		go func() { msg <- panicMsg(func() { _ = tx.Put(table, []byte{2}, []byte{2}) }) }()
+		require.Contains(t, <-msg, "belongs to goroutine")
+	})
+}
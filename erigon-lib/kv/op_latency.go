@@ -0,0 +1,156 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package kv
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/erigontech/erigon-lib/metrics"
+)
+
+// OpLatencyBuckets - log-scale buckets from 1µs to ~16s (with 2x step): storage stalls are in the tail, which default
+// buckets (and averages) hide
+var OpLatencyBuckets = func() []float64 {
+	res := make([]float64, 25)
+	for i := range res {
+		res[i] = 1e-6 * math.Pow(2, float64(i))
+	}
+	return res
+}()
+
+// OpLatency - latency histograms of operations over 1 table: `db_op_seconds{table="...",op="..."}`
+// and bytes (keys + values) read/written: `db_table_bytes{table="...",op="read|write"}`
+type OpLatency struct {
+	Get, Seek, Next, Put    metrics.Histogram
+	BytesRead, BytesWritten metrics.Counter
+}
+
+// AddRead - nil-safe: metrics may be disabled
+func (l *OpLatency) AddRead(k, v []byte) {
+	if l != nil {
+		l.BytesRead.AddInt(len(k) + len(v))
+	}
+}
+
+func (l *OpLatency) AddWrite(k, v []byte) {
+	if l != nil {
+		l.BytesWritten.AddInt(len(k) + len(v))
+	}
+}
+
+var (
+	opLatencyLock sync.Mutex
+	opLatency     sync.Map // table -> *OpLatency
+)
+
+// TableOpLatency - histograms of given table, created on first use
+func TableOpLatency(table string) *OpLatency {
+	if l, ok := opLatency.Load(table); ok {
+		return l.(*OpLatency)
+	}
+	opLatencyLock.Lock()
+	defer opLatencyLock.Unlock()
+	if l, ok := opLatency.Load(table); ok {
+		return l.(*OpLatency)
+	}
+	name := func(op string) string { return fmt.Sprintf(`db_op_seconds{table="%s",op="%s"}`, table, op) }
+	l := &OpLatency{
+		Get:  metrics.NewHistogram(name("get"), OpLatencyBuckets),
+		Seek: metrics.NewHistogram(name("seek"), OpLatencyBuckets),
+		Next: metrics.NewHistogram(name("next"), OpLatencyBuckets),
+		Put:  metrics.NewHistogram(name("put"), OpLatencyBuckets),
+
+		BytesRead:    metrics.NewCounter(fmt.Sprintf(`db_table_bytes{table="%s",op="read"}`, table)),
+		BytesWritten: metrics.NewCounter(fmt.Sprintf(`db_table_bytes{table="%s",op="write"}`, table)),
+	}
+	opLatency.Store(table, l)
+	return l
+}
+
+// DumpOpLatency - prints count and percentiles of all collected histograms. Percentile is upper bound of its bucket.
+// Then bytes read/written per table - sorted by total, to see which table dominates IO.
+func DumpOpLatency(w io.Writer) error {
+	var tables []string
+	opLatency.Range(func(table, _ any) bool {
+		tables = append(tables, table.(string))
+		return true
+	})
+	sort.Strings(tables)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "table\top\tcount\tp50\tp99\tp999\tmax")
+	for _, table := range tables {
+		l := TableOpLatency(table)
+		for _, op := range []struct {
+			name string
+			h    metrics.Histogram
+		}{{"get", l.Get}, {"seek", l.Seek}, {"next", l.Next}, {"put", l.Put}} {
+			var m dto.Metric
+			if err := op.h.Write(&m); err != nil {
+				return err
+			}
+			count := m.GetHistogram().GetSampleCount()
+			if count == 0 {
+				continue
+			}
+			buckets := m.GetHistogram().GetBucket()
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", table, op.name, count,
+				percentile(buckets, count, 0.5), percentile(buckets, count, 0.99), percentile(buckets, count, 0.999), percentile(buckets, count, 1))
+		}
+	}
+
+	total := func(table string) uint64 {
+		l := TableOpLatency(table)
+		return l.BytesRead.GetValueUint64() + l.BytesWritten.GetValueUint64()
+	}
+	sort.SliceStable(tables, func(i, j int) bool { return total(tables[i]) > total(tables[j]) })
+	fmt.Fprintln(tw, "\ntable\tread\twritten")
+	for _, table := range tables {
+		l := TableOpLatency(table)
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", table,
+			datasize.ByteSize(l.BytesRead.GetValueUint64()).HR(), datasize.ByteSize(l.BytesWritten.GetValueUint64()).HR())
+	}
+	return tw.Flush()
+}
+
+func percentile(buckets []*dto.Bucket, count uint64, p float64) string {
+	rank := uint64(math.Ceil(p * float64(count)))
+	for _, b := range buckets {
+		if b.GetCumulativeCount() >= rank {
+			return "<" + time.Duration(b.GetUpperBound()*float64(time.Second)).String()
+		}
+	}
+	return ">" + time.Duration(OpLatencyBuckets[len(OpLatencyBuckets)-1]*float64(time.Second)).String()
+}
+
+// OpLatencyHandler - dump of histograms on demand: `curl http://<metrics.addr>/debug/metrics/db_op_latency`
+func OpLatencyHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	if err := DumpOpLatency(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
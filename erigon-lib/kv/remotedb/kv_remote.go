@@ -101,7 +101,7 @@ func (opts remoteOpts) Open() (*DB, error) {
 	db := &DB{
 		opts:         opts,
 		remoteKV:     opts.remoteKV,
-		log:          log.New("remote_db", opts.DialAddress),
+		log:          log.WithSubsystem(opts.log, "remote").New("remote_db", opts.DialAddress),
 		buckets:      kv.TableCfg{},
 		roTxsLimiter: semaphore.NewWeighted(targetSemCount), // 1 less than max to allow unlocking
 	}
@@ -372,6 +372,14 @@ func (tx *tx) ListBuckets() ([]string, error) {
 // func (c *remoteCursor) Delete(k []byte) error                   { panic("not supported") }
 // func (c *remoteCursor) DeleteCurrent() error                    { panic("not supported") }
 
+// pairKV - protobuf doesn't distinguish nil and empty bytes. Existing key has non-nil value (maybe empty) - same as in mdbx.
+func pairKV(pair *remote.Pair) ([]byte, []byte, error) {
+	if pair.K != nil && pair.V == nil {
+		return pair.K, []byte{}, nil
+	}
+	return pair.K, pair.V, nil
+}
+
 func (c *remoteCursor) first() ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_FIRST}); err != nil {
 		return []byte{}, nil, err
@@ -380,7 +388,7 @@ func (c *remoteCursor) first() ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 
 func (c *remoteCursor) next() ([]byte, []byte, error) {
@@ -391,7 +399,7 @@ func (c *remoteCursor) next() ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) nextDup() ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_NEXT_DUP}); err != nil {
@@ -401,7 +409,7 @@ func (c *remoteCursor) nextDup() ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) nextNoDup() ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_NEXT_NO_DUP}); err != nil {
@@ -411,7 +419,7 @@ func (c *remoteCursor) nextNoDup() ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) prev() ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_PREV}); err != nil {
@@ -421,7 +429,7 @@ func (c *remoteCursor) prev() ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) prevDup() ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_PREV_DUP}); err != nil {
@@ -431,7 +439,7 @@ func (c *remoteCursor) prevDup() ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) prevNoDup() ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_PREV_NO_DUP}); err != nil {
@@ -441,7 +449,7 @@ func (c *remoteCursor) prevNoDup() ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) last() ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_LAST}); err != nil {
@@ -451,7 +459,7 @@ func (c *remoteCursor) last() ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) setRange(k []byte) ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK, K: k}); err != nil {
@@ -461,7 +469,7 @@ func (c *remoteCursor) setRange(k []byte) ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) seekExact(k []byte) ([]byte, []byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK_EXACT, K: k}); err != nil {
@@ -471,7 +479,7 @@ func (c *remoteCursor) seekExact(k []byte) ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) getBothRange(k, v []byte) ([]byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_SEEK_BOTH, K: k, V: v}); err != nil {
@@ -491,7 +499,7 @@ func (c *remoteCursor) seekBothExact(k, v []byte) ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 func (c *remoteCursor) firstDup() ([]byte, error) {
 	if err := c.stream.Send(&remote.Cursor{Cursor: c.id, Op: remote.Op_FIRST_DUP}); err != nil {
@@ -521,7 +529,7 @@ func (c *remoteCursor) getCurrent() ([]byte, []byte, error) {
 	if err != nil {
 		return []byte{}, nil, err
 	}
-	return pair.K, pair.V, nil
+	return pairKV(pair)
 }
 
 func (c *remoteCursor) Current() ([]byte, []byte, error) {
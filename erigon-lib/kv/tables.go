@@ -538,6 +538,10 @@ type TableCfgItem struct {
 	DupToLen   int
 }
 
+// ChaindataValueChecksumTables - critical tables of chaindata: bit rot there manifests as state root mismatch
+// far from damaged key. Values of these tables are stored with checksum if dbg.KVValueChecksums is set for new db.
+var ChaindataValueChecksumTables = []string{Headers, HeaderCanonical, BlockBody, TblAccountVals, TblCommitmentVals}
+
 var ChaindataTablesCfg = TableCfg{
 	HashedStorageDeprecated: {
 		Flags:                     DupSort,
@@ -19,6 +19,7 @@ package temporal
 import (
 	"context"
 
+	"github.com/erigontech/erigon-lib/common/tracing"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/mdbx"
 	"github.com/erigontech/erigon-lib/kv/order"
@@ -70,6 +71,13 @@ func New(db kv.RwDB, agg *state.Aggregator) (*DB, error) {
 func (db *DB) Agg() any            { return db.agg }
 func (db *DB) InternalDB() kv.RwDB { return db.RwDB }
 
+func (db *DB) ForceSync() error {
+	if s, ok := db.RwDB.(kv.HasForceSync); ok {
+		return s.ForceSync()
+	}
+	return nil
+}
+
 func (db *DB) BeginTemporalRo(ctx context.Context) (kv.TemporalTx, error) {
 	kvTx, err := db.RwDB.BeginRo(ctx) //nolint:gocritic
 	if err != nil {
@@ -210,11 +218,17 @@ func (tx *Tx) RangeAsOf(name kv.Domain, fromKey, toKey []byte, asOfTs uint64, as
 	if err != nil {
 		return nil, err
 	}
+	if tracing.Enabled() {
+		it = &spanKV{KV: it, span: tx.span("kv.RangeAsOf", name.String())}
+	}
 	tx.resourcesToClose = append(tx.resourcesToClose, it)
 	return it, nil
 }
 
 func (tx *Tx) GetLatest(name kv.Domain, k []byte) (v []byte, step uint64, err error) {
+	if tracing.Enabled() {
+		defer tx.span("kv.GetLatest", name.String()).End()
+	}
 	v, step, ok, err := tx.filesTx.GetLatest(name, k, tx.MdbxTx)
 	if err != nil {
 		return nil, step, err
@@ -225,10 +239,16 @@ func (tx *Tx) GetLatest(name kv.Domain, k []byte) (v []byte, step uint64, err er
 	return v, step, nil
 }
 func (tx *Tx) GetAsOf(name kv.Domain, k []byte, ts uint64) (v []byte, ok bool, err error) {
+	if tracing.Enabled() {
+		defer tx.span("kv.GetAsOf", name.String()).End()
+	}
 	return tx.filesTx.GetAsOf(tx.MdbxTx, name, k, ts)
 }
 
 func (tx *Tx) HistorySeek(name kv.Domain, key []byte, ts uint64) (v []byte, ok bool, err error) {
+	if tracing.Enabled() {
+		defer tx.span("kv.HistorySeek", name.String()).End()
+	}
 	return tx.filesTx.HistorySeek(name, key, ts, tx.MdbxTx)
 }
 
@@ -237,6 +257,9 @@ func (tx *Tx) IndexRange(name kv.InvertedIdx, k []byte, fromTs, toTs int, asc or
 	if err != nil {
 		return nil, err
 	}
+	if tracing.Enabled() {
+		timestamps = &spanU64{U64: timestamps, span: tx.span("kv.IndexRange", string(name))}
+	}
 	tx.resourcesToClose = append(tx.resourcesToClose, timestamps)
 	return timestamps, nil
 }
@@ -246,6 +269,9 @@ func (tx *Tx) HistoryRange(name kv.Domain, fromTs, toTs int, asc order.By, limit
 	if err != nil {
 		return nil, err
 	}
+	if tracing.Enabled() {
+		it = &spanKV{KV: it, span: tx.span("kv.HistoryRange", name.String())}
+	}
 	tx.resourcesToClose = append(tx.resourcesToClose, it)
 	return it, nil
 }
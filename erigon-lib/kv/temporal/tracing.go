@@ -0,0 +1,53 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package temporal
+
+import (
+	"github.com/erigontech/erigon-lib/common/tracing"
+	"github.com/erigontech/erigon-lib/kv/stream"
+)
+
+// span - span of db operation, child of span of ctx passed to BeginTemporalRo (for example of rpc call).
+// Operations are hot: callers check tracing.Enabled() first.
+func (tx *Tx) span(op, table string) tracing.Span {
+	_, span := tracing.Start(tx.ctx, op)
+	span.SetAttributes("table", table)
+	return span
+}
+
+// spanKV - span of range (walk) ends on Close: it includes iteration by caller
+type spanKV struct {
+	stream.KV
+	span tracing.Span
+}
+
+func (s *spanKV) Close() {
+	s.KV.Close()
+	s.span.End()
+	s.span = tracing.NoopSpan // Close is called by caller and by Rollback
+}
+
+type spanU64 struct {
+	stream.U64
+	span tracing.Span
+}
+
+func (s *spanU64) Close() {
+	s.U64.Close()
+	s.span.End()
+	s.span = tracing.NoopSpan
+}
@@ -0,0 +1,39 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package kv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrValueCorrupted - value of table with checksums doesn't match its checksum: bit rot or bug in storage layer.
+// Use errors.As with *ValueCorruptionError to get details.
+var ErrValueCorrupted = errors.New("value checksum mismatch")
+
+type ValueCorruptionError struct {
+	Table              string
+	Key                []byte // nil if unknown (for example: value read by cursor.FirstDup)
+	Stored, Calculated uint32
+	ValueLen           int
+}
+
+func (e *ValueCorruptionError) Error() string {
+	return fmt.Sprintf("%s: table=%s, key=%x, len(v)=%d, stored=%08x, calculated=%08x", ErrValueCorrupted, e.Table, e.Key, e.ValueLen, e.Stored, e.Calculated)
+}
+
+func (e *ValueCorruptionError) Unwrap() error { return ErrValueCorrupted }
@@ -598,3 +598,55 @@ func TestConcurrent(t *testing.T) {
 		}
 	}
 }
+
+func TestSubsystemLvlFilterHandler(t *testing.T) {
+	defer func() {
+		for name := range SubsystemLvls() {
+			ResetSubsystemLvl(name)
+		}
+	}()
+
+	h, r := testHandler()
+	l := New("k", "v")
+	l.SetHandler(SubsystemLvlFilterHandler(LvlInfo, h))
+	trie := WithSubsystem(l, "trie.commitment")
+	db := WithSubsystem(l, "ethdb").New("label", "chaindata")
+
+	logged := func(l Logger, lvl Lvl) bool {
+		*r = Record{}
+		l.Log(lvl, "msg")
+		return r.Msg == "msg"
+	}
+
+	if logged(trie, LvlDebug) || !logged(trie, LvlInfo) {
+		t.Fatal("subsystem without own level must be filtered by handler level")
+	}
+	if err := SetSubsystemLvls("trie=debug, ethdb=warn"); err != nil {
+		t.Fatal(err)
+	}
+	if !logged(trie, LvlDebug) || logged(trie, LvlTrace) {
+		t.Fatal("subsystem must inherit level of parent subsystem")
+	}
+	if logged(db, LvlInfo) || !logged(db, LvlWarn) {
+		t.Fatal("children of subsystem logger must keep its level")
+	}
+	if r.Subsystem != "ethdb" || len(r.Ctx) != 4 {
+		t.Fatalf("unexpected record %+v", r)
+	}
+	if logged(l, LvlDebug) {
+		t.Fatal("records without subsystem must be filtered by handler level")
+	}
+	if s := SubsystemLvlsString(); s != "ethdb=warn,trie=dbug" {
+		t.Fatalf("unexpected levels %s", s)
+	}
+
+	if err := SetSubsystemLvls("trie=default"); err != nil {
+		t.Fatal(err)
+	}
+	if logged(trie, LvlDebug) {
+		t.Fatal("reset subsystem must be filtered by handler level")
+	}
+	if err := SetSubsystemLvls("trie"); err == nil {
+		t.Fatal("expected error for spec without level")
+	}
+}
@@ -80,6 +80,9 @@ type Record struct {
 	Ctx      []interface{}
 	Call     stack.Call
 	KeyNames RecordKeyNames
+
+	// Subsystem of logger which made the record, see Subsystem
+	Subsystem string
 }
 
 // RecordKeyNames are the predefined names of the log props used by the Logger interface.
@@ -111,8 +114,9 @@ type Logger interface {
 }
 
 type logger struct {
-	ctx []interface{}
-	h   *swapHandler
+	ctx       []interface{}
+	h         *swapHandler
+	subsystem string
 }
 
 func (l *logger) write(msg string, lvl Lvl, ctx []interface{}) {
@@ -127,11 +131,12 @@ func (l *logger) write(msg string, lvl Lvl, ctx []interface{}) {
 			Msg:  msgKey,
 			Lvl:  lvlKey,
 		},
+		Subsystem: l.subsystem,
 	})
 }
 
 func (l *logger) New(ctx ...interface{}) Logger {
-	child := &logger{newContext(l.ctx, ctx), new(swapHandler)}
+	child := &logger{newContext(l.ctx, ctx), new(swapHandler), l.subsystem}
 	child.SetHandler(l.h)
 	return child
 }
@@ -0,0 +1,146 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Subsystem returns a logger of named subsystem. Subsystems are hierarchical,
+// names are separated by dots, e.g. "trie.commitment" is part of "trie".
+//
+// Records of subsystem loggers are written to the same handlers as records of
+// the root logger, but SubsystemLvlFilterHandler lets them through according to
+// the level of their subsystem, if one was set by SetSubsystemLvl:
+//
+//	log.SetSubsystemLvl("trie", log.LvlDebug)
+//	log.Subsystem("trie.commitment").Debug("written") // passes, even if console verbosity is info
+func Subsystem(name string) Logger {
+	return WithSubsystem(root, name)
+}
+
+// WithSubsystem returns a logger which has context of l and belongs to named subsystem.
+// It's useful for components which get their logger from caller.
+func WithSubsystem(l Logger, name string) Logger {
+	parent, ok := l.(*logger)
+	if !ok {
+		return l
+	}
+	child := &logger{parent.ctx, new(swapHandler), name}
+	child.SetHandler(parent.h)
+	return child
+}
+
+var (
+	subsystemLvlsMu sync.Mutex
+	subsystemLvls   atomic.Pointer[map[string]Lvl]
+)
+
+// SetSubsystemLvl sets level of subsystem and all its children which have no own level.
+func SetSubsystemLvl(name string, lvl Lvl) {
+	subsystemLvlsMu.Lock()
+	defer subsystemLvlsMu.Unlock()
+	lvls := SubsystemLvls()
+	lvls[name] = lvl
+	subsystemLvls.Store(&lvls)
+}
+
+// ResetSubsystemLvl makes subsystem use level of parent subsystem, or handler's level.
+func ResetSubsystemLvl(name string) {
+	subsystemLvlsMu.Lock()
+	defer subsystemLvlsMu.Unlock()
+	lvls := SubsystemLvls()
+	delete(lvls, name)
+	subsystemLvls.Store(&lvls)
+}
+
+// SubsystemLvls returns a copy of levels set by SetSubsystemLvl.
+func SubsystemLvls() map[string]Lvl {
+	lvls := map[string]Lvl{}
+	if cur := subsystemLvls.Load(); cur != nil {
+		for k, v := range *cur {
+			lvls[k] = v
+		}
+	}
+	return lvls
+}
+
+// SetSubsystemLvls parses and applies comma separated list of levels, e.g.
+// "trie=debug,stagedsync=trace". Level "default" resets the subsystem.
+func SetSubsystemLvls(spec string) error {
+	type item struct {
+		name  string
+		lvl   Lvl
+		reset bool
+	}
+	var items []item
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, lvlStr, ok := strings.Cut(part, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid subsystem level %q, expected <subsystem>=<level>", part)
+		}
+		if lvlStr == "default" {
+			items = append(items, item{name: name, reset: true})
+			continue
+		}
+		lvl, err := LvlFromString(lvlStr)
+		if err != nil {
+			return fmt.Errorf("subsystem %s: %w", name, err)
+		}
+		items = append(items, item{name: name, lvl: lvl})
+	}
+	for _, it := range items {
+		if it.reset {
+			ResetSubsystemLvl(it.name)
+		} else {
+			SetSubsystemLvl(it.name, it.lvl)
+		}
+	}
+	return nil
+}
+
+// SubsystemLvlsString formats levels set by SetSubsystemLvl in the form accepted by SetSubsystemLvls.
+func SubsystemLvlsString() string {
+	lvls := SubsystemLvls()
+	parts := make([]string, 0, len(lvls))
+	for name, lvl := range lvls {
+		parts = append(parts, name+"="+lvl.String())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+// subsystemLvl returns level of the closest subsystem which has own level.
+func subsystemLvl(name string) (Lvl, bool) {
+	lvls := subsystemLvls.Load()
+	if name == "" || lvls == nil || len(*lvls) == 0 {
+		return 0, false
+	}
+	for {
+		if lvl, ok := (*lvls)[name]; ok {
+			return lvl, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// SubsystemLvlFilterHandler is like LvlFilterHandler, but records of subsystems
+// with own level (see SetSubsystemLvl) are filtered by that level instead of maxLvl.
+func SubsystemLvlFilterHandler(maxLvl Lvl, h Handler) Handler {
+	return FilterHandler(func(r *Record) (pass bool) {
+		if lvl, ok := subsystemLvl(r.Subsystem); ok {
+			return r.Lvl <= lvl
+		}
+		return r.Lvl <= maxLvl
+	}, h)
+}
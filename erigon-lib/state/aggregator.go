@@ -438,6 +438,59 @@ func sendDiagnostics(startIndexingTime time.Time, indexPercent map[string]int, a
 	})
 }
 
+// BuildMissedIndicesIfIdle - like BuildMissedIndices, but returns ok=false and does nothing if files are built in background
+func (a *Aggregator) BuildMissedIndicesIfIdle(ctx context.Context, workers int) (ok bool, err error) {
+	if ok := a.buildingFiles.CompareAndSwap(false, true); !ok {
+		return false, nil
+	}
+	defer a.buildingFiles.Store(false)
+	return true, a.BuildMissedIndices(ctx, workers)
+}
+
+// BuildFilesIfIdle - like BuildFilesInBackground, but builds files of steps which are fully in db and then merges
+// files in caller's goroutine, stopping when ctx is cancelled. Returns ok=false and does nothing if files
+// are built or merged in background.
+func (a *Aggregator) BuildFilesIfIdle(ctx context.Context, toTxNum uint64) (ok bool, err error) {
+	if ok := a.buildingFiles.CompareAndSwap(false, true); !ok {
+		return false, nil
+	}
+	defer a.buildingFiles.Store(false)
+	if ok := a.mergingFiles.CompareAndSwap(false, true); !ok {
+		return false, nil
+	}
+	defer a.mergingFiles.Store(false)
+	a.wg.Add(1)
+	defer a.wg.Done()
+
+	if !a.produce || (toTxNum+1) <= a.visibleFilesMinimaxTxNum.Load()+a.aggregationStep {
+		return true, nil
+	}
+	if a.snapshotBuildSema != nil {
+		if err := a.snapshotBuildSema.Acquire(ctx, 1); err != nil {
+			return true, err
+		}
+		defer a.snapshotBuildSema.Release(1)
+	}
+
+	lastInDB := max(
+		lastIdInDB(a.db, a.d[kv.AccountsDomain]),
+		lastIdInDB(a.db, a.d[kv.CodeDomain]),
+		lastIdInDB(a.db, a.d[kv.StorageDomain]),
+		lastIdInDBNoHistory(a.db, a.d[kv.CommitmentDomain]))
+	for step := a.visibleFilesMinimaxTxNum.Load() / a.StepSize(); step < lastInDB; step++ { //`step` must be fully-written - means `step+1` records must be visible
+		if err := ctx.Err(); err != nil {
+			return true, err
+		}
+		if err := a.buildFiles(ctx, step); err != nil {
+			return true, err
+		}
+	}
+	if dbg.NoMerge() {
+		return true, nil
+	}
+	return true, a.MergeLoop(ctx)
+}
+
 func (a *Aggregator) BuildMissedIndicesInBackground(ctx context.Context, workers int) {
 	if ok := a.buildingFiles.CompareAndSwap(false, true); !ok {
 		return
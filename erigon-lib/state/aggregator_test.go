@@ -430,6 +430,45 @@ func TestNewBtIndex(t *testing.T) {
 	}
 }
 
+func TestAggregatorV3_BuildFilesIfIdle(t *testing.T) {
+	t.Parallel()
+	aggStep := uint64(10)
+	db, agg := testDbAndAggregatorv3(t, aggStep)
+
+	tx, err := db.BeginRw(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+	ac := agg.BeginFilesRo()
+	defer ac.Close()
+	domains, err := NewSharedDomains(WrapTxWithCtx(tx, ac), log.New())
+	require.NoError(t, err)
+	defer domains.Close()
+	maxTx := aggStep * 5
+	generateSharedDomainsUpdates(t, domains, maxTx, newRnd(0), 20, 10, aggStep/2)
+	require.NoError(t, domains.Flush(context.Background(), tx))
+	require.NoError(t, tx.Commit())
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	ok, err := agg.BuildFilesIfIdle(cancelled, maxTx)
+	require.True(t, ok)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Zero(t, agg.visibleFilesMinimaxTxNum.Load())
+
+	agg.buildingFiles.Store(true)
+	ok, err = agg.BuildFilesIfIdle(context.Background(), maxTx)
+	require.NoError(t, err)
+	require.False(t, ok)
+	agg.buildingFiles.Store(false)
+
+	ok, err = agg.BuildFilesIfIdle(context.Background(), maxTx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Positive(t, agg.visibleFilesMinimaxTxNum.Load())
+	require.False(t, agg.buildingFiles.Load())
+	require.False(t, agg.mergingFiles.Load())
+}
+
 func TestAggregatorV3_PruneSmallBatches(t *testing.T) {
 	t.Parallel()
 	aggStep := uint64(10)
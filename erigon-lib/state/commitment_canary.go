@@ -0,0 +1,68 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/erigontech/erigon-lib/commitment"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+// RunCommitmentCanary checks one random path of the stored commitment branches against flat state every interval
+// until ctx is done. Mismatches are logged and counted by commitment.HashCanary metrics, sync is never interrupted.
+// Each sample holds a read transaction only for the time of the check.
+func RunCommitmentCanary(ctx context.Context, db kv.TemporalRoDB, interval time.Duration, tmpdir string, logger log.Logger) {
+	canary := commitment.NewHashCanary(length.Addr, nil, tmpdir, time.Now().UnixNano())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := db.View(ctx, func(tx kv.Tx) error {
+			return sampleCommitmentCanary(canary, tx, logger)
+		}); err != nil && !errors.Is(err, context.Canceled) {
+			logger.Warn("[dbg] commitment canary", "err", err)
+		}
+	}
+}
+
+func sampleCommitmentCanary(canary *commitment.HashCanary, tx kv.Tx, logger log.Logger) error {
+	sd, err := NewSharedDomains(tx, logger)
+	if err != nil {
+		return err
+	}
+	defer sd.Close()
+
+	canary.ResetContext(sd.sdCtx)
+	checked, mismatches, err := canary.Sample()
+	if err != nil {
+		return err
+	}
+	for _, m := range mismatches {
+		logger.Error("[dbg] commitment canary: memoized hash does not match flat state", "txNum", sd.TxNum(), "mismatch", m.String())
+	}
+	logger.Trace("[dbg] commitment canary", "txNum", sd.TxNum(), "branches", checked, "mismatches", len(mismatches))
+	return nil
+}
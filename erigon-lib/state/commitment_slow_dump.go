@@ -0,0 +1,155 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/erigontech/erigon-lib/commitment"
+	"github.com/erigontech/erigon-lib/common/dir"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/common/length"
+)
+
+// slowCommitmentDump - self-contained description of one slow commitment computation (see dbg.SlowCommitmentDump):
+// what was updated, which branches were read and where time was spent. FixtureCmd extracts minimal datadir
+// which is enough to re-execute the block and reproduce computation.
+type slowCommitmentDump struct {
+	Block    uint64           `json:"block"`
+	TxNum    uint64           `json:"txNum"`
+	RootHash hexutility.Bytes `json:"rootHash"`
+	Mode     string           `json:"mode"`
+
+	Took            string `json:"took"`
+	FoldTook        string `json:"foldTook"`
+	UnfoldTook      string `json:"unfoldTook"`
+	UpdateTook      string `json:"updateTook"`
+	BranchWriteTook string `json:"branchWriteTook"`
+	Folds           int    `json:"folds"`
+	Unfolds         int    `json:"unfolds"`
+
+	// lists below are cut to slowCommitmentDumpMaxKeys entries each (then fixtureCmd has only part of accounts), totals are not
+	KeyRanges      []slowCommitmentKeyRange `json:"keyRanges"`
+	PlainKeys      []hexutility.Bytes       `json:"plainKeys"`
+	BranchPrefixes []hexutility.Bytes       `json:"branchPrefixes"`
+	Truncated      bool                     `json:"truncated,omitempty"`
+	KeyRangesTotal int                      `json:"keyRangesTotal"`
+	PlainKeysTotal int                      `json:"plainKeysTotal"`
+	BranchesTotal  int                      `json:"branchPrefixesTotal"`
+
+	FixtureCmd string `json:"fixtureCmd"`
+}
+
+type slowCommitmentKeyRange struct {
+	Account   hexutility.Bytes `json:"account"`
+	Touched   bool             `json:"touched"`
+	Slots     int              `json:"slots,omitempty"`
+	FirstSlot hexutility.Bytes `json:"firstSlot,omitempty"`
+	LastSlot  hexutility.Bytes `json:"lastSlot,omitempty"`
+}
+
+// vars for tests
+var (
+	slowCommitmentDumpMaxKeys  = 10_000 // per list of dump: big blocks touch ~100K keys, it's ~10Mb of json
+	slowCommitmentDumpMaxFiles = 64     // latest dumps are kept, older are removed
+)
+
+// dumpSlowCommitment - writes record to <datadir>/slow_commitment/block_<blockNum>.json, returns path of written file.
+// Removes oldest dumps if there are more than slowCommitmentDumpMaxFiles.
+func (sdc *SharedDomainsCommitmentContext) dumpSlowCommitment(record *commitment.ProcessRecord, blockNum uint64, rootHash []byte) (string, error) {
+	if sdc.sharedDomains.aggTx == nil {
+		return "", fmt.Errorf("dump slow commitment: AggregatorContext is not initialized")
+	}
+	dirs := sdc.sharedDomains.aggTx.a.dirs
+	dumpDir := filepath.Join(dirs.DataDir, "slow_commitment")
+	if err := os.MkdirAll(dumpDir, 0755); err != nil {
+		return "", err
+	}
+
+	d := slowCommitmentDump{
+		Block:           blockNum,
+		TxNum:           sdc.sharedDomains.txNum,
+		RootHash:        rootHash,
+		Mode:            sdc.updates.Mode().String(),
+		Took:            record.Took.String(),
+		FoldTook:        record.FoldTook.String(),
+		UnfoldTook:      record.UnfoldTook.String(),
+		UpdateTook:      record.UpdateTook.String(),
+		BranchWriteTook: record.BranchWriteTook.String(),
+		Folds:           record.Folds,
+		Unfolds:         record.Unfolds,
+	}
+	keyRanges := record.KeyRanges(length.Addr)
+	d.KeyRangesTotal, d.PlainKeysTotal, d.BranchesTotal = len(keyRanges), len(record.PlainKeys), len(record.BranchPrefixes)
+	d.Truncated = max(d.KeyRangesTotal, d.PlainKeysTotal, d.BranchesTotal) > slowCommitmentDumpMaxKeys
+
+	addrs := make([]string, 0, min(len(keyRanges), slowCommitmentDumpMaxKeys))
+	for _, kr := range keyRanges[:min(len(keyRanges), slowCommitmentDumpMaxKeys)] {
+		d.KeyRanges = append(d.KeyRanges, slowCommitmentKeyRange{Account: kr.Account, Touched: kr.Touched, Slots: kr.Slots, FirstSlot: kr.FirstSlot, LastSlot: kr.LastSlot})
+		addrs = append(addrs, hexutility.Encode(kr.Account))
+	}
+	for _, k := range record.PlainKeys[:min(len(record.PlainKeys), slowCommitmentDumpMaxKeys)] {
+		d.PlainKeys = append(d.PlainKeys, k)
+	}
+	for _, p := range record.BranchPrefixes[:min(len(record.BranchPrefixes), slowCommitmentDumpMaxKeys)] {
+		d.BranchPrefixes = append(d.BranchPrefixes, p)
+	}
+	d.FixtureCmd = fmt.Sprintf("integration extract_fixture --datadir=%s --block=%d --addresses=%s --output.datadir=%s",
+		dirs.DataDir, blockNum, strings.Join(addrs, ","), filepath.Join(dumpDir, fmt.Sprintf("fixture_%d", blockNum)))
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	fPath := filepath.Join(dumpDir, fmt.Sprintf("block_%d.json", blockNum))
+	if err := dir.WriteFileWithFsync(fPath, data, 0644); err != nil {
+		return "", err
+	}
+	if err := removeOldSlowCommitmentDumps(dumpDir, slowCommitmentDumpMaxFiles); err != nil {
+		return "", err
+	}
+	return fPath, nil
+}
+
+// removeOldSlowCommitmentDumps - keeps `keep` latest (by modification time) dumps of dumpDir. Fixtures are not touched:
+// they are extracted by user from chosen dumps.
+func removeOldSlowCommitmentDumps(dumpDir string, keep int) error {
+	files, err := filepath.Glob(filepath.Join(dumpDir, "block_*.json"))
+	if err != nil || len(files) <= keep {
+		return err
+	}
+	modTime := make(map[string]int64, len(files))
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTime[f] = info.ModTime().UnixNano()
+	}
+	sort.Slice(files, func(i, j int) bool { return modTime[files[i]] < modTime[files[j]] })
+	for _, f := range files[:len(files)-keep] {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -44,6 +44,7 @@ import (
 	"github.com/erigontech/erigon-lib/common/assert"
 	"github.com/erigontech/erigon-lib/common/dbg"
 	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/common/tracing"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/order"
 	"github.com/erigontech/erigon-lib/kv/rawdbv3"
@@ -724,6 +725,8 @@ func (sd *SharedDomains) SetTrace(b bool) {
 }
 
 func (sd *SharedDomains) ComputeCommitment(ctx context.Context, saveStateAfter bool, blockNum uint64, logPrefix string) (rootHash []byte, err error) {
+	ctx, span := tracing.Start(ctx, "commitment.ComputeCommitment")
+	defer span.End()
 	rootHash, err = sd.sdCtx.ComputeCommitment(ctx, saveStateAfter, blockNum, logPrefix)
 	return
 }
@@ -1340,12 +1343,30 @@ func (sdc *SharedDomainsCommitmentContext) ComputeCommitment(ctx context.Context
 	sdc.patriciaTrie.SetTrace(sdc.sharedDomains.trace)
 	sdc.Reset()
 
+	var record *commitment.ProcessRecord
+	if dbg.SlowCommitmentDump > 0 {
+		if hph, ok := sdc.patriciaTrie.(*commitment.HexPatriciaHashed); ok {
+			record = &commitment.ProcessRecord{}
+			hph.SetProcessRecord(record)
+			defer hph.SetProcessRecord(nil)
+		}
+	}
+
 	rootHash, err = sdc.patriciaTrie.Process(ctx, sdc.updates, logPrefix)
 	if err != nil {
 		return nil, err
 	}
 	sdc.justRestored.Store(false)
 
+	if record != nil && record.Took >= dbg.SlowCommitmentDump {
+		fPath, err := sdc.dumpSlowCommitment(record, blockNum, rootHash)
+		if err != nil {
+			sdc.sharedDomains.logger.Warn("[dbg] slow commitment dump failed", "block", blockNum, "err", err)
+		} else {
+			sdc.sharedDomains.logger.Warn("[dbg] slow commitment", "block", blockNum, "keys", updateCount, "took", record.Took, "dump", fPath)
+		}
+	}
+
 	if saveState {
 		if err := sdc.storeCommitmentState(blockNum, rootHash); err != nil {
 			return nil, err
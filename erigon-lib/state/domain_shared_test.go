@@ -19,13 +19,19 @@ package state
 import (
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
 
+	"github.com/erigontech/erigon-lib/commitment"
+	"github.com/erigontech/erigon-lib/common/dbg"
 	"github.com/erigontech/erigon-lib/common/length"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/rawdbv3"
@@ -108,6 +114,128 @@ func TestSharedDomain_CommitmentKeyReplacement(t *testing.T) {
 	require.Equal(t, expectedHash, resultHash)
 }
 
+func TestSharedDomain_CommitmentCanary(t *testing.T) {
+	t.Parallel()
+
+	stepSize := uint64(100)
+	db, agg := testDbAndAggregatorv3(t, stepSize)
+
+	ctx := context.Background()
+	rwTx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	defer rwTx.Rollback()
+
+	ac := agg.BeginFilesRo()
+	defer ac.Close()
+
+	domains, err := NewSharedDomains(WrapTxWithCtx(rwTx, ac), log.New())
+	require.NoError(t, err)
+	defer domains.Close()
+
+	rnd := newRnd(2342)
+	maxTx := stepSize * 8
+	generateSharedDomainsUpdates(t, domains, maxTx, rnd, length.Addr, 10, stepSize)
+	fillRawdbTxNumsIndexForSharedDomains(t, rwTx, maxTx, stepSize)
+	_, err = domains.ComputeCommitment(ctx, true, domains.BlockNum(), "")
+	require.NoError(t, err)
+	require.NoError(t, domains.Flush(ctx, rwTx))
+	domains.Close()
+
+	domains, err = NewSharedDomains(WrapTxWithCtx(rwTx, ac), log.New())
+	require.NoError(t, err)
+	defer domains.Close()
+
+	canary := commitment.NewHashCanary(length.Addr, domains.sdCtx, agg.tmpdir, 1)
+	var checked int
+	for i := 0; i < 100; i++ {
+		n, mismatches, err := canary.Sample()
+		require.NoError(t, err)
+		require.Empty(t, mismatches)
+		checked += n
+	}
+	require.Positive(t, checked)
+}
+
+func TestSharedDomain_SlowCommitmentDump(t *testing.T) {
+	dbg.SlowCommitmentDump = time.Nanosecond
+	defer func() { dbg.SlowCommitmentDump = 0 }()
+
+	stepSize := uint64(100)
+	db, agg := testDbAndAggregatorv3(t, stepSize)
+
+	ctx := context.Background()
+	rwTx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	defer rwTx.Rollback()
+
+	ac := agg.BeginFilesRo()
+	defer ac.Close()
+
+	domains, err := NewSharedDomains(WrapTxWithCtx(rwTx, ac), log.New())
+	require.NoError(t, err)
+	defer domains.Close()
+
+	rnd := newRnd(2342)
+	maxTx := stepSize * 2
+	generateSharedDomainsUpdates(t, domains, maxTx, rnd, length.Addr, 10, stepSize)
+	fillRawdbTxNumsIndexForSharedDomains(t, rwTx, maxTx, stepSize)
+	// every step is committed by generateSharedDomainsUpdates
+	dumps, err := filepath.Glob(filepath.Join(agg.dirs.DataDir, "slow_commitment", "block_*.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, dumps)
+
+	data, err := os.ReadFile(dumps[0])
+	require.NoError(t, err)
+	var dump slowCommitmentDump
+	require.NoError(t, json.Unmarshal(data, &dump))
+	require.Equal(t, fmt.Sprintf("block_%d.json", dump.Block), filepath.Base(dumps[0]))
+	require.Len(t, dump.RootHash, length.Hash)
+	require.NotEmpty(t, dump.PlainKeys)
+	require.NotEmpty(t, dump.KeyRanges)
+	require.Positive(t, dump.Folds)
+	require.Contains(t, dump.FixtureCmd, fmt.Sprintf("--block=%d", dump.Block))
+}
+
+func TestSharedDomain_SlowCommitmentDumpLimits(t *testing.T) {
+	dbg.SlowCommitmentDump = time.Nanosecond
+	slowCommitmentDumpMaxKeys, slowCommitmentDumpMaxFiles = 2, 1
+	defer func() { dbg.SlowCommitmentDump, slowCommitmentDumpMaxKeys, slowCommitmentDumpMaxFiles = 0, 10_000, 64 }()
+
+	stepSize := uint64(100)
+	db, agg := testDbAndAggregatorv3(t, stepSize)
+
+	ctx := context.Background()
+	rwTx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	defer rwTx.Rollback()
+
+	ac := agg.BeginFilesRo()
+	defer ac.Close()
+
+	domains, err := NewSharedDomains(WrapTxWithCtx(rwTx, ac), log.New())
+	require.NoError(t, err)
+	defer domains.Close()
+
+	rnd := newRnd(2342)
+	maxTx := stepSize * 3
+	generateSharedDomainsUpdates(t, domains, maxTx, rnd, length.Addr, 10, stepSize)
+	fillRawdbTxNumsIndexForSharedDomains(t, rwTx, maxTx, stepSize)
+	dumps, err := filepath.Glob(filepath.Join(agg.dirs.DataDir, "slow_commitment", "block_*.json"))
+	require.NoError(t, err)
+	require.Len(t, dumps, 1) // older are removed
+
+	data, err := os.ReadFile(dumps[0])
+	require.NoError(t, err)
+	var dump slowCommitmentDump
+	require.NoError(t, json.Unmarshal(data, &dump))
+	require.True(t, dump.Truncated)
+	require.Len(t, dump.PlainKeys, 2)
+	require.Greater(t, dump.PlainKeysTotal, 2)
+	require.LessOrEqual(t, len(dump.KeyRanges), 2)
+	require.LessOrEqual(t, len(dump.BranchPrefixes), 2)
+	require.Equal(t, len(dump.KeyRanges), strings.Count(dump.FixtureCmd, "0x"))
+}
+
 func TestSharedDomain_Unwind(t *testing.T) {
 	t.Parallel()
 
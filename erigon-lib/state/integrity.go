@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/erigontech/erigon-lib/commitment"
 	"github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/dir"
 	"github.com/erigontech/erigon-lib/kv"
@@ -31,6 +32,44 @@ func (ac *AggregatorRoTx) IntegirtyInvertedIndexKey(domain kv.Domain, k []byte)
 	return ac.d[domain].IntegrityKey(k)
 }
 
+// IntegrityCommitmentStates - calls `fn` with root hash of commitment state stored at the end of each visible
+// commitment file (source is file name) and with latest commitment state in DB (source is "db").
+// Commitment history is not stored - so root hashes are available only at these points.
+func (ac *AggregatorRoTx) IntegrityCommitmentStates(tx kv.Tx, fn func(source string, blockNum, txNum uint64, rootHash []byte) error) error {
+	dt := ac.d[kv.CommitmentDomain]
+	call := func(source string, v []byte) error {
+		cs := new(commitmentState)
+		if err := cs.Decode(v); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		rootHash, err := commitment.HexTrieExtractStateRoot(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+		return fn(source, cs.blockNum, cs.txNum, rootHash)
+	}
+	for i := range dt.files {
+		v, ok, _, err := dt.getLatestFromFile(i, keyCommitmentState)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := call(dt.files[i].src.decompressor.FileName(), v); err != nil {
+			return err
+		}
+	}
+	v, _, ok, err := dt.getLatestFromDb(keyCommitmentState, tx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	return call("db", v)
+}
+
 func (ac *AggregatorRoTx) IntegrityInvertedIndexAllValuesAreInRange(ctx context.Context, name kv.InvertedIdx, failFast bool, fromStep uint64) error {
 	switch name {
 	case kv.AccountsHistoryIdx:
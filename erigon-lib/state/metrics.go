@@ -59,6 +59,12 @@ var (
 	mxFlushTook            = metrics.GetOrCreateSummary("domain_flush_took")
 	mxCommitmentRunning    = metrics.GetOrCreateGauge("domain_running_commitment")
 	mxCommitmentTook       = metrics.GetOrCreateSummary("domain_commitment_took")
+
+	// existence filters (.kvei) of domain files: skip - file not read (key surely not there), pass - file read,
+	// false_positive - file read, but key is not there
+	mxExistenceFilterSkip          = metrics.GetOrCreateCounter(`domain_existence_filter{result="skip"}`)
+	mxExistenceFilterPass          = metrics.GetOrCreateCounter(`domain_existence_filter{result="pass"}`)
+	mxExistenceFilterFalsePositive = metrics.GetOrCreateCounter(`domain_existence_filter{result="false_positive"}`)
 )
 
 var (
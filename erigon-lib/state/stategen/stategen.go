@@ -0,0 +1,310 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package stategen generates synthetic mainnet-like state: skewed distribution of storage between contracts,
+// realistic code sizes, and stream of blocks updating "popular" accounts more often than others.
+// It allows performance work without mainnet datadir.
+package stategen
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/holiman/uint256"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/types/accounts"
+)
+
+// Writer - receiver of generated state. Implemented by state.SharedDomains.
+type Writer interface {
+	SetTxNum(txNum uint64)
+	SetBlockNum(blockNum uint64)
+	DomainPut(domain kv.Domain, k1, k2 []byte, val, prevVal []byte, prevStep uint64) error
+}
+
+type Config struct {
+	Seed int64
+
+	Accounts          int     // amount of accounts in genesis
+	ContractsFraction float64 // fraction of accounts which are contracts
+
+	// storage slots per contract have Zipf distribution: few contracts have most of storage
+	MaxSlotsPerContract uint64
+	StorageSkew         float64 // Zipf `s` parameter, > 1. bigger value - less contracts with big storage
+
+	CodeSizeMedian int // code size has log-normal distribution, limited by EIP-170
+
+	// blocks after genesis. senders and called contracts are chosen with Zipf distribution
+	TxsPerBlock    int
+	SlotsPerCall   int
+	ActivitySkew   float64 // Zipf `s` parameter, > 1
+	NewSlotsFactor float64 // fraction of written slots which didn't exist before
+}
+
+// DefaultConfig - shape of mainnet state (~30% contracts, most of storage in few contracts, median code ~4Kb), small scale
+var DefaultConfig = Config{
+	Seed:                1,
+	Accounts:            100_000,
+	ContractsFraction:   0.3,
+	MaxSlotsPerContract: 100_000,
+	StorageSkew:         1.5,
+	CodeSizeMedian:      4 * 1024,
+	TxsPerBlock:         150,
+	SlotsPerCall:        4,
+	ActivitySkew:        1.2,
+	NewSlotsFactor:      0.2,
+}
+
+const maxCodeSize = 24576 // EIP-170
+
+type Stats struct {
+	Accounts, Contracts, StorageSlots, CodeBytes uint64
+	Txs, AccountUpdates, StorageUpdates          uint64
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("accounts=%d, contracts=%d, slots=%d, code=%s, txs=%d, accountUpdates=%d, storageUpdates=%d",
+		s.Accounts, s.Contracts, s.StorageSlots, libcommon.ByteCount(s.CodeBytes), s.Txs, s.AccountUpdates, s.StorageUpdates)
+}
+
+type Generator struct {
+	cfg Config
+	rnd *rand.Rand
+
+	addrs     [][]byte // accounts first, then contracts
+	contracts int
+	accs      []accounts.Account
+	slots     [][][]byte // storage locations of each contract
+	stats     Stats
+
+	blockNum, txNum uint64 // last generated
+}
+
+func New(cfg Config) (*Generator, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	return &Generator{cfg: cfg, rnd: rand.New(rand.NewSource(cfg.Seed))}, nil
+}
+
+// validate - rand.NewZipf returns nil for `s <= 1` and needs at least 2 items to choose from
+func (cfg Config) validate() error {
+	if cfg.Accounts < 2 {
+		return fmt.Errorf("stategen: Accounts must be >= 2, got %d", cfg.Accounts)
+	}
+	if cfg.ContractsFraction < 0 || cfg.ContractsFraction > 1 {
+		return fmt.Errorf("stategen: ContractsFraction must be in [0, 1], got %v", cfg.ContractsFraction)
+	}
+	if contracts := int(float64(cfg.Accounts) * cfg.ContractsFraction); contracts == 1 {
+		return fmt.Errorf("stategen: amount of contracts must be 0 or >= 2, got 1 (Accounts=%d, ContractsFraction=%v)", cfg.Accounts, cfg.ContractsFraction)
+	}
+	if cfg.MaxSlotsPerContract > 0 && !(cfg.StorageSkew > 1) {
+		return fmt.Errorf("stategen: StorageSkew must be > 1, got %v", cfg.StorageSkew)
+	}
+	if !(cfg.ActivitySkew > 1) {
+		return fmt.Errorf("stategen: ActivitySkew must be > 1, got %v", cfg.ActivitySkew)
+	}
+	if cfg.TxsPerBlock < 0 || cfg.SlotsPerCall < 0 {
+		return fmt.Errorf("stategen: TxsPerBlock and SlotsPerCall must be >= 0, got %d and %d", cfg.TxsPerBlock, cfg.SlotsPerCall)
+	}
+	return nil
+}
+
+// Addresses - all generated accounts (EOA and contracts)
+func (g *Generator) Addresses() [][]byte { return g.addrs }
+
+// StorageKeys - all generated storage keys in format: address+location
+func (g *Generator) StorageKeys() [][]byte {
+	var res [][]byte
+	for i, locs := range g.slots {
+		for _, loc := range locs {
+			res = append(res, append(libcommon.Copy(g.addrs[g.contractIdx(i)]), loc...))
+		}
+	}
+	return res
+}
+
+func (g *Generator) Stats() Stats { return g.stats }
+
+// LastTxNum - txNum of last generated tx
+func (g *Generator) LastTxNum() uint64 { return g.txNum }
+
+func (g *Generator) contractIdx(i int) int { return len(g.addrs) - g.contracts + i }
+
+// Genesis - writes all accounts, code and storage with txNum=0
+func (g *Generator) Genesis(ctx context.Context, w Writer) error {
+	if len(g.addrs) > 0 {
+		return fmt.Errorf("stategen: Genesis already generated")
+	}
+	w.SetBlockNum(0)
+	w.SetTxNum(0)
+
+	g.contracts = int(float64(g.cfg.Accounts) * g.cfg.ContractsFraction)
+	g.addrs = make([][]byte, g.cfg.Accounts)
+	g.accs = make([]accounts.Account, g.cfg.Accounts)
+	g.slots = make([][][]byte, g.contracts)
+	var slotsDistribution *rand.Zipf
+	if g.cfg.MaxSlotsPerContract > 0 {
+		slotsDistribution = rand.NewZipf(g.rnd, g.cfg.StorageSkew, 1, g.cfg.MaxSlotsPerContract)
+	}
+
+	for i := range g.addrs {
+		if i%10_000 == 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+		}
+		g.addrs[i] = make([]byte, length.Addr)
+		g.rnd.Read(g.addrs[i])
+		acc := accounts.NewAccount()
+		acc.Nonce = uint64(g.rnd.Intn(1000))
+		acc.Balance.SetUint64(g.rnd.Uint64())
+		g.stats.Accounts++
+
+		if ci := i - (len(g.addrs) - g.contracts); ci >= 0 {
+			code := g.code()
+			acc.CodeHash = crypto.Keccak256Hash(code)
+			acc.Incarnation = 1
+			if err := w.DomainPut(kv.CodeDomain, g.addrs[i], nil, code, nil, 0); err != nil {
+				return err
+			}
+			g.stats.Contracts++
+			g.stats.CodeBytes += uint64(len(code))
+
+			if slotsDistribution != nil {
+				slotsAmount := slotsDistribution.Uint64()
+				for j := uint64(0); j < slotsAmount; j++ {
+					loc := g.location(j)
+					g.slots[ci] = append(g.slots[ci], loc)
+					if err := w.DomainPut(kv.StorageDomain, g.addrs[i], loc, g.storageValue(), nil, 0); err != nil {
+						return err
+					}
+					g.stats.StorageSlots++
+				}
+			}
+		}
+		g.accs[i] = acc
+		if err := w.DomainPut(kv.AccountsDomain, g.addrs[i], nil, accounts.SerialiseV3(&acc), nil, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Blocks - writes next `blocks` blocks: each tx updates sender and (maybe) storage of called contract.
+// blockEnd is called after last tx of each block - to compute commitment, flush, etc...
+func (g *Generator) Blocks(ctx context.Context, w Writer, blocks uint64, blockEnd func(blockNum, lastTxNum uint64) error) error {
+	if len(g.addrs) == 0 {
+		return fmt.Errorf("stategen: Genesis must be generated before blocks")
+	}
+	activity := rand.NewZipf(g.rnd, g.cfg.ActivitySkew, 1, uint64(len(g.addrs)-1))
+	var contractActivity *rand.Zipf
+	if g.contracts > 0 {
+		contractActivity = rand.NewZipf(g.rnd, g.cfg.ActivitySkew, 1, uint64(g.contracts-1))
+	}
+
+	for to := g.blockNum + blocks; g.blockNum < to; {
+		g.blockNum++
+		blockNum := g.blockNum
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		w.SetBlockNum(blockNum)
+		for i := 0; i < g.cfg.TxsPerBlock; i++ {
+			g.txNum++
+			w.SetTxNum(g.txNum)
+			g.stats.Txs++
+
+			sender := int(activity.Uint64())
+			acc := &g.accs[sender]
+			acc.Nonce++
+			acc.Balance.Sub(&acc.Balance, uint256.NewInt(uint64(g.rnd.Intn(1_000_000))))
+			if err := w.DomainPut(kv.AccountsDomain, g.addrs[sender], nil, accounts.SerialiseV3(acc), nil, 0); err != nil {
+				return err
+			}
+			g.stats.AccountUpdates++
+
+			if contractActivity == nil {
+				continue
+			}
+			ci := int(contractActivity.Uint64())
+			addr := g.addrs[g.contractIdx(ci)]
+			for j := 0; j < g.cfg.SlotsPerCall; j++ {
+				var loc []byte
+				if len(g.slots[ci]) == 0 || g.rnd.Float64() < g.cfg.NewSlotsFactor {
+					loc = g.location(uint64(len(g.slots[ci])))
+					g.slots[ci] = append(g.slots[ci], loc)
+					g.stats.StorageSlots++
+				} else {
+					loc = g.slots[ci][g.rnd.Intn(len(g.slots[ci]))]
+				}
+				if err := w.DomainPut(kv.StorageDomain, addr, loc, g.storageValue(), nil, 0); err != nil {
+					return err
+				}
+				g.stats.StorageUpdates++
+			}
+		}
+		if blockEnd != nil {
+			if err := blockEnd(blockNum, g.txNum); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// code - log-normal size with median `CodeSizeMedian`, limited by EIP-170
+func (g *Generator) code() []byte {
+	size := int(math.Exp(math.Log(float64(g.cfg.CodeSizeMedian)) + g.rnd.NormFloat64()))
+	size = max(1, min(size, maxCodeSize))
+	code := make([]byte, size)
+	g.rnd.Read(code)
+	return code
+}
+
+// location - half of slots are sequential (simple variables), half are hashes (mappings)
+func (g *Generator) location(i uint64) []byte {
+	loc := make([]byte, length.Hash)
+	if g.rnd.Intn(2) == 0 {
+		binary.BigEndian.PutUint64(loc[length.Hash-8:], i)
+		return loc
+	}
+	g.rnd.Read(loc)
+	return loc
+}
+
+// storageValue - most of values are small numbers, some are full 32 bytes (hashes, packed structs)
+func (g *Generator) storageValue() []byte {
+	if g.rnd.Intn(4) == 0 {
+		v := make([]byte, length.Hash)
+		g.rnd.Read(v)
+		return v
+	}
+	var v uint256.Int
+	v.SetUint64(uint64(g.rnd.Intn(1_000_000)) + 1)
+	return v.Bytes()
+}
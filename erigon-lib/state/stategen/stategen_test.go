@@ -0,0 +1,208 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stategen_test
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/kv/temporal/temporaltest"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/state"
+	"github.com/erigontech/erigon-lib/state/stategen"
+)
+
+// go test -run=XXX -bench=. -benchtime=10000x ./state/stategen
+
+var benchConfig = func() stategen.Config {
+	cfg := stategen.DefaultConfig
+	cfg.Accounts = 10_000
+	cfg.MaxSlotsPerContract = 10_000
+	return cfg
+}()
+
+const benchBlocks = 50
+
+type mapWriter map[string][]byte
+
+func (w mapWriter) SetTxNum(uint64)    {}
+func (w mapWriter) SetBlockNum(uint64) {}
+func (w mapWriter) DomainPut(domain kv.Domain, k1, k2 []byte, val, prevVal []byte, prevStep uint64) error {
+	w[domain.String()+string(k1)+string(k2)] = val
+	return nil
+}
+
+func TestGeneratorIsDeterministic(t *testing.T) {
+	ctx := context.Background()
+	cfg := benchConfig
+	cfg.Accounts = 1000
+
+	var prev mapWriter
+	for i := 0; i < 2; i++ {
+		g, err := stategen.New(cfg)
+		require.NoError(t, err)
+		w := mapWriter{}
+		require.NoError(t, g.Genesis(ctx, w))
+		require.NoError(t, g.Blocks(ctx, w, 10, nil))
+
+		stats := g.Stats()
+		require.Equal(t, uint64(1000), stats.Accounts)
+		require.Equal(t, uint64(300), stats.Contracts)
+		require.Equal(t, uint64(10*cfg.TxsPerBlock), stats.Txs)
+		require.Equal(t, uint64(10*cfg.TxsPerBlock), g.LastTxNum())
+		require.Len(t, g.StorageKeys(), int(stats.StorageSlots))
+		if prev != nil {
+			require.Equal(t, prev, w)
+		}
+		prev = w
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for name, change := range map[string]func(cfg *stategen.Config){
+		"1 account":          func(cfg *stategen.Config) { cfg.Accounts = 1 },
+		"1 contract":         func(cfg *stategen.Config) { cfg.Accounts, cfg.ContractsFraction = 10, 0.1 },
+		"contracts fraction": func(cfg *stategen.Config) { cfg.ContractsFraction = 1.5 },
+		"storage skew":       func(cfg *stategen.Config) { cfg.StorageSkew = 1 },
+		"activity skew":      func(cfg *stategen.Config) { cfg.ActivitySkew = 0.5 },
+		"negative txs":       func(cfg *stategen.Config) { cfg.TxsPerBlock = -1 },
+	} {
+		cfg := stategen.DefaultConfig
+		change(&cfg)
+		_, err := stategen.New(cfg)
+		require.Error(t, err, name)
+	}
+
+	// no contracts and no storage: skews of storage and contracts are not used
+	cfg := stategen.DefaultConfig
+	cfg.Accounts, cfg.ContractsFraction, cfg.MaxSlotsPerContract, cfg.StorageSkew = 2, 0, 0, 0
+	g, err := stategen.New(cfg)
+	require.NoError(t, err)
+	w := mapWriter{}
+	require.NoError(t, g.Genesis(context.Background(), w))
+	require.NoError(t, g.Blocks(context.Background(), w, 2, nil))
+}
+
+func newBenchState(b *testing.B) (kv.TemporalRwDB, *stategen.Generator) {
+	b.Helper()
+	ctx, logger := context.Background(), log.New()
+	db, _ := temporaltest.NewTestDB(b, datadir.New(b.TempDir()))
+
+	tx, err := db.BeginTemporalRw(ctx)
+	require.NoError(b, err)
+	defer tx.Rollback()
+	domains, err := state.NewSharedDomains(tx, logger)
+	require.NoError(b, err)
+	defer domains.Close()
+
+	g, err := stategen.New(benchConfig)
+	require.NoError(b, err)
+	require.NoError(b, g.Genesis(ctx, domains))
+	_, err = domains.ComputeCommitment(ctx, true, 0, "")
+	require.NoError(b, err)
+	require.NoError(b, rawdbv3.TxNums.Append(tx, 0, 0))
+	require.NoError(b, g.Blocks(ctx, domains, benchBlocks, func(blockNum, lastTxNum uint64) error {
+		if _, err := domains.ComputeCommitment(ctx, true, blockNum, ""); err != nil {
+			return err
+		}
+		return rawdbv3.TxNums.Append(tx, blockNum, lastTxNum)
+	}))
+	require.NoError(b, domains.Flush(ctx, tx))
+	require.NoError(b, tx.Commit())
+	b.Logf("generated: %s", g.Stats())
+	return db, g
+}
+
+// BenchmarkResolution - latest state reads of random accounts and storage
+func BenchmarkResolution(b *testing.B) {
+	db, g := newBenchState(b)
+	tx, err := db.BeginTemporalRo(context.Background())
+	require.NoError(b, err)
+	defer tx.Rollback()
+	addrs, storageKeys := g.Addresses(), g.StorageKeys()
+	rnd := rand.New(rand.NewSource(1))
+
+	b.Run("accounts", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _, err := tx.GetLatest(kv.AccountsDomain, addrs[rnd.Intn(len(addrs))])
+			require.NoError(b, err)
+		}
+	})
+	b.Run("storage", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _, err := tx.GetLatest(kv.StorageDomain, storageKeys[rnd.Intn(len(storageKeys))])
+			require.NoError(b, err)
+		}
+	})
+}
+
+// BenchmarkHistoricalQueries - reads of random accounts and storage as of random txNum
+func BenchmarkHistoricalQueries(b *testing.B) {
+	db, g := newBenchState(b)
+	tx, err := db.BeginTemporalRo(context.Background())
+	require.NoError(b, err)
+	defer tx.Rollback()
+	addrs, storageKeys := g.Addresses(), g.StorageKeys()
+	rnd := rand.New(rand.NewSource(1))
+
+	b.Run("accounts", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _, err := tx.GetAsOf(kv.AccountsDomain, addrs[rnd.Intn(len(addrs))], uint64(rnd.Int63n(int64(g.LastTxNum()+1))))
+			require.NoError(b, err)
+		}
+	})
+	b.Run("storage", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _, err := tx.GetAsOf(kv.StorageDomain, storageKeys[rnd.Intn(len(storageKeys))], uint64(rnd.Int63n(int64(g.LastTxNum()+1))))
+			require.NoError(b, err)
+		}
+	})
+}
+
+// BenchmarkRootComputation - state root of 1 block on top of generated state
+func BenchmarkRootComputation(b *testing.B) {
+	ctx := context.Background()
+	db, g := newBenchState(b)
+
+	rootOfNextBlock := func() {
+		b.StopTimer()
+		tx, err := db.BeginTemporalRw(ctx)
+		require.NoError(b, err)
+		defer tx.Rollback()
+		domains, err := state.NewSharedDomains(tx, log.New())
+		require.NoError(b, err)
+		defer domains.Close()
+		require.NoError(b, g.Blocks(ctx, domains, 1, nil))
+		b.StartTimer()
+
+		_, err = domains.ComputeCommitment(ctx, true, domains.BlockNum(), "")
+		require.NoError(b, err)
+	}
+	for i := 0; i < b.N; i++ {
+		rootOfNextBlock()
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/rlphacks"
+	"github.com/erigontech/erigon-lib/types/accounts"
+)
+
+// Golden files: canonical outputs (roots, GenStructStep operator streams, witness bytes) of fixture states.
+// Any change of output fails test. If change is intended - regenerate files and review their diff:
+//
+//	go test ./trie -run TestGolden -update
+var updateGolden = flag.Bool("update", false, "regenerate golden files of trie tests")
+
+type goldenFixture struct {
+	name     string
+	keys     [][]byte // sorted
+	values   [][]byte
+	accounts bool // values are balances of accounts
+	retain   [][]byte
+}
+
+func goldenFixtures() []goldenFixture {
+	fixture := func(name string, keys, values [][]byte, retain [][]byte) goldenFixture {
+		sort.Sort(keysAndValues{keys, values})
+		return goldenFixture{name: name, keys: keys, values: values, retain: retain}
+	}
+	var res []goldenFixture
+
+	res = append(res, fixture("single", [][]byte{[]byte("ABCD0001")}, [][]byte{[]byte("val1")}, [][]byte{[]byte("ABCD0001")}))
+	res = append(res, fixture("shared_prefix",
+		[][]byte{[]byte("ABCD0001"), []byte("ABCD0002"), []byte("ABCE0001")},
+		[][]byte{[]byte("val1"), []byte("val2"), []byte("val3")},
+		[][]byte{[]byte("ABCD0002")}))
+
+	short, long := &goldenFixture{}, &goldenFixture{}
+	for i := 0; i < 16; i++ {
+		k := []byte{byte(i * 16), byte(i)}
+		short.keys, short.values = append(short.keys, k), append(short.values, []byte{byte(i)})
+		long.keys, long.values = append(long.keys, k), append(long.values, bytes.Repeat([]byte{byte(i)}, 40))
+	}
+	res = append(res, fixture("embedded_nodes", short.keys, short.values, [][]byte{{0x30, 3}}))
+	res = append(res, fixture("long_values", long.keys, long.values, [][]byte{{0x30, 3}, {0x31}}))
+
+	dense := &goldenFixture{}
+	for i := 0; i < 256; i++ {
+		dense.keys, dense.values = append(dense.keys, []byte{0xaa, byte(i)}), append(dense.values, []byte(fmt.Sprintf("value%d", i)))
+	}
+	res = append(res, fixture("dense", dense.keys, dense.values, [][]byte{{0xaa, 0}, {0xaa, 0x7f}, {0xaa, 0xff}}))
+
+	rnd := rand.New(rand.NewSource(42))
+	random, seen := &goldenFixture{}, map[string]struct{}{}
+	for len(random.keys) < 300 {
+		k := make([]byte, 8)
+		rnd.Read(k)
+		if _, ok := seen[string(k)]; ok {
+			continue
+		}
+		seen[string(k)] = struct{}{}
+		v := make([]byte, 1+rnd.Intn(64))
+		rnd.Read(v)
+		random.keys, random.values = append(random.keys, k), append(random.values, v)
+		if len(random.keys)%50 == 0 {
+			random.retain = append(random.retain, k)
+		}
+	}
+	res = append(res, fixture("random", random.keys, random.values, random.retain))
+
+	acc := fixture("accounts", nil, nil, nil)
+	for i := 0; i < 20; i++ {
+		acc.keys = append(acc.keys, libcommon.Hash{byte(i * 13), byte(i)}.Bytes())
+		acc.values = append(acc.values, []byte{byte(i + 1)})
+	}
+	acc.accounts, acc.retain = true, [][]byte{acc.keys[3], acc.keys[17]}
+	res = append(res, acc)
+	return res
+}
+
+type keysAndValues struct{ keys, values [][]byte }
+
+func (kv keysAndValues) Len() int           { return len(kv.keys) }
+func (kv keysAndValues) Less(i, j int) bool { return bytes.Compare(kv.keys[i], kv.keys[j]) < 0 }
+func (kv keysAndValues) Swap(i, j int) {
+	kv.keys[i], kv.keys[j] = kv.keys[j], kv.keys[i]
+	kv.values[i], kv.values[j] = kv.values[j], kv.values[i]
+}
+
+// opsRecorder - records operator stream produced by GenStructStep
+type opsRecorder struct {
+	*HashBuilder
+	ops []string
+}
+
+func (r *opsRecorder) add(format string, args ...any) {
+	r.ops = append(r.ops, fmt.Sprintf(format, args...))
+}
+
+func (r *opsRecorder) leaf(length int, keyHex []byte, val rlphacks.RlpSerializable) error {
+	r.add("LEAF %d %x", length, keyHex)
+	return r.HashBuilder.leaf(length, keyHex, val)
+}
+func (r *opsRecorder) leafHash(length int, keyHex []byte, val rlphacks.RlpSerializable) error {
+	r.add("LEAF_HASH %d %x", length, keyHex)
+	return r.HashBuilder.leafHash(length, keyHex, val)
+}
+func (r *opsRecorder) accountLeaf(length int, keyHex []byte, balance *uint256.Int, nonce uint64, incarnation uint64, fieldset uint32, codeSize int) error {
+	r.add("ACCOUNT_LEAF %d %x b=%d n=%d fs=%d", length, keyHex, balance, nonce, fieldset)
+	return r.HashBuilder.accountLeaf(length, keyHex, balance, nonce, incarnation, fieldset, codeSize)
+}
+func (r *opsRecorder) accountLeafHash(length int, keyHex []byte, balance *uint256.Int, nonce uint64, incarnation uint64, fieldset uint32) error {
+	r.add("ACCOUNT_LEAF_HASH %d %x b=%d n=%d fs=%d", length, keyHex, balance, nonce, fieldset)
+	return r.HashBuilder.accountLeafHash(length, keyHex, balance, nonce, incarnation, fieldset)
+}
+func (r *opsRecorder) extension(key []byte) error {
+	r.add("EXTENSION %x", key)
+	return r.HashBuilder.extension(key)
+}
+func (r *opsRecorder) extensionHash(key []byte) error {
+	r.add("EXTENSION_HASH %x", key)
+	return r.HashBuilder.extensionHash(key)
+}
+func (r *opsRecorder) branch(set uint16) error {
+	r.add("BRANCH %016b", set)
+	return r.HashBuilder.branch(set)
+}
+func (r *opsRecorder) branchHash(set uint16) error {
+	r.add("BRANCH_HASH %016b", set)
+	return r.HashBuilder.branchHash(set)
+}
+func (r *opsRecorder) hash(hash []byte) error {
+	r.add("HASH %x", hash)
+	return r.HashBuilder.hash(hash)
+}
+
+func keybytesToHexWithTerminator(k []byte) []byte {
+	res := make([]byte, 0, 2*len(k)+1)
+	for _, b := range k {
+		res = append(res, b/16, b%16)
+	}
+	return append(res, 16)
+}
+
+// goldenOutput - canonical text representation of all outputs of fixture
+func goldenOutput(t *testing.T, f goldenFixture) string {
+	t.Helper()
+	var out strings.Builder
+
+	tr := New(libcommon.Hash{})
+	for i, k := range f.keys {
+		if f.accounts {
+			a := accounts.NewAccount()
+			a.Balance.SetBytes(f.values[i])
+			tr.UpdateAccount(k, &a)
+		} else {
+			tr.Update(k, f.values[i])
+		}
+	}
+	root := tr.Hash()
+	fmt.Fprintf(&out, "root %x\n", root)
+
+	rl := NewRetainList(0)
+	for _, k := range f.retain {
+		rl.AddKey(k)
+	}
+	r := &opsRecorder{HashBuilder: NewHashBuilder(false)}
+	var groups, hasTree, hasHash []uint16
+	step := func(curr, succ []byte, i int) {
+		var data GenStructStepData
+		if f.accounts {
+			var balance uint256.Int
+			balance.SetBytes(f.values[i])
+			data = &GenStructStepAccountData{FieldSet: AccountFieldBalanceOnly, Balance: balance}
+		} else {
+			data = &GenStructStepLeafData{Value: rlphacks.RlpSerializableBytes(f.values[i])}
+		}
+		var err error
+		groups, hasTree, hasHash, err = GenStructStep(rl.Retain, curr, succ, r, nil, data, groups, hasTree, hasHash, false)
+		require.NoError(t, err)
+	}
+	for i := 1; i < len(f.keys); i++ {
+		step(keybytesToHexWithTerminator(f.keys[i-1]), keybytesToHexWithTerminator(f.keys[i]), i-1)
+	}
+	step(keybytesToHexWithTerminator(f.keys[len(f.keys)-1]), nil, len(f.keys)-1)
+	require.Equal(t, root, r.rootHash(), "HashBuilder root differs from trie root")
+	out.WriteString("ops\n")
+	for _, op := range r.ops {
+		fmt.Fprintf(&out, "\t%s\n", op)
+	}
+
+	hr := newHasher(false)
+	defer returnHasherToPool(hr)
+	w, err := NewWitnessBuilder(tr.RootNode, false).Build(&MerklePathLimiter{rl, hr.hash})
+	require.NoError(t, err)
+	var witness bytes.Buffer
+	_, err = w.WriteInto(&witness)
+	require.NoError(t, err)
+	out.WriteString("witness\n")
+	for b := witness.Bytes(); len(b) > 0; {
+		n := min(32, len(b))
+		fmt.Fprintf(&out, "\t%x\n", b[:n])
+		b = b[n:]
+	}
+	return out.String()
+}
+
+func TestGolden(t *testing.T) {
+	for _, f := range goldenFixtures() {
+		t.Run(f.name, func(t *testing.T) {
+			got := goldenOutput(t, f)
+			fileName := filepath.Join("testdata", "golden", f.name+".txt")
+			if *updateGolden {
+				require.NoError(t, os.MkdirAll(filepath.Dir(fileName), 0755))
+				require.NoError(t, os.WriteFile(fileName, []byte(got), 0644))
+				return
+			}
+			want, err := os.ReadFile(fileName)
+			require.NoError(t, err, "regenerate golden files: go test ./trie -run TestGolden -update")
+			if got == string(want) {
+				return
+			}
+			gotLines, wantLines := strings.Split(got, "\n"), strings.Split(string(want), "\n")
+			for i := 0; i < len(gotLines) && i < len(wantLines); i++ {
+				if gotLines[i] != wantLines[i] {
+					t.Fatalf("%s: first difference at line %d\n got: %s\nwant: %s\nif change is intended: go test ./trie -run TestGolden -update", fileName, i+1, gotLines[i], wantLines[i])
+				}
+			}
+			t.Fatalf("%s: got %d lines, want %d lines\nif change is intended: go test ./trie -run TestGolden -update", fileName, len(gotLines), len(wantLines))
+		})
+	}
+}
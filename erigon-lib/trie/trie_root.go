@@ -35,6 +35,8 @@ import (
 	"github.com/erigontech/erigon-lib/types/accounts"
 )
 
+var logger = log.Subsystem("trie")
+
 /*
 **Theoretically:** "Merkle trie root calculation" starts from state, build from state keys - trie,
 on each level of trie calculates intermediate hash of underlying data.
@@ -349,7 +351,7 @@ func (l *FlatDBTrieLoader) logProgress(accountKey, ihK []byte) {
 	} else if ihK != nil {
 		k = makeCurrentKeyStr(ihK)
 	}
-	log.Info(fmt.Sprintf("[%s] Calculating Merkle root", l.logPrefix), "current key", k)
+	logger.Info(fmt.Sprintf("[%s] Calculating Merkle root", l.logPrefix), "current key", k)
 }
 
 func (r *RootHashAggregator) RetainNothing(_ []byte) bool {
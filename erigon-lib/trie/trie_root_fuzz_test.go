@@ -0,0 +1,192 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !nofuzz
+
+package trie
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/dbutils"
+)
+
+// go test -trimpath -v -fuzz=Fuzz_NextSubtree -fuzztime=60s ./trie
+
+// Trie walk merges state and AccTrie cursors using ordering primitives below.
+// Fuzzers check them against naive reference implementations.
+
+func Fuzz_KeyIsBefore(f *testing.F) {
+	f.Add([]byte{1}, []byte{1, 0}, false, false)
+	f.Add([]byte{}, []byte{0}, false, true)
+	f.Add([]byte{0xff}, []byte{0xff}, true, false)
+
+	f.Fuzz(func(t *testing.T, k1, k2 []byte, k1IsNil, k2IsNil bool) {
+		if k1IsNil {
+			k1 = nil
+		}
+		if k2IsNil {
+			k2 = nil
+		}
+		// reference: nil is the end of table, after any key
+		var expect bool
+		switch {
+		case k1 == nil:
+			expect = false
+		case k2 == nil:
+			expect = true
+		default:
+			expect = string(k1) < string(k2)
+		}
+		if got := keyIsBefore(k1, k2); got != expect {
+			t.Fatalf("keyIsBefore(%x, %x) = %t, expected %t", k1, k2, got, expect)
+		}
+	})
+}
+
+// seeds: 0xff boundaries, 31/32-byte keys, storage keys with 8-byte incarnation suffix
+func addSubtreeSeeds(f *testing.F) {
+	f.Add([]byte{}, []byte{0})
+	f.Add([]byte{0xff}, []byte{0xff, 0xff})
+	f.Add([]byte{1, 0xff, 0xff}, []byte{2})
+	f.Add(bytes.Repeat([]byte{0xff}, 31), bytes.Repeat([]byte{0xff}, 32))
+	f.Add(append(bytes.Repeat([]byte{0x11}, 31), 0xff), append(bytes.Repeat([]byte{0x11}, 30), 0x12))
+	f.Add(append(bytes.Repeat([]byte{0x22}, 32), 0, 0, 0, 0, 0, 0, 0, 1), append(bytes.Repeat([]byte{0x22}, 32), 0, 0, 0, 0, 0, 0, 0, 2))
+}
+
+func Fuzz_NextSubtree(f *testing.F) {
+	addSubtreeSeeds(f)
+
+	f.Fuzz(func(t *testing.T, prefix, probe []byte) {
+		next, ok := kv.NextSubtree(prefix)
+
+		// reference: no next subtree only if prefix is all 0xff
+		allFF := true
+		for _, b := range prefix {
+			if b != 0xff {
+				allFF = false
+			}
+		}
+		if ok == allFF {
+			t.Fatalf("NextSubtree(%x): ok=%t", prefix, ok)
+		}
+		if !ok && next != nil {
+			t.Fatalf("NextSubtree(%x): expected nil, got %x", prefix, next)
+		}
+
+		// all keys of subtree are before next subtree, all keys after subtree are not
+		if bytes.HasPrefix(probe, prefix) {
+			if !keyIsBefore(probe, next) {
+				t.Fatalf("key %x of subtree %x is not before next subtree %x", probe, prefix, next)
+			}
+		} else if bytes.Compare(probe, prefix) > 0 {
+			if keyIsBefore(probe, next) {
+				t.Fatalf("key %x after subtree %x is before next subtree %x", probe, prefix, next)
+			}
+		}
+	})
+}
+
+func toNibbles(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i, b := range in {
+		out[i] = b & 0x0f
+	}
+	return out
+}
+
+func Fuzz_NextNibblesSubtree(f *testing.F) {
+	addSubtreeSeeds(f)
+
+	f.Fuzz(func(t *testing.T, prefix, probe []byte) {
+		prefix, probe = toNibbles(prefix), toNibbles(probe)
+		buf := make([]byte, len(prefix))
+		ok := dbutils.NextNibblesSubtree(prefix, &buf)
+
+		// reference: []byte++ in base 16, trailing 0x0f nibbles are dropped
+		expect, expectOk := bytes.Clone(prefix), false
+		for i := len(expect) - 1; i >= 0 && !expectOk; i-- {
+			if expect[i] != 0x0f {
+				expect[i]++
+				expect, expectOk = expect[:i+1], true
+			}
+		}
+		if ok != expectOk {
+			t.Fatalf("NextNibblesSubtree(%x): ok=%t, expected %t", prefix, ok, expectOk)
+		}
+		if !ok {
+			return
+		}
+		if !bytes.Equal(buf, expect) {
+			t.Fatalf("NextNibblesSubtree(%x) = %x, expected %x", prefix, buf, expect)
+		}
+		if bytes.HasPrefix(probe, prefix) {
+			if bytes.Compare(probe, buf) >= 0 {
+				t.Fatalf("key %x of subtree %x is not before next subtree %x", probe, prefix, buf)
+			}
+		} else if bytes.Compare(probe, prefix) > 0 {
+			if bytes.Compare(probe, buf) < 0 {
+				t.Fatalf("key %x after subtree %x is before next subtree %x", probe, prefix, buf)
+			}
+		}
+	})
+}
+
+// Fuzz_IsDenseSequence - brute-force reference: AccTrie records `prev` and `next` form sequence
+// if no state key (of fixed length) can exist after subtree of `prev` and before `next`
+func Fuzz_IsDenseSequence(f *testing.F) {
+	f.Add([]byte{1, 2}, []byte{1, 3})
+	f.Add([]byte{1, 2, 0x0f}, []byte{1, 3, 0})
+	f.Add([]byte{1, 0x0f}, []byte{2})
+	f.Add([]byte{}, []byte{0, 0})
+	f.Add([]byte{0x0f, 0x0f}, []byte{})
+
+	const stateKeyLen = 3
+	f.Fuzz(func(t *testing.T, prev, next []byte) {
+		if len(prev) > stateKeyLen || len(next) > stateKeyLen || (len(prev) == 0 && len(next) == 0) {
+			t.Skip()
+		}
+		prev, next = toNibbles(prev), toNibbles(next)
+
+		var from []byte // first key after subtree of prev
+		end := false
+		if len(prev) > 0 {
+			buf := make([]byte, len(prev))
+			end = !dbutils.NextNibblesSubtree(prev, &buf)
+			from = buf
+		}
+		if !end && bytes.Compare(next, from) < 0 {
+			t.Skip() // next must be after subtree of prev
+		}
+
+		expect := true
+		k := make([]byte, stateKeyLen)
+		for i := 0; i < 1<<(4*stateKeyLen) && !end; i++ {
+			for j := range k {
+				k[j] = byte(i>>(4*(stateKeyLen-1-j))) & 0x0f
+			}
+			if bytes.Compare(k, from) >= 0 && bytes.Compare(k, next) < 0 {
+				expect = false
+				break
+			}
+		}
+		if got := isDenseSequence(prev, next); got != expect {
+			t.Fatalf("isDenseSequence(%x, %x) = %t, expected %t", prev, next, got, expect)
+		}
+	})
+}
@@ -54,6 +54,7 @@ import (
 	"github.com/erigontech/erigon-lib/common/debug"
 	"github.com/erigontech/erigon-lib/common/dir"
 	"github.com/erigontech/erigon-lib/common/disk"
+	"github.com/erigontech/erigon-lib/common/featureflags"
 	"github.com/erigontech/erigon-lib/common/mem"
 	"github.com/erigontech/erigon-lib/config3"
 	"github.com/erigontech/erigon-lib/crypto"
@@ -96,6 +97,7 @@ import (
 	"github.com/erigontech/erigon/eth/consensuschain"
 	"github.com/erigontech/erigon/eth/ethconfig"
 	"github.com/erigontech/erigon/eth/ethconsensusconfig"
+	"github.com/erigontech/erigon/eth/integrity"
 	"github.com/erigontech/erigon/eth/protocols/eth"
 	"github.com/erigontech/erigon/eth/stagedsync"
 	"github.com/erigontech/erigon/eth/stagedsync/stages"
@@ -166,6 +168,7 @@ type Ethereum struct {
 	engineBackendRPC   *engineapi.EngineServer
 	miningRPC          txpoolproto.MiningServer
 	stateChangesClient txpool.StateChangesClient
+	maintenanceJobs    *privateapi.MaintenanceJobs
 
 	miningSealingQuit chan struct{}
 	pendingBlocks     chan *types.Block
@@ -243,6 +246,9 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 	if err := RemoveContents(tmpdir); err != nil { // clean it on startup
 		return nil, fmt.Errorf("clean tmp dir: %s, %w", tmpdir, err)
 	}
+	if err := featureflags.Load(dirs.DataDir, featureflags.ProcessErigon); err != nil {
+		logger.Warn("Can't load feature flags, using defaults", "err", err)
+	}
 
 	// Assemble the Ethereum object
 	rawChainDB, err := node.OpenDatabase(ctx, stack.Config(), kv.ChainDB, "", false, logger)
@@ -796,6 +802,10 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 	// initialize engine backend
 
 	blockRetire := freezeblocks.NewBlockRetire(1, dirs, blockReader, blockWriter, backend.chainDB, heimdallStore, bridgeStore, backend.chainConfig, config, backend.notifications.Events, segmentsBuildLimiter, logger)
+	backend.maintenanceJobs = privateapi.NewMaintenanceJobs(ctx, logger)
+	backend.maintenanceJobs.Register(privateapi.MaintenanceJobPrune, privateapi.PruneJob(backend.chainDB))
+	backend.maintenanceJobs.Register(privateapi.MaintenanceJobBuildFiles, privateapi.BuildFilesJob(backend.chainDB, agg, blockReader))
+	backend.maintenanceJobs.Register(privateapi.MaintenanceJobBuildIndices, privateapi.BuildIndicesJob(agg, blockRetire, backend.notifications.Events, backend.chainConfig))
 
 	var miningRPC txpoolproto.MiningServer = privateapi.NewMiningServer(ctx, backend, ethashApi, logger)
 
@@ -912,6 +922,7 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 		backend.syncUnwindOrder = stagedsync.DefaultUnwindOrder
 		backend.syncPruneOrder = stagedsync.DefaultPruneOrder
 	}
+	backend.syncStages, backend.syncUnwindOrder, backend.syncPruneOrder = stagedsync.WithCustomStages(backend.syncStages, backend.syncUnwindOrder, backend.syncPruneOrder)
 
 	backend.stagedSync = stagedsync.New(config.Sync, backend.syncStages, backend.syncUnwindOrder, backend.syncPruneOrder, logger, stages.ModeApplyingBlocks)
 
@@ -941,7 +952,8 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 
 	checkStateRoot := true
 	pipelineStages := stages2.NewPipelineStages(ctx, backend.chainDB, config, p2pConfig, backend.sentriesClient, backend.notifications, backend.downloaderClient, blockReader, blockRetire, backend.silkworm, backend.forkValidator, logger, checkStateRoot)
-	backend.pipelineStagedSync = stagedsync.New(config.Sync, pipelineStages, stagedsync.PipelineUnwindOrder, stagedsync.PipelinePruneOrder, logger, stages.ModeApplyingBlocks)
+	pipelineStages, pipelineUnwindOrder, pipelinePruneOrder := stagedsync.WithCustomStages(pipelineStages, stagedsync.PipelineUnwindOrder, stagedsync.PipelinePruneOrder)
+	backend.pipelineStagedSync = stagedsync.New(config.Sync, pipelineStages, pipelineUnwindOrder, pipelinePruneOrder, logger, stages.ModeApplyingBlocks)
 	backend.eth1ExecutionServer = eth1.NewEthereumExecutionModule(blockReader, backend.chainDB, backend.pipelineStagedSync, backend.forkValidator, chainConfig, assembleBlockPOS, hook, backend.notifications.Accumulator, backend.notifications.StateChangesConsumer, logger, backend.engine, config.Sync, ctx)
 	executionRpc := direct.NewExecutionClientDirect(backend.eth1ExecutionServer)
 
@@ -1078,7 +1090,16 @@ func (s *Ethereum) Init(stack *node.Node, config *ethconfig.Config, chainConfig
 		}
 	}
 
-	s.apiList = jsonrpc.APIList(chainKv, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, blockReader, &httpRpcCfg, s.engine, s.logger, s.polygonBridge, s.heimdallService)
+	if dbg.BlockInvariantChecks > 0 {
+		headCh, unsubscribe := s.notifications.Events.AddHeaderSubscription()
+		checker := integrity.NewBlockInvariantChecker(s.chainDB, blockReader, stateCache, dbg.BlockInvariantChecks, s.logger)
+		go func() {
+			defer unsubscribe()
+			integrity.RunBlockInvariants(ctx, headCh, checker)
+		}()
+	}
+
+	s.apiList = jsonrpc.APIList(chainKv, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, blockReader, &httpRpcCfg, s.engine, s.logger, s.polygonBridge, s.heimdallService, s.maintenanceJobs)
 
 	if config.SilkwormRpcDaemon && httpRpcCfg.Enabled {
 		interface_log_settings := silkworm.RpcInterfaceLogSettings{
@@ -1596,6 +1617,10 @@ func (s *Ethereum) Start() error {
 		s.bgComponentsEg.Go(func() error { return s.shutterPool.Run(s.sentryCtx) })
 	}
 
+	if dbg.CommitmentCanaryInterval > 0 {
+		go libstate.RunCommitmentCanary(s.sentryCtx, s.chainDB, dbg.CommitmentCanaryInterval, s.config.Dirs.Tmp, s.logger)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,303 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/kvcache"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/metrics"
+	"github.com/erigontech/erigon-lib/state"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/services"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+// BlockInvariant - check of what execution of one block left in db. Blocks are checked while their changesets are kept (near the tip).
+type BlockInvariant int
+
+const (
+	InvariantStateRoot    BlockInvariant = iota // root recomputed from previous state and changes of block equals state root of header and stored root
+	InvariantChangeSet                          // values saved in changeset (used by unwind) equal to history as of beginning of block
+	InvariantHistoryIndex                       // every key changed by block has history index entry inside of block
+	InvariantStateCache                         // state cache (if any) serves same values as db for keys changed by block, latest block only
+	blockInvariantsCount
+)
+
+func (i BlockInvariant) String() string {
+	switch i {
+	case InvariantStateRoot:
+		return "StateRoot"
+	case InvariantChangeSet:
+		return "ChangeSet"
+	case InvariantHistoryIndex:
+		return "HistoryIndex"
+	case InvariantStateCache:
+		return "StateCache"
+	default:
+		return fmt.Sprintf("BlockInvariant(%d)", int(i))
+	}
+}
+
+var ErrInvariantViolated = errors.New("block invariant violated")
+
+var (
+	mxBlockInvariantChecks     = metrics.GetOrCreateCounter("sync_block_invariant_checks")
+	mxBlockInvariantViolations = metrics.GetOrCreateCounter("sync_block_invariant_violations")
+)
+
+var historyIdxOfDomain = map[kv.Domain]kv.InvertedIdx{
+	kv.AccountsDomain: kv.AccountsHistoryIdx,
+	kv.StorageDomain:  kv.StorageHistoryIdx,
+	kv.CodeDomain:     kv.CodeHistoryIdx,
+}
+
+// BlockInvariantChecker - checks `perBlock` invariants of each new block, rotating over all invariants:
+// a few cheap checks per block, but all invariants are checked every few blocks.
+type BlockInvariantChecker struct {
+	db          kv.TemporalRoDB
+	blockReader services.FullBlockReader
+	stateCache  kvcache.Cache // optional
+	perBlock    int
+	next        BlockInvariant
+	logger      log.Logger
+}
+
+func NewBlockInvariantChecker(db kv.TemporalRoDB, blockReader services.FullBlockReader, stateCache kvcache.Cache, perBlock int, logger log.Logger) *BlockInvariantChecker {
+	return &BlockInvariantChecker{db: db, blockReader: blockReader, stateCache: stateCache, perBlock: min(max(perBlock, 1), int(blockInvariantsCount)), logger: logger}
+}
+
+// blockInvariantsMemory - how many recently checked blocks are remembered to detect blocks replaced by reorg
+const blockInvariantsMemory = 256
+
+// RunBlockInvariants - on every new header from headCh until ctx is done checks all executed blocks which were not checked yet,
+// including blocks replaced by reorg: headers may come faster than checks, then checker catches up on next header.
+// Violations are logged and counted, sync is never interrupted.
+func RunBlockInvariants(ctx context.Context, headCh <-chan [][]byte, c *BlockInvariantChecker) {
+	checked := map[uint64]common.Hash{} // blockNum -> hash of checked block
+	var lastChecked uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-headCh:
+			if !ok {
+				return
+			}
+		}
+		if err := c.db.View(ctx, func(tx kv.Tx) error {
+			latestBlock, err := stages.GetStageProgress(tx, stages.Execution)
+			if err != nil {
+				return err
+			}
+			fromBlock := latestBlock
+			if lastChecked > 0 {
+				fromBlock = min(lastChecked+1, latestBlock)
+			}
+			// step back over checked blocks which are not canonical anymore
+			for ; fromBlock > 1; fromBlock-- {
+				hash, ok := checked[fromBlock-1]
+				if !ok {
+					break
+				}
+				canonical, ok, err := c.blockReader.CanonicalHash(ctx, tx, fromBlock-1)
+				if err != nil {
+					return err
+				}
+				if ok && canonical == hash {
+					break
+				}
+			}
+			for blockNum := fromBlock; blockNum <= latestBlock; blockNum++ {
+				hash, ok, err := c.blockReader.CanonicalHash(ctx, tx, blockNum)
+				if err != nil {
+					return err
+				}
+				if !ok || checked[blockNum] == hash {
+					continue
+				}
+				if err := c.CheckBlock(ctx, tx.(kv.TemporalTx), blockNum, latestBlock); err != nil {
+					if !errors.Is(err, ErrInvariantViolated) {
+						return err
+					}
+					c.logger.Error("[integrity] block invariants", "block", blockNum, "err", err)
+				}
+				checked[blockNum] = hash
+			}
+			lastChecked = latestBlock
+			for blockNum := range checked {
+				if blockNum+blockInvariantsMemory < latestBlock || blockNum > latestBlock {
+					delete(checked, blockNum)
+				}
+			}
+			return nil
+		}); err != nil && !errors.Is(err, context.Canceled) {
+			c.logger.Error("[integrity] block invariants", "err", err)
+		}
+	}
+}
+
+// CheckBlock - checks next invariants on blockNum, latestBlock is the latest executed block.
+// Returns error wrapping ErrInvariantViolated if any of checked invariants doesn't hold.
+func (c *BlockInvariantChecker) CheckBlock(ctx context.Context, tx kv.TemporalTx, blockNum, latestBlock uint64) error {
+	header, err := c.blockReader.HeaderByNumber(ctx, tx, blockNum)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return fmt.Errorf("header not found: block=%d", blockNum)
+	}
+	var violations []error
+	for i := 0; i < c.perBlock; i++ {
+		inv := c.next
+		c.next = (c.next + 1) % blockInvariantsCount
+		mxBlockInvariantChecks.Inc()
+		if err := c.Check(ctx, tx, inv, header, latestBlock); err != nil {
+			if !errors.Is(err, ErrInvariantViolated) {
+				return fmt.Errorf("%s: %w", inv, err)
+			}
+			mxBlockInvariantViolations.Inc()
+			violations = append(violations, err)
+		}
+	}
+	return errors.Join(violations...)
+}
+
+// Check - checks one invariant on block of header, latestBlock is the latest executed block
+func (c *BlockInvariantChecker) Check(ctx context.Context, tx kv.TemporalTx, inv BlockInvariant, header *types.Header, latestBlock uint64) error {
+	blockNum := header.Number.Uint64()
+	if inv == InvariantStateRoot {
+		return c.checkStateRoot(ctx, header)
+	}
+	if inv == InvariantStateCache && blockNum != latestBlock { // cache holds only latest state
+		return nil
+	}
+
+	// rest of invariants are about keys changed by block. Changesets are not written during initial sync - nothing to check then
+	diffs, ok, err := state.ReadDiffSet(tx, blockNum, header.Hash())
+	if err != nil || !ok {
+		return err
+	}
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, c.blockReader))
+	fromTxNum, err := txNumsReader.Min(tx, blockNum)
+	if err != nil {
+		return err
+	}
+	toTxNum, err := txNumsReader.Max(tx, blockNum)
+	if err != nil {
+		return err
+	}
+
+	var view kvcache.CacheView
+	if inv == InvariantStateCache {
+		if c.stateCache == nil {
+			return nil
+		}
+		if view, err = c.stateCache.View(ctx, tx); err != nil {
+			return err
+		}
+		if !view.StateV3() {
+			return nil
+		}
+	}
+
+	for domain, idx := range historyIdxOfDomain {
+		var prevKey []byte
+		for _, diff := range diffs[domain] {
+			key := []byte(diff.Key[:len(diff.Key)-8])
+			keyStep := []byte(diff.Key[len(diff.Key)-8:])
+			switch inv {
+			case InvariantChangeSet:
+				// value is stored only if key was in same step before block, otherwise unwind restores it from previous step
+				if !bytes.Equal(keyStep, diff.PrevStepBytes) {
+					continue
+				}
+				v, _, err := tx.GetAsOf(domain, key, fromTxNum)
+				if err != nil {
+					return err
+				}
+				if !bytes.Equal(v, diff.Value) {
+					return fmt.Errorf("%w: %s: block=%d, %s key=%x, changeset=%x, history=%x", ErrInvariantViolated, inv, blockNum, domain, key, diff.Value, v)
+				}
+			case InvariantHistoryIndex:
+				if bytes.Equal(key, prevKey) {
+					continue
+				}
+				it, err := tx.IndexRange(idx, key, int(fromTxNum), int(toTxNum+1), order.Asc, 1)
+				if err != nil {
+					return err
+				}
+				found := it.HasNext()
+				it.Close()
+				if !found {
+					return fmt.Errorf("%w: %s: block=%d, %s key=%x has no entries in txNums [%d, %d]", ErrInvariantViolated, inv, blockNum, idx, key, fromTxNum, toTxNum)
+				}
+			case InvariantStateCache:
+				if bytes.Equal(key, prevKey) {
+					continue
+				}
+				var cached []byte
+				if domain == kv.CodeDomain {
+					cached, err = view.GetCode(key)
+				} else {
+					cached, err = view.Get(key)
+				}
+				if err != nil {
+					return err
+				}
+				v, _, err := tx.GetLatest(domain, key)
+				if err != nil {
+					return err
+				}
+				if !bytes.Equal(v, cached) {
+					return fmt.Errorf("%w: %s: block=%d, %s key=%x, cache=%x, db=%x", ErrInvariantViolated, inv, blockNum, domain, key, cached, v)
+				}
+			}
+			prevKey = key
+		}
+	}
+	return nil
+}
+
+// checkStateRoot - recomputes commitment of block from state of previous block and changes of block.
+// Comparing stored root with header is not enough: it's the root which execution computed, maybe from corrupted branches.
+func (c *BlockInvariantChecker) checkStateRoot(ctx context.Context, header *types.Header) error {
+	blockNum := header.Number.Uint64()
+	res, ok, err := recomputeBlockCommitment(ctx, c.db, c.blockReader, blockNum, c.logger)
+	if err != nil || !ok { // no changesets (for example, in initial sync) - nothing to recompute from
+		return err
+	}
+	defer res.Close()
+	switch {
+	case !bytes.Equal(res.Recomputed, header.Root[:]):
+		return fmt.Errorf("%w: %s: block=%d, recomputed=%x, header=%x", ErrInvariantViolated, InvariantStateRoot, blockNum, res.Recomputed, header.Root)
+	case !bytes.Equal(res.Stored, header.Root[:]):
+		return fmt.Errorf("%w: %s: block=%d, stored=%x, header=%x", ErrInvariantViolated, InvariantStateRoot, blockNum, res.Stored, header.Root)
+	case res.DivergedKey != nil:
+		return fmt.Errorf("%w: %s: block=%d, stored and recomputed branches diverge at prefix=%x", ErrInvariantViolated, InvariantStateRoot, blockNum, res.DivergedKey)
+	}
+	return nil
+}
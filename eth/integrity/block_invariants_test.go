@@ -0,0 +1,117 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/state"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/turbo/stages/mock"
+)
+
+// chainWithTransfers - mock with blocks each sending funds to new account, returns number of the latest block
+func chainWithTransfers(t *testing.T, blocks int) (*mock.MockSentry, uint64) {
+	t.Helper()
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		signer  = types.LatestSignerForChainID(nil)
+		gspec   = &types.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  types.GenesisAlloc{address: {Balance: big.NewInt(1e18)}},
+		}
+	)
+	m := mock.MockWithGenesis(t, gspec, key, false)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, blocks, func(i int, b *core.BlockGen) {
+		to := libcommon.BytesToAddress([]byte{0xaa, byte(i + 1)})
+		txn, err := types.SignTx(types.NewTransaction(b.TxNonce(address), to, uint256.NewInt(1000), 21000, new(uint256.Int), nil), *signer, key)
+		require.NoError(t, err)
+		b.AddTx(txn)
+	})
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(chain))
+	return m, uint64(blocks)
+}
+
+// corruptLatestCommitment - replaces latest value of commitment key by result of fn
+func corruptLatestCommitment(t *testing.T, m *mock.MockSentry, key []byte, fn func(v []byte) []byte) {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := m.DB.BeginTemporalRw(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	sd, err := state.NewSharedDomains(tx, log.New())
+	require.NoError(t, err)
+	defer sd.Close()
+	v, step, err := sd.LatestCommitment(key)
+	require.NoError(t, err)
+	require.NotEmpty(t, v)
+	require.NoError(t, sd.DomainPut(kv.CommitmentDomain, key, nil, fn(libcommon.Copy(v)), v, step))
+	require.NoError(t, sd.Flush(ctx, tx))
+	require.NoError(t, tx.Commit())
+}
+
+func checkStateRootOfBlock(t *testing.T, m *mock.MockSentry, blockNum uint64) error {
+	t.Helper()
+	ctx := context.Background()
+	tx, err := m.DB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	latestBlock, err := stages.GetStageProgress(tx, stages.Execution)
+	require.NoError(t, err)
+	header, err := m.BlockReader.HeaderByNumber(ctx, tx, blockNum)
+	require.NoError(t, err)
+	c := NewBlockInvariantChecker(m.DB, m.BlockReader, nil, 1, m.Log)
+	return c.Check(ctx, tx, InvariantStateRoot, header, latestBlock)
+}
+
+func TestBlockInvariantStateRoot(t *testing.T) {
+	m, latestBlock := chainWithTransfers(t, 4)
+	for blockNum := uint64(1); blockNum <= latestBlock; blockNum++ {
+		require.NoError(t, checkStateRootOfBlock(t, m, blockNum), "block %d", blockNum)
+	}
+
+	var root libcommon.Hash
+	require.NoError(t, m.DB.View(context.Background(), func(tx kv.Tx) error {
+		header, err := m.BlockReader.HeaderByNumber(context.Background(), tx, latestBlock)
+		root = header.Root
+		return err
+	}))
+	corruptLatestCommitment(t, m, []byte("state"), func(v []byte) []byte {
+		i := bytes.Index(v, root[:])
+		require.GreaterOrEqual(t, i, 0)
+		v[i] ^= 0xff
+		return v
+	})
+	err := checkStateRootOfBlock(t, m, latestBlock)
+	require.ErrorIs(t, err, ErrInvariantViolated)
+	require.ErrorContains(t, err, "stored=")
+}
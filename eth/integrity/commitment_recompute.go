@@ -0,0 +1,204 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/commitment"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/membatchwithdb"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/state"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/services"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+// blockCommitment - commitment of one block recomputed in memory from state of previous block and changes of the block,
+// next to commitment stored in db for the block. Must be closed.
+type blockCommitment struct {
+	BlockNum    uint64
+	Recomputed  []byte // root hash recomputed from state of previous block and changes of the block
+	Stored      []byte // root hash stored by execution of the block
+	DivergedKey []byte // deepest branch prefix (compacted nibbles) written by the block which differs from recomputed one, nil if all equal
+
+	closers []func()
+}
+
+func (c *blockCommitment) Close() {
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		c.closers[i]()
+	}
+}
+
+// recomputeBlockCommitment - recomputes commitment of blockNum: state is rewound to the beginning of the block by changesets
+// of blocks since blockNum, then keys changed by the block are written with their values after the block and commitment is computed.
+// Nothing is written to db. Returns ok=false if changesets of some blocks are not available (they are kept only near the tip).
+func recomputeBlockCommitment(ctx context.Context, db kv.TemporalRoDB, blockReader services.FullBlockReader, blockNum uint64, logger log.Logger) (res *blockCommitment, ok bool, err error) {
+	if blockNum == 0 {
+		return nil, false, nil
+	}
+	res = &blockCommitment{BlockNum: blockNum}
+	defer func() {
+		if err != nil || !ok {
+			res.Close()
+		}
+	}()
+
+	// stored: state as of end of block
+	stored, _, ok, err := rewindDomains(ctx, db, blockReader, res, blockNum, logger)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if stored.BlockNum() != blockNum { // commitment is not computed at each block (for example, in initial sync)
+		return nil, false, nil
+	}
+	if res.Stored, err = stored.ComputeCommitment(ctx, false, blockNum, ""); err != nil {
+		return nil, false, err
+	}
+
+	// recomputed: state as of beginning of block plus changes of block
+	sd, blockDiffs, ok, err := rewindDomains(ctx, db, blockReader, res, blockNum-1, logger)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	tx := sd.Tx().(kv.TemporalTx)
+	toTxNum, err := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, blockReader)).Max(tx, blockNum)
+	if err != nil {
+		return nil, false, err
+	}
+	sd.SetBlockNum(blockNum)
+	sd.SetTxNum(toTxNum)
+	for _, domain := range []kv.Domain{kv.AccountsDomain, kv.StorageDomain, kv.CodeDomain} {
+		var prevKey []byte
+		for _, diff := range blockDiffs[domain] {
+			key := []byte(diff.Key[:len(diff.Key)-8])
+			if bytes.Equal(key, prevKey) {
+				continue
+			}
+			prevKey = key
+			v, _, err := stored.GetLatest(domain, key)
+			if err != nil {
+				return nil, false, err
+			}
+			if len(v) == 0 {
+				err = sd.DomainDel(domain, key, nil, nil, 0)
+			} else {
+				err = sd.DomainPut(domain, key, nil, v, nil, 0)
+			}
+			if err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	if res.Recomputed, err = sd.ComputeCommitment(ctx, false, blockNum, ""); err != nil {
+		return nil, false, err
+	}
+
+	// branches written by block go from root to leaves, the deepest diverged one is the origin of divergence
+	var prevKey []byte
+	for _, diff := range blockDiffs[kv.CommitmentDomain] {
+		prefix := []byte(diff.Key[:len(diff.Key)-8])
+		if bytes.Equal(prefix, prevKey) || bytes.Equal(prefix, []byte("state")) {
+			continue
+		}
+		prevKey = prefix
+		want, _, err := stored.LatestCommitment(prefix)
+		if err != nil {
+			return nil, false, err
+		}
+		got, _, err := sd.LatestCommitment(prefix)
+		if err != nil {
+			return nil, false, err
+		}
+		if branchesEqual(want, got) {
+			continue
+		}
+		if res.DivergedKey == nil || len(commitment.CompactedKeyToHex(prefix)) > len(commitment.CompactedKeyToHex(res.DivergedKey)) {
+			res.DivergedKey = prefix
+		}
+	}
+	return res, true, nil
+}
+
+// branchesEqual - compares branches ignoring touch map: it depends on keys touched by execution, not only on state
+func branchesEqual(a, b []byte) bool {
+	if len(a) < 2 || len(b) < 2 {
+		return len(a) == len(b)
+	}
+	return bytes.Equal(a[2:], b[2:])
+}
+
+// rewindDomains - domains over in-memory batch of new tx, unwound to the end of blockNum by changesets of blocks after it.
+// Also returns changeset of block blockNum+1. Each batch needs own tx: DomainRoTx caches cursors of first tx it's used with.
+func rewindDomains(ctx context.Context, db kv.TemporalRoDB, blockReader services.FullBlockReader, c *blockCommitment, blockNum uint64, logger log.Logger) (sd *state.SharedDomains, next *[kv.DomainLen][]state.DomainEntryDiff, ok bool, err error) {
+	tx, err := db.BeginTemporalRo(ctx) //nolint:gocritic
+	if err != nil {
+		return nil, nil, false, err
+	}
+	c.closers = append(c.closers, tx.Rollback)
+	latestBlock, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil || latestBlock < blockNum {
+		return nil, nil, false, err
+	}
+	var changeset *[kv.DomainLen][]state.DomainEntryDiff
+	for n := latestBlock; n > blockNum; n-- {
+		hash, ok, err := blockReader.CanonicalHash(ctx, tx, n)
+		if err != nil || !ok {
+			return nil, nil, false, err
+		}
+		diffs, ok, err := state.ReadDiffSet(tx, n, hash)
+		if err != nil || !ok {
+			return nil, nil, false, err
+		}
+		if changeset == nil {
+			changeset = &diffs
+		} else {
+			for i := range diffs {
+				changeset[i] = state.MergeDiffSets(changeset[i], diffs[i])
+			}
+		}
+		next = &diffs
+	}
+
+	batch := membatchwithdb.NewMemoryBatch(tx, "", logger)
+	c.closers = append(c.closers, batch.Rollback)
+	if changeset != nil {
+		txUnwindTo, err := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, blockReader)).Min(tx, blockNum+1)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		sd, err := state.NewSharedDomains(batch, logger)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		err = sd.Unwind(ctx, batch, blockNum, txUnwindTo, changeset)
+		sd.Close()
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("unwind to %d: %w", blockNum, err)
+		}
+	}
+	if sd, err = state.NewSharedDomains(batch, logger); err != nil {
+		return nil, nil, false, err
+	}
+	c.closers = append(c.closers, sd.Close)
+	return sd, next, true, nil
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/temporal"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/state"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/services"
+)
+
+// E3CommitmentRoot - compares root hashes of commitment states (stored at the end of each commitment file and in DB)
+// with state roots of canonical headers of the blocks those states belong to. Then recomputes commitment of each block
+// which still has changesets (blocks near the tip) from state of previous block and changes of the block: corrupted branches
+// are reported by the deepest prefix where recomputed and stored branches diverge.
+// Blocks outside of [fromBlock, toBlock] are skipped (toBlock=0 means no limit). Each recomputation rewinds state from the tip,
+// so it's in NonDefaultChecks: narrow range by fromBlock/toBlock.
+func E3CommitmentRoot(ctx context.Context, chainDB kv.RwDB, blockReader services.FullBlockReader, agg *state.Aggregator, fromBlock, toBlock uint64, failFast bool, logger log.Logger) error {
+	db, err := temporal.New(chainDB, agg)
+	if err != nil {
+		return err
+	}
+	return commitmentRoot(ctx, db, blockReader, fromBlock, toBlock, failFast, logger)
+}
+
+func commitmentRoot(ctx context.Context, db kv.TemporalRoDB, blockReader services.FullBlockReader, fromBlock, toBlock uint64, failFast bool, logger log.Logger) error {
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var checked, mismatches int
+	mismatch := func(err error) error {
+		mismatches++
+		if failFast {
+			return err
+		}
+		logger.Error(err.Error())
+		return nil
+	}
+	err = tx.(state.HasAggTx).AggTx().(*state.AggregatorRoTx).IntegrityCommitmentStates(tx, func(source string, blockNum, txNum uint64, rootHash []byte) error {
+		if blockNum < fromBlock || (toBlock > 0 && blockNum > toBlock) {
+			return nil
+		}
+		header, err := blockReader.HeaderByNumber(ctx, tx, blockNum)
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			return fmt.Errorf("header not found: block=%d, source=%s", blockNum, source)
+		}
+		checked++
+		if bytes.Equal(header.Root[:], rootHash) {
+			logger.Info("[integrity] CommitmentRoot", "source", source, "block", blockNum, "txNum", txNum, "root", fmt.Sprintf("%x", rootHash))
+			return nil
+		}
+		return mismatch(fmt.Errorf("commitment root mismatch: source=%s, block=%d, txNum=%d, commitment=%x, header=%x", source, blockNum, txNum, rootHash, header.Root))
+	})
+	if err != nil {
+		return err
+	}
+
+	latestBlock, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	lowestUnwindable, err := state.ReadLowestUnwindableBlock(tx)
+	if err != nil {
+		return err
+	}
+	if toBlock == 0 || toBlock > latestBlock {
+		toBlock = latestBlock
+	}
+	var recomputed int
+	for blockNum := max(fromBlock, lowestUnwindable, 1); blockNum <= toBlock; blockNum++ {
+		header, err := blockReader.HeaderByNumber(ctx, tx, blockNum)
+		if err != nil {
+			return err
+		}
+		if header == nil {
+			return fmt.Errorf("header not found: block=%d", blockNum)
+		}
+		res, ok, err := recomputeBlockCommitment(ctx, db, blockReader, blockNum, logger)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		recomputed++
+		switch {
+		case !bytes.Equal(res.Recomputed, header.Root[:]):
+			err = fmt.Errorf("recomputed commitment root mismatch: block=%d, recomputed=%x, header=%x, diverged prefix=%x", blockNum, res.Recomputed, header.Root, res.DivergedKey)
+		case res.DivergedKey != nil:
+			err = fmt.Errorf("commitment branch mismatch: block=%d, prefix=%x", blockNum, res.DivergedKey)
+		}
+		res.Close()
+		if err != nil {
+			if err = mismatch(err); err != nil {
+				return err
+			}
+		}
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("commitment root mismatches: %d of %d stored, %d recomputed", mismatches, checked, recomputed)
+	}
+	logger.Info("[integrity] CommitmentRoot done", "checked", checked, "recomputed", recomputed)
+	return nil
+}
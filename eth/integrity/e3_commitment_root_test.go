@@ -0,0 +1,59 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package integrity
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/state"
+)
+
+func TestCommitmentRoot(t *testing.T) {
+	ctx := context.Background()
+	m, latestBlock := chainWithTransfers(t, 4)
+	require.NoError(t, commitmentRoot(ctx, m.DB, m.BlockReader, 0, 0, true, m.Log))
+
+	// corrupt one of branches written by latest block
+	var prefix []byte
+	require.NoError(t, m.DB.View(ctx, func(tx kv.Tx) error {
+		hash, _, err := m.BlockReader.CanonicalHash(ctx, tx, latestBlock)
+		if err != nil {
+			return err
+		}
+		diffs, _, err := state.ReadDiffSet(tx, latestBlock, hash)
+		for _, diff := range diffs[kv.CommitmentDomain] {
+			if k := []byte(diff.Key[:len(diff.Key)-8]); !bytes.Equal(k, []byte("state")) {
+				prefix = k
+			}
+		}
+		return err
+	}))
+	require.NotNil(t, prefix)
+	corruptLatestCommitment(t, m, prefix, func(v []byte) []byte {
+		v[len(v)-1] ^= 0xff
+		return v
+	})
+
+	err := commitmentRoot(ctx, m.DB, m.BlockReader, latestBlock, latestBlock, true, m.Log)
+	require.ErrorContains(t, err, fmt.Sprintf("commitment branch mismatch: block=%d, prefix=%x", latestBlock, prefix))
+}
@@ -24,8 +24,15 @@ const (
 	InvertedIndex      Check = "InvertedIndex"
 	HistoryNoSystemTxs Check = "HistoryNoSystemTxs"
 	NoBorEventGaps     Check = "NoBorEventGaps"
+	CommitmentRoot     Check = "CommitmentRoot"
 )
 
 var AllChecks = []Check{
 	Blocks, BlocksTxnID, InvertedIndex, HistoryNoSystemTxs, NoBorEventGaps,
 }
+
+// NonDefaultChecks - run only if requested by name: too slow for default run.
+// CommitmentRoot rewinds state from the tip twice per block which has changesets: quadratic in amount of such blocks.
+var NonDefaultChecks = []Check{
+	CommitmentRoot,
+}
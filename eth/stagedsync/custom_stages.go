@@ -0,0 +1,98 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stagedsync
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+)
+
+// customStage - stage registered by embedding application (see cmd/erigoncustom).
+// It moves forward right after stage `after`, and unwinds/prunes right before it.
+type customStage struct {
+	after stages.SyncStage
+	stage *Stage
+}
+
+var (
+	customStages     []customStage
+	customStagesLock sync.Mutex
+)
+
+// RegisterCustomStage - adds stage `s` to the pipeline right after stage `after`.
+// If `after` is not part of the pipeline - stage is added to the end of it.
+// Must be called before node start (for example from `main` of embedding application).
+// Stages can keep their own data in custom buckets and must provide Forward and Unwind funcs.
+func RegisterCustomStage(after stages.SyncStage, s *Stage) {
+	if s == nil || s.ID == "" || s.Forward == nil || s.Unwind == nil {
+		panic("custom stage must have ID, Forward and Unwind")
+	}
+	customStagesLock.Lock()
+	defer customStagesLock.Unlock()
+	for _, cs := range customStages {
+		if cs.stage.ID == s.ID {
+			panic(fmt.Sprintf("custom stage already registered: %s", s.ID))
+		}
+	}
+	customStages = append(customStages, customStage{after: after, stage: s})
+}
+
+// WithCustomStages - returns copies of given stages list, unwind and prune orders with registered custom stages inserted
+func WithCustomStages(stagesList []*Stage, unwindOrder UnwindOrder, pruneOrder PruneOrder) ([]*Stage, UnwindOrder, PruneOrder) {
+	customStagesLock.Lock()
+	defer customStagesLock.Unlock()
+	if len(customStages) == 0 {
+		return stagesList, unwindOrder, pruneOrder
+	}
+
+	resStages := append([]*Stage{}, stagesList...)
+	resUnwind := append(UnwindOrder{}, unwindOrder...)
+	resPrune := append(PruneOrder{}, pruneOrder...)
+	for _, cs := range customStages {
+		for _, s := range resStages {
+			if s.ID == cs.stage.ID {
+				panic(fmt.Sprintf("custom stage id clashes with existing stage: %s", s.ID))
+			}
+		}
+
+		pos := len(resStages)
+		for i, s := range resStages {
+			if s.ID == cs.after {
+				pos = i + 1
+				break
+			}
+		}
+		resStages = append(resStages[:pos], append([]*Stage{cs.stage}, resStages[pos:]...)...)
+		resUnwind = insertBefore(resUnwind, cs.after, cs.stage.ID)
+		resPrune = insertBefore(resPrune, cs.after, cs.stage.ID)
+	}
+	return resStages, resUnwind, resPrune
+}
+
+// insertBefore - inserts `id` right before `before` in `order`, or to the beginning of `order` if `before` not found
+func insertBefore[T ~[]stages.SyncStage](order T, before, id stages.SyncStage) T {
+	pos := 0
+	for i, s := range order {
+		if s == before {
+			pos = i
+			break
+		}
+	}
+	return append(order[:pos], append(T{id}, order[pos:]...)...)
+}
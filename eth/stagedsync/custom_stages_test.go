@@ -0,0 +1,70 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stagedsync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/kv/memdb"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/wrap"
+
+	"github.com/erigontech/erigon/eth/ethconfig"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+)
+
+func TestCustomStages(t *testing.T) {
+	defer func() { customStages = nil }()
+
+	flow := make([]stages.SyncStage, 0)
+	newStage := func(id stages.SyncStage) *Stage {
+		return &Stage{
+			ID: id,
+			Forward: func(badBlockUnwind bool, s *StageState, u Unwinder, txc wrap.TxContainer, logger log.Logger) error {
+				flow = append(flow, id)
+				return nil
+			},
+			Unwind: func(u *UnwindState, s *StageState, txc wrap.TxContainer, logger log.Logger) error {
+				return u.Done(txc.Tx)
+			},
+		}
+	}
+	const customID stages.SyncStage = "com.example.CustomIndex"
+	RegisterCustomStage(stages.Bodies, newStage(customID))
+	require.Panics(t, func() { RegisterCustomStage(stages.Bodies, newStage(customID)) })
+
+	s, unwindOrder, pruneOrder := WithCustomStages(
+		[]*Stage{newStage(stages.Headers), newStage(stages.Bodies), newStage(stages.Senders)},
+		UnwindOrder{stages.Senders, stages.Bodies, stages.Headers},
+		PruneOrder{stages.Senders, stages.Bodies, stages.Headers},
+	)
+	assert.Equal(t, UnwindOrder{stages.Senders, customID, stages.Bodies, stages.Headers}, unwindOrder)
+	assert.Equal(t, PruneOrder{stages.Senders, customID, stages.Bodies, stages.Headers}, pruneOrder)
+
+	state := New(ethconfig.Defaults.Sync, s, unwindOrder, pruneOrder, log.New(), stages.ModeApplyingBlocks)
+	db, tx := memdb.NewTestTx(t)
+	_, err := state.Run(db, wrap.TxContainer{Tx: tx}, true /* initialCycle */, false)
+	require.NoError(t, err)
+	assert.Equal(t, []stages.SyncStage{stages.Headers, stages.Bodies, customID, stages.Senders}, flow)
+
+	require.Panics(t, func() {
+		WithCustomStages([]*Stage{newStage(customID)}, nil, nil)
+	})
+}
@@ -206,6 +206,7 @@ func ExecV3(ctx context.Context,
 	initialCycle bool,
 	isMining bool,
 ) error {
+	ctx = kv.WithSubsystem(ctx, kv.SubsystemSync) // stages ctx is not tagged by stage loop, see kv.AddLogicalWork below
 	inMemExec := txc.Doms != nil
 
 	// TODO: e35 doesn't support parallel-exec yet
@@ -440,7 +441,7 @@ func ExecV3(ctx context.Context,
 		if !execStage.CurrentSyncCycle.IsInitialCycle {
 			var clean func()
 
-			readAhead, clean = blocksReadAhead(ctx, &cfg, 4, true)
+			readAhead, clean = blocksReadAhead(ctx, &cfg, 4)
 			defer clean()
 		}
 	}
@@ -501,6 +502,7 @@ Loop:
 			return executor.getHeader(ctx, hash, number)
 		})
 		totalGasUsed += b.GasUsed()
+		kv.AddLogicalWork(ctx, b.GasUsed())
 		blockContext := core.NewEVMBlockContext(header, getHashFn, cfg.engine, cfg.author /* author */, chainConfig)
 		// print type of engine
 		if parallel {
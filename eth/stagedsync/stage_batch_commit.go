@@ -0,0 +1,140 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stagedsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+// BatchCommitter - lets long stage commit its work in batches instead of holding one giant tx:
+// every `every` it saves stage progress and commits in the same tx (so restart continues exactly from committed point),
+// then begins new tx - which also refreshes the read view to committed state.
+//
+// Optional dirty-space budget (SetDirtyLimit) makes commit due earlier - when tx holds too many dirty pages in RAM.
+//
+// If stage runs inside external tx (owned by caller) - nothing is committed, only progress is saved.
+// Tx() must be re-read after each CommitIfDue: previous tx is not valid after commit.
+type BatchCommitter struct {
+	db         kv.RwDB
+	s          *StageState
+	tx         kv.RwTx
+	external   bool
+	every      time.Duration
+	dirtyLimit uint64
+	onCommit   func(tx kv.RwTx, progress uint64) error
+	lastCommit time.Time
+	commits    int
+	logger     log.Logger
+}
+
+// NewBatchCommitter - if tx is nil, begins own tx on db
+func NewBatchCommitter(ctx context.Context, db kv.RwDB, tx kv.RwTx, s *StageState, every time.Duration, logger log.Logger) (*BatchCommitter, error) {
+	c := &BatchCommitter{db: db, s: s, tx: tx, external: tx != nil, every: every, lastCommit: time.Now(), logger: logger}
+	if c.external {
+		return c, nil
+	}
+	var err error
+	if c.tx, err = db.BeginRw(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *BatchCommitter) Tx() kv.RwTx { return c.tx }
+
+// SetDirtyLimit - commit is due also when tx has more than `limit` bytes of dirty pages. 0 - no limit.
+func (c *BatchCommitter) SetDirtyLimit(limit datasize.ByteSize) { c.dirtyLimit = uint64(limit) }
+
+// SetOnCommit - `f` is called in the same tx right before each commit (own or final),
+// caller can persist its own resumability state - it will be committed atomically with stage progress.
+func (c *BatchCommitter) SetOnCommit(f func(tx kv.RwTx, progress uint64) error) { c.onCommit = f }
+
+func (c *BatchCommitter) due() (bool, error) {
+	if time.Since(c.lastCommit) >= c.every {
+		return true, nil
+	}
+	if c.dirtyLimit == 0 {
+		return false, nil
+	}
+	sptx, ok := c.tx.(kv.HasSpaceDirty)
+	if !ok {
+		return false, nil
+	}
+	spaceDirty, _, err := sptx.SpaceDirty()
+	if err != nil {
+		return false, err
+	}
+	return spaceDirty >= c.dirtyLimit, nil
+}
+
+func (c *BatchCommitter) saveProgress(progress uint64) error {
+	if c.onCommit != nil {
+		if err := c.onCommit(c.tx, progress); err != nil {
+			return err
+		}
+	}
+	return c.s.Update(c.tx, progress)
+}
+
+// CommitIfDue - saves `progress` as stage progress and commits own tx if `every` passed since last commit
+// or dirty-space limit reached. Progress must be consistent with everything written by tx so far.
+func (c *BatchCommitter) CommitIfDue(ctx context.Context, progress uint64) (committed bool, err error) {
+	if c.external {
+		return false, nil
+	}
+	due, err := c.due()
+	if err != nil || !due {
+		return false, err
+	}
+	if err = c.saveProgress(progress); err != nil {
+		return false, err
+	}
+	if err = c.tx.Commit(); err != nil {
+		return false, err
+	}
+	c.commits++
+	c.lastCommit = time.Now()
+	c.logger.Debug("["+c.s.LogPrefix()+"] batch committed", "progress", progress, "commits", c.commits)
+	if c.tx, err = c.db.BeginRw(ctx); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// Commit - saves `progress` as stage progress and commits own tx. Must be called once - at the end of stage.
+func (c *BatchCommitter) Commit(progress uint64) error {
+	if err := c.saveProgress(progress); err != nil {
+		return err
+	}
+	if c.external {
+		return nil
+	}
+	return c.tx.Commit()
+}
+
+// Rollback - rollbacks own uncommitted tx, safe to call after Commit
+func (c *BatchCommitter) Rollback() {
+	if !c.external && c.tx != nil {
+		c.tx.Rollback()
+	}
+}
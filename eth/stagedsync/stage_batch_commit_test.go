@@ -0,0 +1,106 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stagedsync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/memdb"
+	"github.com/erigontech/erigon-lib/log/v3"
+
+	"github.com/erigontech/erigon/eth/ethconfig"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+)
+
+func TestBatchCommitter(t *testing.T) {
+	ctx, logger := context.Background(), log.New()
+	db := memdb.NewTestDB(t, kv.ChainDB)
+	sync := New(ethconfig.Defaults.Sync, []*Stage{{ID: stages.TxLookup}}, nil, nil, logger, stages.ModeApplyingBlocks)
+	s := &StageState{state: sync, ID: stages.TxLookup}
+
+	progressAndKey := func(k string) (progress uint64, found bool) {
+		require.NoError(t, db.View(ctx, func(tx kv.Tx) (err error) {
+			if progress, err = stages.GetStageProgress(tx, stages.TxLookup); err != nil {
+				return err
+			}
+			v, err := tx.GetOne(kv.TxLookup, []byte(k))
+			found = v != nil
+			return err
+		}))
+		return progress, found
+	}
+
+	c, err := NewBatchCommitter(ctx, db, nil, s, 0, logger)
+	require.NoError(t, err)
+	defer c.Rollback()
+	require.NoError(t, c.Tx().Put(kv.TxLookup, []byte("a"), []byte{1}))
+	committed, err := c.CommitIfDue(ctx, 5)
+	require.NoError(t, err)
+	require.True(t, committed)
+
+	// not committed batch is lost together with its progress
+	require.NoError(t, c.Tx().Put(kv.TxLookup, []byte("b"), []byte{1}))
+	c.Rollback()
+	progress, found := progressAndKey("a")
+	require.Equal(t, uint64(5), progress)
+	require.True(t, found)
+	_, found = progressAndKey("b")
+	require.False(t, found)
+
+	// external tx is committed by its owner
+	tx, err := db.BeginRw(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	c, err = NewBatchCommitter(ctx, db, tx, s, 0, logger)
+	require.NoError(t, err)
+	committed, err = c.CommitIfDue(ctx, 7)
+	require.NoError(t, err)
+	require.False(t, committed)
+	require.NoError(t, c.Commit(8))
+	progress, err = stages.GetStageProgress(tx, stages.TxLookup)
+	require.NoError(t, err)
+	require.Equal(t, uint64(8), progress)
+	tx.Rollback()
+
+	// dirty-space limit makes commit due before `every`, onCommit is committed together with progress
+	c, err = NewBatchCommitter(ctx, db, nil, s, time.Hour, logger)
+	require.NoError(t, err)
+	defer c.Rollback()
+	committed, err = c.CommitIfDue(ctx, 9)
+	require.NoError(t, err)
+	require.False(t, committed)
+	c.SetDirtyLimit(1)
+	c.SetOnCommit(func(tx kv.RwTx, progress uint64) error {
+		return tx.Put(kv.TxLookup, []byte("c"), hexutility.EncodeTs(progress))
+	})
+	require.NoError(t, c.Tx().Put(kv.TxLookup, []byte("d"), []byte{1}))
+	committed, err = c.CommitIfDue(ctx, 10)
+	require.NoError(t, err)
+	require.True(t, committed)
+	c.Rollback()
+	progress, found = progressAndKey("c")
+	require.Equal(t, uint64(10), progress)
+	require.True(t, found)
+	_, found = progressAndKey("d")
+	require.True(t, found)
+}
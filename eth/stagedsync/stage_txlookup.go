@@ -23,6 +23,8 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/c2h5oh/datasize"
+
 	"github.com/erigontech/erigon-lib/chain"
 	libcommon "github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/hexutility"
@@ -67,15 +69,22 @@ func StageTxLookupCfg(
 	}
 }
 
+// TxLookup is built by batches of blocks, own tx is committed (with progress) between batches at most once per txLookupCommitEvery
+// or earlier - if tx accumulated more than txLookupDirtyLimit of dirty pages
+const (
+	txLookupBatchBlocks = 1_000_000
+	txLookupCommitEvery = 5 * time.Minute
+	txLookupDirtyLimit  = 2 * datasize.GB
+)
+
 func SpawnTxLookup(s *StageState, tx kv.RwTx, toBlock uint64, cfg TxLookupCfg, ctx context.Context, logger log.Logger) (err error) {
-	useExternalTx := tx != nil
-	if !useExternalTx {
-		tx, err = cfg.db.BeginRw(ctx)
-		if err != nil {
-			return err
-		}
-		defer tx.Rollback()
+	committer, err := NewBatchCommitter(ctx, cfg.db, tx, s, txLookupCommitEvery, logger)
+	if err != nil {
+		return err
 	}
+	defer committer.Rollback()
+	committer.SetDirtyLimit(txLookupDirtyLimit)
+	tx = committer.Tx()
 	logPrefix := s.LogPrefix()
 	endBlock, err := s.ExecutionAt(tx)
 	if err != nil {
@@ -110,27 +119,24 @@ func SpawnTxLookup(s *StageState, tx kv.RwTx, toBlock uint64, cfg TxLookupCfg, c
 	if startBlock > 0 {
 		startBlock++
 	}
-	// etl.Transform uses ExtractEndKey as exclusive bound, therefore endBlock + 1
-	if err = txnLookupTransform(logPrefix, tx, startBlock, endBlock+1, ctx, cfg, logger); err != nil {
-		return fmt.Errorf("txnLookupTransform: %w", err)
-	}
-
-	if cfg.borConfig != nil {
-		if err = borTxnLookupTransform(logPrefix, tx, startBlock, endBlock+1, ctx.Done(), cfg, logger); err != nil {
-			return fmt.Errorf("borTxnLookupTransform: %w", err)
+	for from := startBlock; from <= endBlock; from += txLookupBatchBlocks {
+		to := min(from+txLookupBatchBlocks-1, endBlock)
+		tx = committer.Tx()
+		// etl.Transform uses ExtractEndKey as exclusive bound, therefore to + 1
+		if err = txnLookupTransform(logPrefix, tx, from, to+1, ctx, cfg, logger); err != nil {
+			return fmt.Errorf("txnLookupTransform: %w", err)
 		}
-	}
-
-	if err = s.Update(tx, endBlock); err != nil {
-		return err
-	}
 
-	if !useExternalTx {
-		if err = tx.Commit(); err != nil {
+		if cfg.borConfig != nil {
+			if err = borTxnLookupTransform(logPrefix, tx, from, to+1, ctx.Done(), cfg, logger); err != nil {
+				return fmt.Errorf("borTxnLookupTransform: %w", err)
+			}
+		}
+		if _, err = committer.CommitIfDue(ctx, to); err != nil {
 			return err
 		}
 	}
-	return nil
+	return committer.Commit(endBlock)
 }
 
 // txnLookupTransform - [startKey, endKey)
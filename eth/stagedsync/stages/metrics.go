@@ -18,6 +18,7 @@ package stages
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/huandu/xstrings"
 
@@ -27,6 +28,17 @@ import (
 
 var SyncMetrics = map[SyncStage]metrics.Gauge{}
 
+// SyncSpeedMetrics - blocks per second processed by the stage during its last forward run
+var SyncSpeedMetrics = map[SyncStage]metrics.Gauge{}
+
+// SyncBytesSpeedMetrics - bytes per second written to db by the stage during its last forward run (pages made dirty
+// in tx of sync cycle). There is no keys/s: stages don't share notion of key (headers, txs, domain keys, index entries)
+// and kv doesn't count Put calls - bytes/s is the comparable-across-stages measure of write load.
+var SyncBytesSpeedMetrics = map[SyncStage]metrics.Gauge{}
+
+// SyncEtaMetrics - estimated seconds left until the stage reaches the tip known by Headers stage
+var SyncEtaMetrics = map[SyncStage]metrics.Gauge{}
+
 func init() {
 	for _, v := range AllStages {
 		SyncMetrics[v] = metrics.GetOrCreateGauge(
@@ -35,7 +47,56 @@ func init() {
 				xstrings.ToSnakeCase(string(v)),
 			),
 		)
+		SyncSpeedMetrics[v] = metrics.GetOrCreateGauge(
+			fmt.Sprintf(
+				`sync_speed{stage="%s"}`,
+				xstrings.ToSnakeCase(string(v)),
+			),
+		)
+		SyncBytesSpeedMetrics[v] = metrics.GetOrCreateGauge(
+			fmt.Sprintf(
+				`sync_bytes_speed{stage="%s"}`,
+				xstrings.ToSnakeCase(string(v)),
+			),
+		)
+		SyncEtaMetrics[v] = metrics.GetOrCreateGauge(
+			fmt.Sprintf(
+				`sync_eta_seconds{stage="%s"}`,
+				xstrings.ToSnakeCase(string(v)),
+			),
+		)
+	}
+}
+
+// UpdateSpeedMetrics - records throughput of one forward run of the stage (from -> to blocks in `took`)
+// and returns estimated time to reach `tip` with this throughput. Returns 0 if estimation is not possible.
+func UpdateSpeedMetrics(id SyncStage, from, to, tip uint64, took time.Duration) (blocksPerSec float64, eta time.Duration) {
+	if to <= from || took <= 0 {
+		return 0, 0
+	}
+	blocksPerSec = float64(to-from) / took.Seconds()
+	if tip > to {
+		eta = time.Duration(float64(tip-to) / blocksPerSec * float64(time.Second))
+	}
+	if m, ok := SyncSpeedMetrics[id]; ok {
+		m.Set(blocksPerSec)
+	}
+	if m, ok := SyncEtaMetrics[id]; ok {
+		m.Set(eta.Seconds())
+	}
+	return blocksPerSec, eta
+}
+
+// UpdateBytesSpeedMetrics - records bytes written by one forward run of the stage in `took`, returns bytes per second
+func UpdateBytesSpeedMetrics(id SyncStage, written uint64, took time.Duration) (bytesPerSec float64) {
+	if took <= 0 {
+		return 0
+	}
+	bytesPerSec = float64(written) / took.Seconds()
+	if m, ok := SyncBytesSpeedMetrics[id]; ok {
+		m.Set(bytesPerSec)
 	}
+	return bytesPerSec
 }
 
 // UpdateMetrics - need update metrics manually because current "metrics" package doesn't support labels
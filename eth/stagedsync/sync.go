@@ -242,7 +242,7 @@ func New(cfg ethconfig.Sync, stagesList []*Stage, unwindOrder UnwindOrder, prune
 		unwindOrder:   unwindStages,
 		pruningOrder:  pruneStages,
 		logPrefixes:   logPrefixes,
-		logger:        logger,
+		logger:        log.WithSubsystem(logger, "stagedsync"),
 		stagesIdsList: stagesIdsList,
 		mode:          mode,
 	}
@@ -525,6 +525,7 @@ func (s *Sync) runStage(stage *Stage, db kv.RwDB, txc wrap.TxContainer, initialC
 		return err
 	}
 
+	dirtyBefore, hasDirty := spaceDirty(txc.Tx)
 	if err = stage.Forward(badBlockUnwind, stageState, s, txc, s.logger); err != nil {
 		wrappedError := fmt.Errorf("[%s] %w", s.LogPrefix(), err)
 		s.logger.Debug("Error while executing stage", "err", wrappedError)
@@ -533,8 +534,26 @@ func (s *Sync) runStage(stage *Stage, db kv.RwDB, txc wrap.TxContainer, initialC
 
 	took := time.Since(start)
 	logPrefix := s.LogPrefix()
+	var speed, bytesSpeed float64
+	var eta time.Duration
+	// metrics are best-effort: stage is already done and must not fail because of them
+	if progress, tip, err := s.stageProgressAndTip(stage.ID, txc.Tx, db); err != nil {
+		s.logger.Warn(fmt.Sprintf("[%s] can't read stage progress, skip speed metrics", logPrefix), "err", err)
+	} else {
+		speed, eta = stages.UpdateSpeedMetrics(stage.ID, stageState.BlockNumber, progress, tip, took)
+	}
+	if dirtyAfter, ok := spaceDirty(txc.Tx); ok && hasDirty && dirtyAfter > dirtyBefore {
+		bytesSpeed = stages.UpdateBytesSpeedMetrics(stage.ID, dirtyAfter-dirtyBefore, took)
+	}
 	if took > 60*time.Second {
-		s.logger.Info(fmt.Sprintf("[%s] DONE", logPrefix), "in", took, "block", stageState.BlockNumber)
+		logCtx := []interface{}{"in", took, "block", stageState.BlockNumber}
+		if speed > 0 {
+			logCtx = append(logCtx, "blk/s", fmt.Sprintf("%.1f", speed), "eta", eta.Truncate(time.Second))
+		}
+		if bytesSpeed > 0 {
+			logCtx = append(logCtx, "written/s", libcommon.ByteCount(uint64(bytesSpeed)))
+		}
+		s.logger.Info(fmt.Sprintf("[%s] DONE", logPrefix), logCtx...)
 	} else {
 		s.logger.Debug(fmt.Sprintf("[%s] DONE", logPrefix), "in", took)
 	}
@@ -542,6 +561,33 @@ func (s *Sync) runStage(stage *Stage, db kv.RwDB, txc wrap.TxContainer, initialC
 	return nil
 }
 
+// stageProgressAndTip - reads progress of given stage and progress of Headers stage (which is the best known tip)
+func (s *Sync) stageProgressAndTip(id stages.SyncStage, tx kv.Tx, db kv.RoDB) (progress, tip uint64, err error) {
+	read := func(tx kv.Tx) error {
+		if progress, err = stages.GetStageProgress(tx, id); err != nil {
+			return err
+		}
+		if tip, err = stages.GetStageProgress(tx, stages.Headers); err != nil {
+			return err
+		}
+		return nil
+	}
+	if tx != nil {
+		return progress, tip, read(tx)
+	}
+	return progress, tip, db.View(context.Background(), read)
+}
+
+// spaceDirty - bytes of pages modified by tx, ok=false if tx is nil (stage uses own txs) or doesn't report it
+func spaceDirty(tx kv.Tx) (uint64, bool) {
+	sptx, ok := tx.(kv.HasSpaceDirty)
+	if !ok {
+		return 0, false
+	}
+	dirty, _, err := sptx.SpaceDirty()
+	return dirty, err == nil
+}
+
 func (s *Sync) unwindStage(initialCycle bool, stage *Stage, db kv.RwDB, txc wrap.TxContainer) error {
 	start := time.Now()
 	stageState, err := s.StageState(stage.ID, txc.Tx, db, initialCycle, false)
@@ -0,0 +1,236 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package privateapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/erigontech/erigon-lib/chain"
+	"github.com/erigontech/erigon-lib/common/dbg"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/log/v3"
+	libstate "github.com/erigontech/erigon-lib/state"
+	"github.com/erigontech/erigon/eth/ethconfig/estimate"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/services"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+// MaintenanceJobFunc - body of maintenance job, runs in own goroutine next to sync and must stop when ctx is cancelled
+type MaintenanceJobFunc func(ctx context.Context) error
+
+// Kinds of maintenance jobs of node
+const (
+	MaintenanceJobPrune        = "prune"         // prune state history which is already in files
+	MaintenanceJobBuildFiles   = "build_files"   // build files of state which is only in db, then merge files
+	MaintenanceJobBuildIndices = "build_indices" // build missed indices and accessors of state and block files
+)
+
+// Statuses of maintenance job
+const (
+	MaintenanceJobRunning = "running"
+	MaintenanceJobDone    = "done"
+	MaintenanceJobFailed  = "failed"
+)
+
+// MaintenanceJob - state of started job, polled by admin_maintenanceJob
+type MaintenanceJob struct {
+	ID         uint64 `json:"id"`
+	Kind       string `json:"kind"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	StartedAt  uint64 `json:"startedAt"` // unix seconds
+	FinishedAt uint64 `json:"finishedAt,omitempty"`
+}
+
+// maxFinishedMaintenanceJobs - amount of latest finished jobs kept to be polled
+const maxFinishedMaintenanceJobs = 64
+
+// MaintenanceJobs - maintenance jobs (pruning, files build, etc.) started by admin in background of running node,
+// instead of stopping node and running separate command. At most one job of each kind runs at a time.
+// Jobs are in-process: reachable only by rpcdaemon embedded into node, not over ETHBACKEND.
+type MaintenanceJobs struct {
+	ctx    context.Context
+	funcs  map[string]MaintenanceJobFunc
+	jobs   []*MaintenanceJob // oldest first
+	lastID uint64
+	mu     sync.Mutex
+	logger log.Logger
+}
+
+func NewMaintenanceJobs(ctx context.Context, logger log.Logger) *MaintenanceJobs {
+	return &MaintenanceJobs{ctx: ctx, funcs: map[string]MaintenanceJobFunc{}, logger: logger}
+}
+
+// Register - adds kind of job
+func (j *MaintenanceJobs) Register(kind string, f MaintenanceJobFunc) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.funcs[kind] = f
+}
+
+func (j *MaintenanceJobs) Kinds() []string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.kinds()
+}
+
+func (j *MaintenanceJobs) kinds() []string {
+	kinds := make([]string, 0, len(j.funcs))
+	for kind := range j.funcs {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Start - starts job of given kind in background, returns copy of it with id to poll
+func (j *MaintenanceJobs) Start(kind string) (*MaintenanceJob, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, ok := j.funcs[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown maintenance job %q, supported: %s", kind, strings.Join(j.kinds(), ", "))
+	}
+	for _, job := range j.jobs {
+		if job.Kind == kind && job.Status == MaintenanceJobRunning {
+			return nil, fmt.Errorf("maintenance job %q is already running: id=%d", kind, job.ID)
+		}
+	}
+	j.lastID++
+	job := &MaintenanceJob{ID: j.lastID, Kind: kind, Status: MaintenanceJobRunning, StartedAt: uint64(time.Now().Unix())}
+	j.jobs = append(j.jobs, job)
+	go j.run(job, f)
+	res := *job
+	return &res, nil
+}
+
+func (j *MaintenanceJobs) run(job *MaintenanceJob, f MaintenanceJobFunc) {
+	j.logger.Info("[maintenance] job started", "id", job.ID, "kind", job.Kind)
+	started := time.Now()
+	err := func() (err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = fmt.Errorf("%+v, trace: %s", rec, dbg.Stack())
+			}
+		}()
+		return f(j.ctx)
+	}()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job.FinishedAt = uint64(time.Now().Unix())
+	if err != nil {
+		job.Status = MaintenanceJobFailed
+		job.Error = err.Error()
+		j.logger.Warn("[maintenance] job failed", "id", job.ID, "kind", job.Kind, "took", time.Since(started), "err", err)
+	} else {
+		job.Status = MaintenanceJobDone
+		j.logger.Info("[maintenance] job done", "id", job.ID, "kind", job.Kind, "took", time.Since(started))
+	}
+
+	finished := 0
+	for _, job := range j.jobs {
+		if job.Status != MaintenanceJobRunning {
+			finished++
+		}
+	}
+	jobs := j.jobs[:0]
+	for _, job := range j.jobs {
+		if finished > maxFinishedMaintenanceJobs && job.Status != MaintenanceJobRunning {
+			finished--
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	j.jobs = jobs
+}
+
+// Jobs - copies of running and latest finished jobs, or of one job if id is not 0
+func (j *MaintenanceJobs) Jobs(id uint64) []MaintenanceJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var res []MaintenanceJob
+	for _, job := range j.jobs {
+		if id == 0 || job.ID == id {
+			res = append(res, *job)
+		}
+	}
+	return res
+}
+
+// PruneJob - prunes state history which is already in files. Each batch is pruned in own short write tx, not to block sync.
+func PruneJob(db kv.RwDB) MaintenanceJobFunc {
+	return func(ctx context.Context) error {
+		for hasMore := true; hasMore; {
+			if err := db.Update(ctx, func(tx kv.RwTx) (err error) {
+				hasMore, err = tx.(libstate.HasAggTx).AggTx().(*libstate.AggregatorRoTx).PruneSmallBatches(ctx, 10*time.Second, tx)
+				return err
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// BuildFilesJob - builds files of executed state which is only in db, then merges files. Stops when job's ctx is
+// cancelled, between steps and inside of collation/merge. Fails if files are built or merged in background at the moment.
+func BuildFilesJob(db kv.RoDB, agg *libstate.Aggregator, blockReader services.FullBlockReader) MaintenanceJobFunc {
+	return func(ctx context.Context) error {
+		var toTxNum uint64
+		if err := db.View(ctx, func(tx kv.Tx) error {
+			executed, err := stages.GetStageProgress(tx, stages.Execution)
+			if err != nil {
+				return err
+			}
+			toTxNum, err = rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, blockReader)).Max(tx, executed)
+			return err
+		}); err != nil {
+			return err
+		}
+		ok, err := agg.BuildFilesIfIdle(ctx, toTxNum)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("state files are built in background, try later")
+		}
+		return nil
+	}
+}
+
+// BuildIndicesJob - builds missed indices and accessors of state and block files
+func BuildIndicesJob(agg *libstate.Aggregator, blockRetire *freezeblocks.BlockRetire, notifier services.DBEventNotifier, cc *chain.Config) MaintenanceJobFunc {
+	return func(ctx context.Context) error {
+		ok, err := agg.BuildMissedIndicesIfIdle(ctx, estimate.IndexSnapshot.Workers())
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("state files are built in background, try later")
+		}
+		return blockRetire.BuildMissedIndicesIfNeed(ctx, "[maintenance]", notifier, cc)
+	}
+}
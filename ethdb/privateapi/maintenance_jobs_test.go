@@ -0,0 +1,94 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package privateapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+func waitMaintenanceJob(t *testing.T, jobs *MaintenanceJobs, id uint64) *MaintenanceJob {
+	t.Helper()
+	var job *MaintenanceJob
+	require.Eventually(t, func() bool {
+		job = &jobs.Jobs(id)[0]
+		return job.Status != MaintenanceJobRunning
+	}, 10*time.Second, time.Millisecond)
+	return job
+}
+
+func TestMaintenanceJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs := NewMaintenanceJobs(ctx, log.New())
+	release := make(chan struct{})
+	jobs.Register("wait", func(ctx context.Context) error {
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	jobs.Register("fail", func(ctx context.Context) error { return errors.New("no space left") })
+	jobs.Register("panic", func(ctx context.Context) error { panic("boom") })
+	require.Equal(t, []string{"fail", "panic", "wait"}, jobs.Kinds())
+
+	_, err := jobs.Start("compact")
+	require.ErrorContains(t, err, "unknown maintenance job")
+
+	job, err := jobs.Start("wait")
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), job.ID)
+	require.Equal(t, MaintenanceJobRunning, job.Status)
+	_, err = jobs.Start("wait")
+	require.ErrorContains(t, err, "already running: id=1")
+
+	failed, err := jobs.Start("fail")
+	require.NoError(t, err)
+	job = waitMaintenanceJob(t, jobs, failed.ID)
+	require.Equal(t, MaintenanceJobFailed, job.Status)
+	require.Equal(t, "no space left", job.Error)
+	require.NotZero(t, job.FinishedAt)
+
+	panicked, err := jobs.Start("panic")
+	require.NoError(t, err)
+	job = waitMaintenanceJob(t, jobs, panicked.ID)
+	require.Equal(t, MaintenanceJobFailed, job.Status)
+	require.Contains(t, job.Error, "boom")
+
+	close(release)
+	job = waitMaintenanceJob(t, jobs, 1)
+	require.Equal(t, MaintenanceJobDone, job.Status)
+	require.Empty(t, job.Error)
+	require.Len(t, jobs.Jobs(0), 3)
+
+	// only latest finished jobs are kept
+	for i := 0; i < maxFinishedMaintenanceJobs; i++ {
+		job, err := jobs.Start("fail")
+		require.NoError(t, err)
+		waitMaintenanceJob(t, jobs, job.ID)
+	}
+	require.Len(t, jobs.Jobs(0), maxFinishedMaintenanceJobs)
+	require.Empty(t, jobs.Jobs(1))
+}
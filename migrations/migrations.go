@@ -101,6 +101,11 @@ func AppliedMigrations(tx kv.Tx, withPayload bool) (map[string][]byte, error) {
 	return applied, err
 }
 
+// MigrationProgress - progress saved by not finished migration (it will continue from it), nil if not started
+func MigrationProgress(tx kv.Tx, name string) ([]byte, error) {
+	return tx.GetOne(kv.Migrations, []byte("_progress_"+name))
+}
+
 func (m *Migrator) HasPendingMigrations(db kv.RwDB) (bool, error) {
 	var has bool
 	if err := db.View(context.Background(), func(tx kv.Tx) error {
@@ -211,7 +216,7 @@ func (m *Migrator) Apply(db kv.RwDB, dataDir, chaindata string, logger log.Logge
 		logger.Info("Apply migration", "name", v.Name)
 		var progress []byte
 		if err := db.View(context.Background(), func(tx kv.Tx) (err error) {
-			progress, err = tx.GetOne(kv.Migrations, []byte("_progress_"+v.Name))
+			progress, err = MigrationProgress(tx, v.Name)
 			return err
 		}); err != nil {
 			return fmt.Errorf("migrator.Apply: %w", err)
@@ -235,7 +235,6 @@ func (n *Node) openDataDir(ctx context.Context) error {
 		return nil // ephemeral
 	}
 
-	instdir := n.config.Dirs.DataDir
 	for retry := 0; ; retry++ {
 		l, locked, err := datadir.TryFlock(n.config.Dirs)
 		if err != nil {
@@ -243,9 +242,9 @@ func (n *Node) openDataDir(ctx context.Context) error {
 		}
 		if !locked {
 			if retry >= 10 {
-				return fmt.Errorf("%w: %s", datadir.ErrDataDirLocked, instdir)
+				return datadir.LockedError(n.config.Dirs)
 			}
-			log.Error(datadir.ErrDataDirLocked.Error() + ", retry in 2 sec")
+			log.Error(datadir.LockedError(n.config.Dirs).Error() + ", retry in 2 sec")
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
@@ -262,7 +261,7 @@ func (n *Node) openDataDir(ctx context.Context) error {
 func (n *Node) closeDataDir() {
 	// Release instance directory lock.
 	if n.dirLock != nil {
-		if err := n.dirLock.Unlock(); err != nil {
+		if err := datadir.Unflock(n.config.Dirs, n.dirLock); err != nil {
 			n.logger.Error("Can't release datadir lock", "err", err)
 		}
 		n.dirLock = nil
@@ -22,8 +22,12 @@ package node
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -114,9 +118,19 @@ func TestNodeUsedDataDir(t *testing.T) {
 	}
 
 	// Create a second node based on the same data directory and ensure failure
-	if _, err := New(context.Background(), &nodecfg.Config{Dirs: datadir.New(dir)}, log.New()); !errors.Is(err, datadir.ErrDataDirLocked) {
+	_, err := New(context.Background(), &nodecfg.Config{Dirs: datadir.New(dir)}, log.New())
+	if !errors.Is(err, datadir.ErrDataDirLocked) {
 		t.Fatalf("duplicate datadir failure mismatch: have %v, want %v", err, datadir.ErrDataDirLocked)
 	}
+	if want := fmt.Sprintf("pid: %d", os.Getpid()); !strings.Contains(err.Error(), want) {
+		t.Fatalf("duplicate datadir failure must name owner: have %v, want %s", err, want)
+	}
+
+	// pid of owner is removed with lock
+	original.Close()
+	if _, err := os.Stat(filepath.Join(dir, "LOCK.pid")); !os.IsNotExist(err) {
+		t.Fatalf("pid file must be removed on close: %v", err)
+	}
 }
 
 // Tests whether a Lifecycle can be registered.
@@ -184,6 +184,8 @@ func DialContext(ctx context.Context, rawurl string, logger log.Logger) (*Client
 		return DialWebsocket(ctx, rawurl, "", logger)
 	case "stdio":
 		return DialStdIO(ctx, logger)
+	case "":
+		return DialIPC(ctx, rawurl, logger)
 	default:
 		return nil, fmt.Errorf("no known transport for URL scheme %q", u.Scheme)
 	}
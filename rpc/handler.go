@@ -31,6 +31,9 @@ import (
 
 	jsoniter "github.com/json-iterator/go"
 
+	"github.com/erigontech/erigon-lib/common/dbg"
+	"github.com/erigontech/erigon-lib/common/tracing"
+	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/log/v3"
 
 	"github.com/erigontech/erigon/rpc/rpccfg"
@@ -481,7 +484,17 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter
 		return msg.errorResponse(&InvalidParamsError{err.Error()})
 	}
 	start := time.Now()
-	answer := h.runMethod(cp.ctx, msg, callb, args, stream)
+	ctx := cp.ctx
+	if dbg.KVIOAmplification { // attribute db bytes of call to rpc, see kv.WithSubsystem
+		ctx = kv.WithSubsystem(ctx, kv.SubsystemRPC)
+		kv.AddLogicalWork(ctx, 1)
+	}
+	if tracing.Enabled() { // db operations of call are children of its span: see kv/temporal
+		var span tracing.Span
+		ctx, span = tracing.Start(ctx, "rpc."+msg.Method)
+		defer span.End()
+	}
+	answer := h.runMethod(ctx, msg, callb, args, stream)
 
 	// Collect the statistics for RPC calls if metrics is enabled.
 	// We only care about pure rpc call. Filter out subscription.
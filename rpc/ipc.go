@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	"context"
 	"net"
 
 	"github.com/erigontech/erigon-lib/log/v3"
@@ -40,3 +41,24 @@ func (s *Server) ServeListener(l net.Listener) error {
 		go s.ServeCodec(NewCodec(conn), 0)
 	}
 }
+
+// IPCListen creates a listener on the given unix socket path (named pipes are not supported).
+// Stale socket file left by previous run is removed and the socket is accessible by owner only.
+func IPCListen(endpoint string) (net.Listener, error) {
+	return ipcListen(endpoint)
+}
+
+// DialIPC create a new IPC client that connects to the given endpoint. On Unix it assumes
+// the endpoint is the full path to a unix socket.
+//
+// The context is used for the initial connection establishment. It does not
+// affect subsequent interactions with the client.
+func DialIPC(ctx context.Context, endpoint string, logger log.Logger) (*Client, error) {
+	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		conn, err := newIPCConnection(ctx, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return NewCodec(conn), err
+	}, logger)
+}
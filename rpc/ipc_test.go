@@ -0,0 +1,69 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build unix
+
+package rpc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+func TestIPCClientServer(t *testing.T) {
+	logger := log.New()
+	server := newTestServer(logger)
+	defer server.Stop()
+
+	endpoint := filepath.Join(t.TempDir(), "erigon.ipc")
+	// stale socket file of previous run must not prevent start
+	if err := os.WriteFile(endpoint, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	l, err := IPCListen(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go server.ServeListener(l)
+
+	info, err := os.Stat(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("wrong socket permissions: %v", info.Mode().Perm())
+	}
+
+	client, err := DialContext(context.Background(), endpoint, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var resp echoResult
+	if err := client.Call(&resp, "test_echo", "hello", 10, &echoArgs{"world"}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(resp, echoResult{"hello", 10, &echoArgs{"world"}}) {
+		t.Errorf("incorrect result %#v", resp)
+	}
+}
@@ -120,7 +120,7 @@ var snapshotCommand = cli.Command{
 				if err != nil {
 					return err
 				}
-				defer l.Unlock()
+				defer datadir.Unflock(dirs, l)
 
 				return doIndicesCommand(c, dirs)
 			},
@@ -138,7 +138,7 @@ var snapshotCommand = cli.Command{
 				if err != nil {
 					return err
 				}
-				defer l.Unlock()
+				defer datadir.Unflock(dirs, l)
 
 				return doRetireCommand(c, dirs)
 			},
@@ -258,9 +258,11 @@ var snapshotCommand = cli.Command{
 			Description: "run slow validation of files. use --check to run single",
 			Flags: joinFlags([]cli.Flag{
 				&utils.DataDirFlag,
-				&cli.StringFlag{Name: "check", Usage: fmt.Sprintf("one of: %s", integrity.AllChecks)},
+				&cli.StringFlag{Name: "check", Usage: fmt.Sprintf("one of: %s, or not run by default: %s", integrity.AllChecks, integrity.NonDefaultChecks)},
 				&cli.BoolFlag{Name: "failFast", Value: true, Usage: "to stop after 1st problem or print WARN log and continue check"},
 				&cli.Uint64Flag{Name: "fromStep", Value: 0, Usage: "skip files before given step"},
+				&SnapshotFromFlag,
+				&SnapshotToFlag,
 			}),
 		},
 		{
@@ -543,7 +545,11 @@ func doIntegrity(cliCtx *cli.Context) error {
 	defer clean()
 
 	blockReader, _ := blockRetire.IO()
-	for _, chk := range integrity.AllChecks {
+	checks := integrity.AllChecks
+	if slices.Contains(integrity.NonDefaultChecks, requestedCheck) {
+		checks = integrity.NonDefaultChecks
+	}
+	for _, chk := range checks {
 		if requestedCheck != "" && requestedCheck != chk {
 			continue
 		}
@@ -568,6 +574,10 @@ func doIntegrity(cliCtx *cli.Context) error {
 			if err := integrity.NoGapsInBorEvents(ctx, chainDB, blockReader, 0, 0, failFast); err != nil {
 				return err
 			}
+		case integrity.CommitmentRoot:
+			if err := integrity.E3CommitmentRoot(ctx, chainDB, blockReader, agg, from, cliCtx.Uint64(SnapshotToFlag.Name), failFast, logger); err != nil {
+				return err
+			}
 
 		default:
 			return fmt.Errorf("unknown check: %s", chk)
@@ -29,6 +29,7 @@ import (
 
 	"github.com/erigontech/erigon-lib/common/disk"
 	"github.com/erigontech/erigon-lib/common/mem"
+	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/metrics"
 
 	"github.com/pelletier/go-toml"
@@ -172,6 +173,8 @@ func SetupCobra(cmd *cobra.Command, filePrefix string) log.Logger {
 	if metricsEnabled && metricsAddr != "" {
 		metricsAddress = fmt.Sprintf("%s:%d", metricsAddr, metricsPort)
 		metricsMux = metrics.Setup(metricsAddress, logger)
+		metricsMux.HandleFunc("/debug/metrics/db_op_latency", kv.OpLatencyHandler)
+		metricsMux.HandleFunc("/debug/metrics/db_io_amplification", kv.IOAmplificationHandler)
 	}
 
 	if pprof {
@@ -220,6 +223,8 @@ func Setup(ctx *cli.Context, rootLogger bool) (log.Logger, *http.ServeMux, *http
 		metricsPort := ctx.Int(metricsPortFlag.Name)
 		metricsAddress = fmt.Sprintf("%s:%d", metricsAddr, metricsPort)
 		metricsMux = metrics.Setup(metricsAddress, logger)
+		metricsMux.HandleFunc("/debug/metrics/db_op_latency", kv.OpLatencyHandler)
+		metricsMux.HandleFunc("/debug/metrics/db_io_amplification", kv.IOAmplificationHandler)
 	}
 
 	if pprofEnabled {
@@ -515,6 +515,12 @@ func (e *EthereumExecutionModule) updateForkChoice(ctx context.Context, original
 			sendForkchoiceErrorWithoutWaiting(e.logger, outcomeCh, err, stateFlushingInParallel)
 			return
 		}
+		if s, ok := e.db.(kv.HasForceSync); ok { // durable Update doesn't fsync if db commits in SafeNoSync mode, see dbg.MdbxSyncPeriod
+			if err := s.ForceSync(); err != nil {
+				sendForkchoiceErrorWithoutWaiting(e.logger, outcomeCh, err, stateFlushingInParallel)
+				return
+			}
+		}
 
 		var m runtime.MemStats
 		dbg.ReadMemStats(&m)
@@ -21,7 +21,9 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/erigontech/erigon-lib/common/featureflags"
 	remote "github.com/erigontech/erigon-lib/gointerfaces/remoteproto"
+	"github.com/erigontech/erigon/ethdb/privateapi"
 	"github.com/erigontech/erigon/p2p"
 
 	"github.com/erigontech/erigon/turbo/rpchelper"
@@ -38,17 +40,41 @@ type AdminAPI interface {
 
 	// AddPeer requests connecting to a remote node.
 	AddPeer(ctx context.Context, url string) (bool, error)
+
+	// Profiling of rpcdaemon process (see ./admin_profiling.go). Files are written to <datadir>/pprof, returned values are paths of written files.
+	StartCPUProfile(ctx context.Context, name string) (string, error)
+	StopCPUProfile(ctx context.Context) error
+	WriteHeapProfile(ctx context.Context, name string) (string, error)
+	BlockProfile(ctx context.Context, name string, nsec uint) (string, error)
+	GoroutineStacks(ctx context.Context, name string) (string, error)
+
+	// Log levels of subsystems of rpcdaemon process (see ./admin_logging.go), in form: trie=debug,stagedsync=trace
+	SetLogLevels(ctx context.Context, levels string) (string, error)
+	LogLevels(ctx context.Context) (string, error)
+
+	// Runtime feature flags of rpcdaemon process (see ./admin_feature_flags.go), persisted in <datadir>/feature_flags_<process>.json
+	FeatureFlags(ctx context.Context) ([]featureflags.Info, error)
+	SetFeatureFlag(ctx context.Context, name string, enabled bool) (featureflags.Info, error)
+
+	// Maintenance jobs (pruning, files build, etc.) run by node in background (see ./admin_maintenance.go). Only in rpcdaemon embedded into node.
+	StartMaintenanceJob(ctx context.Context, kind string) (*privateapi.MaintenanceJob, error)
+	MaintenanceJob(ctx context.Context, id uint64) (*privateapi.MaintenanceJob, error)
+	MaintenanceJobs(ctx context.Context) (*MaintenanceJobs, error)
 }
 
 // AdminAPIImpl data structure to store things needed for admin_* commands.
 type AdminAPIImpl struct {
-	ethBackend rpchelper.ApiBackend
+	ethBackend      rpchelper.ApiBackend
+	dataDir         string
+	maintenanceJobs *privateapi.MaintenanceJobs // nil in standalone rpcdaemon
 }
 
 // NewAdminAPI returns AdminAPIImpl instance.
-func NewAdminAPI(eth rpchelper.ApiBackend) *AdminAPIImpl {
+func NewAdminAPI(eth rpchelper.ApiBackend, dataDir string, maintenanceJobs *privateapi.MaintenanceJobs) *AdminAPIImpl {
 	return &AdminAPIImpl{
-		ethBackend: eth,
+		ethBackend:      eth,
+		dataDir:         dataDir,
+		maintenanceJobs: maintenanceJobs,
 	}
 }
 
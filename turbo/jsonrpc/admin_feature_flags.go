@@ -0,0 +1,33 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+
+	"github.com/erigontech/erigon-lib/common/featureflags"
+)
+
+// FeatureFlags implements admin_featureFlags. Returns all flags known by this process.
+func (api *AdminAPIImpl) FeatureFlags(ctx context.Context) ([]featureflags.Info, error) {
+	return featureflags.All(), nil
+}
+
+// SetFeatureFlag implements admin_setFeatureFlag. New value is applied immediately and survives restart.
+func (api *AdminAPIImpl) SetFeatureFlag(ctx context.Context, name string, enabled bool) (featureflags.Info, error) {
+	return featureflags.Set(name, enabled)
+}
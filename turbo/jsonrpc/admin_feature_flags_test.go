@@ -0,0 +1,48 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/featureflags"
+)
+
+func TestAdminFeatureFlags(t *testing.T) {
+	api := NewAdminAPI(nil, "", nil)
+	ctx := context.Background()
+
+	flags, err := api.FeatureFlags(ctx)
+	require.NoError(t, err)
+	var names []string
+	for _, f := range flags {
+		names = append(names, f.Name)
+	}
+	require.Contains(t, names, "state_cache")
+
+	defer api.SetFeatureFlag(ctx, "state_cache", true) //nolint:errcheck
+	info, err := api.SetFeatureFlag(ctx, "state_cache", false)
+	require.NoError(t, err)
+	require.False(t, info.Enabled)
+	require.True(t, info.Default)
+
+	_, err = api.SetFeatureFlag(ctx, "no_such_flag", true)
+	require.ErrorIs(t, err, featureflags.ErrUnknownFlag)
+}
@@ -0,0 +1,37 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+// SetLogLevels implements admin_setLogLevels. Levels are comma separated <subsystem>=<level> pairs, level "default"
+// makes subsystem use console and disk verbosity again. Returns levels of all subsystems after the change.
+func (api *AdminAPIImpl) SetLogLevels(ctx context.Context, levels string) (string, error) {
+	if err := log.SetSubsystemLvls(levels); err != nil {
+		return "", err
+	}
+	return log.SubsystemLvlsString(), nil
+}
+
+// LogLevels implements admin_logLevels. Subsystems without own level are not listed.
+func (api *AdminAPIImpl) LogLevels(ctx context.Context) (string, error) {
+	return log.SubsystemLvlsString(), nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminLogLevels(t *testing.T) {
+	api := NewAdminAPI(nil, "", nil)
+	ctx := context.Background()
+	defer api.SetLogLevels(ctx, "trie.commitment=default,stagedsync=default") //nolint:errcheck
+
+	levels, err := api.SetLogLevels(ctx, "trie.commitment=debug,stagedsync=trace")
+	require.NoError(t, err)
+	require.Equal(t, "stagedsync=trace,trie.commitment=dbug", levels)
+
+	levels, err = api.SetLogLevels(ctx, "stagedsync=default")
+	require.NoError(t, err)
+	require.Equal(t, "trie.commitment=dbug", levels)
+
+	_, err = api.SetLogLevels(ctx, "stagedsync=loud")
+	require.Error(t, err)
+	levels, err = api.LogLevels(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "trie.commitment=dbug", levels)
+}
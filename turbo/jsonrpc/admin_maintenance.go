@@ -0,0 +1,66 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/erigontech/erigon/ethdb/privateapi"
+)
+
+// errNoMaintenanceJobs - standalone rpcdaemon has no access to jobs, they run in-process of node
+var errNoMaintenanceJobs = errors.New("maintenance jobs are available only in rpcdaemon embedded into node")
+
+type MaintenanceJobs struct {
+	Jobs  []privateapi.MaintenanceJob `json:"jobs"`
+	Kinds []string                    `json:"kinds"`
+}
+
+// StartMaintenanceJob implements admin_startMaintenanceJob. Starts job of given kind in background of node,
+// returned id can be polled by admin_maintenanceJob.
+func (api *AdminAPIImpl) StartMaintenanceJob(_ context.Context, kind string) (*privateapi.MaintenanceJob, error) {
+	if api.maintenanceJobs == nil {
+		return nil, errNoMaintenanceJobs
+	}
+	return api.maintenanceJobs.Start(kind)
+}
+
+// MaintenanceJob implements admin_maintenanceJob.
+func (api *AdminAPIImpl) MaintenanceJob(_ context.Context, id uint64) (*privateapi.MaintenanceJob, error) {
+	if api.maintenanceJobs == nil {
+		return nil, errNoMaintenanceJobs
+	}
+	jobs := api.maintenanceJobs.Jobs(id)
+	if id == 0 || len(jobs) == 0 {
+		return nil, fmt.Errorf("maintenance job %d not found", id)
+	}
+	return &jobs[0], nil
+}
+
+// MaintenanceJobs implements admin_maintenanceJobs. Returns running and latest finished jobs, and kinds of jobs supported by node.
+func (api *AdminAPIImpl) MaintenanceJobs(_ context.Context) (*MaintenanceJobs, error) {
+	if api.maintenanceJobs == nil {
+		return nil, errNoMaintenanceJobs
+	}
+	jobs := api.maintenanceJobs.Jobs(0)
+	if jobs == nil {
+		jobs = []privateapi.MaintenanceJob{}
+	}
+	return &MaintenanceJobs{Jobs: jobs, Kinds: api.maintenanceJobs.Kinds()}, nil
+}
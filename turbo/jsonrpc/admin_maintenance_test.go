@@ -0,0 +1,79 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/ethdb/privateapi"
+	"github.com/erigontech/erigon/turbo/stages/mock"
+)
+
+func TestAdminMaintenanceJobs(t *testing.T) {
+	m := mock.Mock(t)
+	ctx := context.Background()
+	maintenanceJobs := privateapi.NewMaintenanceJobs(ctx, log.New())
+	maintenanceJobs.Register(privateapi.MaintenanceJobPrune, privateapi.PruneJob(m.DB))
+	maintenanceJobs.Register("fail", func(ctx context.Context) error { return errors.New("no space left") })
+	api := NewAdminAPI(nil, "", maintenanceJobs)
+
+	jobs, err := api.MaintenanceJobs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"fail", "prune"}, jobs.Kinds)
+	require.Empty(t, jobs.Jobs)
+
+	_, err = api.StartMaintenanceJob(ctx, "compact")
+	require.ErrorContains(t, err, "unknown maintenance job")
+
+	wait := func(id uint64) *privateapi.MaintenanceJob {
+		var job *privateapi.MaintenanceJob
+		require.Eventually(t, func() bool {
+			job, err = api.MaintenanceJob(ctx, id)
+			require.NoError(t, err)
+			return job.Status != "running"
+		}, 10*time.Second, time.Millisecond)
+		return job
+	}
+	prune, err := api.StartMaintenanceJob(ctx, privateapi.MaintenanceJobPrune)
+	require.NoError(t, err)
+	require.Equal(t, "prune", prune.Kind)
+	job := wait(prune.ID)
+	require.Equal(t, "done", job.Status)
+	require.Empty(t, job.Error)
+
+	failed, err := api.StartMaintenanceJob(ctx, "fail")
+	require.NoError(t, err)
+	job = wait(failed.ID)
+	require.Equal(t, "failed", job.Status)
+	require.Equal(t, "no space left", job.Error)
+
+	jobs, err = api.MaintenanceJobs(ctx)
+	require.NoError(t, err)
+	require.Len(t, jobs.Jobs, 2)
+	_, err = api.MaintenanceJob(ctx, 100)
+	require.ErrorContains(t, err, "maintenance job 100 not found")
+
+	// standalone rpcdaemon
+	_, err = NewAdminAPI(nil, "", nil).MaintenanceJobs(ctx)
+	require.ErrorIs(t, err, errNoMaintenanceJobs)
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/erigontech/erigon/turbo/debug"
+)
+
+// maxProfileDuration - limit of `nsec` param of sampling profiles, to not hold RPC connection forever
+const maxProfileDuration = 5 * time.Minute
+
+// profilePath - path of profile file in <datadir>/pprof. Only base name of `name` is used: RPC caller can't write outside of this dir.
+// Empty name means: <kind>-<timestamp>.<ext>
+func (api *AdminAPIImpl) profilePath(name, kind, ext string) (string, error) {
+	if api.dataDir == "" {
+		return "", errors.New("profiling requires --datadir")
+	}
+	dir := filepath.Join(api.dataDir, "pprof")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if name == "" {
+		name = fmt.Sprintf("%s-%s.%s", kind, time.Now().UTC().Format("20060102-150405"), ext)
+	}
+	name = filepath.Base(filepath.Clean(name))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return "", fmt.Errorf("invalid profile file name: %q", name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// StartCPUProfile implements admin_startCPUProfile. Profile is written until admin_stopCPUProfile is called.
+func (api *AdminAPIImpl) StartCPUProfile(ctx context.Context, name string) (string, error) {
+	file, err := api.profilePath(name, "cpu", "prof")
+	if err != nil {
+		return "", err
+	}
+	if err := debug.Handler.StartCPUProfile(file); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// StopCPUProfile implements admin_stopCPUProfile.
+func (api *AdminAPIImpl) StopCPUProfile(ctx context.Context) error {
+	return debug.Handler.StopCPUProfile()
+}
+
+// WriteHeapProfile implements admin_writeHeapProfile.
+func (api *AdminAPIImpl) WriteHeapProfile(ctx context.Context, name string) (string, error) {
+	file, err := api.profilePath(name, "heap", "prof")
+	if err != nil {
+		return "", err
+	}
+	if err := debug.Handler.WriteMemProfile(file); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// BlockProfile implements admin_blockProfile. Collects goroutine blocking events for nsec seconds.
+func (api *AdminAPIImpl) BlockProfile(ctx context.Context, name string, nsec uint) (string, error) {
+	if time.Duration(nsec)*time.Second > maxProfileDuration {
+		return "", fmt.Errorf("profile duration must be <= %s", maxProfileDuration)
+	}
+	file, err := api.profilePath(name, "block", "prof")
+	if err != nil {
+		return "", err
+	}
+	if err := debug.Handler.BlockProfile(file, nsec); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// GoroutineStacks implements admin_goroutineStacks. Dumps stacks of all goroutines as text.
+func (api *AdminAPIImpl) GoroutineStacks(ctx context.Context, name string) (string, error) {
+	file, err := api.profilePath(name, "goroutines", "txt")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, []byte(debug.Handler.Stacks()), 0644); err != nil { //nolint:gosec
+		return "", err
+	}
+	return file, nil
+}
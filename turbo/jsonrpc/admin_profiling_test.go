@@ -0,0 +1,53 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminProfiling(t *testing.T) {
+	dataDir := t.TempDir()
+	api := NewAdminAPI(nil, dataDir, nil)
+	ctx := context.Background()
+
+	file, err := api.WriteHeapProfile(ctx, "../../heap.prof")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dataDir, "pprof", "heap.prof"), file)
+	require.FileExists(t, file)
+
+	file, err = api.GoroutineStacks(ctx, "")
+	require.NoError(t, err)
+	stacks, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Contains(t, string(stacks), "TestAdminProfiling")
+
+	file, err = api.StartCPUProfile(ctx, "cpu.prof")
+	require.NoError(t, err)
+	_, err = api.StartCPUProfile(ctx, "cpu2.prof")
+	require.Error(t, err)
+	require.NoError(t, api.StopCPUProfile(ctx))
+	require.FileExists(t, file)
+
+	_, err = NewAdminAPI(nil, "", nil).WriteHeapProfile(ctx, "heap.prof")
+	require.Error(t, err)
+}
@@ -524,3 +524,27 @@ func TestAccountAt(t *testing.T) {
 		require.Equal(0, int(results.Nonce))
 	})
 }
+
+func TestDbStats(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	api := NewPrivateDebugAPI(newBaseApiForTest(m), m.DB, 0)
+
+	all, err := api.DbStats(m.Ctx, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, all)
+	for i := 1; i < len(all); i++ {
+		require.GreaterOrEqual(t, all[i-1].SizeBytes, all[i].SizeBytes)
+	}
+
+	table := kv.Headers
+	headers, err := api.DbStats(m.Ctx, &table)
+	require.NoError(t, err)
+	require.Len(t, headers, 1)
+	require.Equal(t, kv.Headers, headers[0].Table)
+	require.Positive(t, uint64(headers[0].Entries))
+	require.Positive(t, uint64(headers[0].SizeBytes))
+
+	unknown := "NoSuchTable"
+	_, err = api.DbStats(m.Ctx, &unknown)
+	require.ErrorContains(t, err, "unknown table")
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/erigontech/mdbx-go/mdbx"
+
+	"github.com/erigontech/erigon-lib/common/hexutil"
+)
+
+// TableStats - disk usage of a chaindata table
+type TableStats struct {
+	Table         string         `json:"table"`
+	Entries       hexutil.Uint64 `json:"entries"`
+	SizeBytes     hexutil.Uint64 `json:"sizeBytes"`
+	Depth         hexutil.Uint64 `json:"depth"` // height of b-tree
+	BranchPages   hexutil.Uint64 `json:"branchPages"`
+	LeafPages     hexutil.Uint64 `json:"leafPages"`
+	OverflowPages hexutil.Uint64 `json:"overflowPages"` // values which don't fit into a page
+}
+
+type tableStater interface {
+	BucketSize(table string) (uint64, error)
+	BucketStat(table string) (*mdbx.Stat, error)
+}
+
+// DbStats implements debug_dbStats. Returns disk usage of given chaindata table or of all tables, sorted by size.
+// Tables are inspected in a read transaction, so it's safe to call on a running node. Needs local access to chaindata.
+func (api *PrivateDebugAPIImpl) DbStats(ctx context.Context, table *string) ([]TableStats, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	stater, ok := tx.(tableStater)
+	if !ok {
+		return nil, errors.New("table statistics are not available over remote db, run rpcdaemon with --datadir")
+	}
+
+	var tables []string
+	if table != nil {
+		if _, ok := api.db.AllTables()[*table]; !ok {
+			return nil, errors.New("unknown table: " + *table)
+		}
+		tables = []string{*table}
+	} else {
+		for name, cfg := range api.db.AllTables() {
+			if !cfg.IsDeprecated {
+				tables = append(tables, name)
+			}
+		}
+	}
+
+	res := make([]TableStats, 0, len(tables))
+	for _, name := range tables {
+		st, err := stater.BucketStat(name)
+		if err != nil {
+			return nil, err
+		}
+		size, err := stater.BucketSize(name)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, TableStats{
+			Table:         name,
+			Entries:       hexutil.Uint64(st.Entries),
+			SizeBytes:     hexutil.Uint64(size),
+			Depth:         hexutil.Uint64(st.Depth),
+			BranchPages:   hexutil.Uint64(st.BranchPages),
+			LeafPages:     hexutil.Uint64(st.LeafPages),
+			OverflowPages: hexutil.Uint64(st.OverflowPages),
+		})
+	}
+	slices.SortFunc(res, func(a, b TableStats) int {
+		return cmp.Or(cmp.Compare(b.SizeBytes, a.SizeBytes), cmp.Compare(a.Table, b.Table))
+	})
+	return res, nil
+}
@@ -40,6 +40,7 @@ type ErigonAPI interface {
 	GetHeaderByHash(_ context.Context, hash common.Hash) (*types.Header, error)
 	GetBlockByTimestamp(ctx context.Context, timeStamp rpc.Timestamp, fullTx bool) (map[string]interface{}, error)
 	GetBalanceChangesInBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*hexutil.Big, error)
+	GetGasStats(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) (*GasStats, error) // see ./erigon_gas_stats.go
 
 	// Receipt related (see ./erigon_receipts.go)
 	GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*types.Log, error)
@@ -48,6 +49,8 @@ type ErigonAPI interface {
 	GetLatestLogs(ctx context.Context, crit filters.FilterCriteria, logOptions filters.LogFilterOptions) (types.ErigonLogs, error)
 	// Gets cannonical block receipt through hash. If the block is not cannonical returns error
 	GetBlockReceiptsByBlockHash(ctx context.Context, cannonicalBlockHash common.Hash) ([]map[string]interface{}, error)
+	// Gets receipts of all canonical blocks in range [fromBlock, toBlock] - one element per block
+	GetBlockReceiptsRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([][]map[string]interface{}, error)
 
 	// NodeInfo returns a collection of metadata known about the host.
 	NodeInfo(ctx context.Context) ([]p2p.NodeInfo, error)
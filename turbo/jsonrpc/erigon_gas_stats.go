@@ -0,0 +1,130 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/turbo/rpchelper"
+)
+
+// maxGasStatsRange - limit of blocks amount served by one erigon_getGasStats call. Only headers are read - so it can be big.
+const maxGasStatsRange = 8192
+
+// BlockGasStats - gas and fee fields of one block header
+type BlockGasStats struct {
+	Number        hexutil.Uint64  `json:"number"`
+	Timestamp     hexutil.Uint64  `json:"timestamp"`
+	BaseFeePerGas *hexutil.Big    `json:"baseFeePerGas,omitempty"`
+	GasUsed       hexutil.Uint64  `json:"gasUsed"`
+	GasLimit      hexutil.Uint64  `json:"gasLimit"`
+	GasUsedRatio  float64         `json:"gasUsedRatio"`
+	BlobGasUsed   *hexutil.Uint64 `json:"blobGasUsed,omitempty"`
+	ExcessBlobGas *hexutil.Uint64 `json:"excessBlobGas,omitempty"`
+}
+
+// GasStats - per-block stats and their summary over range of blocks
+type GasStats struct {
+	FromBlock        hexutil.Uint64  `json:"fromBlock"`
+	ToBlock          hexutil.Uint64  `json:"toBlock"`
+	GasUsed          hexutil.Uint64  `json:"gasUsed"`
+	AvgGasUsedRatio  float64         `json:"avgGasUsedRatio"`
+	MinBaseFeePerGas *hexutil.Big    `json:"minBaseFeePerGas,omitempty"`
+	MaxBaseFeePerGas *hexutil.Big    `json:"maxBaseFeePerGas,omitempty"`
+	AvgBaseFeePerGas *hexutil.Big    `json:"avgBaseFeePerGas,omitempty"`
+	Blocks           []BlockGasStats `json:"blocks"`
+}
+
+// GetGasStats implements erigon_getGasStats. Returns base fee and gas usage of each block in [fromBlock, toBlock] and their summary.
+// Unlike eth_feeHistory it reads only headers: no bodies and no receipts. Percentiles of priority fees need
+// effective tip of each txn (body and receipts), they are served by eth_feeHistory's rewardPercentiles.
+func (api *ErigonImpl) GetGasStats(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) (*GasStats, error) {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, _, _, err := rpchelper.GetBlockNumber(ctx, rpc.BlockNumberOrHashWithNumber(fromBlock), tx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	to, _, _, err := rpchelper.GetBlockNumber(ctx, rpc.BlockNumberOrHashWithNumber(toBlock), tx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("fromBlock %d is greater than toBlock %d", from, to)
+	}
+	if to-from+1 > maxGasStatsRange {
+		return nil, fmt.Errorf("requested range of %d blocks exceeds limit of %d", to-from+1, maxGasStatsRange)
+	}
+
+	res := &GasStats{FromBlock: hexutil.Uint64(from), ToBlock: hexutil.Uint64(to), Blocks: make([]BlockGasStats, 0, to-from+1)}
+	var sumRatio float64
+	var minBaseFee, maxBaseFee, sumBaseFee *big.Int
+	var baseFeeBlocks int64
+	for blockNum := from; blockNum <= to; blockNum++ {
+		header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNum)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, fmt.Errorf("block header not found: %d", blockNum)
+		}
+		s := BlockGasStats{
+			Number:    hexutil.Uint64(blockNum),
+			Timestamp: hexutil.Uint64(header.Time),
+			GasUsed:   hexutil.Uint64(header.GasUsed),
+			GasLimit:  hexutil.Uint64(header.GasLimit),
+		}
+		if header.GasLimit > 0 {
+			s.GasUsedRatio = float64(header.GasUsed) / float64(header.GasLimit)
+		}
+		if header.BaseFee != nil {
+			s.BaseFeePerGas = (*hexutil.Big)(new(big.Int).Set(header.BaseFee))
+			if minBaseFee == nil {
+				minBaseFee, maxBaseFee, sumBaseFee = new(big.Int).Set(header.BaseFee), new(big.Int).Set(header.BaseFee), new(big.Int)
+			}
+			if header.BaseFee.Cmp(minBaseFee) < 0 {
+				minBaseFee.Set(header.BaseFee)
+			}
+			if header.BaseFee.Cmp(maxBaseFee) > 0 {
+				maxBaseFee.Set(header.BaseFee)
+			}
+			sumBaseFee.Add(sumBaseFee, header.BaseFee)
+			baseFeeBlocks++
+		}
+		s.BlobGasUsed = (*hexutil.Uint64)(header.BlobGasUsed)
+		s.ExcessBlobGas = (*hexutil.Uint64)(header.ExcessBlobGas)
+
+		res.GasUsed += s.GasUsed
+		sumRatio += s.GasUsedRatio
+		res.Blocks = append(res.Blocks, s)
+	}
+	res.AvgGasUsedRatio = sumRatio / float64(len(res.Blocks))
+	if baseFeeBlocks > 0 {
+		res.MinBaseFeePerGas = (*hexutil.Big)(minBaseFee)
+		res.MaxBaseFeePerGas = (*hexutil.Big)(maxBaseFee)
+		res.AvgBaseFeePerGas = (*hexutil.Big)(sumBaseFee.Div(sumBaseFee, big.NewInt(baseFeeBlocks)))
+	}
+	return res, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/erigontech/erigon/core/rawdb"
+)
+
+func TestGetGasStats(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	api := NewErigonAPI(newBaseApiForTest(m), m.DB, nil)
+	ctx := context.Background()
+
+	stats, err := api.GetGasStats(ctx, 1, 5)
+	require.NoError(t, err)
+	require.Len(t, stats.Blocks, 5)
+
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	var gasUsed uint64
+	for i, s := range stats.Blocks {
+		header := rawdb.ReadHeaderByNumber(tx, uint64(i+1))
+		require.Equal(t, header.GasUsed, uint64(s.GasUsed))
+		require.Equal(t, header.GasLimit, uint64(s.GasLimit))
+		gasUsed += header.GasUsed
+	}
+	require.Equal(t, gasUsed, uint64(stats.GasUsed))
+
+	_, err = api.GetGasStats(ctx, 5, 1)
+	require.Error(t, err)
+}
@@ -391,6 +391,62 @@ func (api *ErigonImpl) GetBlockReceiptsByBlockHash(ctx context.Context, cannonic
 	return result, nil
 }
 
+// maxBlockReceiptsRange - limit of blocks amount served by one erigon_getBlockReceiptsRange call
+const maxBlockReceiptsRange = 1024
+
+// GetBlockReceiptsRange implements erigon_getBlockReceiptsRange. Returns receipts of all canonical blocks in [fromBlock, toBlock],
+// all read in one db transaction.
+func (api *ErigonImpl) GetBlockReceiptsRange(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([][]map[string]interface{}, error) {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, _, _, err := rpchelper.GetBlockNumber(ctx, rpc.BlockNumberOrHashWithNumber(fromBlock), tx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	to, _, _, err := rpchelper.GetBlockNumber(ctx, rpc.BlockNumberOrHashWithNumber(toBlock), tx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("fromBlock %d is greater than toBlock %d", from, to)
+	}
+	if to-from+1 > maxBlockReceiptsRange {
+		return nil, fmt.Errorf("requested range of %d blocks exceeds limit of %d", to-from+1, maxBlockReceiptsRange)
+	}
+	chainConfig, err := api.chainConfig(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]map[string]interface{}, 0, to-from+1)
+	for blockNum := from; blockNum <= to; blockNum++ {
+		hash, ok, err := api._blockReader.CanonicalHash(ctx, tx, blockNum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("canonical hash not found for block %d", blockNum)
+		}
+		block, err := api.blockWithSenders(ctx, tx, hash, blockNum)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			return nil, fmt.Errorf("block %d not found", blockNum)
+		}
+		receipts, err := api.marshalBlockReceipts(ctx, tx, block, chainConfig)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, receipts)
+	}
+	return result, nil
+}
+
 // GetLogsByNumber implements erigon_getLogsByHash. Returns all the logs that appear in a block given the block's hash.
 // func (api *ErigonImpl) GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error) {
 // 	tx, err := api.db.Begin(ctx, false)
@@ -233,6 +233,16 @@ func TestGetBlockReceiptsByBlockHash(t *testing.T) {
 	})
 
 	require.NoError(t, err)
+
+	receiptsRange, err := api.GetBlockReceiptsRange(context.Background(), 0, 4)
+	require.NoError(t, err)
+	require.Len(t, receiptsRange, 5)
+	for i, receiptsFromBlock := range receiptsRange {
+		a, _ := json.Marshal(receiptsFromBlock)
+		assert.Equal(t, expect[uint64(i)], string(a))
+	}
+	_, err = api.GetBlockReceiptsRange(context.Background(), 3, 1)
+	require.Error(t, err)
 }
 
 // newTestBackend creates a chain with a number of explicitly defined blocks and
@@ -343,91 +343,125 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs
 // minutes on mainnet.  The current limit has been chosen arbitrarily as
 // 'useful' without likely being overly computationally intense.
 
-// GetProof is partially implemented; no Storage proofs, and proofs must be for
-// blocks within maxGetProofRewindBlockCount blocks of the head.
+// GetProof implements eth_getProof. Proofs are generated from the commitment domain by loading merkle paths
+// of the requested account and storage keys into witness trie. Proofs must be for blocks within
+// maxGetProofRewindBlockCount blocks of the head: for older blocks execution is rewound in memory.
 func (api *APIImpl) GetProof(ctx context.Context, address libcommon.Address, storageKeys []libcommon.Hash, blockNrOrHash rpc.BlockNumberOrHash) (*accounts.AccProofResult, error) {
-	return nil, errors.New("not supported by Erigon3")
-	/*
-		tx, err := api.db.BeginTemporalRo(ctx)
-		if err != nil {
-			return nil, err
-		}
-		defer tx.Rollback()
+	roTx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer roTx.Rollback()
 
-		blockNr, _, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
-		if err != nil {
-			return nil, err
-		}
+	blockNr, _, _, err := rpchelper.GetBlockNumber(ctx, blockNrOrHash, roTx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	header, err := api._blockReader.HeaderByNumber(ctx, roTx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("header not found for block %d", blockNr)
+	}
 
-		header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNr)
-		if err != nil {
-			return nil, err
-		}
+	latestBlock, err := rpchelper.GetLatestBlockNumber(roTx)
+	if err != nil {
+		return nil, err
+	}
+	if latestBlock < blockNr {
+		// shouldn't happen, but check anyway
+		return nil, fmt.Errorf("block number is in the future latest=%d requested=%d", latestBlock, blockNr)
+	}
+	if latestBlock-blockNr > uint64(api.MaxGetProofRewindBlockCount) {
+		return nil, fmt.Errorf("requested block is too old, block must be within %d blocks of the head block number (currently %d)", uint64(api.MaxGetProofRewindBlockCount), latestBlock)
+	}
 
-		latestBlock, err := rpchelper.GetLatestBlockNumber(tx)
+	batch := membatchwithdb.NewMemoryBatch(roTx, "", api.logger)
+	defer batch.Rollback()
+	if blockNr < latestBlock {
+		engine, ok := api.engine().(consensus.Engine)
+		if !ok {
+			return nil, errors.New("engine is not consensus.Engine")
+		}
+		chainConfig, err := api.chainConfig(ctx, roTx)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("error loading chain config: %v", err)
 		}
-
-		if latestBlock < blockNr {
-			// shouldn't happen, but check anyway
-			return nil, fmt.Errorf("block number is in the future latest=%d requested=%d", latestBlock, blockNr)
+		// state after block #blockNr is the state before block #blockNr+1
+		cfg := stagedsync.StageWitnessCfg(true, 0, chainConfig, engine, api._blockReader, api.dirs)
+		if err = stagedsync.RewindStagesForWitness(batch, blockNr+1, latestBlock, &cfg, false, ctx, api.logger); err != nil {
+			return nil, err
 		}
+	}
 
-		rl := trie.NewRetainList(0)
-		var loader *trie.FlatDBTrieLoader
-		if blockNr < latestBlock {
-			if latestBlock-blockNr > uint64(api.MaxGetProofRewindBlockCount) {
-				return nil, fmt.Errorf("requested block is too old, block must be within %d blocks of the head block number (currently %d)", uint64(api.MaxGetProofRewindBlockCount), latestBlock)
-			}
-			batch := membatchwithdb.NewMemoryBatch(tx, api.dirs.Tmp, api.logger)
-			defer batch.Rollback()
-
-			unwindState := &stagedsync.UnwindState{UnwindPoint: blockNr}
-			stageState := &stagedsync.StageState{BlockNumber: latestBlock}
+	domains, err := libstate.NewSharedDomains(batch, log.New())
+	if err != nil {
+		return nil, err
+	}
+	defer domains.Close()
+	sdCtx := libstate.NewSharedDomainsCommitmentContext(domains, commitment.ModeUpdate, commitment.VariantHexPatriciaTrie)
+	hph, ok := sdCtx.Trie().(*commitment.HexPatriciaHashed)
+	if !ok {
+		return nil, errors.New("casting to HexPatriciaTrieHashed failed")
+	}
 
-			hashStageCfg := stagedsync.StageHashStateCfg(nil, api.dirs, api.historyV3(batch))
-			if err := stagedsync.UnwindHashStateStage(unwindState, stageState, batch, hashStageCfg, ctx, api.logger); err != nil {
-				return nil, err
-			}
+	// touched keys are not really updated, they are only loaded into the grid to build merkle paths
+	updates := commitment.NewUpdates(commitment.ModeDirect, sdCtx.TempDir(), hph.HashAndNibblizeKey)
+	defer updates.Close()
+	updates.TouchPlainKey(string(address.Bytes()), nil, updates.TouchAccount)
+	for _, key := range storageKeys {
+		updates.TouchPlainKey(string(append(address.Bytes(), key.Bytes()...)), nil, updates.TouchStorage)
+	}
+	hph.SetTrace(false)
+	proofTrie, _, err := hph.GenerateWitness(ctx, updates, nil, header.Root[:], "eth_getProof")
+	if err != nil {
+		return nil, err
+	}
 
-			interHashStageCfg := stagedsync.StageTrieCfg(nil, false, false, false, api.dirs.Tmp, api._blockReader, nil, api.historyV3(batch), api._agg)
-			loader, err = stagedsync.UnwindIntermediateHashesForTrieLoader("eth_getProof", rl, unwindState, stageState, batch, interHashStageCfg, nil, nil, ctx.Done(), api.logger)
-			if err != nil {
-				return nil, err
-			}
-			tx = batch
-		} else {
-			loader = trie.NewFlatDBTrieLoader("eth_getProof", rl, nil, nil, false)
-		}
+	addrHash := crypto.Keccak256(address.Bytes())
+	accountProof, err := proofTrie.Prove(addrHash, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	// absent account is proven with zero nonce, balance, code and storage hashes
+	proof := &accounts.AccProofResult{
+		Address:      address,
+		AccountProof: make([]hexutility.Bytes, len(accountProof)),
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		StorageProof: make([]accounts.StorProofResult, len(storageKeys)),
+	}
+	for i, p := range accountProof {
+		proof.AccountProof[i] = p
+	}
+	if acc, _ := proofTrie.GetAccount(addrHash); acc != nil {
+		proof.Balance = (*hexutil.Big)(acc.Balance.ToBig())
+		proof.Nonce = hexutil.Uint64(acc.Nonce)
+		proof.CodeHash = acc.CodeHash
+		proof.StorageHash = acc.Root
+	}
 
-		reader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, 0, api.filters, api.stateCache, "")
-		if err != nil {
-			return nil, err
-		}
-		a, err := reader.ReadAccountData(address)
+	for i, key := range storageKeys {
+		fullKey := append(libcommon.CopyBytes(addrHash), crypto.Keccak256(key.Bytes())...)
+		// skip account part of the path: storage proof starts from the storage root
+		storageProof, err := proofTrie.Prove(fullKey, 2*len(addrHash), true)
 		if err != nil {
 			return nil, err
 		}
-		if a == nil {
-			a = &accounts.Account{}
+		value := new(big.Int)
+		if v, ok := proofTrie.Get(fullKey); ok {
+			value.SetBytes(v)
 		}
-		pr, err := trie.NewProofRetainer(address, a, storageKeys, rl)
-		if err != nil {
-			return nil, err
+		proof.StorageProof[i] = accounts.StorProofResult{
+			Key:   key,
+			Value: (*hexutil.Big)(value),
+			Proof: make([]hexutility.Bytes, len(storageProof)),
 		}
-
-		loader.SetProofRetainer(pr)
-		root, err := loader.CalcTrieRoot(tx, nil)
-		if err != nil {
-			return nil, err
-		}
-
-		if root != header.Root {
-			return nil, fmt.Errorf("mismatch in expected state root computed %v vs %v indicates bug in proof implementation", root, header.Root)
+		for j, p := range storageProof {
+			proof.StorageProof[i].Proof[j] = p
 		}
-		return pr.ProofResult()
-	*/
+	}
+	return proof, nil
 }
 
 func (api *APIImpl) GetWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (hexutility.Bytes, error) {
@@ -18,15 +18,24 @@ package jsonrpc
 
 import (
 	"context"
+	"fmt"
 	"strings"
 
 	"github.com/erigontech/erigon-lib/log/v3"
 
+	"github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/debug"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/types/accounts"
 	"github.com/erigontech/erigon/core/types"
 	"github.com/erigontech/erigon/eth/filters"
 	"github.com/erigontech/erigon/rpc"
 	"github.com/erigontech/erigon/turbo/rpchelper"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
 )
 
 // NewPendingTransactionFilter new transaction filter
@@ -303,3 +312,208 @@ func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc
 
 	return rpcSub, nil
 }
+
+const (
+	// maxWatchedKeys - limit of addresses plus storage slots in one addressChanges subscription
+	maxWatchedKeys = 10_000
+	// addressChangesReorgDepth - amount of recent blocks whose notifications are remembered to be sent back as removed on reorg
+	addressChangesReorgDepth = 128
+)
+
+// AddressChangesCriteria - accounts and storage slots watched by addressChanges subscription
+type AddressChangesCriteria struct {
+	Addresses []common.Address                 `json:"addresses"` // notify about changes of balance, nonce, code or deletion
+	Storage   map[common.Address][]common.Hash `json:"storage"`   // notify about changes of given slots, empty list of slots means all slots of address
+}
+
+// AddressChange - new state of watched account or storage slot, changed by block
+type AddressChange struct {
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	Address     common.Address  `json:"address"`
+	Deleted     bool            `json:"deleted,omitempty"`
+	Balance     *hexutil.Big    `json:"balance,omitempty"`
+	Nonce       *hexutil.Uint64 `json:"nonce,omitempty"`
+	CodeHash    *common.Hash    `json:"codeHash,omitempty"`
+	StorageKey  *common.Hash    `json:"storageKey,omitempty"`
+	Value       *common.Hash    `json:"value,omitempty"`
+	// Removed is true if block of this change was unwound by reorg - change is sent again with this flag
+	Removed bool `json:"removed"`
+}
+
+type addressChangesOfBlock struct {
+	number  uint64
+	hash    common.Hash
+	changes []*AddressChange
+}
+
+// AddressChanges send a notification for each watched account or storage slot changed by new block.
+// Changed keys are taken from state history of block - so cost doesn't depend on amount of watched keys.
+// On reorg, notifications of unwound blocks are sent again with removed=true (newest block first), then changes of new blocks.
+func (api *APIImpl) AddressChanges(ctx context.Context, crit AddressChangesCriteria) (*rpc.Subscription, error) {
+	if api.filters == nil {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	watchedAmount := len(crit.Addresses)
+	for _, slots := range crit.Storage {
+		watchedAmount += max(len(slots), 1)
+	}
+	if watchedAmount == 0 || watchedAmount > maxWatchedKeys {
+		return &rpc.Subscription{}, fmt.Errorf("amount of addresses and storage slots must be in [1, %d], got %d", maxWatchedKeys, watchedAmount)
+	}
+	watchedAccounts := make(map[common.Address]struct{}, len(crit.Addresses))
+	for _, addr := range crit.Addresses {
+		watchedAccounts[addr] = struct{}{}
+	}
+	watchedStorage := make(map[common.Address]map[common.Hash]struct{}, len(crit.Storage))
+	for addr, slots := range crit.Storage {
+		watchedStorage[addr] = make(map[common.Hash]struct{}, len(slots))
+		for _, slot := range slots {
+			watchedStorage[addr][slot] = struct{}{}
+		}
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer debug.LogPanic()
+		headers, id := api.filters.SubscribeNewHeads(32)
+		defer api.filters.UnsubscribeHeads(id)
+		notify := func(change *AddressChange) {
+			if err := notifier.Notify(rpcSub.ID, change); err != nil {
+				log.Warn("[rpc] error while notifying subscription", "err", err)
+			}
+		}
+		var notified []addressChangesOfBlock
+		for {
+			select {
+			case h, ok := <-headers:
+				if h != nil {
+					blockNum, hash := h.Number.Uint64(), h.Hash()
+					if len(notified) > 0 && notified[len(notified)-1].number == blockNum && notified[len(notified)-1].hash == hash {
+						continue // same block again
+					}
+					// unwound blocks: new block has same or lower number, or replaces parent
+					for len(notified) > 0 {
+						last := notified[len(notified)-1]
+						if last.number < blockNum-1 || (last.number == blockNum-1 && last.hash == h.ParentHash) {
+							break
+						}
+						for _, change := range last.changes {
+							removed := *change
+							removed.Removed = true
+							notify(&removed)
+						}
+						notified = notified[:len(notified)-1]
+					}
+
+					changes, err := api.addressChanges(context.Background(), h, watchedAccounts, watchedStorage)
+					if err != nil {
+						log.Warn("[rpc] error while reading address changes", "block", blockNum, "err", err)
+					}
+					for _, change := range changes {
+						notify(change)
+					}
+					if len(notified) == addressChangesReorgDepth {
+						notified = notified[1:]
+					}
+					notified = append(notified, addressChangesOfBlock{number: blockNum, hash: hash, changes: changes})
+				}
+				if !ok {
+					log.Warn("[rpc] new heads channel was closed")
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+func (api *APIImpl) addressChanges(ctx context.Context, header *types.Header, watchedAccounts map[common.Address]struct{}, watchedStorage map[common.Address]map[common.Hash]struct{}) ([]*AddressChange, error) {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNum := header.Number.Uint64()
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, api._blockReader))
+	minTxNum, err := txNumsReader.Min(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	maxTxNum, err := txNumsReader.Max(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*AddressChange
+	if len(watchedAccounts) > 0 {
+		it, err := tx.HistoryRange(kv.AccountsDomain, int(minTxNum), int(maxTxNum+1), order.Asc, -1)
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		for it.HasNext() {
+			k, _, err := it.Next()
+			if err != nil {
+				return nil, err
+			}
+			addr := common.BytesToAddress(k)
+			if _, ok := watchedAccounts[addr]; !ok {
+				continue
+			}
+			v, _, err := tx.GetAsOf(kv.AccountsDomain, k, maxTxNum+1)
+			if err != nil {
+				return nil, err
+			}
+			change := &AddressChange{BlockNumber: hexutil.Uint64(blockNum), BlockHash: header.Hash(), Address: addr}
+			if len(v) == 0 {
+				change.Deleted = true
+			} else {
+				var acc accounts.Account
+				if err := accounts.DeserialiseV3(&acc, v); err != nil {
+					return nil, err
+				}
+				nonce := hexutil.Uint64(acc.Nonce)
+				change.Balance, change.Nonce, change.CodeHash = (*hexutil.Big)(acc.Balance.ToBig()), &nonce, &acc.CodeHash
+			}
+			res = append(res, change)
+		}
+	}
+	if len(watchedStorage) > 0 {
+		it, err := tx.HistoryRange(kv.StorageDomain, int(minTxNum), int(maxTxNum+1), order.Asc, -1)
+		if err != nil {
+			return nil, err
+		}
+		defer it.Close()
+		for it.HasNext() {
+			k, _, err := it.Next()
+			if err != nil {
+				return nil, err
+			}
+			addr, slot := common.BytesToAddress(k[:length.Addr]), common.BytesToHash(k[length.Addr:])
+			slots, ok := watchedStorage[addr]
+			if !ok {
+				continue
+			}
+			if _, ok := slots[slot]; !ok && len(slots) > 0 {
+				continue
+			}
+			v, _, err := tx.GetAsOf(kv.StorageDomain, k, maxTxNum+1)
+			if err != nil {
+				return nil, err
+			}
+			value := common.BytesToHash(v)
+			res = append(res, &AddressChange{BlockNumber: hexutil.Uint64(blockNum), BlockHash: header.Hash(), Address: addr, StorageKey: &slot, Value: &value})
+		}
+	}
+	return res, nil
+}
@@ -17,25 +17,37 @@
 package jsonrpc
 
 import (
+	"context"
+	"math/big"
 	"math/rand"
 	"sync"
 	"testing"
 	"time"
 
 	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
 	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/crypto"
+	remote "github.com/erigontech/erigon-lib/gointerfaces/remoteproto"
 
 	"github.com/erigontech/erigon/rpc/rpccfg"
 
+	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	txpool "github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
 	"github.com/erigontech/erigon-lib/kv/kvcache"
 
 	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/rlp"
 
 	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/types"
 	"github.com/erigontech/erigon/eth/filters"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/rpc"
 	"github.com/erigontech/erigon/turbo/rpchelper"
 	"github.com/erigontech/erigon/turbo/stages/mock"
 )
@@ -117,3 +129,124 @@ func TestLogsSubscribeAndUnsubscribe_WithoutConcurrentMapIssue(t *testing.T) {
 	}
 	wg.Wait()
 }
+
+func TestAddressChangesReorg(t *testing.T) {
+	var (
+		key, _    = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender    = crypto.PubkeyToAddress(key.PublicKey)
+		signer    = types.LatestSignerForChainID(nil)
+		contract  = libcommon.HexToAddress("0xcc")
+		coinbaseA = libcommon.HexToAddress("0xaa")
+		coinbaseB = libcommon.HexToAddress("0xbb")
+		gspec     = &types.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: types.GenesisAlloc{
+				sender:   {Balance: big.NewInt(1e18)},
+				contract: {Balance: new(big.Int), Code: hexutil.MustDecode("0x4360005500")}, // SSTORE(0, NUMBER)
+			},
+		}
+	)
+	m := mock.MockWithGenesis(t, gspec, key, false)
+	generate := func(n int, coinbaseFrom int) *core.ChainPack {
+		chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, n, func(i int, b *core.BlockGen) {
+			if i < coinbaseFrom {
+				b.SetCoinbase(coinbaseA)
+			} else {
+				b.SetCoinbase(coinbaseB)
+			}
+			txn, err := types.SignTx(types.NewTransaction(b.TxNonce(sender), contract, new(uint256.Int), 100_000, new(uint256.Int), nil), *signer, key)
+			require.NoError(t, err)
+			b.AddTx(txn)
+		})
+		require.NoError(t, err)
+		return chain
+	}
+	chainA, chainB := generate(3, 3), generate(4, 1) // common block 1, then fork
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ff := rpchelper.New(ctx, rpchelper.DefaultFiltersConfig, nil, nil, nil, func() {}, m.Log)
+	api := NewEthAPI(NewBaseApi(ff, kvcache.New(kvcache.DefaultCoherentConfig), m.BlockReader, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, nil), m.DB, nil, nil, nil, 5000000, 1e18, 100_000, false, 100_000, 128, m.Log)
+	server := rpc.NewServer(50, false, false, true, m.Log, 100)
+	defer server.Stop()
+	require.NoError(t, server.RegisterName("eth", api))
+	client := rpc.DialInProc(server, m.Log)
+	defer client.Close()
+
+	changes := make(chan *AddressChange, 100)
+	sub, err := client.Subscribe(ctx, "eth", changes, "addressChanges", AddressChangesCriteria{
+		Addresses: []libcommon.Address{coinbaseA, coinbaseB},
+		Storage:   map[libcommon.Address][]libcommon.Hash{contract: {{}}},
+	})
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	sendHeader := func(h *types.Header) {
+		data, err := rlp.EncodeToBytes(h)
+		require.NoError(t, err)
+		ff.OnNewEvent(&remote.SubscribeReply{Type: remote.Event_HEADER, Data: data})
+	}
+	receive := func() *AddressChange {
+		select {
+		case change := <-changes:
+			return change
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("no notification")
+		}
+		return nil
+	}
+	// account change of coinbase and storage change of contract in each block
+	expectBlock := func(block *types.Block, coinbase libcommon.Address, removed bool) {
+		t.Helper()
+		change := receive()
+		assert.Equal(t, block.NumberU64(), uint64(change.BlockNumber))
+		assert.Equal(t, block.Hash(), change.BlockHash)
+		assert.Equal(t, coinbase, change.Address)
+		assert.Nil(t, change.StorageKey)
+		assert.NotNil(t, change.Balance)
+		assert.Equal(t, removed, change.Removed)
+
+		change = receive()
+		assert.Equal(t, block.Hash(), change.BlockHash)
+		assert.Equal(t, contract, change.Address)
+		assert.Equal(t, libcommon.Hash{}, *change.StorageKey)
+		assert.Equal(t, libcommon.BigToHash(block.Number()), *change.Value)
+		assert.Equal(t, removed, change.Removed)
+	}
+
+	require.NoError(t, m.InsertChain(chainA))
+	// subscription goroutine subscribes to heads asynchronously: repeat first header until it's noticed, repeats are ignored
+	for first := false; !first; {
+		sendHeader(chainA.Headers[0])
+		select {
+		case change := <-changes:
+			changes <- change
+			first = true
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	expectBlock(chainA.Blocks[0], coinbaseA, false)
+	for _, h := range chainA.Headers[1:] {
+		sendHeader(h)
+	}
+	expectBlock(chainA.Blocks[1], coinbaseA, false)
+	expectBlock(chainA.Blocks[2], coinbaseA, false)
+
+	require.Equal(t, chainA.Blocks[0].Hash(), chainB.Blocks[0].Hash())
+	require.NoError(t, m.InsertChain(chainB))
+	for _, h := range chainB.Headers[1:] {
+		sendHeader(h)
+	}
+	expectBlock(chainA.Blocks[2], coinbaseA, true)
+	expectBlock(chainA.Blocks[1], coinbaseA, true)
+	expectBlock(chainB.Blocks[1], coinbaseB, false)
+	expectBlock(chainB.Blocks[2], coinbaseB, false)
+	expectBlock(chainB.Blocks[3], coinbaseB, false)
+	select {
+	case change := <-changes:
+		t.Fatalf("unexpected notification %+v", change)
+	default:
+	}
+}
@@ -532,6 +532,11 @@ func (api *APIImpl) GetBlockReceipts(ctx context.Context, numberOrHash rpc.Block
 	if err != nil {
 		return nil, err
 	}
+	return api.marshalBlockReceipts(ctx, tx, block, chainConfig)
+}
+
+// marshalBlockReceipts - all receipts of block (including bor state-sync receipt) in RPC format
+func (api *BaseAPI) marshalBlockReceipts(ctx context.Context, tx kv.TemporalTx, block *types.Block, chainConfig *chain.Config) ([]map[string]interface{}, error) {
 	receipts, err := api.getReceipts(ctx, tx, block)
 	if err != nil {
 		return nil, fmt.Errorf("getReceipts error: %w", err)
@@ -543,7 +548,7 @@ func (api *APIImpl) GetBlockReceipts(ctx context.Context, numberOrHash rpc.Block
 	}
 
 	if chainConfig.Bor != nil {
-		events, err := api.stateSyncEvents(ctx, tx, block.Hash(), blockNum, chainConfig)
+		events, err := api.stateSyncEvents(ctx, tx, block.Hash(), block.NumberU64(), chainConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,97 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/tracing"
+	"github.com/erigontech/erigon-lib/kv/kvcache"
+	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/rpc/rpccfg"
+	"github.com/erigontech/erigon/turbo/rpchelper"
+)
+
+type spanParentKey struct{}
+
+type recordedSpan struct {
+	name, parent string
+	attrs        []any
+	ended        bool
+}
+
+func (s *recordedSpan) SetAttributes(kv ...any) { s.attrs = append(s.attrs, kv...) }
+func (s *recordedSpan) End()                    { s.ended = true }
+
+// recordingTracer - keeps name of parent span in ctx, as real tracers keep span context
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	parent, _ := ctx.Value(spanParentKey{}).(string)
+	s := &recordedSpan{name: name, parent: parent}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanParentKey{}, name), s
+}
+
+func TestRpcCallSpans(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	ctx := context.Background()
+	ff := rpchelper.New(ctx, rpchelper.DefaultFiltersConfig, nil, nil, nil, func() {}, m.Log)
+	api := NewEthAPI(NewBaseApi(ff, kvcache.New(kvcache.DefaultCoherentConfig), m.BlockReader, false, rpccfg.DefaultEvmCallTimeout, m.Engine, m.Dirs, nil), m.DB, nil, nil, nil, 5000000, 1e18, 100_000, false, 100_000, 128, m.Log)
+	server := rpc.NewServer(50, false, false, true, m.Log, 100)
+	defer server.Stop()
+	require.NoError(t, server.RegisterName("eth", api))
+	client := rpc.DialInProc(server, m.Log)
+	defer client.Close()
+
+	tr := &recordingTracer{}
+	tracing.SetTracer(tr)
+	defer tracing.SetTracer(nil)
+
+	var balance hexutil.Big
+	require.NoError(t, client.Call(&balance, "eth_getBalance", "0x71562b71999873db5b286df957af199ec94617f7", "0x1"))
+	require.NotZero(t, balance.ToInt().Sign())
+
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	var call, read *recordedSpan
+	for _, s := range tr.spans {
+		switch {
+		case s.name == "rpc.eth_getBalance":
+			call = s
+		case strings.HasPrefix(s.name, "kv.Get") && s.parent == "rpc.eth_getBalance":
+			read = s
+		}
+	}
+	require.NotNil(t, call)
+	require.True(t, call.ended)
+	require.NotNil(t, read)
+	require.True(t, read.ended)
+	require.Equal(t, []any{"table", "accounts"}, read.attrs)
+}
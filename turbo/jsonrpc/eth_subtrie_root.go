@@ -0,0 +1,141 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/erigontech/erigon-lib/commitment"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/kv/membatchwithdb"
+	libstate "github.com/erigontech/erigon-lib/state"
+	"github.com/erigontech/erigon/consensus"
+	"github.com/erigontech/erigon/eth/stagedsync"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/turbo/rpchelper"
+)
+
+// SubtrieRootResult is the result of eth_getSubtrieRoot.
+type SubtrieRootResult struct {
+	BlockNumber hexutil.Uint64   `json:"blockNumber"`
+	Prefix      string           `json:"prefix"`
+	Hash        libcommon.Hash   `json:"hash"`
+	Witness     hexutility.Bytes `json:"witness,omitempty"` // RLP of the branch node hashing to Hash
+}
+
+// GetSubtrieRoot implements eth_getSubtrieRoot. Returns the hash of the state trie branch node at prefix, given
+// as hex nibbles ("" for the root), recomputed on the server from flat state at the given block. Lets a client
+// check a subtree of its own state without downloading it. Like eth_getProof, blocks must be within
+// maxGetProofRewindBlockCount blocks of the head.
+func (api *APIImpl) GetSubtrieRoot(ctx context.Context, prefix string, blockNrOrHash rpc.BlockNumberOrHash, withWitness *bool) (*SubtrieRootResult, error) {
+	nibbles, err := parseNibbles(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	roTx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer roTx.Rollback()
+
+	blockNr, _, _, err := rpchelper.GetBlockNumber(ctx, blockNrOrHash, roTx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	latestBlock, err := rpchelper.GetLatestBlockNumber(roTx)
+	if err != nil {
+		return nil, err
+	}
+	if latestBlock < blockNr {
+		// shouldn't happen, but check anyway
+		return nil, fmt.Errorf("block number is in the future latest=%d requested=%d", latestBlock, blockNr)
+	}
+	if latestBlock-blockNr > uint64(api.MaxGetProofRewindBlockCount) {
+		return nil, fmt.Errorf("requested block is too old, block must be within %d blocks of the head block number (currently %d)", uint64(api.MaxGetProofRewindBlockCount), latestBlock)
+	}
+
+	batch := membatchwithdb.NewMemoryBatch(roTx, "", api.logger)
+	defer batch.Rollback()
+	if blockNr < latestBlock {
+		engine, ok := api.engine().(consensus.Engine)
+		if !ok {
+			return nil, errors.New("engine is not consensus.Engine")
+		}
+		chainConfig, err := api.chainConfig(ctx, roTx)
+		if err != nil {
+			return nil, fmt.Errorf("error loading chain config: %v", err)
+		}
+		// state after block #blockNr is the state before block #blockNr+1
+		cfg := stagedsync.StageWitnessCfg(true, 0, chainConfig, engine, api._blockReader, api.dirs)
+		if err = stagedsync.RewindStagesForWitness(batch, blockNr+1, latestBlock, &cfg, false, ctx, api.logger); err != nil {
+			return nil, err
+		}
+	}
+
+	domains, err := libstate.NewSharedDomains(batch, api.logger)
+	if err != nil {
+		return nil, err
+	}
+	defer domains.Close()
+	sdCtx := libstate.NewSharedDomainsCommitmentContext(domains, commitment.ModeUpdate, commitment.VariantHexPatriciaTrie)
+	hph, ok := sdCtx.Trie().(*commitment.HexPatriciaHashed)
+	if !ok {
+		return nil, errors.New("casting to HexPatriciaTrieHashed failed")
+	}
+
+	hash, node, found, err := hph.BranchHash(nibbles)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no branch at prefix %q in block %d", prefix, blockNr)
+	}
+	result := &SubtrieRootResult{
+		BlockNumber: hexutil.Uint64(blockNr),
+		Prefix:      prefix,
+		Hash:        libcommon.BytesToHash(hash),
+	}
+	if withWitness != nil && *withWitness {
+		result.Witness = node
+	}
+	return result, nil
+}
+
+// parseNibbles parses a hex string (optionally 0x-prefixed) into nibbles, one per character.
+func parseNibbles(s string) ([]byte, error) {
+	s = strings.TrimPrefix(s, "0x")
+	nibbles := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			nibbles[i] = c - '0'
+		case c >= 'a' && c <= 'f':
+			nibbles[i] = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			nibbles[i] = c - 'A' + 10
+		default:
+			return nil, fmt.Errorf("invalid nibble %q in prefix %q", c, s)
+		}
+	}
+	return nibbles, nil
+}
@@ -0,0 +1,174 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/rlp"
+	"github.com/erigontech/erigon-lib/trie"
+	"github.com/erigontech/erigon-lib/types/accounts"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/rawdb"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/turbo/stages/mock"
+)
+
+func TestGetSubtrieRoot(t *testing.T) {
+	m, _, _ := chainWithDeployedContract(t)
+	api := NewEthAPI(newBaseApiForTest(m), m.DB, nil, nil, nil, 5000000, 1e18, 100_000, false, 1, 128, log.New())
+	ctx := context.Background()
+
+	tx, err := m.DB.BeginRo(ctx)
+	require.NoError(t, err)
+	head := rawdb.ReadCurrentHeader(tx)
+	require.NotNil(t, head)
+	parent := rawdb.ReadHeaderByNumber(tx, head.Number.Uint64()-1)
+	tx.Rollback()
+	require.NotNil(t, parent)
+
+	withWitness := true
+	res, err := api.GetSubtrieRoot(ctx, "", rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &withWitness)
+	require.NoError(t, err)
+	require.Equal(t, head.Root, res.Hash)
+	require.Equal(t, head.Root.Bytes(), crypto.Keccak256(res.Witness))
+
+	res, err = api.GetSubtrieRoot(ctx, "", rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(parent.Number.Int64())), nil)
+	require.NoError(t, err)
+	require.Equal(t, parent.Root, res.Hash)
+	require.Nil(t, res.Witness)
+
+	_, err = api.GetSubtrieRoot(ctx, "0xzz", rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), nil)
+	require.ErrorContains(t, err, "invalid nibble")
+
+	_, err = api.GetSubtrieRoot(ctx, "", rpc.BlockNumberOrHashWithNumber(0), nil)
+	require.ErrorContains(t, err, "requested block is too old")
+}
+
+// TestGetSubtrieRootPrefix - branches below the root, at prefixes of odd and even length. Expected hashes are computed
+// by independent trie implementation: trie of accounts under prefix is extension node of prefix over the branch.
+func TestGetSubtrieRootPrefix(t *testing.T) {
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	alloc := types.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(1e18)}}
+	for i := 0; i < 300; i++ {
+		alloc[libcommon.BytesToAddress(crypto.Keccak256([]byte{byte(i), byte(i >> 8)}))] = types.GenesisAccount{Balance: big.NewInt(int64(i + 1))}
+	}
+	m := mock.MockWithGenesis(t, &types.Genesis{Config: params.TestChainConfig, Alloc: alloc}, key, false)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 1, nil) // block reward of zero coinbase
+	require.NoError(t, err)
+	require.NoError(t, m.InsertChain(chain))
+	api := NewEthAPI(newBaseApiForTest(m), m.DB, nil, nil, nil, 5000000, 1e18, 100_000, false, 1, 128, log.New())
+	ctx := context.Background()
+
+	// state of all accounts, by nibbles of hashed address
+	tx, err := m.DB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	head := rawdb.ReadCurrentHeader(tx)
+	addrs := []libcommon.Address{{}}
+	for addr := range alloc {
+		addrs = append(addrs, addr)
+	}
+	accs := map[string]*accounts.Account{}
+	full := trie.New(trie.EmptyRoot)
+	for _, addr := range addrs {
+		enc, _, err := tx.GetLatest(kv.AccountsDomain, addr[:])
+		require.NoError(t, err)
+		require.NotEmpty(t, enc)
+		acc := &accounts.Account{}
+		require.NoError(t, accounts.DeserialiseV3(acc, enc))
+		hashed := crypto.Keccak256(addr[:])
+		accs[string(hashed)] = acc
+		full.UpdateAccount(hashed, acc)
+	}
+	require.Equal(t, head.Root, full.Hash())
+
+	nibblesOf := func(hashed string) string { return fmt.Sprintf("%x", hashed) }
+	// branchPrefix - some prefix of given length which has branch node: at least 2 keys under it, differing in next nibble
+	branchPrefix := func(length int) string {
+		next := map[string]map[byte]struct{}{}
+		for hashed := range accs {
+			n := nibblesOf(hashed)
+			if next[n[:length]] == nil {
+				next[n[:length]] = map[byte]struct{}{}
+			}
+			next[n[:length]][n[length]] = struct{}{}
+		}
+		prefixes := make([]string, 0, len(next))
+		for p := range next {
+			prefixes = append(prefixes, p)
+		}
+		sort.Strings(prefixes)
+		for _, p := range prefixes {
+			if len(next[p]) >= 2 {
+				return p
+			}
+		}
+		t.Fatalf("no branch at prefix of length %d", length)
+		return ""
+	}
+	compact := func(nibbles string) []byte {
+		res := []byte{0}
+		if len(nibbles)%2 == 1 {
+			res[0], nibbles = 0x10|hexNibble(nibbles[0]), nibbles[1:]
+		}
+		for i := 0; i < len(nibbles); i += 2 {
+			res = append(res, hexNibble(nibbles[i])<<4|hexNibble(nibbles[i+1]))
+		}
+		return res
+	}
+
+	for _, length := range []int{1, 2, 3} {
+		prefix := branchPrefix(length)
+		t.Run(fmt.Sprintf("prefix=%s", prefix), func(t *testing.T) {
+			sub := trie.New(trie.EmptyRoot)
+			for hashed, acc := range accs {
+				if bytes.HasPrefix([]byte(nibblesOf(hashed)), []byte(prefix)) {
+					sub.UpdateAccount([]byte(hashed), acc)
+				}
+			}
+
+			withWitness := true
+			res, err := api.GetSubtrieRoot(ctx, "0x"+prefix, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), &withWitness)
+			require.NoError(t, err)
+			require.Equal(t, res.Hash.Bytes(), crypto.Keccak256(res.Witness))
+			extension, err := rlp.EncodeToBytes([][]byte{compact(prefix), res.Hash.Bytes()})
+			require.NoError(t, err)
+			require.Equal(t, sub.Hash().Bytes(), crypto.Keccak256(extension))
+		})
+	}
+}
+
+func hexNibble(c byte) byte {
+	if c >= 'a' {
+		return c - 'a' + 10
+	}
+	return c - '0'
+}
@@ -34,6 +34,7 @@ import (
 
 type GraphQLAPI interface {
 	GetBlockDetails(ctx context.Context, number rpc.BlockNumber) (map[string]interface{}, error)
+	GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error)
 	GetChainID(ctx context.Context) (*big.Int, error)
 }
 
@@ -78,7 +79,34 @@ func (api *GraphQLAPIImpl) GetBlockDetails(ctx context.Context, blockNumber rpc.
 	if block == nil {
 		return nil, nil
 	}
+	return api.getBlockDetailsImpl(ctx, tx, block, blockNumber)
+}
+
+func (api *GraphQLAPIImpl) GetBlockDetailsByHash(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNumber, err := api._blockReader.HeaderNumber(ctx, tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	if blockNumber == nil {
+		return nil, nil
+	}
+	block, err := api.blockWithSenders(ctx, tx, hash, *blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, nil
+	}
+	return api.getBlockDetailsImpl(ctx, tx, block, rpc.BlockNumber(*blockNumber))
+}
 
+func (api *GraphQLAPIImpl) getBlockDetailsImpl(ctx context.Context, tx kv.TemporalTx, block *types.Block, blockNumber rpc.BlockNumber) (map[string]interface{}, error) {
 	getBlockRes, err := api.delegateGetBlockByNumber(tx, block, blockNumber, false)
 	if err != nil {
 		return nil, err
@@ -69,6 +69,11 @@ var (
 		Value: log.LvlInfo.String(),
 	}
 
+	LogSubsystemVerbosityFlag = cli.StringFlag{
+		Name:  "log.subsystem.verbosity",
+		Usage: "Set the log levels of subsystems, overriding console and disk verbosity. Example: trie=debug,stagedsync=trace",
+	}
+
 	LogBlockDelayFlag = cli.BoolFlag{
 		Name:  "log.delays",
 		Usage: "Enable block delay logging",
@@ -85,5 +90,6 @@ var Flags = []cli.Flag{
 	&LogDirPathFlag,
 	&LogDirPrefixFlag,
 	&LogDirVerbosityFlag,
+	&LogSubsystemVerbosityFlag,
 	&LogBlockDelayFlag,
 }
@@ -75,6 +75,10 @@ func SetupLoggerCtx(filePrefix string, ctx *cli.Context,
 		dirLevel = dirDefaultLevel
 	}
 
+	if err := log.SetSubsystemLvls(ctx.String(LogSubsystemVerbosityFlag.Name)); err != nil {
+		log.Warn("invalid --"+LogSubsystemVerbosityFlag.Name, "err", err)
+	}
+
 	dirPath := ""
 	if !ctx.Bool(LogDirDisableFlag.Name) && dirPath != "/dev/null" {
 		dirPath = ctx.String(LogDirPathFlag.Name)
@@ -137,6 +141,12 @@ func SetupLoggerCmd(filePrefix string, cmd *cobra.Command) log.Logger {
 		dirLevel = log.LvlInfo
 	}
 
+	if subsystemLvls, err := cmd.Flags().GetString(LogSubsystemVerbosityFlag.Name); err == nil {
+		if err := log.SetSubsystemLvls(subsystemLvls); err != nil {
+			log.Warn("invalid --"+LogSubsystemVerbosityFlag.Name, "err", err)
+		}
+	}
+
 	dirPath := ""
 	disableFileLogging, err := cmd.Flags().GetBool(LogDirDisableFlag.Name)
 	if err != nil {
@@ -215,9 +225,9 @@ func initSeparatedLogging(
 	var consoleHandler log.Handler
 
 	if consoleJson {
-		consoleHandler = log.LvlFilterHandler(consoleLevel, log.StreamHandler(os.Stderr, log.JsonFormat()))
+		consoleHandler = log.SubsystemLvlFilterHandler(consoleLevel, log.StreamHandler(os.Stderr, log.JsonFormat()))
 	} else {
-		consoleHandler = log.LvlFilterHandler(consoleLevel, log.StderrHandler)
+		consoleHandler = log.SubsystemLvlFilterHandler(consoleLevel, log.StderrHandler)
 	}
 	logger.SetHandler(consoleHandler)
 
@@ -245,7 +255,7 @@ func initSeparatedLogging(
 	}
 	userLog := log.StreamHandler(lumberjack, dirFormat)
 
-	mux := log.MultiHandler(consoleHandler, log.LvlFilterHandler(dirLevel, userLog))
+	mux := log.MultiHandler(consoleHandler, log.SubsystemLvlFilterHandler(dirLevel, userLog))
 	logger.SetHandler(mux)
 	logger.Info("logging to file system", "log dir", dirPath, "file prefix", filePrefix, "log level", dirLevel, "json", dirJson)
 }
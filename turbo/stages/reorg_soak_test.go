@@ -0,0 +1,178 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stages_test
+
+import (
+	"context"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/dbg"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/rawdb"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/turbo/stages/mock"
+)
+
+// Reorg soak test: random chains with frequent reorgs of random depth are inserted through full pipeline
+// (execution, history, unwind). After each step: state, state history, canonical markers, tx lookup and stages
+// progress are checked against expected values, which are derived from chain description - not from db.
+//
+// Long run: REORG_SOAK_STEPS=1000 REORG_SOAK_SEED=7 go test ./turbo/stages -run TestReorgSoak -v
+var (
+	reorgSoakSteps = dbg.EnvInt("REORG_SOAK_STEPS", 8)
+	reorgSoakSeed  = dbg.EnvInt("REORG_SOAK_SEED", 1)
+)
+
+const (
+	reorgSoakMaxDepth      = 6
+	reorgSoakRecipients    = 8
+	reorgSoakMaxTxsInBlock = 3
+)
+
+var reorgSoakRecipientAddrs = func() (res []libcommon.Address) {
+	for i := 0; i < reorgSoakRecipients; i++ {
+		res = append(res, libcommon.Address{19: byte(i + 1), 0: 0xaa})
+	}
+	return res
+}()
+
+type soakTransfer struct {
+	to     libcommon.Address
+	amount uint64
+}
+
+// soakBlockTransfers - content of block is fully defined by its seed
+func soakBlockTransfers(seed int64) []soakTransfer {
+	rnd := rand.New(rand.NewSource(seed))
+	res := make([]soakTransfer, rnd.Intn(reorgSoakMaxTxsInBlock+1))
+	for i := range res {
+		res[i] = soakTransfer{to: reorgSoakRecipientAddrs[rnd.Intn(reorgSoakRecipients)], amount: 1 + uint64(rnd.Intn(1000))}
+	}
+	return res
+}
+
+func TestReorgSoak(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	key, _ := crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	gspec := &types.Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  types.GenesisAlloc{sender: {Balance: big.NewInt(params.Ether)}},
+	}
+	m := mock.MockWithGenesis(t, gspec, key, false)
+	gen := mock.MockWithGenesis(t, gspec, key, false) // state of genesis, to generate chains from
+	signer := types.LatestSigner(params.TestChainConfig)
+
+	generate := func(blockSeeds []int64) *core.ChainPack {
+		chain, err := core.GenerateChain(gen.ChainConfig, gen.Genesis, gen.Engine, gen.DB, len(blockSeeds), func(i int, b *core.BlockGen) {
+			b.SetCoinbase(libcommon.Address{0: 0xcc, 19: byte(blockSeeds[i])})
+			for _, tr := range soakBlockTransfers(blockSeeds[i]) {
+				txn, err := types.SignTx(types.NewTransaction(b.TxNonce(sender), tr.to, uint256.NewInt(tr.amount), params.TxGas, uint256.NewInt(params.InitialBaseFee), nil), *signer, key)
+				require.NoError(t, err)
+				b.AddTx(txn)
+			}
+		})
+		require.NoError(t, err)
+		return chain
+	}
+
+	rnd := rand.New(rand.NewSource(int64(reorgSoakSeed)))
+	var canonical []int64 // seeds of blocks 1..N
+	nextSeed := int64(1)
+	for step := 0; step < reorgSoakSteps; step++ {
+		depth := rnd.Intn(min(reorgSoakMaxDepth, len(canonical)) + 1)
+		canonical = canonical[:len(canonical)-depth]
+		// PoW: fork becomes canonical only if it's heavier - longer than unwound part
+		for i := depth + 1 + rnd.Intn(reorgSoakMaxDepth); i > 0; i-- {
+			canonical = append(canonical, nextSeed)
+			nextSeed++
+		}
+		t.Logf("step %d: unwind %d blocks, head %d", step, depth, len(canonical))
+
+		chain := generate(canonical)
+		require.NoError(t, m.InsertChain(chain), "step %d", step)
+		checkSoakInvariants(t, m, chain, canonical, sender)
+	}
+}
+
+func checkSoakInvariants(t *testing.T, m *mock.MockSentry, chain *core.ChainPack, blockSeeds []int64, sender libcommon.Address) {
+	t.Helper()
+	tx, err := m.DB.BeginTemporalRo(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+	head := chain.TopBlock.NumberU64()
+
+	for _, stage := range []stages.SyncStage{stages.Headers, stages.Bodies, stages.Senders, stages.Execution, stages.TxLookup} {
+		progress, err := stages.GetStageProgress(tx, stage)
+		require.NoError(t, err)
+		require.Equal(t, head, progress, "stage %s", stage)
+	}
+	require.Equal(t, chain.TopBlock.Hash(), rawdb.ReadHeadBlockHash(tx))
+
+	// canonical markers of current chain, and nothing above head
+	for i, b := range chain.Blocks {
+		h, err := rawdb.ReadCanonicalHash(tx, b.NumberU64())
+		require.NoError(t, err)
+		require.Equal(t, b.Hash(), h, "canonical hash of block %d", i+1)
+		for _, txn := range b.Transactions() {
+			blockNum, _, err := rawdb.ReadTxLookupEntry(tx, txn.Hash())
+			require.NoError(t, err)
+			require.NotNil(t, blockNum, "tx lookup of tx in block %d", b.NumberU64())
+			require.Equal(t, b.NumberU64(), *blockNum)
+		}
+	}
+	h, err := rawdb.ReadCanonicalHash(tx, head+1)
+	require.NoError(t, err)
+	require.Equal(t, libcommon.Hash{}, h, "canonical hash above head")
+
+	// latest state and state after each block
+	balances := map[libcommon.Address]uint64{}
+	var nonce uint64
+	for i, seed := range blockSeeds {
+		for _, tr := range soakBlockTransfers(seed) {
+			balances[tr.to] += tr.amount
+			nonce++
+		}
+		r := m.NewHistoryStateReader(uint64(i)+2, tx) // state at beginning of next block
+		if uint64(i)+1 == head {
+			r = m.NewStateReader(tx)
+		}
+		for _, addr := range reorgSoakRecipientAddrs {
+			a, err := r.ReadAccountData(addr)
+			require.NoError(t, err)
+			var balance uint64
+			if a != nil {
+				balance = a.Balance.Uint64()
+			}
+			require.Equal(t, balances[addr], balance, "balance of %x after block %d", addr, i+1)
+		}
+		a, err := r.ReadAccountData(sender)
+		require.NoError(t, err)
+		require.Equal(t, nonce, a.Nonce, "nonce of sender after block %d", i+1)
+	}
+}
@@ -208,6 +208,7 @@ func StageLoopIteration(ctx context.Context, db kv.RwDB, txc wrap.TxContainer, s
 		}
 	}() // avoid crash because Erigon's core does many things
 
+	ctx = kv.WithSubsystem(ctx, kv.SubsystemSync)
 	externalTx := txc.Tx != nil
 	finishProgressBefore, borProgressBefore, headersProgressBefore, gasUsed, err := stagesHeadersAndFinish(db, txc.Tx)
 	if err != nil {